@@ -0,0 +1,59 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAssemblyAndSysoOwnership(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module asm\n\ngo 1.21\n",
+		"app/main.go":      "package main\n\nimport \"asm/fast\"\n\nfunc main() { fast.Sum() }\n",
+		"other/main.go":    "package main\n\nfunc main() {}\n",
+		"fast/fast.go":     "package fast\n\nfunc Sum()\n",
+		"fast/sum_amd64.s": "TEXT ·Sum(SB),$0\n\tRET\n",
+		"fast/sum_arm64.s": "TEXT ·Sum(SB),$0\n\tRET\n",
+		"fast/rsrc.syso":   "",
+		"fast/notes.txt":   "",
+	})
+	hostAsm := filepath.Join(root, "fast", "sum_"+runtime.GOARCH+".s")
+	if _, err := os.Stat(hostAsm); err != nil {
+		t.Skipf("no assembly fixture for this architecture")
+	}
+	syso := filepath.Join(root, "fast", "rsrc.syso")
+	finder := New(root)
+
+	for _, file := range []string{hostAsm, syso} {
+		if isMine, err := finder.ThisFileIsMine("app/main.go", file, EventWrite); err != nil || !isMine {
+			t.Errorf("expected app/main.go to own %s, got %v, %v", filepath.Base(file), isMine, err)
+		}
+		if isMine, err := finder.ThisFileIsMine("other/main.go", file, EventWrite); err != nil || isMine {
+			t.Errorf("expected other/main.go NOT to own %s, got %v, %v", filepath.Base(file), isMine, err)
+		}
+		if pkg := finder.filePathToPackage[file]; pkg != "asm/fast" {
+			t.Errorf("expected %s to be indexed in asm/fast, got %q", filepath.Base(file), pkg)
+		}
+	}
+
+	// Writing an assembly file refreshes its package
+	finder.mu.RLock()
+	before := finder.packageCache["asm/fast"]
+	finder.mu.RUnlock()
+	if _, err := finder.ThisFileIsMine("app/main.go", hostAsm, EventWrite); err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	finder.mu.RLock()
+	if finder.packageCache["asm/fast"] == before {
+		t.Error("expected the package to be refreshed on an assembly write")
+	}
+	finder.mu.RUnlock()
+
+	if !finder.IsWatchRelevant(hostAsm) || !finder.IsWatchRelevant(syso) {
+		t.Error("expected assembly and syso files to be watch-relevant")
+	}
+	if finder.IsWatchRelevant(filepath.Join(root, "fast", "notes.txt")) {
+		t.Error("expected text files to stay irrelevant")
+	}
+}
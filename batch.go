@@ -0,0 +1,128 @@
+package depfind
+
+import (
+	"errors"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileEvent is a file change given to ApplyEvents
+type FileEvent struct {
+	Path  string `json:"path"`  // absolute, or relative to the primary root
+	Event string `json:"event"` // EventWrite, EventCreate, EventRemove or EventRename
+}
+
+// ApplyEvents updates the cache for a burst of events (git checkout, go
+// generate, save all) at once, instead of once per event through
+// ThisFileIsMine. Only the last event of each path counts, since files are
+// read as they are now, and rewrites with the indexed content are dropped
+// when SetSkipUnchangedWrites is enabled. Each affected package is then
+// reloaded once, and files of new packages cost one full rebuild for the
+// whole batch. Queries and chmod events are ignored, as are files no handler
+// could own (outside the roots or scope, gitignored, tool-managed,
+// vendored). Route the events afterwards with EventQuery, which reads the
+// updated cache without applying them again; removed files answer from
+// their last-known package until they exist again. Errors of the packages that
+// could not be reloaded are joined; the others are applied.
+func (g *GoDepFind) ApplyEvents(events []FileEvent) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+
+	last := make(map[string]string, len(events))
+	for _, ev := range events {
+		if !RequiresCacheUpdate(ev.Event) {
+			continue
+		}
+		path := g.rootPath(ev.Path)
+		if !g.inRoots(path) || !g.inScope(path) || g.gitIgnored(path) || g.inToolDir(path) ||
+			g.isExcluded(path) || g.inNestedModule(path) {
+			continue
+		}
+		last[path] = ev.Event
+	}
+
+	packageDirs := make(map[string][]string) // cached package directory -> its changed files
+	var single []string                      // paths applied one at a time: directories, other files
+	rebuild := false
+	paths := make([]string, 0, len(last))
+	for path := range last {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		event := last[path]
+		g.recordEvent("", path, event)
+		// The batch is not routed, so no handler has seen the new content
+		delete(g.seenContent, path)
+		if event != EventWrite {
+			delete(g.indexedContent, path)
+		}
+		if !sourceExts[filepath.Ext(path)] {
+			single = append(single, path)
+			continue
+		}
+		if event == EventWrite && g.indexedContentUnchanged(path) {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			g.tombstone(path) // so EventQuery still finds its owners
+		}
+		dir := filepath.Dir(path)
+		if g.packageInDir(dir) != "" {
+			packageDirs[dir] = append(packageDirs[dir], path)
+			continue
+		}
+		if pkg, err := build.ImportDir(dir, 0); err == nil && len(pkg.GoFiles) > 0 {
+			rebuild = true // a new package
+			continue
+		}
+		single = append(single, path)
+	}
+
+	if rebuild {
+		if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+			return err
+		}
+		return nil
+	}
+
+	var errs []error
+	dirs := make([]string, 0, len(packageDirs))
+	for dir := range packageDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		pkgPath := g.packageInDir(dir)
+		if !hasGoFiles(dir) {
+			// Every file is gone: drop the package as single removals do
+			single = append(single, packageDirs[dir]...)
+			continue
+		}
+		if err := g.reloadPackage(pkgPath, g.packageCache[pkgPath]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, path := range single {
+		if err := g.updateCacheForFileWithContext(path, last[path], ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasGoFiles reports whether dir still exists with Go files for the host
+func hasGoFiles(dir string) bool {
+	if _, err := os.Stat(dir); err != nil {
+		return false
+	}
+	var noGo *build.NoGoError
+	_, err := build.ImportDir(dir, 0)
+	return !errors.As(err, &noGo)
+}
@@ -0,0 +1,114 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyEvents(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module ae\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"ae/lib\"\n\nfunc main() { lib.A() }\n",
+		"cli/main.go":  "package main\n\nfunc main() {}\n",
+		"lib/a.go":     "package lib\n\nfunc A() {}\n",
+		"lib/b.go":     "package lib\n\nfunc B() {}\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+		"docs/x.md":    "# docs\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	aFile := filepath.Join(root, "lib", "a.go")
+	bFile := filepath.Join(root, "lib", "b.go")
+	cFile := filepath.Join(root, "lib", "c.go")
+	utilFile := filepath.Join(root, "util", "util.go")
+
+	// A burst on one package: several writes, a new file, a removed one
+	if err := os.WriteFile(aFile, []byte("package lib\n\nimport \"ae/util\"\n\nfunc A() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cFile, []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(bFile); err != nil {
+		t.Fatal(err)
+	}
+	err := finder.ApplyEvents([]FileEvent{
+		{Path: aFile, Event: EventWrite},
+		{Path: "lib/a.go", Event: EventWrite},
+		{Path: cFile, Event: EventCreate},
+		{Path: cFile, Event: EventWrite},
+		{Path: bFile, Event: EventRemove},
+		{Path: aFile, Event: EventChmod},
+		{Path: filepath.Join(root, "docs", "x.md"), Event: EventWrite},
+	})
+	if err != nil {
+		t.Fatalf("ApplyEvents: %v", err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", utilFile, EventQuery); err != nil || !isMine {
+		t.Errorf("expected util.go to be owned through the new import, got %v, %v", isMine, err)
+	}
+	finder.mu.RLock()
+	indexedC, indexedB := finder.filePathToPackage[cFile], finder.filePathToPackage[bFile]
+	finder.mu.RUnlock()
+	if indexedC != "ae/lib" || indexedB != "" {
+		t.Errorf("expected c.go indexed and b.go dropped, got %q and %q", indexedC, indexedB)
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 1 {
+		t.Errorf("expected the burst without a full rebuild, got %d full rebuilds", rebuilds)
+	}
+
+	// The removal is routed afterwards from the last-known package
+	for _, tc := range []struct {
+		handler string
+		want    bool
+	}{{"app/main.go", true}, {"cli/main.go", false}} {
+		if isMine, err := finder.ThisFileIsMine(tc.handler, bFile, EventQuery); err != nil || isMine != tc.want {
+			t.Errorf("%s: expected the removed b.go owned = %v, got %v, %v", tc.handler, tc.want, isMine, err)
+		}
+	}
+
+	// Files of new packages cost a single rebuild
+	for _, name := range []string{"feature/f.go", "feature/g.go", "other/o.go"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package "+filepath.Base(filepath.Dir(path))+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = finder.ApplyEvents([]FileEvent{
+		{Path: "feature/f.go", Event: EventCreate},
+		{Path: "feature/g.go", Event: EventCreate},
+		{Path: "other/o.go", Event: EventCreate},
+	})
+	if err != nil {
+		t.Fatalf("ApplyEvents: %v", err)
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 2 {
+		t.Errorf("expected one rebuild for the new packages, got %d full rebuilds", rebuilds)
+	}
+	finder.mu.RLock()
+	indexed := finder.filePathToPackage[filepath.Join(root, "other", "o.go")]
+	finder.mu.RUnlock()
+	if indexed != "ae/other" {
+		t.Errorf("expected other/o.go indexed, got %q", indexed)
+	}
+
+	// A package losing all its files is dropped
+	if err := os.RemoveAll(filepath.Join(root, "util")); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.ApplyEvents([]FileEvent{{Path: utilFile, Event: EventRemove}}); err != nil {
+		t.Fatalf("ApplyEvents: %v", err)
+	}
+	finder.mu.RLock()
+	_, cached := finder.packageCache["ae/util"]
+	finder.mu.RUnlock()
+	if cached {
+		t.Error("expected ae/util to be dropped")
+	}
+}
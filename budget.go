@@ -0,0 +1,122 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Completeness tells how much of the ownership decision a budgeted answer covers
+type Completeness int
+
+const (
+	// CompletenessNone: nothing could be decided in the budget (the finder was busy)
+	CompletenessNone Completeness = iota
+	// CompletenessDirect: only the direct check ran (the file is the handler's
+	// main file, lies in its directory or in a package the main file imports);
+	// transitive imports were not checked
+	CompletenessDirect
+	// CompletenessFull: the answer is the one ThisFileIsMine returns
+	CompletenessFull
+)
+
+// String returns the completeness name
+func (c Completeness) String() string {
+	switch c {
+	case CompletenessDirect:
+		return "direct"
+	case CompletenessFull:
+		return "full"
+	default:
+		return "none"
+	}
+}
+
+// ThisFileIsMineWithin is ThisFileIsMine for interactive callers: it returns
+// the best answer computable within budget. When the full decision (which may
+// need to list packages and walk the import graph) is not ready in time, the
+// direct check answers instead, flagged with its Completeness, and onFull
+// (if not nil) later receives the full answer from another goroutine. onFull
+// is never called when the returned answer is already complete.
+func (g *GoDepFind) ThisFileIsMineWithin(budget time.Duration, mainInputFileRelativePath, fileAbsPath, event string, onFull func(isMine bool, err error)) (bool, Completeness, error) {
+	// The direct check is computed before the full decision takes the lock
+	direct, completeness := g.directOwnership(mainInputFileRelativePath, fileAbsPath)
+
+	type answer struct {
+		isMine bool
+		err    error
+	}
+	result := make(chan answer)
+	late := make(chan struct{})
+	go func() {
+		isMine, err := g.ThisFileIsMine(mainInputFileRelativePath, fileAbsPath, event)
+		select {
+		case result <- answer{isMine, err}:
+		case <-late:
+			if onFull != nil {
+				onFull(isMine, err)
+			}
+		}
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case a := <-result:
+		return a.isMine, CompletenessFull, a.err
+	case <-timer.C:
+		close(late)
+		return direct, completeness, nil
+	}
+}
+
+// directOwnership reports, without touching the cache, whether fileAbsPath is
+// the handler's main file, lies in its directory or belongs to a cached
+// package the main file imports directly. It does not wait for a busy
+// finder: CompletenessNone is returned instead.
+func (g *GoDepFind) directOwnership(mainInputFileRelativePath, fileAbsPath string) (bool, Completeness) {
+	if !g.mu.TryRLock() {
+		return false, CompletenessNone
+	}
+	defer g.mu.RUnlock()
+
+	if fileAbsPath == "" || mainInputFileRelativePath == "" {
+		return false, CompletenessNone
+	}
+	fileAbs := g.rootPath(fileAbsPath)
+	mainAbs := g.rootPath(mainInputFileRelativePath)
+	if fileAbs == mainAbs {
+		return true, CompletenessDirect
+	}
+	if filepath.Ext(fileAbs) != ".go" {
+		return false, CompletenessDirect
+	}
+	if filepath.Dir(fileAbs) == filepath.Dir(mainAbs) {
+		return true, CompletenessDirect
+	}
+
+	pkgPath, indexed := g.filePathToPackage[fileAbs]
+	if !indexed {
+		return false, CompletenessDirect
+	}
+	for _, imp := range g.directImports(mainAbs) {
+		if g.resolveImport(imp, filepath.Dir(mainAbs)) == pkgPath {
+			return true, CompletenessDirect
+		}
+	}
+	return false, CompletenessDirect
+}
+
+// directImports returns the imports of a handler main file as written, from
+// its closure when the file did not change since, parsed otherwise
+func (g *GoDepFind) directImports(handlerAbsPath string) []string {
+	info, err := os.Stat(handlerAbsPath)
+	if err != nil {
+		return nil
+	}
+	if entry := g.closures[handlerAbsPath]; entry != nil && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.imports
+	}
+	imports, _ := g.parseFileImports(handlerAbsPath)
+	return imports
+}
@@ -0,0 +1,101 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThisFileIsMineWithin(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module budget\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"budget/lib\"\n\nfunc main() { lib.Do() }\n",
+		"app/util.go": "package main\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+
+	// Listing is blocked, so only the direct check fits in the budget
+	finder.listMu.Lock()
+	full := make(chan bool, 1)
+	isMine, completeness, err := finder.ThisFileIsMineWithin(20*time.Millisecond, "app/main.go", "lib/lib.go", EventWrite,
+		func(isMine bool, err error) {
+			if err != nil {
+				t.Errorf("full answer failed: %v", err)
+			}
+			full <- isMine
+		})
+	if err != nil || isMine || completeness != CompletenessDirect {
+		t.Errorf("expected a direct partial answer, got %v, %s, %v", isMine, completeness, err)
+	}
+	finder.listMu.Unlock()
+
+	select {
+	case isMine := <-full:
+		if !isMine {
+			t.Error("expected the full answer to claim the imported file")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("full answer was never delivered")
+	}
+
+	// Same directory as the handler main is decided directly
+	cold := New(root)
+	cold.listMu.Lock()
+	isMine, completeness, _ = cold.ThisFileIsMineWithin(10*time.Millisecond, "app/main.go", "app/util.go", EventWrite, nil)
+	cold.listMu.Unlock()
+	if !isMine || completeness != CompletenessDirect {
+		t.Errorf("expected a direct claim for a file next to main, got %v, %s", isMine, completeness)
+	}
+
+	// A warm cache answers fully within the budget and never calls back
+	isMine, completeness, err = finder.ThisFileIsMineWithin(30*time.Second, "app/main.go", "lib/lib.go", EventWrite,
+		func(bool, error) { t.Error("callback must not run for a full answer") })
+	if err != nil || !isMine || completeness != CompletenessFull {
+		t.Errorf("expected a full answer, got %v, %s, %v", isMine, completeness, err)
+	}
+}
+
+func TestDirectOwnershipImports(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module budget\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"budget/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nimport \"budget/util\"\n\nfunc Do() { util.U() }\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+	})
+	finder := New(root)
+	libFile := filepath.Join(root, "lib", "lib.go")
+	utilFile := filepath.Join(root, "util", "util.go")
+
+	// Before the cache is built nothing maps the file to a package
+	if isMine, completeness := finder.directOwnership("app/main.go", libFile); isMine || completeness != CompletenessDirect {
+		t.Errorf("expected no direct claim on a cold cache, got %v, %s", isMine, completeness)
+	}
+
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if isMine, completeness := finder.directOwnership("app/main.go", libFile); !isMine || completeness != CompletenessDirect {
+		t.Errorf("expected a direct claim on a package main imports, got %v, %s", isMine, completeness)
+	}
+	if isMine, _ := finder.directOwnership("app/main.go", utilFile); isMine {
+		t.Error("expected a transitive import not to be claimed by the direct check")
+	}
+
+	// The imports are read again once the main file changes
+	mainFile := filepath.Join(root, "app", "main.go")
+	if err := os.WriteFile(mainFile, []byte("package main\n\nimport \"budget/util\"\n\nfunc main() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(mainFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if isMine, _ := finder.directOwnership("app/main.go", utilFile); !isMine {
+		t.Error("expected the new direct import to be claimed")
+	}
+	if isMine, _ := finder.directOwnership("app/main.go", libFile); isMine {
+		t.Error("expected the dropped import not to be claimed")
+	}
+}
@@ -0,0 +1,108 @@
+package depfind
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// BuildStatus tells whether a package currently builds, as far as the finder
+// can tell without compiling it: its last import succeeded and every Go file
+// it compiles parses
+type BuildStatus string
+
+const (
+	BuildUnknown BuildStatus = ""       // not a loaded package of the roots
+	BuildOK      BuildStatus = "ok"     // loaded and every compiled file parses
+	BuildBroken  BuildStatus = "broken" // the last import failed or a compiled file has a syntax error
+)
+
+// syntaxCheck is the outcome of fully parsing a file at a modification time
+// and size
+type syntaxCheck struct {
+	modTime int64
+	size    int64
+	err     string
+}
+
+// PackageBuildStatus returns whether a package of the roots currently builds
+// and, when broken, the first error found, see BuildStatus. Decide reports
+// the same for the package of an owned file.
+func (g *GoDepFind) PackageBuildStatus(pkgPath string) (BuildStatus, string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil && !isPartialFailure(err) {
+		return BuildUnknown, "", err
+	}
+	status, detail := g.packageBuildStatus(pkgPath)
+	return status, detail, nil
+}
+
+// packageBuildStatus checks the last import attempt of a package, then
+// parses the files it compiles; parses are memoized by modification time and
+// size so routing a burst of events does not reparse unchanged files
+func (g *GoDepFind) packageBuildStatus(pkgPath string) (BuildStatus, string) {
+	if pkgPath == "" {
+		return BuildUnknown, ""
+	}
+	if reason, failed := g.loadErrors[pkgPath]; failed {
+		return BuildBroken, reason
+	}
+	g.listMu.Lock()
+	skipped, isSkipped := g.skipped[pkgPath]
+	g.listMu.Unlock()
+	if isSkipped {
+		return BuildBroken, skipped.Reason
+	}
+
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return BuildUnknown, ""
+	}
+	for _, list := range [][]string{pkg.GoFiles, pkg.CgoFiles} {
+		for _, name := range list {
+			if err := g.syntaxError(filepath.Join(pkg.Dir, name)); err != "" {
+				return BuildBroken, err
+			}
+		}
+	}
+	return BuildOK, ""
+}
+
+// recordLoadError records the outcome of reloading a package, nil clearing
+// a previous failure
+func (g *GoDepFind) recordLoadError(pkgPath string, err error) {
+	if err == nil {
+		delete(g.loadErrors, pkgPath)
+		return
+	}
+	if g.loadErrors == nil {
+		g.loadErrors = make(map[string]string)
+	}
+	g.loadErrors[pkgPath] = err.Error()
+}
+
+// syntaxError returns the first syntax error of a Go file, or "". A file
+// that cannot be read is reported as an error too: go build would fail on it.
+func (g *GoDepFind) syntaxError(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err.Error()
+	}
+	check := syntaxCheck{modTime: info.ModTime().UnixNano(), size: info.Size()}
+	if last, ok := g.syntaxChecks[path]; ok && last.modTime == check.modTime && last.size == check.size {
+		return last.err
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution); err != nil {
+		check.err = fmt.Sprint(err)
+	}
+	if g.syntaxChecks == nil {
+		g.syntaxChecks = make(map[string]syntaxCheck)
+	}
+	g.syntaxChecks[path] = check
+	return check.err
+}
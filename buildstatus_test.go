@@ -0,0 +1,66 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecisionBuildStatus(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module status\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"status/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() { helper() }\n",
+		"lib/other.go": "package lib\n\nfunc helper() {}\n",
+	})
+	libFile := filepath.Join(root, "lib/lib.go")
+	otherFile := filepath.Join(root, "lib/other.go")
+	finder := New(root)
+
+	decide := func(step string, want BuildStatus) Decision {
+		t.Helper()
+		decision, err := finder.Decide("app/main.go", libFile, EventWrite)
+		if err != nil || !decision.Owned {
+			t.Fatalf("%s: %+v, %v", step, decision, err)
+		}
+		if decision.Package != "status/lib" || decision.Build != want {
+			t.Errorf("%s: got package %q status %q (%s), want status/lib %q", step, decision.Package, decision.Build, decision.BuildError, want)
+		}
+		return decision
+	}
+	decide("clean package", BuildOK)
+
+	// A syntax error in another file of the package breaks it
+	if err := os.WriteFile(otherFile, []byte("package lib\n\nfunc helper() {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if decision := decide("syntax error", BuildBroken); !strings.Contains(decision.BuildError, "other.go") {
+		t.Errorf("expected the broken file in the error, got %q", decision.BuildError)
+	}
+
+	// So does a failed import (two package names in one directory), which
+	// the routing reports as a cache update error
+	if err := os.WriteFile(otherFile, []byte("package other\n\nfunc helper() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.Decide("app/main.go", libFile, EventWrite); err == nil {
+		t.Fatal("expected the failed reload to be reported")
+	}
+	if status, _, err := finder.PackageBuildStatus("status/lib"); err != nil || status != BuildBroken {
+		t.Errorf("PackageBuildStatus while broken: %q, %v", status, err)
+	}
+
+	// Fixed: the next reload clears it
+	if err := os.WriteFile(otherFile, []byte("package lib\n\nfunc helper() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	decide("fixed", BuildOK)
+
+	if status, _, err := finder.PackageBuildStatus("status/missing"); err != nil || status != BuildUnknown {
+		t.Errorf("unknown package: %q, %v", status, err)
+	}
+	if decision, _ := finder.Decide("app/main.go", filepath.Join(root, "README.md"), EventWrite); decision.Package != "" || decision.Build != BuildUnknown {
+		t.Errorf("a file in no package should carry no status: %+v", decision)
+	}
+}
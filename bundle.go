@@ -0,0 +1,214 @@
+package depfind
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// BundleVersion is the version of the Bundle layout, bumped on incompatible
+// changes
+const BundleVersion = 1
+
+// Bundle gathers everything needed to investigate an unexpected ownership
+// answer, meant to be attached to bug reports as JSON, see DebugBundle
+type Bundle struct {
+	Version     int               `json:"version"`
+	Handler     string            `json:"handler"` // handler main file as given
+	Path        string            `json:"path"`    // file as given
+	Paths       BundlePaths       `json:"paths"`
+	Cache       BundleCache       `json:"cache"`
+	Steps       []BundleStep      `json:"steps"`                 // resolution checks in the order ThisFileIsMine applies them
+	Explanation *Explanation      `json:"explanation,omitempty"` // final answer, see ExplainOwnership
+	Error       string            `json:"error,omitempty"`       // error met while resolving, if any
+	Warnings    []Warning         `json:"warnings,omitempty"`
+	Environment BundleEnvironment `json:"environment"`
+}
+
+// BundlePaths are the inputs of a Bundle after normalization
+type BundlePaths struct {
+	HandlerAbs string `json:"handler_abs"`
+	FileAbs    string `json:"file_abs"`
+	Root       string `json:"root,omitempty"`     // root holding the file, "" when outside every root
+	Relative   string `json:"relative,omitempty"` // file relative to Root
+}
+
+// BundleCache describes the state of the cache when the bundle was taken
+type BundleCache struct {
+	WasInitialized bool   `json:"was_initialized"` // built before DebugBundle was called
+	Packages       int    `json:"packages"`
+	MainPackages   int    `json:"main_packages"`
+	IndexedFiles   int    `json:"indexed_files"`
+	IndexedNames   int    `json:"indexed_names"` // distinct file names usable by the filename fallback
+	Closures       int    `json:"closures"`      // cached handler import closures
+	GraphVersion   uint64 `json:"graph_version"`
+	Skipped        int    `json:"skipped"` // packages left out of the graph, see SkippedPackages
+}
+
+// BundleStep is one check of the resolution of a file to a handler
+type BundleStep struct {
+	Check  string `json:"check"`
+	Result bool   `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// BundleEnvironment is the toolchain and finder configuration
+type BundleEnvironment struct {
+	GoVersion        string   `json:"go_version"`
+	GOOS             string   `json:"goos"`
+	GOARCH           string   `json:"goarch"`
+	GOROOT           string   `json:"goroot"`
+	GOPATH           string   `json:"gopath"`
+	GOFLAGS          string   `json:"goflags,omitempty"`
+	GOWORK           string   `json:"gowork,omitempty"`
+	GO111MODULE      string   `json:"go111module,omitempty"`
+	ToolchainError   string   `json:"toolchain_error,omitempty"`
+	Roots            []string `json:"roots"`
+	Scope            string   `json:"scope,omitempty"`
+	ScopePatterns    []string `json:"scope_patterns,omitempty"` // see SetScope
+	TestImports      bool     `json:"test_imports"`
+	MaxDepth         int      `json:"max_depth"`
+	FilenameFallback int      `json:"filename_fallback"` // see FilenameFallback
+	RespectGitignore bool     `json:"respect_gitignore"`
+	SkipUnchanged    bool     `json:"skip_unchanged"`
+}
+
+// JSON returns the bundle as indented JSON
+func (b *Bundle) JSON() ([]byte, error) {
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// DebugBundle collects the inputs, normalized paths, cache state, every
+// resolution check, the final explanation and the environment of an
+// ownership question into one Bundle, without routing an event. Errors met
+// while resolving are recorded in the bundle rather than returned.
+func (g *GoDepFind) DebugBundle(mainInputFileRelativePath, path string) (*Bundle, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.debugBundle(mainInputFileRelativePath, path)
+}
+
+func (g *GoDepFind) debugBundle(mainInputFileRelativePath, path string) (*Bundle, error) {
+	if mainInputFileRelativePath == "" || path == "" {
+		return nil, fmt.Errorf("handler and file paths cannot be empty")
+	}
+	if g.closed {
+		return nil, ErrClosed
+	}
+
+	b := &Bundle{
+		Version:     BundleVersion,
+		Handler:     mainInputFileRelativePath,
+		Path:        path,
+		Environment: g.bundleEnvironment(),
+	}
+	b.Cache.WasInitialized = g.cachedModule
+	b.Paths.HandlerAbs = g.rootPath(mainInputFileRelativePath)
+	b.Paths.FileAbs = g.rootPath(path)
+	if rel, ok := g.relToRoot(b.Paths.FileAbs); ok {
+		b.Paths.Relative = filepath.ToSlash(rel)
+		b.Paths.Root = strings.TrimSuffix(b.Paths.FileAbs, string(filepath.Separator)+rel)
+	}
+
+	if err := g.ensureCacheInitialized(); err != nil && !isPartialFailure(err) {
+		b.Error = err.Error()
+	}
+	b.Steps = g.bundleSteps(mainInputFileRelativePath, b.Paths.HandlerAbs, b.Paths.FileAbs)
+	if b.Error == "" {
+		exp, err := g.explainOwnership(mainInputFileRelativePath, path)
+		if err != nil {
+			b.Error = err.Error()
+		}
+		b.Explanation = exp
+	}
+
+	b.Cache.Packages = len(g.packageCache)
+	b.Cache.MainPackages = len(g.mainPackages)
+	b.Cache.IndexedFiles = len(g.filePathToPackage)
+	b.Cache.IndexedNames = len(g.fileToPackages)
+	b.Cache.Closures = len(g.closures)
+	b.Cache.GraphVersion = g.graphVersion
+	g.listMu.Lock()
+	b.Cache.Skipped = len(g.skipped)
+	b.Warnings = append([]Warning(nil), g.warnings...)
+	g.listMu.Unlock()
+	return b, nil
+}
+
+// bundleSteps evaluates each ownership check independently, so a bundle
+// shows every condition and not only the one that decided
+func (g *GoDepFind) bundleSteps(mainInputFileRelativePath, handlerAbs, fileAbs string) []BundleStep {
+	var steps []BundleStep
+	step := func(check string, result bool, detail string) {
+		steps = append(steps, BundleStep{Check: check, Result: result, Detail: detail})
+	}
+
+	_, err := os.Stat(handlerAbs)
+	step("handler-exists", err == nil, errorDetail(err))
+	info, err := os.Stat(fileAbs)
+	detail := errorDetail(err)
+	if err == nil {
+		detail = fmt.Sprintf("%d bytes", info.Size())
+	}
+	step("file-exists", err == nil, detail)
+	step("is-handler-main", fileAbs == handlerAbs, "")
+	step("in-roots", g.inRoots(fileAbs), strings.Join(g.rootDirs, ", "))
+	step("in-scope", g.inScope(fileAbs), g.scope)
+	step("gitignored", g.gitIgnored(fileAbs), "")
+	step("tool-dir", g.inToolDir(fileAbs), "")
+	step("excluded-tree", g.isExcluded(fileAbs), "")
+	step("nested-module", g.inNestedModule(fileAbs), "")
+
+	handlers, forced := g.forcedHandlers(fileAbs)
+	step("forced", forced, strings.Join(handlers, ", "))
+	step("test-only", g.testOnlyFiles[fileAbs], "")
+	tags, _ := g.handlerContext(mainInputFileRelativePath)
+	step("excluded-by-tags", g.excludedByHandlerTags(mainInputFileRelativePath, fileAbs), strings.Join(sortedKeys(tags), ","))
+	step("handler-subtree", g.inHandlerSubtree(mainInputFileRelativePath, fileAbs), "")
+
+	pkgPath, indexed := g.filePathToPackage[fileAbs]
+	step("path-index", indexed, pkgPath)
+	variant := g.variantPackage(fileAbs)
+	step("build-variant", variant != "", variant)
+	byName := g.fileToPackages[filepath.Base(fileAbs)]
+	step("filename-index", len(byName) > 0, strings.Join(byName, ", "))
+	return steps
+}
+
+// bundleEnvironment describes the toolchain and the finder settings
+func (g *GoDepFind) bundleEnvironment() BundleEnvironment {
+	env := BundleEnvironment{
+		GoVersion:        runtime.Version(),
+		GOOS:             build.Default.GOOS,
+		GOARCH:           build.Default.GOARCH,
+		GOROOT:           build.Default.GOROOT,
+		GOPATH:           build.Default.GOPATH,
+		GOFLAGS:          os.Getenv("GOFLAGS"),
+		GOWORK:           os.Getenv("GOWORK"),
+		GO111MODULE:      os.Getenv("GO111MODULE"),
+		Roots:            append([]string(nil), g.rootDirs...),
+		Scope:            g.scope,
+		ScopePatterns:    append([]string(nil), g.scopePatterns...),
+		TestImports:      g.testImports,
+		MaxDepth:         g.maxDepth,
+		FilenameFallback: int(g.filenameFallback),
+		RespectGitignore: g.respectGitignore,
+		SkipUnchanged:    g.skipUnchanged,
+	}
+	if g.toolchainErr != nil {
+		env.ToolchainError = g.toolchainErr.Error()
+	}
+	return env
+}
+
+// errorDetail returns the message of err, or ""
+func errorDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,107 @@
+package depfind
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugBundle(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module bd\n\ngo 1.21\n",
+		"server/main.go": "package main\n\nimport \"bd/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":    "package main\n\nfunc main() {}\n",
+		"db/db.go":       "package db\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+	dbFile := filepath.Join(root, "db", "db.go")
+
+	bundle, err := finder.DebugBundle("cli/main.go", dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Version != BundleVersion || bundle.Cache.WasInitialized {
+		t.Errorf("unexpected header: version %d, was initialized %v", bundle.Version, bundle.Cache.WasInitialized)
+	}
+	if bundle.Paths.Root != root || bundle.Paths.Relative != "db/db.go" {
+		t.Errorf("unexpected paths: %+v", bundle.Paths)
+	}
+	if bundle.Cache.Packages == 0 || bundle.Cache.MainPackages != 2 || bundle.Cache.IndexedFiles == 0 {
+		t.Errorf("unexpected cache stats: %+v", bundle.Cache)
+	}
+	if bundle.Explanation == nil || bundle.Explanation.Owned || bundle.Explanation.Reason != ReasonOwnedByOther {
+		t.Errorf("unexpected explanation: %+v", bundle.Explanation)
+	}
+	steps := make(map[string]BundleStep)
+	for _, step := range bundle.Steps {
+		steps[step.Check] = step
+	}
+	if step := steps["path-index"]; !step.Result || step.Detail != "bd/db" {
+		t.Errorf("unexpected path-index step: %+v", step)
+	}
+	if step := steps["in-roots"]; !step.Result {
+		t.Errorf("unexpected in-roots step: %+v", step)
+	}
+	if bundle.Environment.GoVersion == "" || len(bundle.Environment.Roots) != 1 {
+		t.Errorf("unexpected environment: %+v", bundle.Environment)
+	}
+
+	data, err := bundle.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("bundle is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"paths", "cache", "steps", "explanation", "environment"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("bundle JSON lacks %q", key)
+		}
+	}
+
+	// Resolution errors are recorded, not returned
+	bundle, err = finder.DebugBundle("missing/main.go", dbFile)
+	if err != nil || !strings.Contains(bundle.Error, "does not exist") {
+		t.Errorf("expected the missing handler to be recorded, got %q, %v", bundle.Error, err)
+	}
+
+	finder.Close()
+	if _, err := finder.DebugBundle("cli/main.go", dbFile); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestDebugThisFileIsMineLogsBundle(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module dl\n\ngo 1.21\n",
+		"server/main.go": "package main\n\nimport \"dl/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":    "package main\n\nfunc main() {}\n",
+		"db/db.go":       "package db\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+	var logged []string
+	finder.SetLogger(func(message ...any) {
+		for _, m := range message {
+			if s, ok := m.(string); ok && strings.HasPrefix(s, "{") {
+				logged = append(logged, s)
+			}
+		}
+	})
+	dbFile := filepath.Join(root, "db", "db.go")
+
+	if isMine, err := finder.DebugThisFileIsMine("server/main.go", dbFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected server to own db.go, got %v, %v", isMine, err)
+	}
+	if len(logged) != 0 {
+		t.Errorf("expected no bundle for an owned file, got %d", len(logged))
+	}
+	if isMine, err := finder.DebugThisFileIsMine("cli/main.go", dbFile, EventWrite); err != nil || isMine {
+		t.Fatalf("expected cli not to own db.go, got %v, %v", isMine, err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], `"reason": "owned-by-other"`) {
+		t.Errorf("expected one bundle naming the reason, got %v", logged)
+	}
+}
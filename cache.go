@@ -3,7 +3,10 @@ package depfind
 import (
 	"fmt"
 	"go/build"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // updateCacheForFile updates cache based on file events
@@ -14,16 +17,16 @@ func (g *GoDepFind) updateCacheForFile(filePath, event string) error {
 	}
 
 	switch event {
-	case "write":
+	case EventWrite:
 		// Refresh the package to update imports without breaking incoming dependencies
 		return g.refreshPackageCache(filePath)
-	case "create":
+	case EventCreate:
 		// Re-scan dependencies of the parent package + update fileToPackage mapping
 		return g.handleFileCreate(filePath)
-	case "remove":
+	case EventRemove:
 		// Invalidate dependencies pointing to that file + remove from fileToPackage
 		return g.handleFileRemove(filePath)
-	case "rename":
+	case EventRename:
 		// Treat as remove + create sequence
 		if err := g.handleFileRemove(filePath); err != nil {
 			return err
@@ -36,11 +39,18 @@ func (g *GoDepFind) updateCacheForFile(filePath, event string) error {
 
 // ensureCacheInitialized initializes cache if not already done (lazy loading)
 func (g *GoDepFind) ensureCacheInitialized() error {
+	if g.closed {
+		return ErrClosed
+	}
 	if !g.cachedModule {
 		err := g.rebuildCache()
 		// Mark as initialized even if it fails to avoid constant retries on every event
 		g.cachedModule = true
+		if isPartialFailure(err) {
+			return nil // each failed package was already reported as skipped
+		}
 		if err != nil {
+			g.warn(Warning{Kind: WarnCacheRebuild, Message: err.Error()})
 			// Initialize empty maps to ensure lookups don't panic
 			if g.packageCache == nil {
 				g.packageCache = make(map[string]*build.Package)
@@ -60,8 +70,9 @@ func (g *GoDepFind) ensureCacheInitialized() error {
 			// Do not return the error - fallback mechanism will handle the absence of cache
 			return nil
 		}
+		return nil
 	}
-	return nil
+	return g.settleLazy()
 }
 
 // invalidatePackageCache invalidates cache for a specific package
@@ -75,6 +86,7 @@ func (g *GoDepFind) invalidatePackageCache(filePath string) error {
 	// Remove from caches
 	delete(g.packageCache, pkg)
 	delete(g.dependencyGraph, pkg)
+	g.graphChanged()
 
 	// Also remove from reverseDeps (packages I import shouldn't point to me anymore)
 	// Note: We intentionally DO NOT remove from other packages' dependency lists (incoming edges)
@@ -108,19 +120,29 @@ func (g *GoDepFind) refreshPackageCache(filePath string) error {
 		// Should not happen if findPackage... returned it, but safe fallback
 		return g.handleFileCreate(filePath)
 	}
-	pkgDir := pkg.Dir
+	return g.reloadPackage(targetPkgPath, pkg)
+}
 
+// reloadPackage re-imports a cached package from its directory and updates
+// its outgoing edges and file indices in place
+func (g *GoDepFind) reloadPackage(targetPkgPath string, pkg *build.Package) error {
 	// 3. Re-import the package to get updated imports
 	// We use build.ImportDir similar to getPackages
-	newPkg, err := g.importPackageFromDir(pkgDir)
+	newPkg, err := g.importPackageFromDir(pkg.Dir)
 	if err != nil {
 		// If we can't import it (e.g. syntax error), we shouldn't break the graph.
 		// We can just keep the old one or warn. For now, we abort upgrade.
+		g.recordLoadError(targetPkgPath, err)
 		return fmt.Errorf("failed to refresh package %s: %w", targetPkgPath, err)
 	}
 
-	// 4. Update Package Cache
+	// 4. Update Package Cache, and the file indices: a file renamed inside
+	// the package is known under its new name right away
+	g.recordLoadError(targetPkgPath, nil)
 	g.packageCache[targetPkgPath] = newPkg
+	g.syncFileIndex(targetPkgPath, pkg, newPkg)
+	g.unstampPackage(pkg)
+	g.stampPackage(newPkg)
 
 	// 5. Update Dependency Graph (Outgoing edges)
 	oldImports := g.dependencyGraph[targetPkgPath]
@@ -129,7 +151,9 @@ func (g *GoDepFind) refreshPackageCache(filePath string) error {
 		newImports = append(newImports, newPkg.TestImports...)
 		newImports = append(newImports, newPkg.XTestImports...)
 	}
+	newImports = append(newImports, g.virtualTargets(targetPkgPath)...)
 	g.dependencyGraph[targetPkgPath] = newImports
+	g.packageImportsChanged(targetPkgPath, oldImports, newImports)
 
 	// 6. Update Reverse Dependencies (incoming edges to MY imports)
 	// We need to update the reverseDeps of the packages I import.
@@ -165,8 +189,12 @@ func (g *GoDepFind) refreshPackageCache(filePath string) error {
 
 // importPackageFromDir matches logic in getPackages for a single directory
 func (g *GoDepFind) importPackageFromDir(dir string) (*build.Package, error) {
-	// Try ImportDir
-	return build.ImportDir(dir, 0)
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	g.resolveLocalImports(pkg)
+	return pkg, nil
 }
 
 func (g *GoDepFind) addReverseDep(target, dependent string) {
@@ -199,11 +227,21 @@ func (g *GoDepFind) handleFileCreate(filePath string) error {
 	if err != nil {
 		return err
 	}
+	// A file new to a cached package (or back after a remove) is not indexed
+	// yet: it belongs to the package of its directory
+	if pkg == "" {
+		if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
+			pkg = g.packageInDir(filepath.Dir(g.rootPath(filePath)))
+		}
+	}
 
 	if pkg != "" {
 		// Update path mapping
 		if absPath, err := filepath.Abs(filePath); err == nil {
 			g.filePathToPackage[absPath] = pkg
+			if strings.HasSuffix(absPath, "_test.go") {
+				g.testOnlyFiles[absPath] = true
+			}
 		}
 
 		// Add to filename mapping (don't overwrite, append if not exists)
@@ -212,8 +250,22 @@ func (g *GoDepFind) handleFileCreate(filePath string) error {
 			g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkg)
 		}
 
+		// A file created (or saved over) in a cached package changes it now
+		if cached := g.packageCache[pkg]; cached != nil && hasGoFiles(cached.Dir) {
+			return g.reloadPackage(pkg, cached)
+		}
 		return g.invalidatePackageCache(filePath)
 	}
+
+	// Unknown location: it may be a renamed package directory
+	dir := filepath.Dir(filePath)
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		dir = filePath
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		_, err = g.detectPackageRename(absDir)
+		return err
+	}
 	return nil
 }
 
@@ -222,22 +274,124 @@ func (g *GoDepFind) handleFileRemove(filePath string) error {
 	// Remove from path mapping
 	if filePath != "" {
 		if absPath, err := filepath.Abs(filePath); err == nil {
+			g.tombstone(absPath)
 			delete(g.filePathToPackage, absPath)
+			delete(g.testOnlyFiles, absPath)
 		}
 	}
 
 	// Remove from filename mapping requires package lookup first
+	pkg := ""
 	if filePath != "" {
-		pkg, _ := g.findPackageContainingFileByPath(filePath)
+		var err error
+		if pkg, err = g.findPackageContainingFileByPath(filePath); err != nil {
+			return err
+		}
 		if pkg != "" {
-			fileName := filepath.Base(filePath)
-			g.fileToPackages[fileName] = removeString(g.fileToPackages[fileName], pkg)
+			g.unindexFileName(filepath.Base(filePath), pkg)
+		}
+	}
+
+	// The package may live on without this file: reload it so the edges of
+	// its remaining files stay in the graph instead of being dropped
+	if pkg != "" {
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			if cached := g.packageCache[pkg]; cached != nil {
+				if _, err := build.ImportDir(cached.Dir, 0); err == nil {
+					return g.refreshPackageCache(filePath)
+				}
+			}
 		}
 	}
 
 	return g.invalidatePackageCache(filePath)
 }
 
+// tombstone remembers the package of an indexed file being removed, so every
+// handler asked afterwards answers from it, see removedFiles
+func (g *GoDepFind) tombstone(absPath string) {
+	if pkg, indexed := g.filePathToPackage[absPath]; indexed {
+		if g.removedFiles == nil {
+			g.removedFiles = make(map[string]string)
+		}
+		g.removedFiles[absPath] = pkg
+	}
+}
+
+// indexedFiles returns the absolute paths of the files of pkg that the file
+// indices map, each flagged when it is test-only: Go files (and assembly/syso
+// files, which rebuild the package too), variants the host build excludes by
+// their name suffix or constraint (db_js.go, util_windows.go), test files if
+// enabled and external test files (package foo_test), which are always
+// indexed so they resolve consistently; claiming them is up to each handler,
+// under its build context. Gitignored files are left out, see
+// SetRespectGitignore.
+func (g *GoDepFind) indexedFiles(pkg *build.Package) map[string]bool {
+	files := make(map[string]bool)
+	add := func(names []string, testOnly bool) {
+		for _, name := range names {
+			if absPath := filepath.Join(pkg.Dir, name); !g.gitIgnored(absPath) {
+				files[absPath] = testOnly
+			}
+		}
+	}
+	add(buildFiles(pkg), false)
+	for _, variant := range packageVariants(pkg) {
+		if !variant.Host {
+			add([]string{variant.File}, false)
+		}
+	}
+	if g.testImports {
+		add(pkg.TestGoFiles, true)
+	}
+	add(pkg.XTestGoFiles, true)
+	return files
+}
+
+// indexFile maps a file by absolute path (unique) and by file name (may
+// have multiple packages)
+func (g *GoDepFind) indexFile(absPath, pkgPath string, testOnly bool) {
+	g.filePathToPackage[absPath] = pkgPath
+	if testOnly {
+		g.testOnlyFiles[absPath] = true
+	}
+	fileName := filepath.Base(absPath)
+	if !contains(g.fileToPackages[fileName], pkgPath) {
+		g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
+	}
+}
+
+// unindexFileName forgets that pkgPath has a file named fileName, dropping
+// names no package has anymore
+func (g *GoDepFind) unindexFileName(fileName, pkgPath string) {
+	if pkgs := removeString(g.fileToPackages[fileName], pkgPath); len(pkgs) > 0 {
+		g.fileToPackages[fileName] = pkgs
+	} else {
+		delete(g.fileToPackages, fileName)
+	}
+}
+
+// syncFileIndex moves the file indices of a reloaded package from its old
+// file list to its new one
+func (g *GoDepFind) syncFileIndex(pkgPath string, oldPkg, newPkg *build.Package) {
+	before, after := g.indexedFiles(oldPkg), g.indexedFiles(newPkg)
+	for absPath := range before {
+		if _, kept := after[absPath]; kept {
+			continue
+		}
+		if g.filePathToPackage[absPath] == pkgPath {
+			delete(g.filePathToPackage, absPath)
+			delete(g.testOnlyFiles, absPath)
+		}
+		g.unindexFileName(filepath.Base(absPath), pkgPath)
+	}
+	for absPath, testOnly := range after {
+		if _, known := before[absPath]; !known {
+			g.indexFile(absPath, pkgPath, testOnly)
+		}
+	}
+}
+
 // Helper functions
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -257,20 +411,59 @@ func removeString(slice []string, item string) []string {
 	return slice
 }
 
-// rebuildCache rebuilds the entire cache from scratch
+// rebuildCache rebuilds the entire cache from scratch. A *MultiError lists
+// the packages that could not be loaded; the cache is built without them.
 func (g *GoDepFind) rebuildCache() error {
+	g.lastRebuild = time.Now()
+	g.rebuildPending = false // a deferred rebuild is satisfied by this one
+	g.graphChanged()
+	g.resetListReport()
+	g.loadErrors = nil // load failures of this rebuild are skipped packages
+	g.detectNestedModules()
+
 	// 1. Get all packages
-	allPaths, err := g.listPackages("./...")
+	allPaths, err := g.listAllPackages()
 	if err != nil {
 		return fmt.Errorf("failed to list packages: %w", err)
 	}
 
+	g.listedCount = len(allPaths)
+	g.activeStrategy = g.chooseStrategy(len(allPaths))
+	g.lazyPending = nil
+	g.lazyClosures = nil
+	if g.activeStrategy == StrategyLazy {
+		// Only what the running query needs is loaded, see SetCacheStrategy
+		g.lazyPending = make(map[string]bool, len(allPaths))
+		for _, pkgPath := range allPaths {
+			g.lazyPending[pkgPath] = true
+		}
+		g.commitPackages(make(map[string]*build.Package))
+		if err := g.settleLazy(); err != nil {
+			return fmt.Errorf("failed to get packages: %w", err)
+		}
+		g.cachedModule = true
+		return g.skippedError()
+	}
+
 	// 2. Build package cache
+	// Packages that fail to load are left out; the rest is still committed
 	packages, err := g.getPackages(allPaths)
-	if err != nil {
+	if err != nil && !isPartialFailure(err) {
 		return fmt.Errorf("failed to get packages: %w", err)
 	}
+	g.commitPackages(packages)
+
+	// 7. Mark cache as initialized
+	g.cachedModule = true
+
+	return g.skippedError()
+}
+
+// commitPackages replaces the cache with loaded packages, then derives the
+// graph, the file indices and the main packages from them
+func (g *GoDepFind) commitPackages(packages map[string]*build.Package) {
 	g.packageCache = packages
+	g.renamedPackages = nil // sources are authoritative again
 
 	// 3. Build dependency graph and reverse dependencies
 	g.dependencyGraph = make(map[string][]string)
@@ -309,35 +502,15 @@ func (g *GoDepFind) rebuildCache() error {
 
 	// 4. Build file-to-package mappings
 	g.filePathToPackage = make(map[string]string)
+	g.testOnlyFiles = make(map[string]bool)
 	g.fileToPackages = make(map[string][]string)
+	g.fileStamps = make(map[string]int64)
 	for pkgPath, pkg := range packages {
 		if pkg != nil {
-			// Map Go files by absolute path AND collect by filename
-			for _, file := range pkg.GoFiles {
-				// Absolute path mapping (unique)
-				absPath := filepath.Join(pkg.Dir, file)
-				g.filePathToPackage[absPath] = pkgPath
-
-				// Filename mapping (may have multiple packages)
-				fileName := filepath.Base(file)
-				g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
-			}
-
-			// Map test files if enabled
-			if g.testImports {
-				for _, file := range pkg.TestGoFiles {
-					absPath := filepath.Join(pkg.Dir, file)
-					g.filePathToPackage[absPath] = pkgPath
-					fileName := filepath.Base(file)
-					g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
-				}
-				for _, file := range pkg.XTestGoFiles {
-					absPath := filepath.Join(pkg.Dir, file)
-					g.filePathToPackage[absPath] = pkgPath
-					fileName := filepath.Base(file)
-					g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
-				}
+			for absPath, testOnly := range g.indexedFiles(pkg) {
+				g.indexFile(absPath, pkgPath, testOnly)
 			}
+			g.stampPackage(pkg)
 		}
 	}
 
@@ -349,48 +522,62 @@ func (g *GoDepFind) rebuildCache() error {
 		}
 	}
 
-	// 6. Mark cache as initialized
-	g.cachedModule = true
-
-	return nil
+	// 6. Re-apply edges injected by tooling
+	g.applyVirtualEdges()
 }
 
 // cachedMainImportsPackage checks if a main package imports a target package using cache
-func (g *GoDepFind) cachedMainImportsPackage(mainPath, targetPkg string) bool {
-	// Use cached dependency graph for faster lookups
-	visited := make(map[string]bool)
-	return g.cachedImports(mainPath, targetPkg, visited)
+func (g *GoDepFind) cachedMainImportsPackage(mainPath, targetPkg string) (bool, error) {
+	return g.cachedImports(mainPath, targetPkg)
 }
 
-// isSameFile compares two file paths for equality (robust absolute comparison)
+// isSameFile compares two file paths for equality. When both paths exist the
+// comparison uses file identity (os.SameFile), so hardlinks, bind mounts and
+// alternate names such as Windows 8.3 short names match; otherwise it falls
+// back to normalized absolute path comparison.
 func (g *GoDepFind) isSameFile(filePath1, filePath2 string) bool {
-	abs1, err1 := filepath.Abs(filePath1)
-	abs2, err2 := filepath.Abs(filePath2)
-	if err1 != nil || err2 != nil {
-		return filePath1 == filePath2
-	}
+	candidates1 := g.fileCandidates(filePath1)
+	candidates2 := g.fileCandidates(filePath2)
 
-	// If one is relative, try to make it absolute relative to any rootDir
-	if !filepath.IsAbs(filePath2) {
-		for _, root := range g.rootDirs {
-			abs2FromRoot, err := filepath.Abs(filepath.Join(root, filePath2))
-			if err == nil && abs2FromRoot == abs1 {
-				abs2 = abs2FromRoot
-				break
+	// Cheap path comparison first
+	for _, c1 := range candidates1 {
+		for _, c2 := range candidates2 {
+			if c1 == c2 {
+				return true
 			}
 		}
 	}
-	if !filepath.IsAbs(filePath1) {
-		for _, root := range g.rootDirs {
-			abs1FromRoot, err := filepath.Abs(filepath.Join(root, filePath1))
-			if err == nil && abs1FromRoot == abs2 {
-				abs1 = abs1FromRoot
-				break
+
+	// File identity comparison for paths that exist on disk
+	for _, c1 := range candidates1 {
+		info1, err := os.Stat(c1)
+		if err != nil {
+			continue
+		}
+		for _, c2 := range candidates2 {
+			if info2, err := os.Stat(c2); err == nil && os.SameFile(info1, info2) {
+				return true
 			}
 		}
 	}
 
-	return abs1 == abs2
+	return false
+}
+
+// fileCandidates returns the absolute paths a file path may refer to: the path
+// resolved against the working directory and, when relative, against each rootDir
+func (g *GoDepFind) fileCandidates(filePath string) []string {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return []string{filePath}
+	}
+	candidates := []string{abs}
+	if !filepath.IsAbs(filePath) {
+		for _, root := range g.rootDirs {
+			candidates = append(candidates, filepath.Join(root, filePath))
+		}
+	}
+	return candidates
 }
 
 // updateCacheForFileWithContext updates cache based on file events and handler context
@@ -400,19 +587,35 @@ func (g *GoDepFind) updateCacheForFileWithContext(filePath, event, handlerMainFi
 		return err
 	}
 
+	// A file back on disk is indexed again, not answered from its tombstone
+	if _, err := os.Stat(filePath); err == nil {
+		delete(g.removedFiles, filePath)
+	}
+
 	switch event {
-	case "write":
+	case EventWrite:
+		// Identical rewrites (format on save) leave the graph as it is
+		if g.indexedContentUnchanged(filePath) {
+			return nil
+		}
 		// Only rescan fully if the modified file is the handler's mainInputFileRelativePath
 		if handlerMainFile != "" && g.isSameFile(filePath, handlerMainFile) {
 			return g.rescanMainPackageDependencies(filePath)
 		}
 		// For non-main files, use refreshPackageCache to update dependencies without full rescan
 		return g.refreshPackageCache(filePath)
-	case "create":
+	case EventCreate:
 		return g.handleFileCreate(filePath)
-	case "remove":
+	case EventRemove:
 		return g.handleFileRemove(filePath)
-	case "rename":
+	case EventRename:
+		// Watchers report a rename on the old name, the new one or both: an
+		// existing file in a cached package is the new name
+		if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
+			if pkgPath := g.packageInDir(filepath.Dir(filePath)); pkgPath != "" {
+				return g.reloadPackage(pkgPath, g.packageCache[pkgPath])
+			}
+		}
 		if err := g.handleFileRemove(filePath); err != nil {
 			return err
 		}
@@ -422,38 +625,26 @@ func (g *GoDepFind) updateCacheForFileWithContext(filePath, event, handlerMainFi
 	return nil
 }
 
-// rescanMainPackageDependencies rescans only the dependencies of the main package
-func (g *GoDepFind) rescanMainPackageDependencies(mainInputFileRelativePath string) error {
-	// Simpler and robust: rebuild entire cache for module when main changes.
-	// This ensures dependencyGraph, file mappings and mainPackages stay consistent.
-	if err := g.rebuildCache(); err != nil {
-		return err
-	}
-	return nil
-}
-
-// cachedImports returns true if path imports targetPkg transitively using cache
-func (g *GoDepFind) cachedImports(path, targetPkg string, visited map[string]bool) bool {
-	if visited[path] {
-		return false // Avoid cycles
+// cachedImports returns true if path imports targetPkg transitively using
+// cache. The walk is breadth-first so each package is reached at its minimum
+// depth; when a max depth is configured and the target was not found within
+// it, a *MaxDepthError is returned if the limit cut the walk.
+func (g *GoDepFind) cachedImports(path, targetPkg string) (bool, error) {
+	reach, truncated := reachDepth(g.dependencyGraph, []string{path}, g.maxDepth)
+	if reach[targetPkg] {
+		return true, nil
 	}
-	visited[path] = true
-
-	if path == targetPkg {
-		return true
-	}
-
-	// Use cached dependency graph
-	if deps, exists := g.dependencyGraph[path]; exists {
-		for _, dep := range deps {
-			if dep == targetPkg {
-				return true
-			}
-			if g.cachedImports(dep, targetPkg, visited) {
-				return true
-			}
-		}
+	if truncated {
+		return false, &MaxDepthError{Path: path, MaxDepth: g.maxDepth}
 	}
+	return false, nil
+}
 
-	return false
+// buildFiles returns the non-test files compiled into a package: Go sources,
+// assembly (.s) and precompiled objects (.syso)
+func buildFiles(pkg *build.Package) []string {
+	files := make([]string, 0, len(pkg.GoFiles)+len(pkg.SFiles)+len(pkg.SysoFiles))
+	files = append(files, pkg.GoFiles...)
+	files = append(files, pkg.SFiles...)
+	return append(files, pkg.SysoFiles...)
 }
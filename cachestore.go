@@ -0,0 +1,79 @@
+package depfind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CacheStore keeps the persisted caches (see SetCacheStore and SaveCacheTo)
+// as opaque blobs under a key. FileCacheStore is the default; other
+// implementations (bbolt, SQLite, a remote blob store) let machines share a
+// warmed cache, e.g. keyed by commit. Calls are serialized by the finder
+// owning the store, but a store shared between finders must be safe for
+// concurrent use.
+type CacheStore interface {
+	// Get returns the blob stored under key, an error wrapping
+	// fs.ErrNotExist when there is none
+	Get(key string) ([]byte, error)
+	// Put replaces the blob stored under key; readers must never observe a
+	// partial write
+	Put(key string, data []byte) error
+}
+
+// FileCacheStore stores each blob in a file: the key joined to Dir, or the
+// key itself when it is absolute or Dir is empty. Files are replaced
+// atomically and their directory is created as needed.
+type FileCacheStore struct {
+	Dir string
+}
+
+// Get reads the file of key
+func (s FileCacheStore) Get(key string) ([]byte, error) {
+	return os.ReadFile(s.path(key))
+}
+
+// Put writes the file of key through a temporary file renamed over it
+func (s FileCacheStore) Put(key string, data []byte) error {
+	if err := writeFileAtomic(s.path(key), data); err != nil {
+		return fmt.Errorf("cannot write cache file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to path through a temporary file of its own
+// renamed over it, creating its directory as needed. Concurrent writers
+// (processes sharing a FileCacheStore) never share a temporary file, so the
+// last rename wins with a whole file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s FileCacheStore) path(key string) string {
+	if s.Dir == "" || filepath.IsAbs(key) {
+		return key
+	}
+	return filepath.Join(s.Dir, key)
+}
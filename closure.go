@@ -0,0 +1,259 @@
+package depfind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// handlerClosure caches what a handler main file reaches: its parsed imports
+// are kept until the file changes on disk, and the transitive closure until
+// the dependency graph changes
+type handlerClosure struct {
+	modTime   time.Time
+	size      int64
+	imports   []string        // direct imports of the handler file as written
+	version   uint64          // graphVersion the closure was computed for
+	tags      string          // key of the tag graph followed, "" for the shared graph, see handlerEdges
+	reach     map[string]bool // packages imported directly or transitively
+	truncated bool            // the walk of reach was cut at the max depth
+	decisions map[string]bool // package -> owned by the handler, memoized with reach
+}
+
+// graphChanged invalidates every handler closure after a dependency graph mutation
+func (g *GoDepFind) graphChanged() {
+	g.graphVersion++
+}
+
+// packageImportsChanged invalidates the closures affected by new outgoing
+// edges of pkgPath. Rewriting a package without changing its host import
+// set keeps the closures of the shared graph; otherwise only the handlers
+// reaching pkgPath can gain or lose packages through it, the others stay
+// valid. The per-tag graphs and the closures following them are always
+// dropped: the files other targets build (db_js.go) may have changed
+// imports while the host ones did not. So are the closures loaded under
+// StrategyLazy, which may now miss a package still to load.
+func (g *GoDepFind) packageImportsChanged(pkgPath string, oldImports, newImports []string) {
+	g.tagGraphs = nil
+	g.lazyClosures = nil
+	for _, entry := range g.closures {
+		if entry.tags != "" {
+			entry.reach = nil
+			entry.decisions = nil
+		}
+	}
+	if sameImportSet(oldImports, newImports) {
+		return
+	}
+	for _, entry := range g.closures {
+		if entry.reach[pkgPath] {
+			entry.reach = nil
+			entry.decisions = nil
+		}
+	}
+}
+
+// sameImportSet reports whether two import lists hold the same packages
+func sameImportSet(a, b []string) bool {
+	setA := make(map[string]bool, len(a))
+	for _, imp := range a {
+		setA[imp] = true
+	}
+	setB := make(map[string]bool, len(b))
+	for _, imp := range b {
+		if !setA[imp] {
+			return false
+		}
+		setB[imp] = true
+	}
+	return len(setA) == len(setB)
+}
+
+// handlerOwnsPackage memoizes doesPackageBelongToHandler per (handler,
+// package); the memo lives and dies with the handler's closure, so it is
+// dropped when the handler file's imports or a package it reaches change.
+func (g *GoDepFind) handlerOwnsPackage(targetPkg, mainInputFileRelativePath string) (bool, error) {
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	if _, err := g.handlerReach(mainInputFileRelativePath, handlerAbsPath); err != nil {
+		return g.doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath)
+	}
+	entry := g.closures[handlerAbsPath]
+	if owned, ok := entry.decisions[targetPkg]; ok {
+		return owned, nil
+	}
+	owned, err := g.doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath)
+	if err != nil {
+		return false, err
+	}
+	if entry.decisions == nil {
+		entry.decisions = make(map[string]bool)
+	}
+	entry.decisions[targetPkg] = owned
+	return owned, nil
+}
+
+// handlerReach returns the packages the handler main file imports directly
+// or transitively (honoring the max depth). The file is only parsed again
+// when its size or modification time changes, and the closure is only
+// recomputed after the dependency graph changed. Imports are followed in the
+// graph of the handler's build configuration, see handlerEdges.
+func (g *GoDepFind) handlerReach(mainInputFileRelativePath, handlerAbsPath string) (map[string]bool, error) {
+	info, err := os.Stat(handlerAbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := g.closures[handlerAbsPath]
+	if entry == nil || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		imports, err := g.parseFileImports(handlerAbsPath)
+		if err != nil {
+			return nil, err
+		}
+		entry = &handlerClosure{modTime: info.ModTime(), size: info.Size(), imports: imports}
+		if g.closures == nil {
+			g.closures = make(map[string]*handlerClosure)
+		}
+		g.closures[handlerAbsPath] = entry
+	}
+
+	edges, tags := g.handlerEdges(mainInputFileRelativePath)
+	if entry.reach != nil && entry.version == g.graphVersion && entry.tags == tags {
+		return entry.reach, nil
+	}
+
+	// Imports are resolved now since renamed packages change with the graph;
+	// virtual edges declared on the handler's own package count as imports
+	roots := make([]string, 0, len(entry.imports))
+	for _, imp := range entry.imports {
+		roots = append(roots, g.resolveImport(imp, filepath.Dir(handlerAbsPath)))
+	}
+	if handlerPkg, exists := g.filePathToPackage[handlerAbsPath]; exists {
+		roots = append(roots, g.virtualTargets(handlerPkg)...)
+	}
+
+	entry.reach, entry.truncated = reachDepth(edges, roots, g.maxDepth)
+	entry.version = g.graphVersion
+	entry.tags = tags
+	entry.decisions = nil
+	return entry.reach, nil
+}
+
+// reachFrom returns roots plus every package they import transitively in the
+// cached graph, honoring the max depth
+func (g *GoDepFind) reachFrom(roots []string) map[string]bool {
+	return g.reachWithin(roots, g.maxDepth)
+}
+
+// reachWithin is reachFrom with an explicit max depth (0 for unlimited)
+func (g *GoDepFind) reachWithin(roots []string, maxDepth int) map[string]bool {
+	return reachIn(g.dependencyGraph, roots, maxDepth)
+}
+
+// reachIn is reachWithin following the edges of a given graph, see graphFor
+func reachIn(edges map[string][]string, roots []string, maxDepth int) map[string]bool {
+	reach, _ := reachDepth(edges, roots, maxDepth)
+	return reach
+}
+
+// reachDepth is reachIn also reporting whether the max depth cut the walk:
+// a package at the max depth imports one that was not reached
+func reachDepth(edges map[string][]string, roots []string, maxDepth int) (map[string]bool, bool) {
+	// Breadth-first so each package is reached at its minimum depth
+	truncated := false
+	reach := make(map[string]bool)
+	queue := make([]string, 0, len(roots))
+	depth := make(map[string]int)
+	for _, imp := range roots {
+		if !reach[imp] {
+			reach[imp] = true
+			depth[imp] = 0
+			queue = append(queue, imp)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if maxDepth > 0 && depth[current] >= maxDepth {
+			for _, dep := range edges[current] {
+				truncated = truncated || !reach[dep]
+			}
+			continue
+		}
+		for _, dep := range edges[current] {
+			if !reach[dep] {
+				reach[dep] = true
+				depth[dep] = depth[current] + 1
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return reach, truncated
+}
+
+// HandlerDiff lists the packages a handler gained or lost after a refresh
+type HandlerDiff struct {
+	Gained []string `json:"gained,omitempty"`
+	Lost   []string `json:"lost,omitempty"`
+}
+
+// Changed reports whether the handler's package set changed
+func (d HandlerDiff) Changed() bool {
+	return len(d.Gained) > 0 || len(d.Lost) > 0
+}
+
+// RefreshHandler re-parses the imports of a handler main file after it
+// changed and updates only that handler's package and closure, as a cheaper
+// alternative to the full rebuild triggered by a write event on the main.
+// It returns the packages (direct and transitive) the handler gained or lost.
+func (g *GoDepFind) RefreshHandler(mainInputFileRelativePath string) (HandlerDiff, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	if _, err := os.Stat(handlerAbsPath); err != nil {
+		if os.IsNotExist(err) {
+			return HandlerDiff{}, fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+		}
+		return HandlerDiff{}, fmt.Errorf("cannot access handler main file %s: %w", mainInputFileRelativePath, err)
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return HandlerDiff{}, err
+	}
+
+	// Baseline: the cached closure, or what the cached graph knows of the
+	// handler's package when the handler was never queried
+	var before map[string]bool
+	if entry := g.closures[handlerAbsPath]; entry != nil && entry.reach != nil {
+		before = entry.reach
+	} else if pkg, ok := g.filePathToPackage[handlerAbsPath]; ok {
+		before = g.reachFrom(g.dependencyGraph[pkg])
+	}
+
+	// Re-import just the handler's package, then recompute its closure
+	if err := g.refreshPackageCache(handlerAbsPath); err != nil {
+		return HandlerDiff{}, err
+	}
+	delete(g.closures, handlerAbsPath)
+	after, err := g.handlerReach(mainInputFileRelativePath, handlerAbsPath)
+	if err != nil {
+		return HandlerDiff{}, err
+	}
+
+	var diff HandlerDiff
+	for pkg := range after {
+		if !before[pkg] {
+			diff.Gained = append(diff.Gained, pkg)
+		}
+	}
+	for pkg := range before {
+		if !after[pkg] {
+			diff.Lost = append(diff.Lost, pkg)
+		}
+	}
+	sort.Strings(diff.Gained)
+	sort.Strings(diff.Lost)
+	return diff, nil
+}
@@ -0,0 +1,117 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHandlerClosureCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module hc\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"hc/a\"\n\nfunc main() { a.A() }\n",
+		"a/a.go":      "package a\n\nimport \"hc/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go":      "package b\n\nfunc B() {}\n",
+		"c/c.go":      "package c\n\nfunc C() {}\n",
+	})
+	mainFile := filepath.Join(root, "app", "main.go")
+	finder := New(root)
+	finder.mu.Lock()
+	defer finder.mu.Unlock()
+
+	if imports, err := finder.handlerFileImportsPackage("app/main.go", "hc/b"); err != nil || !imports {
+		t.Fatal("expected hc/b to be reached transitively")
+	}
+	entry := finder.closures[mainFile]
+	if entry == nil || entry.reach == nil {
+		t.Fatal("expected the handler closure to be cached")
+	}
+	reach := entry.reach
+
+	// Repeated queries reuse both the parsed imports and the closure
+	finder.handlerFileImportsPackage("app/main.go", "hc/c")
+	if finder.closures[mainFile] != entry || !sameMap(finder.closures[mainFile].reach, reach) {
+		t.Error("expected the cached closure to be reused")
+	}
+
+	// Refreshing a package without changing its imports keeps the closure
+	if err := finder.refreshPackageCache(filepath.Join(root, "a", "a.go")); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	finder.handlerFileImportsPackage("app/main.go", "hc/b")
+	if !sameMap(finder.closures[mainFile].reach, reach) {
+		t.Error("expected the closure to survive a refresh with the same imports")
+	}
+
+	// New imports in a reached package recompute the closure but keep the parsed imports
+	if err := os.WriteFile(filepath.Join(root, "a", "a.go"), []byte("package a\n\nimport (\n\t\"hc/b\"\n\t\"strings\"\n)\n\nfunc A() { b.B(); strings.ToUpper(\"\") }\n"), 0644); err != nil {
+		t.Fatalf("write a: %v", err)
+	}
+	if err := finder.refreshPackageCache(filepath.Join(root, "a", "a.go")); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	finder.handlerFileImportsPackage("app/main.go", "hc/b")
+	if finder.closures[mainFile] != entry || sameMap(entry.reach, reach) {
+		t.Error("expected only the closure to be recomputed after an import change")
+	}
+
+	// Editing the main file re-parses it
+	if err := os.WriteFile(mainFile, []byte("package main\n\nimport (\n\t\"hc/a\"\n\t\"hc/c\"\n)\n\nfunc main() { a.A(); c.C() }\n"), 0644); err != nil {
+		t.Fatalf("write main: %v", err)
+	}
+	if imports, err := finder.handlerFileImportsPackage("app/main.go", "hc/c"); err != nil || !imports {
+		t.Error("expected the edited main file to be re-parsed")
+	}
+	if finder.closures[mainFile] == entry {
+		t.Error("expected a new closure entry after the main file changed")
+	}
+}
+
+// sameMap reports whether two maps are the same instance
+func sameMap(a, b map[string]bool) bool {
+	return a != nil && reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func TestOwnershipMemoInvalidation(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module memo\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"memo/a\"\n\nfunc main() { a.A() }\n",
+		"admin/main.go": "package main\n\nimport \"memo/c\"\n\nfunc main() { c.C() }\n",
+		"a/a.go":        "package a\n\nfunc A() {}\n",
+		"b/b.go":        "package b\n\nfunc B() {}\n",
+		"c/c.go":        "package c\n\nfunc C() {}\n",
+	})
+	finder := New(root)
+	bFile := filepath.Join(root, "b", "b.go")
+	aFile := filepath.Join(root, "a", "a.go")
+
+	for _, handler := range []string{"app/main.go", "admin/main.go"} {
+		if isMine, _ := finder.ThisFileIsMine(handler, bFile, EventWrite); isMine {
+			t.Fatalf("expected %s not to own b before the import", handler)
+		}
+	}
+	appEntry := finder.closures[filepath.Join(root, "app", "main.go")]
+	adminEntry := finder.closures[filepath.Join(root, "admin", "main.go")]
+	if owned, ok := appEntry.decisions["memo/b"]; !ok || owned {
+		t.Fatalf("expected the (handler, package) decision to be memoized, got %v, %v", owned, ok)
+	}
+	adminReach := adminEntry.reach
+
+	// a starts importing b: only handlers reaching a are invalidated
+	if err := os.WriteFile(aFile, []byte("package a\n\nimport \"memo/b\"\n\nfunc A() { b.B() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", aFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	if isMine, _ := finder.ThisFileIsMine("app/main.go", bFile, EventCheck); !isMine {
+		t.Error("expected app to own b after a imports it")
+	}
+	if isMine, _ := finder.ThisFileIsMine("admin/main.go", bFile, EventCheck); isMine {
+		t.Error("expected admin still not to own b")
+	}
+	if !sameMap(adminEntry.reach, adminReach) {
+		t.Error("expected the closure of a handler not reaching a to be kept")
+	}
+}
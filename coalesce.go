@@ -0,0 +1,145 @@
+package depfind
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SetEventWindow makes Decide report, in Decision.Recent, how many other
+// events the same handler was given within window before this one, so a
+// router or a handler can coalesce the rebuilds of a burst (save all, git
+// checkout) instead of rebuilding per file. Zero (the default) disables the
+// tracking. See CoalesceByHandler to aggregate the events themselves.
+func (g *GoDepFind) SetEventWindow(window time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.eventWindow = window
+	if window <= 0 {
+		g.recentEvents = nil
+	}
+}
+
+// noteHandlerEvent records an event owned by a handler and returns how many
+// others it was given within the event window (mu held)
+func (g *GoDepFind) noteHandlerEvent(mainInputFileRelativePath string) int {
+	if g.eventWindow <= 0 {
+		return 0
+	}
+	now := time.Now()
+	handler := handlerKey(mainInputFileRelativePath)
+	recent := g.recentEvents[handler][:0]
+	for _, at := range g.recentEvents[handler] {
+		if now.Sub(at) <= g.eventWindow {
+			recent = append(recent, at)
+		}
+	}
+	if g.recentEvents == nil {
+		g.recentEvents = make(map[string][]time.Time)
+	}
+	g.recentEvents[handler] = append(recent, now)
+	return len(recent)
+}
+
+// HandlerBatch is the events a Coalescer aggregated for one handler
+type HandlerBatch struct {
+	Handler string   `json:"handler"` // handler main file as given to Add
+	Files   []string `json:"files"`   // distinct files, sorted
+	Events  int      `json:"events"`  // events aggregated, duplicates included
+}
+
+// Coalescer aggregates owned events per handler and delivers one batch per
+// handler per window, see CoalesceByHandler
+type Coalescer struct {
+	window  time.Duration
+	deliver func(HandlerBatch)
+
+	mu      sync.Mutex
+	pending map[string]*pendingBatch // handler -> batch of the open window
+	closed  bool
+}
+
+// pendingBatch is a batch whose window is still open
+type pendingBatch struct {
+	files  map[string]bool
+	events int
+	timer  *time.Timer
+}
+
+// CoalesceByHandler returns a Coalescer calling deliver once per handler per
+// window: the first event for a handler opens its window, the events added
+// until it closes are delivered together. Route with ThisFileIsMine or
+// Decide, then Add the owned events:
+//
+//	c := depfind.CoalesceByHandler(100*time.Millisecond, func(b depfind.HandlerBatch) { rebuild(b.Handler) })
+//	if mine, _ := finder.ThisFileIsMine(main, path, event); mine {
+//		c.Add(main, path)
+//	}
+//
+// deliver runs on the goroutine of the window's timer (of Flush when
+// flushing), so batches of different handlers may be delivered concurrently.
+func CoalesceByHandler(window time.Duration, deliver func(HandlerBatch)) *Coalescer {
+	return &Coalescer{window: window, deliver: deliver, pending: make(map[string]*pendingBatch)}
+}
+
+// Add records an event of file for handler. It is ignored after Close.
+func (c *Coalescer) Add(handler, file string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	batch := c.pending[handler]
+	if batch == nil {
+		batch = &pendingBatch{files: make(map[string]bool)}
+		batch.timer = time.AfterFunc(c.window, func() { c.flushHandler(handler, batch) })
+		c.pending[handler] = batch
+	}
+	batch.files[file] = true
+	batch.events++
+}
+
+// Flush delivers every open window now, synchronously
+func (c *Coalescer) Flush() {
+	c.mu.Lock()
+	batches := c.pending
+	c.pending = make(map[string]*pendingBatch)
+	c.mu.Unlock()
+
+	handlers := make([]string, 0, len(batches))
+	for handler := range batches {
+		handlers = append(handlers, handler)
+	}
+	sort.Strings(handlers)
+	for _, handler := range handlers {
+		batches[handler].timer.Stop() // a timer already fired finds its batch taken
+		c.deliver(batches[handler].batch(handler))
+	}
+}
+
+// Close flushes the open windows and ignores later events
+func (c *Coalescer) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.Flush()
+}
+
+// flushHandler delivers a batch when its window closes, unless Flush took it
+func (c *Coalescer) flushHandler(handler string, batch *pendingBatch) {
+	c.mu.Lock()
+	if c.pending[handler] != batch {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, handler)
+	c.mu.Unlock()
+	c.deliver(batch.batch(handler))
+}
+
+// batch returns the delivered form of a pending batch
+func (b *pendingBatch) batch(handler string) HandlerBatch {
+	return HandlerBatch{Handler: handler, Files: sortedKeys(b.files), Events: b.events}
+}
@@ -0,0 +1,96 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDecisionRecentEvents(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module burst\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"burst/lib\"\n\nfunc main() { lib.A(); lib.B() }\n",
+		"admin/main.go": "package main\n\nimport \"burst/lib\"\n\nfunc main() { lib.A() }\n",
+		"lib/a.go":      "package lib\n\nfunc A() {}\n",
+		"lib/b.go":      "package lib\n\nfunc B() {}\n",
+	})
+	finder := New(root)
+	files := []string{filepath.Join(root, "lib/a.go"), filepath.Join(root, "lib/b.go"), filepath.Join(root, "lib/a.go")}
+
+	// Disabled by default
+	if decision, err := finder.Decide("app/main.go", files[0], EventWrite); err != nil || decision.Recent != 0 {
+		t.Fatalf("without a window: %+v, %v", decision, err)
+	}
+
+	finder.SetEventWindow(time.Minute)
+	for i, file := range files {
+		decision, err := finder.Decide("app/main.go", file, EventWrite)
+		if err != nil || !decision.Owned {
+			t.Fatalf("event %d: %+v, %v", i, decision, err)
+		}
+		if decision.Recent != i {
+			t.Errorf("event %d: Recent = %d, want %d", i, decision.Recent, i)
+		}
+	}
+	// Counted per handler
+	if decision, _ := finder.Decide("admin/main.go", files[0], EventWrite); decision.Recent != 0 {
+		t.Errorf("admin: Recent = %d, want 0", decision.Recent)
+	}
+}
+
+func TestCoalesceByHandler(t *testing.T) {
+	var mu sync.Mutex
+	var batches []HandlerBatch
+	done := make(chan struct{}, 4)
+	c := CoalesceByHandler(50*time.Millisecond, func(b HandlerBatch) {
+		mu.Lock()
+		batches = append(batches, b)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+	defer c.Close()
+
+	c.Add("app/main.go", "/src/lib/a.go")
+	c.Add("app/main.go", "/src/lib/b.go")
+	c.Add("app/main.go", "/src/lib/a.go")
+	c.Add("admin/main.go", "/src/lib/a.go")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("batches not delivered")
+		}
+	}
+
+	mu.Lock()
+	sort.Slice(batches, func(i, j int) bool { return batches[i].Handler < batches[j].Handler })
+	want := []HandlerBatch{
+		{Handler: "admin/main.go", Files: []string{"/src/lib/a.go"}, Events: 1},
+		{Handler: "app/main.go", Files: []string{"/src/lib/a.go", "/src/lib/b.go"}, Events: 3},
+	}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("batches = %+v, want %+v", batches, want)
+	}
+	batches = nil
+	mu.Unlock()
+
+	// Flush delivers an open window right away, and only once
+	slow := CoalesceByHandler(time.Hour, func(b HandlerBatch) {
+		mu.Lock()
+		batches = append(batches, b)
+		mu.Unlock()
+	})
+	slow.Add("app/main.go", "/src/lib/a.go")
+	slow.Flush()
+	slow.Close()
+	slow.Add("app/main.go", "/src/lib/b.go") // ignored after Close
+	slow.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 1 || batches[0].Events != 1 {
+		t.Errorf("flushed batches = %+v", batches)
+	}
+}
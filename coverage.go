@@ -0,0 +1,187 @@
+package depfind
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MainCoverage is the share of a coverage profile compiled into one main,
+// see CoverageByMain
+type MainCoverage struct {
+	Main       string   `json:"main"`       // handler main file, relative to the primary root
+	Statements int      `json:"statements"` // statements of the profiled files the main compiles
+	Covered    int      `json:"covered"`    // of which executed at least once
+	Packages   []string `json:"packages"`   // profiled packages the main compiles, sorted
+}
+
+// Percent returns the covered share of the statements, 0 without any
+func (c MainCoverage) Percent() float64 {
+	if c.Statements == 0 {
+		return 0
+	}
+	return 100 * float64(c.Covered) / float64(c.Statements)
+}
+
+// CoverageReport attributes a coverage profile to the mains, see
+// CoverageByMain
+type CoverageReport struct {
+	Mode         string         `json:"mode"`                   // set, count or atomic
+	Mains        []MainCoverage `json:"mains"`                  // every handler, sorted by main file
+	Unattributed []string       `json:"unattributed,omitempty"` // profiled files no main compiles, as named in the profile, sorted
+}
+
+// coverageBlock is a block of a profile: its statements, and whether any
+// run executed it
+type coverageBlock struct {
+	statements int
+	covered    bool
+}
+
+// CoverageByMain reads a coverage profile (go test -coverprofile, possibly
+// merged from several runs) and attributes each profiled file to the mains
+// compiling it, per file under each main's own build context as in
+// WhichMainsUseFile: one test run of the whole module then yields the
+// coverage of the server and of the wasm binary separately. A block listed
+// several times counts once, covered when any run executed it.
+func (g *GoDepFind) CoverageByMain(profilePath string) (*CoverageReport, error) {
+	mode, blocks, err := readCoverageProfile(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CoverageReport{Mode: mode, Mains: []MainCoverage{}}
+	perMain := make(map[string]*MainCoverage, len(handlers))
+	packages := make(map[string]map[string]bool, len(handlers))
+	for _, h := range handlers {
+		perMain[h.MainFile] = &MainCoverage{Main: h.MainFile}
+		packages[h.MainFile] = make(map[string]bool)
+	}
+	names := make([]string, 0, len(blocks))
+	for name := range blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	closures := make(map[string]map[string]bool)
+	for _, name := range names {
+		pkgPath := path.Dir(name)
+		mains := g.mainsUsingFile(g.profiledFile(name), handlers, closures)
+		if len(mains) == 0 {
+			report.Unattributed = append(report.Unattributed, name)
+			continue
+		}
+		for _, mainFile := range mains {
+			coverage := perMain[mainFile]
+			for _, block := range blocks[name] {
+				coverage.Statements += block.statements
+				if block.covered {
+					coverage.Covered += block.statements
+				}
+			}
+			packages[mainFile][pkgPath] = true
+		}
+	}
+	for mainFile, coverage := range perMain {
+		coverage.Packages = sortedKeys(packages[mainFile])
+		report.Mains = append(report.Mains, *coverage)
+	}
+	sort.Slice(report.Mains, func(i, j int) bool { return report.Mains[i].Main < report.Mains[j].Main })
+	return report, nil
+}
+
+// profiledFile resolves a file as a profile names it ("example.com/app/db/db.go")
+// to an absolute path, through the directory of its package
+func (g *GoDepFind) profiledFile(name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	pkgPath := path.Dir(name)
+	dir, ok := g.packageDir(pkgPath)
+	if !ok || dir == "" {
+		if pkg := g.packageCache[pkgPath]; pkg != nil {
+			dir = pkg.Dir
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, path.Base(name))
+}
+
+// readCoverageProfile parses a coverage profile into its mode and the blocks
+// of each file, keyed by position so repeated blocks merge
+func readCoverageProfile(profilePath string) (string, map[string]map[string]*coverageBlock, error) {
+	file, err := os.Open(profilePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read coverage profile: %w", err)
+	}
+	defer file.Close()
+
+	var mode string
+	blocks := make(map[string]map[string]*coverageBlock)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if m, ok := strings.CutPrefix(text, "mode:"); ok {
+			// Merged profiles repeat the mode line
+			mode = strings.TrimSpace(m)
+			continue
+		}
+		if mode == "" {
+			return "", nil, fmt.Errorf("%s:%d: not a coverage profile, missing mode line", profilePath, line)
+		}
+
+		// name.go:line.column,line.column statements count, the name may hold spaces
+		rest, countField, ok1 := cutLast(text, " ")
+		rest, statementsField, ok2 := cutLast(rest, " ")
+		name, position, ok3 := cutLast(rest, ":")
+		statements, err1 := strconv.Atoi(statementsField)
+		count, err2 := strconv.Atoi(countField)
+		if !ok1 || !ok2 || !ok3 || name == "" || err1 != nil || err2 != nil {
+			return "", nil, fmt.Errorf("%s:%d: malformed coverage block %q", profilePath, line, text)
+		}
+		if blocks[name] == nil {
+			blocks[name] = make(map[string]*coverageBlock)
+		}
+		block := blocks[name][position]
+		if block == nil {
+			block = &coverageBlock{statements: statements}
+			blocks[name][position] = block
+		}
+		block.covered = block.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("cannot read coverage profile: %w", err)
+	}
+	if mode == "" {
+		return "", nil, fmt.Errorf("%s: not a coverage profile, missing mode line", profilePath)
+	}
+	return mode, blocks, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
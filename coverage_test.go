@@ -0,0 +1,68 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCoverageByMain(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module cv\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"cv/db\"\n\nfunc main() { db.Open() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"cv/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":        "package main\n\nimport \"cv/util\"\n\nfunc main() { util.Do() }\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "package db\n\nfunc open() {}\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nimport \"cv/sqlite\"\n\nfunc open() { sqlite.Open() }\n",
+		"sqlite/sqlite.go":   "package sqlite\n\nfunc Open() {}\n",
+		"util/util.go":       "package util\n\nfunc Do() {}\n",
+	})
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	// Two merged runs: the repeated native block was executed by the first one
+	content := "mode: set\n" +
+		"cv/db/db.go:3.14,3.24 1 1\n" +
+		"cv/db/db_native.go:7.14,7.29 1 1\n" +
+		"cv/sqlite/sqlite.go:3.15,3.17 2 0\n" +
+		"cv/util/util.go:3.13,3.15 1 0\n" +
+		"mode: set\n" +
+		"cv/db/db_native.go:7.14,7.29 1 0\n" +
+		"cv/gen/gen.go:1.1,1.2 1 1\n"
+	if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := New(root).CoverageByMain(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CoverageReport{
+		Mode: "set",
+		Mains: []MainCoverage{
+			{Main: "cli/main.go", Statements: 1, Covered: 0, Packages: []string{"cv/util"}},
+			{Main: "pwa/main.server.go", Statements: 4, Covered: 2, Packages: []string{"cv/db", "cv/sqlite"}},
+			{Main: "pwa/main.wasm.go", Statements: 1, Covered: 1, Packages: []string{"cv/db"}},
+		},
+		Unattributed: []string{"cv/gen/gen.go"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("CoverageByMain =\n%+v\nwant\n%+v", report, want)
+	}
+	if got := report.Mains[1].Percent(); got != 50 {
+		t.Errorf("Percent = %v, want 50", got)
+	}
+
+	if err := os.WriteFile(profile, []byte("cv/db/db.go:3.14,3.24 1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(root).CoverageByMain(profile); err == nil {
+		t.Error("expected an error for a profile without a mode line")
+	}
+	if err := os.WriteFile(profile, []byte("mode: set\ncv/db/db.go 1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(root).CoverageByMain(profile); err == nil {
+		t.Error("expected an error for a malformed block")
+	}
+}
@@ -0,0 +1,151 @@
+package depfind
+
+import (
+	"sort"
+	"strings"
+)
+
+// DetectCycles reports the import cycles of the cached module graph. The go
+// tool refuses to build them, while the dependency walks of the finder only
+// step over them, so they are otherwise invisible. Each strongly connected
+// set of packages is reported once, as its shortest cycle through its
+// smallest import path, closed on that package: ["a", "b", "a"] means a
+// imports b which imports a. Cycles are sorted.
+func (g *GoDepFind) DetectCycles() ([][]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	var cycles [][]string
+	for _, component := range g.stronglyConnected() {
+		if cycle := g.shortestCycle(component); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], "\x00") < strings.Join(cycles[j], "\x00")
+	})
+	return cycles, nil
+}
+
+// stronglyConnected returns the strongly connected components of the cached
+// packages (Tarjan's algorithm, iterative), each sorted. Single packages are
+// only returned when they import themselves.
+func (g *GoDepFind) stronglyConnected() [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	type frame struct {
+		pkg  string
+		next int // position in the package's imports
+	}
+	for _, start := range sortedKeys(g.cachedPackageSet()) {
+		if _, seen := index[start]; seen {
+			continue
+		}
+		calls := []frame{{pkg: start}}
+		index[start], lowlink[start] = len(index), len(index)
+		stack = append(stack, start)
+		onStack[start] = true
+
+		for len(calls) > 0 {
+			top := &calls[len(calls)-1]
+			deps := g.dependencyGraph[top.pkg]
+			if top.next < len(deps) {
+				dep := deps[top.next]
+				top.next++
+				if g.packageCache[dep] == nil {
+					continue // stdlib and external leaves cannot close a cycle
+				}
+				if _, seen := index[dep]; !seen {
+					index[dep], lowlink[dep] = len(index), len(index)
+					stack = append(stack, dep)
+					onStack[dep] = true
+					calls = append(calls, frame{pkg: dep})
+				} else if onStack[dep] {
+					lowlink[top.pkg] = min(lowlink[top.pkg], index[dep])
+				}
+				continue
+			}
+
+			pkg := top.pkg
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				parent := calls[len(calls)-1].pkg
+				lowlink[parent] = min(lowlink[parent], lowlink[pkg])
+			}
+			if lowlink[pkg] != index[pkg] {
+				continue
+			}
+			var component []string
+			for {
+				member := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[member] = false
+				component = append(component, member)
+				if member == pkg {
+					break
+				}
+			}
+			if len(component) > 1 || contains(g.dependencyGraph[pkg], pkg) {
+				sort.Strings(component)
+				components = append(components, component)
+			}
+		}
+	}
+	return components
+}
+
+// cachedPackageSet returns the import paths of the cached packages
+func (g *GoDepFind) cachedPackageSet() map[string]bool {
+	set := make(map[string]bool, len(g.packageCache))
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil {
+			set[pkgPath] = true
+		}
+	}
+	return set
+}
+
+// shortestCycle returns the shortest cycle through the first (smallest)
+// package of a sorted strongly connected component, staying inside it
+func (g *GoDepFind) shortestCycle(component []string) []string {
+	start := component[0]
+	members := make(map[string]bool, len(component))
+	for _, pkg := range component {
+		members[pkg] = true
+	}
+
+	parent := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		deps := append([]string(nil), g.dependencyGraph[current]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if dep == start {
+				cycle := []string{start}
+				for pkg := current; pkg != start; pkg = parent[pkg] {
+					cycle = append(cycle, pkg)
+				}
+				cycle = append(cycle, start)
+				for i, j := 1, len(cycle)-2; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			}
+			if _, seen := parent[dep]; !seen && members[dep] {
+				parent[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectCycles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module cy\n\ngo 1.21\n",
+		"cmd/main.go": "package main\n\nimport _ \"cy/a\"\n\nfunc main() {}\n",
+		"a/a.go":      "package a\n\nimport _ \"cy/b\"\n",
+		"b/b.go":      "package b\n\nimport (\n\t_ \"cy/c\"\n\t_ \"fmt\"\n)\n",
+		"c/c.go":      "package c\n\nimport _ \"cy/a\"\n",
+		"x/x.go":      "package x\n\nimport _ \"cy/y\"\n",
+		"y/y.go":      "package y\n\nimport _ \"cy/x\"\n",
+		"ok/ok.go":    "package ok\n\nimport _ \"cy/a\"\n",
+	})
+	finder := New(root)
+
+	cycles, err := finder.DetectCycles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"cy/a", "cy/b", "cy/c", "cy/a"},
+		{"cy/x", "cy/y", "cy/x"},
+	}
+	if !reflect.DeepEqual(cycles, want) {
+		t.Errorf("DetectCycles() = %v, want %v", cycles, want)
+	}
+}
+
+func TestDetectCyclesNone(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module nc\n\ngo 1.21\n",
+		"cmd/main.go": "package main\n\nimport _ \"nc/lib\"\n\nfunc main() {}\n",
+		"lib/lib.go":  "package lib\n\nimport _ \"fmt\"\n",
+	})
+	cycles, err := New(root).DetectCycles()
+	if err != nil || len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v, %v", cycles, err)
+	}
+}
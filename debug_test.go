@@ -84,7 +84,7 @@ func TestDebugMatchesHandlerFile(t *testing.T) {
 
 	// Step 6: Check if any main package imports this target package and matches the handler
 	for _, mainPath := range finder.mainPackages {
-		imports := finder.cachedMainImportsPackage(mainPath, targetPkg)
+		imports, _ := finder.cachedMainImportsPackage(mainPath, targetPkg)
 		matches := finder.matchesHandlerFile(mainPath, handlerFile)
 		fmt.Printf("Loop: cachedMainImportsPackage(%q, %q)=%v && matchesHandlerFile(%q, %q)=%v\n",
 			mainPath, targetPkg, imports, mainPath, handlerFile, matches)
@@ -0,0 +1,86 @@
+package depfind
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// Divergence is where the closures of two handlers part, see
+// GoDepFind.Divergence
+type Divergence struct {
+	MainA  string      `json:"main_a"`
+	MainB  string      `json:"main_b"`
+	Shared []string    `json:"shared"` // cached packages both mains compile, sorted
+	OnlyA  []GraphEdge `json:"only_a"` // shared package -> package only MainA compiles, sorted
+	OnlyB  []GraphEdge `json:"only_b"` // shared package -> package only MainB compiles, sorted
+}
+
+// Divergence compares what two handler main files compile, each under its
+// own build context as in WhichMainsUseFile, and returns the nearest points
+// where they part: the imports of shared packages that only one of them
+// compiles. For a server and a wasm main of the same pwa package, an edge
+// of OnlyA such as "app/pwa -> app/database" is the import to move out of
+// the shared code so the wasm bundle stops pulling server-only packages.
+// Packages below those edges are left out: they follow from them. When a
+// main's own package is not shared, the edge has an empty From. Only cached
+// packages are compared, not the standard library.
+func (g *GoDepFind) Divergence(mainA, mainB string) (*Divergence, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	tagsA, closureA, err := g.handlerFileClosure(mainA)
+	if err != nil {
+		return nil, err
+	}
+	tagsB, closureB, err := g.handlerFileClosure(mainB)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Divergence{MainA: mainA, MainB: mainB, Shared: []string{}}
+	for pkgPath := range closureA {
+		if closureB[pkgPath] {
+			d.Shared = append(d.Shared, pkgPath)
+		}
+	}
+	sort.Strings(d.Shared)
+	d.OnlyA = g.divergentEdges(mainA, tagsA, closureA, closureB, d.Shared)
+	d.OnlyB = g.divergentEdges(mainB, tagsB, closureB, closureA, d.Shared)
+	return d, nil
+}
+
+// handlerFileClosure returns the build context of a handler main file and
+// the cached packages it compiles
+func (g *GoDepFind) handlerFileClosure(mainInputFileRelativePath string) (TagSet, map[string]bool, error) {
+	tags, ok := g.handlerContext(mainInputFileRelativePath)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot read the build constraints of %s", mainInputFileRelativePath)
+	}
+	mainAbs := g.rootPath(mainInputFileRelativePath)
+	if !g.isMainPackage(g.packageInDir(filepath.Dir(mainAbs))) {
+		return nil, nil, fmt.Errorf("%s is not in a cached main package", mainInputFileRelativePath)
+	}
+	return tags, g.fileClosure(mainAbs, tags), nil
+}
+
+// divergentEdges returns the imports, under tags, of the shared packages
+// that only own reaches, plus the main's own package when other lacks it
+func (g *GoDepFind) divergentEdges(mainInputFileRelativePath string, tags TagSet, own, other map[string]bool, shared []string) []GraphEdge {
+	edges := []GraphEdge{}
+	if start := g.packageInDir(filepath.Dir(g.rootPath(mainInputFileRelativePath))); !other[start] {
+		edges = append(edges, GraphEdge{To: start})
+	}
+	for _, from := range shared {
+		for _, to := range sortedUnique(g.taggedImports(from, tags)) {
+			if own[to] && !other[to] {
+				edges = append(edges, GraphEdge{From: from, To: to})
+			}
+		}
+	}
+	sortEdges(edges)
+	return edges
+}
@@ -0,0 +1,57 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDivergence(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module dv\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport (\n\t\"dv/db\"\n\t\"dv/ui\"\n)\n\nfunc main() { db.Open(); ui.Render() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport (\n\t\"dv/db\"\n\t\"dv/ui\"\n)\n\nfunc main() { db.Open(); ui.Render() }\n",
+		"cli/main.go":        "package main\n\nimport \"dv/util\"\n\nfunc main() { util.Do() }\n",
+		"ui/ui.go":           "package ui\n\nfunc Render() {}\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "//go:build wasm\n\npackage db\n\nimport \"dv/jsglue\"\n\nfunc open() { jsglue.Call() }\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nimport \"dv/sqlite\"\n\nfunc open() { sqlite.Open() }\n",
+		"jsglue/glue.go":     "package jsglue\n\nfunc Call() {}\n",
+		"sqlite/sqlite.go":   "package sqlite\n\nimport \"dv/util\"\n\nfunc Open() { util.Do() }\n",
+		"util/util.go":       "package util\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+
+	d, err := finder.Divergence("pwa/main.server.go", "pwa/main.wasm.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Divergence{
+		MainA:  "pwa/main.server.go",
+		MainB:  "pwa/main.wasm.go",
+		Shared: []string{"dv/db", "dv/pwa", "dv/ui"},
+		OnlyA:  []GraphEdge{{From: "dv/db", To: "dv/sqlite"}},
+		OnlyB:  []GraphEdge{{From: "dv/db", To: "dv/jsglue"}},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("Divergence = %+v, want %+v", d, want)
+	}
+
+	// Different main packages diverge at their own package
+	d, err = finder.Divergence("cli/main.go", "pwa/main.server.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantA := []GraphEdge{{To: "dv/cli"}}; !reflect.DeepEqual(d.OnlyA, wantA) {
+		t.Errorf("OnlyA = %+v, want %+v", d.OnlyA, wantA)
+	}
+	if wantB := []GraphEdge{{To: "dv/pwa"}}; !reflect.DeepEqual(d.OnlyB, wantB) {
+		t.Errorf("OnlyB = %+v, want %+v", d.OnlyB, wantB)
+	}
+	if !reflect.DeepEqual(d.Shared, []string{"dv/util"}) {
+		t.Errorf("Shared = %v, want [dv/util]", d.Shared)
+	}
+
+	if _, err := finder.Divergence("ui/ui.go", "cli/main.go"); err == nil {
+		t.Error("expected an error for a file outside a main package")
+	}
+}
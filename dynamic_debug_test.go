@@ -205,10 +205,10 @@ func main() {
 			}
 		}
 
-		importsPackage := finder.handlerFileImportsPackage(relMain, targetPkg)
+		importsPackage, _ := finder.handlerFileImportsPackage(relMain, targetPkg)
 		logf(t, "Does %s import %s? %v", relMain, targetPkg, importsPackage)
 
-		belongs := finder.doesPackageBelongToHandler(targetPkg, relMain)
+		belongs, _ := finder.doesPackageBelongToHandler(targetPkg, relMain)
 		logf(t, "Package %s belongs to handler %s: %v", targetPkg, relMain, belongs)
 	}
 
@@ -0,0 +1,46 @@
+package depfind
+
+// Event operations understood by ThisFileIsMine. Handlers should pass these
+// values so every handler treats a file change the same way.
+//
+//   - EventWrite, EventCreate, EventRemove, EventRename mutate a file and
+//     update the cache before ownership is decided (writing a handler's own
+//     main file rebuilds it entirely; remove/rename of a deleted file answer
+//     from the last-known state, then purge it).
+//   - EventChmod mutates file metadata only; the cache is left untouched.
+//   - EventQuery only asks who owns the file and is guaranteed never to
+//     touch the cache, the journal or the no-change memory, even when the
+//     file changed on disk. EventCheck and unknown values are queries too.
+const (
+	EventWrite  = "write"
+	EventCreate = "create"
+	EventRemove = "remove"
+	EventRename = "rename"
+	EventChmod  = "chmod"
+	EventQuery  = "query"
+
+	// Deprecated: use EventQuery; still accepted as a query
+	EventCheck = "check"
+)
+
+// IsMutationEvent reports whether op means the file changed on disk,
+// including metadata-only changes
+func IsMutationEvent(op string) bool {
+	return op == EventChmod || RequiresCacheUpdate(op)
+}
+
+// IsQueryEvent reports whether op is read-only: every value that is not a
+// mutation, EventQuery first
+func IsQueryEvent(op string) bool {
+	return !IsMutationEvent(op)
+}
+
+// RequiresCacheUpdate reports whether op can change package contents or
+// imports, and therefore updates the cache when routed through ThisFileIsMine
+func RequiresCacheUpdate(op string) bool {
+	switch op {
+	case EventWrite, EventCreate, EventRemove, EventRename:
+		return true
+	}
+	return false
+}
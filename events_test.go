@@ -0,0 +1,79 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEventSemantics(t *testing.T) {
+	cases := []struct {
+		op               string
+		mutation, update bool
+	}{
+		{EventWrite, true, true},
+		{EventCreate, true, true},
+		{EventRemove, true, true},
+		{EventRename, true, true},
+		{EventChmod, true, false},
+		{EventQuery, false, false},
+		{EventCheck, false, false},
+		{"", false, false},
+		{"WRITE", false, false},
+	}
+	for _, tc := range cases {
+		if got := IsMutationEvent(tc.op); got != tc.mutation {
+			t.Errorf("IsMutationEvent(%q) = %v, want %v", tc.op, got, tc.mutation)
+		}
+		if got := IsQueryEvent(tc.op); got == tc.mutation {
+			t.Errorf("IsQueryEvent(%q) = %v, want %v", tc.op, got, !tc.mutation)
+		}
+		if got := RequiresCacheUpdate(tc.op); got != tc.update {
+			t.Errorf("RequiresCacheUpdate(%q) = %v, want %v", tc.op, got, tc.update)
+		}
+	}
+}
+
+func TestQueryEventsNeverUpdateCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module qe\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"qe/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+		"util/u.go":   "package util\n\nfunc U() {}\n",
+	})
+	finder := New(root)
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	version := finder.graphVersion
+	imports := slices.Clone(finder.packageCache["qe/lib"].Imports)
+
+	// The file changes on disk, but only queries are routed
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"qe/util\"\n\nfunc Do() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []string{EventQuery, EventCheck, "unknown"} {
+		if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, event); err != nil || !isMine {
+			t.Errorf("%s: expected lib.go to be owned, got %v, %v", event, isMine, err)
+		}
+	}
+	if status, err := finder.CheckFileOwnership("app/main.go", "lib.go", libFile); err != nil || status != "owned" {
+		t.Errorf("CheckFileOwnership = %q, %v", status, err)
+	}
+	if _, err := finder.OwnershipPriority("app/main.go", libFile); err != nil {
+		t.Fatal(err)
+	}
+	if finder.graphVersion != version || !slices.Equal(finder.packageCache["qe/lib"].Imports, imports) {
+		t.Errorf("queries updated the cache: imports %v", finder.packageCache["qe/lib"].Imports)
+	}
+
+	// The next mutation picks the change up
+	if _, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(finder.packageCache["qe/lib"].Imports, "qe/util") {
+		t.Errorf("expected the write to refresh lib imports, got %v", finder.packageCache["qe/lib"].Imports)
+	}
+}
@@ -46,7 +46,7 @@ func (g *GoDepFind) CheckFileOwnership(mainInputFileRelativePath, fileName, file
 	}
 
 	// Check ownership using existing logic
-	belongs, err := g.thisFileIsMine(mainInputFileRelativePath, filePath, "check")
+	belongs, err := g.thisFileIsMine(mainInputFileRelativePath, filePath, EventQuery)
 	if err != nil {
 		return "", err
 	}
@@ -56,67 +56,3 @@ func (g *GoDepFind) CheckFileOwnership(mainInputFileRelativePath, fileName, file
 	}
 	return "not-owned", nil
 }
-
-// AnalyzeFileImpact analyzes the impact of a file change with validation
-// Yet another example showing reusability
-func (g *GoDepFind) AnalyzeFileImpact(mainInputFileRelativePath, fileName, filePath, event string) (*FileImpactResult, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	// Reuse centralized validation
-	shouldProcess, err := g.validateInputForProcessing(mainInputFileRelativePath, fileName, filePath)
-	if err != nil {
-		return nil, err
-	}
-	if !shouldProcess {
-		return &FileImpactResult{
-			Status: "skipped",
-			Reason: "File is invalid, empty, or being written",
-			Impact: "none",
-		}, nil
-	}
-
-	// Perform impact analysis
-	mainPackages, err := g.goFileComesFromMain(fileName)
-	if err != nil {
-		return nil, err
-	}
-
-	belongs, err := g.thisFileIsMine(mainInputFileRelativePath, filePath, event)
-	if err != nil {
-		return nil, err
-	}
-
-	return &FileImpactResult{
-		Status:           "analyzed",
-		BelongsToHandler: belongs,
-		AffectedMains:    mainPackages,
-		Impact:           calculateImpact(len(mainPackages), belongs),
-	}, nil
-}
-
-// FileImpactResult represents the result of file impact analysis
-type FileImpactResult struct {
-	Status           string   `json:"status"`
-	Reason           string   `json:"reason,omitempty"`
-	BelongsToHandler bool     `json:"belongs_to_handler"`
-	AffectedMains    []string `json:"affected_mains"`
-	Impact           string   `json:"impact"`
-}
-
-// calculateImpact determines the impact level based on analysis results
-func calculateImpact(mainCount int, belongsToHandler bool) string {
-	if mainCount == 0 {
-		return "none"
-	}
-	if belongsToHandler && mainCount == 1 {
-		return "low"
-	}
-	if belongsToHandler && mainCount > 1 {
-		return "medium"
-	}
-	if !belongsToHandler && mainCount > 0 {
-		return "high" // File affects mains but doesn't belong to current handler
-	}
-	return "unknown"
-}
@@ -0,0 +1,393 @@
+package depfind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OwnershipReason classifies why a handler owns a file or not
+type OwnershipReason string
+
+const (
+	// ReasonHandlerMainFile: the file is the handler's own main file
+	ReasonHandlerMainFile OwnershipReason = "handler-main-file"
+	// ReasonExternalModule: the file is outside every root, assumed to be a locally replaced module
+	ReasonExternalModule OwnershipReason = "external-module"
+	// ReasonForced: an override pins the file to the handler, see ForceOwnership
+	ReasonForced OwnershipReason = "forced"
+	// ReasonHandlerPackage: the file belongs to the handler's main package
+	ReasonHandlerPackage OwnershipReason = "handler-package"
+	// ReasonImported: the handler main imports the file's package, see Chain
+	ReasonImported OwnershipReason = "imported"
+	// ReasonHandlerSubtree: the file is under the main's directory, see SetHandlerOwnsSubtree
+	ReasonHandlerSubtree OwnershipReason = "handler-subtree"
+	// ReasonPathFallback: the file is in no known package and was assigned by location
+	ReasonPathFallback OwnershipReason = "path-fallback"
+
+	// ReasonNotInRoot: the file is outside the roots and not routed, see NotInRootError
+	ReasonNotInRoot OwnershipReason = "not-in-root"
+	// ReasonOutOfScope: the file is outside the finder's scope, see ScopedFinder
+	ReasonOutOfScope OwnershipReason = "out-of-scope"
+	// ReasonGitignored: the file is ignored by a .gitignore, see SetRespectGitignore
+	ReasonGitignored OwnershipReason = "gitignored"
+	// ReasonToolDir: the file is in a tool-managed directory (.git, node_modules, dist, ...), see SetSkipToolDirs
+	ReasonToolDir OwnershipReason = "tool-dir"
+	// ReasonExcludedTree: the file is in an excluded vendored tree, see ExcludedDirs
+	ReasonExcludedTree OwnershipReason = "excluded-tree"
+	// ReasonNestedModule: the file is in a module nested in a root, see ModuleFinder
+	ReasonNestedModule OwnershipReason = "nested-module"
+	// ReasonTestOnly: the file is test-only and the handler does not claim tests
+	ReasonTestOnly OwnershipReason = "test-only"
+	// ReasonExcludedByTags: the handler's build tags, or the target of its main
+	// file, exclude the file, see SetHandlerTags
+	ReasonExcludedByTags OwnershipReason = "excluded-by-tags"
+	// ReasonNotInPackage: the file is in no known package and guessing is disabled
+	ReasonNotInPackage OwnershipReason = "not-in-package"
+	// ReasonNotReachable: no main imports the file's package
+	ReasonNotReachable OwnershipReason = "not-reachable"
+	// ReasonForcedToOther: an override pins the file to other handlers, see OwnedBy
+	ReasonForcedToOther OwnershipReason = "forced-to-other"
+	// ReasonOwnedByOther: only other mains import the file's package, see OwnedBy
+	ReasonOwnedByOther OwnershipReason = "owned-by-other"
+)
+
+// Explanation is a structured answer to "why does (or doesn't) this handler
+// own this file", ready to display when a change did not rebuild something
+type Explanation struct {
+	Handler string          `json:"handler"`
+	File    string          `json:"file"`
+	Owned   bool            `json:"owned"`
+	Reason  OwnershipReason `json:"reason"`
+	Package string          `json:"package,omitempty"`  // package containing the file, when known
+	Chain   []string        `json:"chain,omitempty"`    // handler main file, then the import path to Package
+	OwnedBy []string        `json:"owned_by,omitempty"` // other main packages importing Package, or handlers the file is pinned to
+
+	// ByFilename is set when the file's path is not indexed and Package was
+	// guessed from its name, see SetFilenameFallback
+	ByFilename bool `json:"by_filename,omitempty"`
+
+	// Variant is set when the file is only built for some targets, by its
+	// GOOS/GOARCH name suffix or its constraint: the answer holds under
+	// Variant.Context() only
+	Variant *FileVariant `json:"variant,omitempty"`
+}
+
+func (e Explanation) String() string {
+	verdict := "does not own"
+	if e.Owned {
+		verdict = "owns"
+	}
+	s := fmt.Sprintf("%s %s %s: %s", e.Handler, verdict, e.File, e.Reason)
+	switch {
+	case len(e.Chain) > 0:
+		s += " (" + strings.Join(e.Chain, " -> ") + ")"
+	case len(e.OwnedBy) > 0:
+		s += " (imported by " + strings.Join(e.OwnedBy, ", ") + ")"
+	}
+	if e.ByFilename {
+		s += " [package guessed by filename]"
+	}
+	if e.Variant != nil {
+		s += " [built for " + e.Variant.Context() + " only]"
+	}
+	return s
+}
+
+// ExplainOwnership reports whether the handler owns filePath and why, using
+// the same rules as ThisFileIsMine without updating the cache. Ownership via
+// imports comes with the import chain from the handler main file; a file not
+// owned names the reason, and the other mains importing its package if any.
+func (g *GoDepFind) ExplainOwnership(mainInputFileRelativePath, filePath string) (*Explanation, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.explainOwnership(mainInputFileRelativePath, filePath)
+}
+
+func (g *GoDepFind) explainOwnership(mainInputFileRelativePath, filePath string) (*Explanation, error) {
+	if filePath == "" || mainInputFileRelativePath == "" {
+		return nil, fmt.Errorf("handler and file paths cannot be empty")
+	}
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	if _, err := os.Stat(handlerAbsPath); err != nil {
+		return nil, fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+	}
+	fileAbsPath := g.rootPath(filePath)
+	exp := &Explanation{Handler: mainInputFileRelativePath, File: filePath}
+
+	if fileAbsPath == handlerAbsPath {
+		exp.Owned, exp.Reason = true, ReasonHandlerMainFile
+		return exp, nil
+	}
+	if !g.inRoots(fileAbsPath) {
+		if g.checkExternalFile(fileAbsPath) != nil {
+			exp.Reason = ReasonNotInRoot
+			return exp, nil
+		}
+		exp.Owned, exp.Reason = true, ReasonExternalModule
+		return exp, nil
+	}
+	if !g.inScope(fileAbsPath) {
+		exp.Reason = ReasonOutOfScope
+		return exp, nil
+	}
+	if g.gitIgnored(fileAbsPath) {
+		exp.Reason = ReasonGitignored
+		return exp, nil
+	}
+	if g.inToolDir(fileAbsPath) {
+		exp.Reason = ReasonToolDir
+		return exp, nil
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if g.isExcluded(fileAbsPath) {
+		exp.Reason = ReasonExcludedTree
+		return exp, nil
+	}
+	if g.inNestedModule(fileAbsPath) {
+		exp.Reason = ReasonNestedModule
+		return exp, nil
+	}
+
+	decision, err := g.packageOwnership(mainInputFileRelativePath, fileAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	exp.Owned, exp.Reason, exp.Package, exp.OwnedBy = decision.Owned, decision.Reason, decision.Package, decision.OwnedBy
+	exp.ByFilename = decision.ByFilename
+	if pkgPath := exp.Package; pkgPath != "" || exp.Reason == ReasonExcludedByTags {
+		if pkgPath == "" {
+			pkgPath = g.filePathToPackage[fileAbsPath]
+		}
+		exp.Variant = g.fileVariant(pkgPath, fileAbsPath)
+	}
+
+	switch exp.Reason {
+	case ReasonImported:
+		exp.Chain = g.importChain(mainInputFileRelativePath, handlerAbsPath, exp.Package)
+	case ReasonNotReachable:
+		for _, mainPkg := range g.mainPackages {
+			imports, err := g.cachedMainImportsPackage(mainPkg, exp.Package)
+			if err != nil {
+				return nil, err
+			}
+			if mainPkg == exp.Package || imports {
+				exp.OwnedBy = append(exp.OwnedBy, mainPkg)
+			}
+		}
+		sort.Strings(exp.OwnedBy)
+		if len(exp.OwnedBy) > 0 {
+			exp.Reason = ReasonOwnedByOther
+		}
+	}
+	return exp, nil
+}
+
+// ownershipDecision is the outcome of the package-based ownership rules
+type ownershipDecision struct {
+	Owned   bool
+	Reason  OwnershipReason
+	Package string
+	OwnedBy []string // handlers the file is pinned to, see ForceOwnership
+
+	ByFilename bool // Package was guessed from the file name
+}
+
+// packageOwnership applies the package-based ownership rules for a file
+// inside the roots, see checkPackageBasedOwnership
+func (g *GoDepFind) packageOwnership(mainInputFileRelativePath, fileAbsPath string) (ownershipDecision, error) {
+	if err := g.ensureCacheInitialized(); err != nil {
+		return ownershipDecision{}, err
+	}
+	if handlers, forced := g.forcedHandlers(fileAbsPath); forced {
+		if contains(handlers, handlerKey(mainInputFileRelativePath)) {
+			return ownershipDecision{Owned: true, Reason: ReasonForced}, nil
+		}
+		return ownershipDecision{Reason: ReasonForcedToOther, OwnedBy: handlers}, nil
+	}
+	if g.testOnlyFiles[fileAbsPath] && !g.claimsTests(mainInputFileRelativePath) {
+		return ownershipDecision{Reason: ReasonTestOnly, Package: g.filePathToPackage[fileAbsPath]}, nil
+	}
+	if g.excludedByHandlerTags(mainInputFileRelativePath, fileAbsPath) {
+		return ownershipDecision{Reason: ReasonExcludedByTags}, nil
+	}
+	if g.inHandlerSubtree(mainInputFileRelativePath, fileAbsPath) {
+		return ownershipDecision{Owned: true, Reason: ReasonHandlerSubtree}, nil
+	}
+
+	// Find which packages may contain the target file
+	candidates, err := g.findPackagesForFile(fileAbsPath)
+	if err != nil {
+		return ownershipDecision{}, err
+	}
+
+	// Fallback: empty cache (go list failed), but file is under a rootDir
+	// where the handler also exists -> assume it belongs
+	if len(candidates) == 0 {
+		if g.filenameFallback == FallbackDisabled && len(g.packageCache) > 0 {
+			return ownershipDecision{Reason: ReasonNotInPackage}, nil // unknown file and guessing is disabled
+		}
+		for _, root := range g.rootDirs {
+			handlerMainAbs := filepath.Join(root, mainInputFileRelativePath)
+			_, statErr := os.Stat(handlerMainAbs)
+			if err := g.fsError(statErr); err != nil {
+				return ownershipDecision{}, err
+			}
+			if statErr == nil {
+				if strings.HasPrefix(fileAbsPath, root+string(filepath.Separator)) {
+					return ownershipDecision{Owned: true, Reason: ReasonPathFallback}, nil
+				}
+			}
+		}
+		return ownershipDecision{Reason: ReasonNotInPackage}, nil
+	}
+
+	// Check if target package should belong to this handler
+	_, indexed := g.filePathToPackage[fileAbsPath]
+	_, removed := g.removedFiles[fileAbsPath]
+	indexed = indexed || removed || g.variantPackage(fileAbsPath) != ""
+	for _, targetPkg := range candidates {
+		owned, err := g.handlerOwnsPackage(targetPkg, mainInputFileRelativePath)
+		if err != nil {
+			return ownershipDecision{}, err
+		}
+		if owned {
+			reason := ReasonImported
+			if g.isMainPackage(targetPkg) {
+				reason = ReasonHandlerPackage
+			}
+			return ownershipDecision{Owned: true, Reason: reason, Package: targetPkg, ByFilename: !indexed}, nil
+		}
+	}
+	return ownershipDecision{Reason: ReasonNotReachable, Package: candidates[0], ByFilename: !indexed}, nil
+}
+
+// importChain returns the shortest import path from the handler main file to
+// targetPkg: the main file, then each package down to targetPkg
+func (g *GoDepFind) importChain(mainInputFileRelativePath, handlerAbsPath, targetPkg string) []string {
+	imports, err := g.parseFileImports(handlerAbsPath)
+	if err != nil {
+		return nil
+	}
+	var queue []string
+	parent := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, imp := range imports {
+		imp = g.resolveImport(imp, filepath.Dir(handlerAbsPath))
+		if !seen[imp] {
+			seen[imp] = true
+			queue = append(queue, imp)
+		}
+	}
+	if handlerPkg, ok := g.filePathToPackage[handlerAbsPath]; ok {
+		for _, to := range g.virtualTargets(handlerPkg) {
+			if !seen[to] {
+				seen[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == targetPkg {
+			chain := []string{current}
+			for p, ok := parent[current]; ok; p, ok = parent[p] {
+				chain = append(chain, p)
+			}
+			chain = append(chain, mainInputFileRelativePath)
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			return chain
+		}
+		for _, dep := range g.dependencyGraph[current] {
+			if !seen[dep] {
+				seen[dep] = true
+				parent[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// maxImportChains bounds the chains returned by ExplainImportPath
+const maxImportChains = 32
+
+// ExplainImportPath returns the shortest import chains from fromPkg to toPkg
+// in the cached graph, virtual edges included: each chain starts with
+// fromPkg and ends with toPkg, and chains are sorted. There may be several
+// chains of the same length (at most 32 are returned); none means fromPkg
+// does not depend on toPkg.
+func (g *GoDepFind) ExplainImportPath(fromPkg, toPkg string) ([][]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.packageCache[fromPkg]; !ok {
+		return nil, fmt.Errorf("package not found in cache: %s", fromPkg)
+	}
+	if fromPkg == toPkg {
+		return [][]string{{fromPkg}}, nil
+	}
+
+	// Breadth-first, keeping every parent at the minimum distance
+	dist := map[string]int{fromPkg: 0}
+	parents := make(map[string][]string)
+	queue := []string{fromPkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, found := dist[toPkg]; found && dist[current] >= dist[toPkg] {
+			break
+		}
+		for _, dep := range g.dependencyGraph[current] {
+			d, seen := dist[dep]
+			if !seen {
+				dist[dep] = dist[current] + 1
+				queue = append(queue, dep)
+			} else if d != dist[current]+1 {
+				continue
+			}
+			if !contains(parents[dep], current) {
+				parents[dep] = append(parents[dep], current)
+			}
+		}
+	}
+	if _, found := dist[toPkg]; !found {
+		return [][]string{}, nil
+	}
+
+	// Walk the parents back from toPkg
+	var chains [][]string
+	var walk func(pkg string, suffix []string)
+	walk = func(pkg string, suffix []string) {
+		if len(chains) >= maxImportChains {
+			return
+		}
+		suffix = append([]string{pkg}, suffix...)
+		if pkg == fromPkg {
+			chains = append(chains, suffix)
+			return
+		}
+		candidates := append([]string(nil), parents[pkg]...)
+		sort.Strings(candidates)
+		for _, parent := range candidates {
+			walk(parent, suffix)
+		}
+	}
+	walk(toPkg, nil)
+
+	sort.Slice(chains, func(i, j int) bool {
+		return strings.Join(chains[i], "\x00") < strings.Join(chains[j], "\x00")
+	})
+	return chains, nil
+}
@@ -0,0 +1,138 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainOwnership(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module ex\n\ngo 1.21\n",
+		"app/main.go":        "package main\n\nimport \"ex/db\"\n\nfunc main() { db.Open() }\n",
+		"app/extra.go":       "package main\n",
+		"admin/main.go":      "package main\n\nimport \"ex/auth\"\n\nfunc main() { auth.Check() }\n",
+		"db/db.go":           "package db\n\nimport \"ex/driver\"\n\nfunc Open() { driver.Load() }\n",
+		"db/db_ext_test.go":  "package db_test\n\nimport \"testing\"\n\nfunc TestX(t *testing.T) {}\n",
+		"driver/driver.go":   "package driver\n\nfunc Load() {}\n",
+		"driver/tiny.go":     "//go:build !tinygo\n\npackage driver\n",
+		"auth/auth.go":       "package auth\n\nfunc Check() {}\n",
+		"orphan/orphan.go":   "package orphan\n",
+		"third_party/x/x.go": "package x\n",
+		"third_party/x/y.go": "package y\n", // mixed packages exclude the tree
+	})
+	external := writeTree(t, map[string]string{"lib.go": "package lib\n"})
+	finder := New(root)
+	finder.SetHandlerTags("app/main.go", "tinygo")
+
+	cases := []struct {
+		file    string
+		owned   bool
+		reason  OwnershipReason
+		chain   []string
+		ownedBy []string
+	}{
+		{"third_party/x/x.go", false, ReasonExcludedTree, nil, nil}, // first query, cold cache
+		{"app/main.go", true, ReasonHandlerMainFile, nil, nil},
+		{"app/extra.go", true, ReasonHandlerPackage, nil, nil},
+		{"driver/driver.go", true, ReasonImported, []string{"app/main.go", "ex/db", "ex/driver"}, nil},
+		{filepath.Join(external, "lib.go"), true, ReasonExternalModule, nil, nil},
+		{"db/db_ext_test.go", false, ReasonTestOnly, nil, nil},
+		{"driver/tiny.go", false, ReasonExcludedByTags, nil, nil},
+		{"auth/auth.go", false, ReasonOwnedByOther, nil, []string{"ex/admin"}},
+		{"orphan/orphan.go", false, ReasonNotReachable, nil, nil},
+		{"third_party/x/x.go", false, ReasonExcludedTree, nil, nil},
+	}
+	for _, tc := range cases {
+		exp, err := finder.ExplainOwnership("app/main.go", tc.file)
+		if err != nil {
+			t.Fatalf("ExplainOwnership(%s): %v", tc.file, err)
+		}
+		if exp.Owned != tc.owned || exp.Reason != tc.reason {
+			t.Errorf("ExplainOwnership(%s) = %v/%s, want %v/%s", tc.file, exp.Owned, exp.Reason, tc.owned, tc.reason)
+		}
+		if !reflect.DeepEqual(exp.Chain, tc.chain) {
+			t.Errorf("ExplainOwnership(%s).Chain = %v, want %v", tc.file, exp.Chain, tc.chain)
+		}
+		if !reflect.DeepEqual(exp.OwnedBy, tc.ownedBy) {
+			t.Errorf("ExplainOwnership(%s).OwnedBy = %v, want %v", tc.file, exp.OwnedBy, tc.ownedBy)
+		}
+
+		// The explanation agrees with routing
+		if isMine, err := finder.ThisFileIsMine("app/main.go", tc.file, EventCheck); err != nil || isMine != exp.Owned {
+			t.Errorf("ThisFileIsMine(%s) = %v, %v; explanation says %v", tc.file, isMine, err, exp.Owned)
+		}
+	}
+
+	exp, _ := finder.ExplainOwnership("app/main.go", "auth/auth.go")
+	if s := exp.String(); !strings.Contains(s, "does not own") || !strings.Contains(s, "ex/admin") {
+		t.Errorf("unexpected String(): %s", s)
+	}
+	if _, err := finder.ExplainOwnership("missing/main.go", "db/db.go"); err == nil {
+		t.Error("expected an error for a missing handler")
+	}
+
+	scoped := finder.ScopedFinder("admin")
+	if exp, err := scoped.ExplainOwnership("admin/main.go", "db/db.go"); err != nil || exp.Reason != ReasonOutOfScope {
+		t.Errorf("expected out-of-scope from a scoped finder, got %+v, %v", exp, err)
+	}
+}
+
+func TestExplainImportPath(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module ip\n\ngo 1.21\n",
+		"app/main.go":      "package main\n\nimport (\n\t\"ip/api\"\n\t\"ip/jobs\"\n)\n\nfunc main() { api.A(); jobs.J() }\n",
+		"api/api.go":       "package api\n\nimport \"ip/db\"\n\nfunc A() { db.Open() }\n",
+		"jobs/jobs.go":     "package jobs\n\nimport \"ip/db\"\n\nfunc J() { db.Open() }\n",
+		"db/db.go":         "package db\n\nimport \"ip/driver\"\n\nfunc Open() { driver.Load() }\n",
+		"driver/driver.go": "package driver\n\nfunc Load() {}\n",
+		"orphan/orphan.go": "package orphan\n",
+	})
+	finder := New(root)
+
+	chains, err := finder.ExplainImportPath("ip/app", "ip/driver")
+	if err != nil {
+		t.Fatalf("ExplainImportPath: %v", err)
+	}
+	want := [][]string{
+		{"ip/app", "ip/api", "ip/db", "ip/driver"},
+		{"ip/app", "ip/jobs", "ip/db", "ip/driver"},
+	}
+	if !reflect.DeepEqual(chains, want) {
+		t.Errorf("ExplainImportPath = %v, want %v", chains, want)
+	}
+
+	if chains, err := finder.ExplainImportPath("ip/app", "ip/orphan"); err != nil || len(chains) != 0 {
+		t.Errorf("expected no chain to an unrelated package, got %v, %v", chains, err)
+	}
+	if _, err := finder.ExplainImportPath("ip/missing", "ip/db"); err == nil {
+		t.Error("expected an error for an unknown source package")
+	}
+}
+
+func TestExplainOwnershipByFilename(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module fn\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"fn/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":    "package db\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+	if exp, err := finder.ExplainOwnership("app/main.go", "db/db.go"); err != nil || exp.ByFilename {
+		t.Fatalf("expected an indexed resolution, got %+v, %v", exp, err)
+	}
+
+	// A file the cache has never seen only matches by name
+	stray := writeTree(t, map[string]string{"db.go": "package stray\n"})
+	if err := os.Rename(filepath.Join(stray, "db.go"), filepath.Join(root, "app", "db.go")); err != nil {
+		t.Fatal(err)
+	}
+	exp, err := finder.ExplainOwnership("app/main.go", "app/db.go")
+	if err != nil || !exp.ByFilename || exp.Package != "fn/db" || exp.Reason != ReasonImported {
+		t.Errorf("expected a filename-based resolution, got %+v, %v", exp, err)
+	}
+	if !strings.Contains(exp.String(), "guessed by filename") {
+		t.Errorf("unexpected String(): %s", exp)
+	}
+}
@@ -0,0 +1,173 @@
+package depfind
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExportSchemaVersion is the version of the ExportJSON schema; it changes
+// only when fields are removed or change meaning
+const ExportSchemaVersion = 1
+
+// GraphExport is the document written by ExportJSON
+type GraphExport struct {
+	Version  int               `json:"version"`
+	Packages []ExportedPackage `json:"packages"`
+}
+
+// ExportedPackage is a cached package with its files and edges
+type ExportedPackage struct {
+	PackageInfo
+	GoFiles     []string `json:"go_files"`     // file names in Dir, sorted
+	Imports     []string `json:"imports"`      // direct dependencies, virtual edges included, sorted
+	ReverseDeps []string `json:"reverse_deps"` // cached packages importing this one, sorted
+}
+
+// ExportJSON writes the cached dependency data (packages, their Go files,
+// imports, reverse dependencies and main markers) as indented JSON in a
+// stable schema: packages and every list are sorted, so two exports of the
+// same tree are byte-identical.
+func (g *GoDepFind) ExportJSON(w io.Writer) error {
+	g.mu.Lock()
+	export, err := g.graphExport()
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// graphExport builds the export document from the cache (mu held)
+func (g *GoDepFind) graphExport() (*GraphExport, error) {
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	export := &GraphExport{Version: ExportSchemaVersion, Packages: []ExportedPackage{}}
+	for importPath, pkg := range g.packageCache {
+		if pkg == nil {
+			continue
+		}
+		export.Packages = append(export.Packages, ExportedPackage{
+			PackageInfo: newPackageInfo(importPath, pkg),
+			GoFiles:     sortedUnique(pkg.GoFiles),
+			Imports:     sortedUnique(g.dependencyGraph[importPath]),
+			ReverseDeps: sortedUnique(g.reverseDeps[importPath]),
+		})
+	}
+	sort.Slice(export.Packages, func(i, j int) bool { return export.Packages[i].Path < export.Packages[j].Path })
+	return export, nil
+}
+
+// OwnershipFormat selects the encoding written by ExportOwnership
+type OwnershipFormat string
+
+const (
+	OwnershipJSON OwnershipFormat = "json" // indented OwnershipExport document
+	OwnershipCSV  OwnershipFormat = "csv"  // file,package,mains,targets header then one row per file; lists space separated
+)
+
+// OwnershipExport is the document written by ExportOwnership in OwnershipJSON
+type OwnershipExport struct {
+	Version int              `json:"version"` // ExportSchemaVersion
+	Files   []OwnedFileEntry `json:"files"`
+}
+
+// OwnedFileEntry is a source file and the handlers compiling it
+type OwnedFileEntry struct {
+	File    string   `json:"file"`    // relative to the primary root, slash separated
+	Package string   `json:"package"` // import path of its package
+	Mains   []string `json:"mains"`   // handler main files compiling it, as WhichMainsUseFile
+	Targets []string `json:"targets"` // import paths of the main packages of Mains, the binaries to declare
+}
+
+// ExportOwnership writes, for every non-test Go file of the cached packages,
+// the handlers whose build compiles it (see WhichMainsUseFile), so an
+// external build graph generator (a Bazel or Please migration) can bootstrap
+// target definitions from the analysis. Files built for no handler are
+// listed with empty Mains. Files and lists are sorted: two exports of the
+// same tree are byte-identical.
+func (g *GoDepFind) ExportOwnership(w io.Writer, format OwnershipFormat) error {
+	if format != OwnershipJSON && format != OwnershipCSV {
+		return fmt.Errorf("unknown ownership format %q", format)
+	}
+	g.mu.Lock()
+	export, err := g.ownershipExport()
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if format == OwnershipJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"file", "package", "mains", "targets"})
+	for _, entry := range export.Files {
+		writer.Write([]string{entry.File, entry.Package, strings.Join(entry.Mains, " "), strings.Join(entry.Targets, " ")})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ownershipExport builds the ownership document from the cache (mu held)
+func (g *GoDepFind) ownershipExport() (*OwnershipExport, error) {
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(handlers)) // main file -> main package
+	for _, h := range handlers {
+		targets[h.MainFile] = h.Package
+	}
+
+	export := &OwnershipExport{Version: ExportSchemaVersion, Files: []OwnedFileEntry{}}
+	closures := make(map[string]map[string]bool)
+	for _, pkgPath := range sortedKeys(g.cachedPackageSet()) {
+		pkg := g.packageCache[pkgPath]
+		names := append(append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...), pkg.IgnoredGoFiles...)
+		for _, name := range sortedUnique(names) {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			path := filepath.Join(pkg.Dir, name)
+			entry := OwnedFileEntry{File: path, Package: pkgPath, Mains: []string{}, Targets: []string{}}
+			if rel, err := filepath.Rel(g.rootDirs[0], path); err == nil && !strings.HasPrefix(rel, "..") {
+				entry.File = filepath.ToSlash(rel)
+			}
+			target := make(map[string]bool)
+			for _, main := range g.mainsUsingFile(path, handlers, closures) {
+				entry.Mains = append(entry.Mains, main)
+				if targets[main] != "" {
+					target[targets[main]] = true
+				}
+			}
+			entry.Targets = sortedKeys(target)
+			export.Files = append(export.Files, entry)
+		}
+	}
+	sort.Slice(export.Files, func(i, j int) bool { return export.Files[i].File < export.Files[j].File })
+	return export, nil
+}
+
+// sortedUnique returns a sorted copy of list without duplicates, never nil
+func sortedUnique(list []string) []string {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	return sortedKeys(set)
+}
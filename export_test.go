@@ -0,0 +1,89 @@
+package depfind
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExportJSON(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module exp\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"exp/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "// Package lib does things.\npackage lib\n\nfunc Do() {}\n",
+		"lib/extra.go": "package lib\n",
+	})
+	finder := New(root)
+
+	var first, second bytes.Buffer
+	if err := finder.ExportJSON(&first); err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if err := finder.ExportJSON(&second); err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("expected exports to be byte-identical")
+	}
+
+	var export GraphExport
+	if err := json.Unmarshal(first.Bytes(), &export); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if export.Version != ExportSchemaVersion || len(export.Packages) != 2 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+	app, lib := export.Packages[0], export.Packages[1]
+	if app.Path != "exp/app" || !app.Main || !reflect.DeepEqual(app.Imports, []string{"exp/lib"}) || len(app.ReverseDeps) != 0 {
+		t.Errorf("unexpected app package: %+v", app)
+	}
+	if lib.Path != "exp/lib" || lib.Main || lib.Doc != "Package lib does things." ||
+		!reflect.DeepEqual(lib.GoFiles, []string{"extra.go", "lib.go"}) || !reflect.DeepEqual(lib.ReverseDeps, []string{"exp/app"}) {
+		t.Errorf("unexpected lib package: %+v", lib)
+	}
+}
+
+func TestExportOwnership(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module own\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"own/db\"\n\nfunc main() { db.Open() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"own/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":           "package db\n\nfunc Open() {}\n",
+		"db/db_js.go":        "package db\n",
+		"db/db_test.go":      "package db\n",
+		"unused/unused.go":   "package unused\n",
+	})
+	finder := New(root)
+
+	var buf bytes.Buffer
+	if err := finder.ExportOwnership(&buf, OwnershipJSON); err != nil {
+		t.Fatalf("ExportOwnership: %v", err)
+	}
+	var export OwnershipExport
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	both := []string{"pwa/main.server.go", "pwa/main.wasm.go"}
+	want := []OwnedFileEntry{
+		{File: "db/db.go", Package: "own/db", Mains: both, Targets: []string{"own/pwa"}},
+		{File: "db/db_js.go", Package: "own/db", Mains: []string{"pwa/main.wasm.go"}, Targets: []string{"own/pwa"}},
+		{File: "pwa/main.server.go", Package: "own/pwa", Mains: []string{"pwa/main.server.go"}, Targets: []string{"own/pwa"}},
+		{File: "pwa/main.wasm.go", Package: "own/pwa", Mains: []string{"pwa/main.wasm.go"}, Targets: []string{"own/pwa"}},
+		{File: "unused/unused.go", Package: "own/unused", Mains: []string{}, Targets: []string{}},
+	}
+	if !reflect.DeepEqual(export.Files, want) {
+		t.Errorf("ExportOwnership files = %+v, want %+v", export.Files, want)
+	}
+
+	buf.Reset()
+	if err := finder.ExportOwnership(&buf, OwnershipCSV); err != nil {
+		t.Fatalf("ExportOwnership: %v", err)
+	}
+	if want := "file,package,mains,targets\ndb/db.go,own/db,pwa/main.server.go pwa/main.wasm.go,own/pwa\n"; !bytes.HasPrefix(buf.Bytes(), []byte(want)) {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+	if err := finder.ExportOwnership(&buf, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
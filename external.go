@@ -0,0 +1,153 @@
+package depfind
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// externalTarget is a reverse dependency target inside a third-party module
+// the go.mod of a root requires. Its packages live in the module cache and
+// are never loaded: an import matching the target is enough.
+type externalTarget struct {
+	module    string // required module path
+	path      string // package path, the prefix of a "/..." pattern
+	recursive bool   // "/..." pattern
+	version   string // only match modules requiring this version, "" for any
+}
+
+// matchesImport reports whether an import path is one of the target packages
+func (t externalTarget) matchesImport(imp string) bool {
+	if imp == t.path {
+		return true
+	}
+	return t.recursive && strings.HasPrefix(imp, t.path+"/")
+}
+
+// externalTarget resolves a FindReverseDeps target ("github.com/gorilla/mux",
+// "github.com/gorilla/mux@v1.8.0", "golang.org/x/net/...") against the
+// requirements of the root modules. ok is false for targets no root module
+// requires (local packages, the standard library), which are listed as before.
+func (g *GoDepFind) externalTarget(target string) (externalTarget, bool) {
+	path, version, _ := strings.Cut(target, "@")
+	t := externalTarget{path: path, version: version}
+	if trimmed, ok := strings.CutSuffix(path, "/..."); ok {
+		t.path, t.recursive = trimmed, true
+	}
+	if _, local := g.moduleDir(t.path); local {
+		return externalTarget{}, false
+	}
+	for _, root := range g.rootDirs {
+		modRoot := findModuleRoot(root)
+		if modRoot == "" {
+			continue
+		}
+		requires, err := requiredModules(filepath.Join(modRoot, "go.mod"))
+		if err != nil {
+			continue
+		}
+		for module := range requires {
+			if (t.path == module || strings.HasPrefix(t.path, module+"/")) && len(module) > len(t.module) {
+				t.module = module
+			}
+		}
+	}
+	return t, t.module != ""
+}
+
+// externalMatcher marks as targets the imports of loaded source packages that
+// match external targets, honoring the version required by the go.mod
+// governing each importing package
+type externalMatcher struct {
+	targets  []externalTarget
+	requires map[string]map[string]string // module root -> required module -> version
+}
+
+// clone returns a matcher for the same targets with its own memo, for
+// concurrent scans
+func (m *externalMatcher) clone() *externalMatcher {
+	return &externalMatcher{targets: m.targets}
+}
+
+// markImports adds the imports of pkg matching a target to found
+func (m *externalMatcher) markImports(pkg *build.Package, testImports bool, found map[string]bool) {
+	if len(m.targets) == 0 {
+		return
+	}
+	imports := pkg.Imports
+	if testImports {
+		imports = append(append(append([]string(nil), imports...), pkg.TestImports...), pkg.XTestImports...)
+	}
+	for _, imp := range imports {
+		for _, t := range m.targets {
+			if t.matchesImport(imp) && (t.version == "" || m.requiredVersion(pkg.Dir, t.module) == t.version) {
+				found[imp] = true
+			}
+		}
+	}
+}
+
+// requiredVersion returns the version of module required by the go.mod
+// governing dir, memoized per module root
+func (m *externalMatcher) requiredVersion(dir, module string) string {
+	modRoot := findModuleRoot(dir)
+	if modRoot == "" {
+		return ""
+	}
+	requires, ok := m.requires[modRoot]
+	if !ok {
+		requires, _ = requiredModules(filepath.Join(modRoot, "go.mod"))
+		if m.requires == nil {
+			m.requires = make(map[string]map[string]string)
+		}
+		m.requires[modRoot] = requires
+	}
+	return requires[module]
+}
+
+// requiredModules parses the require directives of a go.mod file into
+// module path -> version
+func requiredModules(goModPath string) (map[string]string, error) {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read go.mod: %w", err)
+	}
+	defer file.Close()
+
+	requires := make(map[string]string)
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case !inBlock:
+			rest, ok := strings.CutPrefix(line, "require ")
+			if !ok {
+				continue
+			}
+			line = rest
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		requires[unquoteModField(fields[0])] = unquoteModField(fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read go.mod: %w", err)
+	}
+	return requires, nil
+}
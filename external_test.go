@@ -0,0 +1,60 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindReverseDepsExternalModules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module ext\n\ngo 1.21\n\nrequire (\n\tgithub.com/gorilla/mux v1.8.0\n\tgolang.org/x/net v0.20.0 // indirect\n)\n",
+		"api/api.go":         "package api\n\nimport \"github.com/gorilla/mux\"\n\nvar R = mux.NewRouter()\n",
+		"web/web.go":         "package web\n\nimport \"ext/api\"\n\nvar R = api.R\n",
+		"ws/ws.go":           "package ws\n\nimport \"golang.org/x/net/websocket\"\n\nvar _ websocket.Conn\n",
+		"plain/plain.go":     "package plain\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+		"cmd/server/main.go": "package main\n\nimport _ \"ext/web\"\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+
+	tests := []struct {
+		targets []string
+		want    []string
+	}{
+		{[]string{"github.com/gorilla/mux"}, []string{"ext/api", "ext/cmd/server", "ext/web"}},
+		{[]string{"github.com/gorilla/mux@v1.8.0"}, []string{"ext/api", "ext/cmd/server", "ext/web"}},
+		{[]string{"github.com/gorilla/mux@v1.7.0"}, nil}, // go.mod requires another version
+		{[]string{"golang.org/x/net/..."}, []string{"ext/ws"}},
+		{[]string{"golang.org/x/net/websocket"}, []string{"ext/ws"}},
+		{[]string{"golang.org/x/net/http2"}, nil},
+		{[]string{"github.com/gorilla/mux", "fmt"}, []string{"ext/api", "ext/cmd/server", "ext/plain", "ext/web"}},
+	}
+	for _, tt := range tests {
+		got, err := finder.FindReverseDeps("./...", tt.targets)
+		if err != nil {
+			t.Errorf("FindReverseDeps(%v): %v", tt.targets, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindReverseDeps(%v) = %v, want %v", tt.targets, got, tt.want)
+		}
+	}
+}
+
+func TestRequiredModules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod": "module req\n\ngo 1.21\n\nrequire example.com/single v1.0.0\n\nrequire (\n\t\"example.com/quoted\" v2.1.0+incompatible\n\texample.com/other v0.3.0 // indirect\n)\n",
+	})
+	requires, err := requiredModules(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"example.com/single": "v1.0.0",
+		"example.com/quoted": "v2.1.0+incompatible",
+		"example.com/other":  "v0.3.0",
+	}
+	if !reflect.DeepEqual(requires, want) {
+		t.Errorf("requiredModules = %v, want %v", requires, want)
+	}
+}
@@ -0,0 +1,44 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFilenameFallbackModes(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":          "module fb\n\ngo 1.21\n",
+		"app/main.go":     "package main\n\nimport \"fb/a\"\n\nfunc main() { a.Do() }\n",
+		"a/util.go":       "package a\n\nfunc Do() {}\n",
+		"b/util.go":       "package b\n",
+		"scratch/util.go": "package scratch\n",
+	})
+	unindexed := filepath.Join(root, "scratch", "util.go")
+
+	tests := []struct {
+		mode     FilenameFallback
+		expected bool
+	}{
+		{FallbackFirstMatch, false},
+		{FallbackAllMatches, true},
+		{FallbackDisabled, false},
+	}
+	for _, tt := range tests {
+		finder := New(root)
+		finder.SetFilenameFallback(tt.mode)
+		if err := finder.ensureCacheInitialized(); err != nil {
+			t.Fatalf("init cache: %v", err)
+		}
+		// Make the file unknown to the path index and the name ambiguous, with a deterministic order
+		delete(finder.filePathToPackage, unindexed)
+		finder.fileToPackages["util.go"] = []string{"fb/b", "fb/a"}
+
+		isMine, err := finder.ThisFileIsMine("app/main.go", unindexed, "check")
+		if err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+		if isMine != tt.expected {
+			t.Errorf("mode %d: expected %v, got %v", tt.mode, tt.expected, isMine)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WhichMainsUseFile returns the handler main files (relative to the primary
+// root, sorted) whose build compiles path. Unlike GoFileComesFromMain the
+// analysis is per file: each main file is followed under its own build
+// context (SetHandlerTags, or the target of the main file), through the
+// imports of the files that context compiles only. A file only built for
+// wasm (db_js.go, //go:build wasm) is then used by main.wasm.go but not by
+// main.server.go, even though both import its package.
+func (g *GoDepFind) WhichMainsUseFile(path string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+	return g.mainsUsingFile(g.rootPath(path), handlers, make(map[string]map[string]bool)), nil
+}
+
+// WhichMainsUseFiles is WhichMainsUseFile for a batch of paths, such as the
+// burst of events following a git checkout: handlers are discovered and each
+// main's closure is computed once for the whole batch. Every path is a key of
+// the result, mapped to nil when no main uses it.
+func (g *GoDepFind) WhichMainsUseFiles(paths []string) (map[string][]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+	closures := make(map[string]map[string]bool)
+	result := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		result[path] = g.mainsUsingFile(g.rootPath(path), handlers, closures)
+	}
+	return result, nil
+}
+
+// mainsUsingFile returns the main files of handlers compiling fileAbsPath.
+// closures memoizes the per-file closure of each main file across calls.
+func (g *GoDepFind) mainsUsingFile(fileAbsPath string, handlers []HandlerDefinition, closures map[string]map[string]bool) []string {
+	pkgPath := g.filePathToPackage[fileAbsPath]
+	if pkgPath == "" {
+		pkgPath = g.variantPackage(fileAbsPath)
+	}
+	if pkgPath == "" && filepath.Ext(fileAbsPath) == ".go" {
+		pkgPath = g.packageInDir(filepath.Dir(fileAbsPath))
+	}
+	if pkgPath == "" {
+		return nil
+	}
+
+	var mains []string
+	for _, h := range handlers {
+		tags, ok := g.handlerContext(h.MainFile)
+		if !ok {
+			continue
+		}
+		if filepath.Ext(fileAbsPath) == ".go" {
+			if matched, err := tags.MatchFile(fileAbsPath); err == nil && !matched {
+				continue
+			}
+		}
+		closure, ok := closures[h.MainFile]
+		if !ok {
+			closure = g.fileClosure(g.rootPath(h.MainFile), tags)
+			closures[h.MainFile] = closure
+		}
+		if closure[pkgPath] {
+			mains = append(mains, h.MainFile)
+		}
+	}
+	sort.Strings(mains)
+	return mains
+}
+
+// fileClosure returns the cached packages compiled with the main file at
+// mainAbs under tags: its own package, then every package imported by a
+// file the tags include, transitively
+func (g *GoDepFind) fileClosure(mainAbs string, tags TagSet) map[string]bool {
+	start := g.packageInDir(filepath.Dir(mainAbs))
+	if start == "" {
+		return nil
+	}
+	closure := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, imp := range g.taggedImports(current, tags) {
+			if g.packageCache[imp] != nil && !closure[imp] {
+				closure[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return closure
+}
+
+// taggedImports returns the imports of the non-test Go files of a cached
+// package that tags include, host-built or not
+func (g *GoDepFind) taggedImports(pkgPath string, tags TagSet) []string {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return nil
+	}
+	entries, err := os.ReadDir(pkg.Dir)
+	if err != nil {
+		return pkg.Imports
+	}
+	var imports []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file := filepath.Join(pkg.Dir, name)
+		if matched, err := tags.MatchFile(file); err != nil || !matched || packageClause(file) != pkg.Name {
+			continue
+		}
+		specs, err := g.parseFileImportSpecs(file)
+		if err != nil {
+			continue
+		}
+		for _, spec := range specs {
+			if imp := g.resolveImport(spec.Path, pkg.Dir); !contains(imports, imp) {
+				imports = append(imports, imp)
+			}
+		}
+	}
+	return imports
+}
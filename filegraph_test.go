@@ -0,0 +1,83 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhichMainsUseFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module fg\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"fg/db\"\n\nfunc main() { db.Open() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"fg/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":        "package main\n\nimport \"fg/util\"\n\nfunc main() { util.Do() }\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "package db\n\nimport \"fg/jsglue\"\n\nfunc open() { jsglue.Call() }\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nimport \"fg/sqlite\"\n\nfunc open() { sqlite.Open() }\n",
+		"jsglue/glue.go":     "package jsglue\n\nfunc Call() {}\n",
+		"sqlite/sqlite.go":   "package sqlite\n\nfunc Open() {}\n",
+		"util/util.go":       "package util\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+
+	cases := map[string][]string{
+		"db/db.go":         {"pwa/main.server.go", "pwa/main.wasm.go"},
+		"db/db_js.go":      {"pwa/main.wasm.go"},
+		"db/db_native.go":  {"pwa/main.server.go"},
+		"jsglue/glue.go":   {"pwa/main.wasm.go"},
+		"sqlite/sqlite.go": {"pwa/main.server.go"},
+		"util/util.go":     {"cli/main.go"},
+	}
+	for file, want := range cases {
+		mains, err := finder.WhichMainsUseFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatalf("WhichMainsUseFile(%s): %v", file, err)
+		}
+		if !reflect.DeepEqual(mains, want) {
+			t.Errorf("WhichMainsUseFile(%s) = %v, want %v", file, mains, want)
+		}
+	}
+
+}
+
+func TestWhichMainsUseFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module fgb\n\ngo 1.21\n",
+		"app/main.go":    "package main\n\nimport \"fgb/lib\"\n\nfunc main() { lib.Do() }\n",
+		"cli/main.go":    "package main\n\nimport (\n\t\"fgb/lib\"\n\t\"fgb/util\"\n)\n\nfunc main() { lib.Do(); util.Do() }\n",
+		"lib/lib.go":     "package lib\n\nfunc Do() {}\n",
+		"util/util.go":   "package util\n\nfunc Do() {}\n",
+		"docs/readme.md": "# docs\n",
+	})
+	finder := New(root)
+
+	paths := []string{
+		filepath.Join(root, "lib", "lib.go"),
+		"util/util.go",
+		filepath.Join(root, "docs", "readme.md"),
+	}
+	got, err := finder.WhichMainsUseFiles(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		paths[0]: {"app/main.go", "cli/main.go"},
+		paths[1]: {"cli/main.go"},
+		paths[2]: nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WhichMainsUseFiles = %v, want %v", got, want)
+	}
+
+	// Same answers as one call per file
+	for _, path := range paths {
+		mains, err := finder.WhichMainsUseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(mains, got[path]) {
+			t.Errorf("WhichMainsUseFile(%s) = %v, batch gave %v", path, mains, got[path])
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsSameFileIdentity(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"app/copy.go": "package main\n",
+	})
+	finder := New(root)
+
+	mainPath := filepath.Join(root, "app", "main.go")
+	linkPath := filepath.Join(root, "app", "main_link.go")
+	if err := os.Link(mainPath, linkPath); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		{"same absolute path", mainPath, mainPath, true},
+		{"relative to root", mainPath, "app/main.go", true},
+		{"hardlink resolves to same file", mainPath, linkPath, true},
+		{"different file", mainPath, filepath.Join(root, "app", "copy.go"), false},
+		{"missing files compare by path", filepath.Join(root, "gone.go"), "gone.go", true},
+		{"missing different files", filepath.Join(root, "a.go"), "b.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finder.isSameFile(tt.a, tt.b); got != tt.expected {
+				t.Errorf("isSameFile(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package depfind
+
+// Finder is the minimal routing API consumed by dev servers and build tools.
+// It is implemented by *GoDepFind; downstream packages can depend on it and
+// inject fakes in their unit tests instead of building real temp modules.
+// Methods are only added to it in major versions.
+type Finder interface {
+	// ThisFileIsMine reports whether the handler owning mainInputFileRelativePath
+	// should process the event for fileAbsPath
+	ThisFileIsMine(mainInputFileRelativePath, fileAbsPath, event string) (bool, error)
+	// GoFileComesFromMain returns the main packages that depend on fileName
+	GoFileComesFromMain(fileName string) ([]string, error)
+	// FindReverseDeps returns the packages matching sourcePath that import any target
+	FindReverseDeps(sourcePath string, targetPaths []string) ([]string, error)
+	// IsWatchRelevant cheaply filters paths before routing events
+	IsWatchRelevant(path string) bool
+}
+
+var _ Finder = (*GoDepFind)(nil)
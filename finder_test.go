@@ -0,0 +1,29 @@
+package depfind
+
+import "testing"
+
+// fakeFinder shows how consumers replace the finder in their own tests
+type fakeFinder struct {
+	owned map[string]bool
+}
+
+func (f fakeFinder) ThisFileIsMine(_, fileAbsPath, _ string) (bool, error) {
+	return f.owned[fileAbsPath], nil
+}
+func (f fakeFinder) GoFileComesFromMain(string) ([]string, error)       { return nil, nil }
+func (f fakeFinder) FindReverseDeps(string, []string) ([]string, error) { return nil, nil }
+func (f fakeFinder) IsWatchRelevant(path string) bool                   { return f.owned[path] }
+
+func TestFinderInterface(t *testing.T) {
+	route := func(finder Finder, file string) bool {
+		isMine, err := finder.ThisFileIsMine("appAserver/main.go", file, EventWrite)
+		return err == nil && isMine
+	}
+
+	if !route(fakeFinder{owned: map[string]bool{"lib.go": true}}, "lib.go") {
+		t.Error("expected the fake to be usable through Finder")
+	}
+	if !route(New("testproject"), "appAserver/main.go") {
+		t.Error("expected *GoDepFind to be usable through Finder")
+	}
+}
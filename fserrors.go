@@ -0,0 +1,47 @@
+package depfind
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// FSErrorPolicy decides what lookups do with file system errors other than
+// a missing file, such as permission denied or I/O errors, see
+// SetFSErrorPolicy
+type FSErrorPolicy int
+
+const (
+	// FailOpen answers as if the unreadable file were absent: a handler main
+	// file that cannot be read imports nothing. This is the default.
+	FailOpen FSErrorPolicy = iota
+	// FailClosed fails the query with the error, so an unreadable file is
+	// never mistaken for a file the handler does not own
+	FailClosed
+)
+
+// SetFSErrorPolicy chooses how ownership lookups (ThisFileIsMine,
+// AnalyzeFileImpact, ImpactSince...) treat file system errors. Under either
+// policy each error is recorded as a WarnFSError warning, see Warnings and
+// SetLogger.
+func (g *GoDepFind) SetFSErrorPolicy(policy FSErrorPolicy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.fsErrorPolicy = policy
+}
+
+// fsError applies the FSErrorPolicy to an error met during a lookup. A nil
+// error, a missing file or an error not coming from the file system (a
+// syntax error) returns nil, as before; other errors are recorded as a
+// warning and returned only under FailClosed.
+func (g *GoDepFind) fsError(err error) error {
+	var pathErr *fs.PathError
+	if err == nil || errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pathErr) {
+		return nil
+	}
+	g.warn(Warning{Kind: WarnFSError, Dir: pathErr.Path, Message: err.Error()})
+	if g.fsErrorPolicy == FailClosed {
+		return err
+	}
+	return nil
+}
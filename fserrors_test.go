@@ -0,0 +1,52 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSErrorPolicy(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module fe\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"fe/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	// A handler main file that exists but cannot be read as a file
+	if err := os.MkdirAll(filepath.Join(root, "tool", "main.go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	libFile := filepath.Join(root, "lib", "lib.go")
+
+	finder := New(root)
+	isMine, err := finder.ThisFileIsMine("tool/main.go", libFile, EventWrite)
+	if err != nil || isMine {
+		t.Fatalf("FailOpen: expected not owned without error, got %v, %v", isMine, err)
+	}
+	if !hasWarning(finder.Warnings(), WarnFSError) {
+		t.Errorf("FailOpen: expected an fs-error warning, got %v", finder.Warnings())
+	}
+
+	finder.SetFSErrorPolicy(FailClosed)
+	if _, err := finder.ThisFileIsMine("tool/main.go", libFile, EventWrite); err == nil {
+		t.Error("FailClosed: expected the read error to be returned")
+	}
+	// A file whose path cannot be resolved (a parent is a file)
+	if _, err := finder.ThisFileIsMine("app/main.go", filepath.Join(libFile, "x.s"), EventWrite); err == nil {
+		t.Error("FailClosed: expected the stat error to be returned")
+	}
+
+	// Readable files are unaffected
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+}
+
+func hasWarning(warnings []Warning, kind WarningKind) bool {
+	for _, w := range warnings {
+		if w.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package depfind
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ignoreRule is one pattern line of a .gitignore file
+type ignoreRule struct {
+	pattern  string // slash separated, without the markers below
+	negate   bool   // "!pattern" re-includes
+	dirOnly  bool   // "pattern/" only matches directories
+	anchored bool   // contains a slash: matched against the path from the .gitignore directory
+}
+
+// ignoreFile is a parsed .gitignore, kept until the file changes on disk
+type ignoreFile struct {
+	modTime time.Time
+	rules   []ignoreRule
+}
+
+// SetRespectGitignore makes the finder honor .gitignore files in the roots
+// (and their subdirectories): ignored paths are never indexed, never routed
+// to a handler and not watch-relevant. Generated output directories such as
+// dist/ then stop flowing through validation and lookups. Disabled by
+// default; the cache is rebuilt on the next query.
+func (g *GoDepFind) SetRespectGitignore(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.respectGitignore != enabled {
+		g.respectGitignore = enabled
+		g.cachedModule = false
+	}
+}
+
+// gitIgnored reports whether an absolute path inside a root is ignored by
+// the .gitignore files of its root and directories. A path inside an
+// ignored directory is ignored whatever deeper files say, like git.
+func (g *GoDepFind) gitIgnored(absPath string) bool {
+	if !g.respectGitignore {
+		return false
+	}
+	rel, ok := g.relToRoot(absPath)
+	if !ok || rel == "." {
+		return false
+	}
+	root := strings.TrimSuffix(absPath, string(filepath.Separator)+rel)
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	isDir := false
+	if info, err := os.Stat(absPath); err == nil {
+		isDir = info.IsDir()
+	}
+	for i := range parts {
+		last := i == len(parts)-1
+		if g.ignoredBy(root, parts[:i+1], !last || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredBy evaluates the .gitignore files from root down to the parent of
+// parts (the path components below root); the last matching rule wins
+func (g *GoDepFind) ignoredBy(root string, parts []string, isDir bool) bool {
+	ignored := false
+	dir := root
+	for depth := 0; depth < len(parts); depth++ {
+		if depth > 0 {
+			dir = filepath.Join(dir, parts[depth-1])
+		}
+		rel := strings.Join(parts[depth:], "/")
+		for _, rule := range g.ignoreRules(dir) {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// ignoreRules returns the rules of dir/.gitignore, parsing it again only
+// when it changed
+func (g *GoDepFind) ignoreRules(dir string) []ignoreRule {
+	g.ignoreMu.Lock()
+	defer g.ignoreMu.Unlock()
+
+	file := filepath.Join(dir, ".gitignore")
+	info, err := os.Stat(file)
+	if err != nil {
+		delete(g.ignoreFiles, dir)
+		return nil
+	}
+	if cached, ok := g.ignoreFiles[dir]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.rules
+	}
+
+	rules := parseGitignore(file)
+	if g.ignoreFiles == nil {
+		g.ignoreFiles = make(map[string]*ignoreFile)
+	}
+	g.ignoreFiles[dir] = &ignoreFile{modTime: info.ModTime(), rules: rules}
+	return rules
+}
+
+// parseGitignore reads the rules of a .gitignore file
+func parseGitignore(file string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\") // escaped leading "#" or "!"
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matches reports whether the rule matches rel, the slash separated path
+// from the .gitignore directory
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return globMatch(r.pattern, path.Base(rel))
+	}
+	return globMatch(r.pattern, rel)
+}
+
+// globMatch matches a slash separated path against a pattern where "**"
+// stands for any number of directories
+func globMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(name) > 0 // "dir/**" matches inside dir, not dir itself
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,62 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRespectGitignore(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                "module gi\n\ngo 1.21\n",
+		".gitignore":            "# build output\ndist/\n*_gen.go\n/tmp\n",
+		"app/main.go":           "package main\n\nimport \"gi/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":            "package lib\n\nfunc Do() {}\n",
+		"lib/lib_gen.go":        "package lib\n",
+		"pwa/.gitignore":        "public/**\n!public/keep.go\n",
+		"pwa/public/js/app.go":  "package js\n",
+		"pwa/public/keep.go":    "package public\n",
+		"dist/assets/assets.go": "package assets\n",
+		"tmp/scratch.go":        "package tmp\n",
+		"sub/tmp/notignored.go": "package tmp\n",
+	})
+	finder := New(root)
+	finder.SetRespectGitignore(true)
+
+	cases := []struct {
+		file    string
+		ignored bool
+	}{
+		{"lib/lib.go", false},
+		{"lib/lib_gen.go", true},
+		{"dist/assets/assets.go", true},
+		{"pwa/public/js/app.go", true},
+		{"pwa/public/keep.go", false},
+		{"tmp/scratch.go", true},
+		{"sub/tmp/notignored.go", false},
+	}
+	for _, tc := range cases {
+		abs := filepath.Join(root, tc.file)
+		if relevant := finder.IsWatchRelevant(abs); relevant == tc.ignored {
+			t.Errorf("IsWatchRelevant(%s) = %v, want %v", tc.file, relevant, !tc.ignored)
+		}
+	}
+
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib_gen.go"), EventWrite); err != nil || isMine {
+		t.Errorf("expected an ignored file not to be routed, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected a tracked file to be routed, got %v, %v", isMine, err)
+	}
+	if _, indexed := finder.filePathToPackage[filepath.Join(root, "lib/lib_gen.go")]; indexed {
+		t.Error("expected the ignored file not to be indexed")
+	}
+	if exp, _ := finder.ExplainOwnership("app/main.go", "lib/lib_gen.go"); exp.Reason != ReasonGitignored {
+		t.Errorf("expected gitignored explanation, got %+v", exp)
+	}
+
+	// Disabled by default
+	plain := New(root)
+	if !plain.IsWatchRelevant(filepath.Join(root, "lib/lib_gen.go")) {
+		t.Error("expected .gitignore to be ignored unless enabled")
+	}
+}
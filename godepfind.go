@@ -1,19 +1,36 @@
 package depfind
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"go/build"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type GoDepFind struct {
 	mu          sync.RWMutex
 	rootDirs    []string
 	testImports bool
+	claimTests  map[string]bool   // handler main file -> claims test-only files, see SetHandlerClaimsTests
+	ownSubtree  map[string]bool   // handler main file -> owns its directory subtree, see SetHandlerOwnsSubtree
+	handlerTags map[string]TagSet // handler main file -> build tags its files are evaluated with, see SetHandlerTags
+	maxDepth    int               // 0 = unlimited, see SetMaxDepth
+	scope       string            // subdirectory restriction, see ScopedFinder
+
+	scopePatterns []string // package patterns the cache loads, see SetScope
+
+	filenameFallback FilenameFallback // unindexed file lookups, see SetFilenameFallback
+	fsErrorPolicy    FSErrorPolicy    // unreadable files during lookups, see SetFSErrorPolicy
+
+	virtualEdges map[string]map[string]string // from -> to -> reason, see AddVirtualEdge
 
 	// Cache fields
 	cachedModule      bool
@@ -21,8 +38,90 @@ type GoDepFind struct {
 	dependencyGraph   map[string][]string // pkg -> dependencies
 	reverseDeps       map[string][]string // pkg -> reverse dependencies
 	filePathToPackage map[string]string   // absolute file path -> package path (NEW: unique mapping)
+	testOnlyFiles     map[string]bool     // absolute file path -> indexed file is a _test.go file
+	removedFiles      map[string]string   // removed file -> its last package, until the path exists again
 	fileToPackages    map[string][]string // filename -> list of package paths (NEW: multiple packages per filename)
 	mainPackages      []string
+
+	// Synthesized module support for roots without go.mod
+	modulePath string // configured via SetModulePath
+
+	toolchainErr    error // non-nil when the go command is missing, see Check
+	scannerFallback bool  // walk the roots instead of go list, see SetScannerFallback
+
+	// Listing results (guarded by listMu because listing also happens under
+	// the read lock)
+	listMu        sync.Mutex
+	packageDirs   map[string]string         // import path -> directory
+	skipped       map[string]SkippedPackage // import path -> why it was left out
+	excludedDirs  []string                  // vendored trees excluded from the graph
+	nestedModules []NestedModule            // modules with their own go.mod inside a root
+	warnings      []Warning                 // tolerated errors, see Warnings
+
+	logger func(message ...any) // optional warning stream, see SetLogger
+
+	onPackageRenamed func(PackageRenamed) // notified when a package directory is renamed
+	renamedPackages  map[string]string    // old import path -> new path, until the next full rebuild
+
+	journal        *os.File // append-only event journal, see SetJournal
+	lastJournalKey string   // last journaled event, to skip duplicates across handlers
+
+	rebuildInterval time.Duration // minimum time between full rebuilds, see SetRebuildInterval
+	lastRebuild     time.Time     // start of the last full rebuild
+	rebuildPending  bool          // a main-file write is waiting for the next allowed rebuild
+	rebuildTimer    *time.Timer   // runs the pending rebuild
+	warming         *warmup       // cancellation and progress of the running WarmCache
+
+	watchdogStop     chan struct{}  // stops the watchdog goroutine, see SetWatchdog
+	watchdogCloser   bool           // the watchdog stop is registered with onClose
+	watchdogBaseline *environment   // environment of the last check
+	watchdogStatus   WatchdogStatus // outcome of the last check
+
+	closures     map[string]*handlerClosure // handler main file -> cached imports, see handlerReach
+	graphVersion uint64                     // bumped on every dependency graph mutation
+	tagGraphs    map[string]*tagGraph       // tag set key -> graph of that build configuration, see graphFor
+
+	moduleFinders map[string]*GoDepFind // nested module dir -> its finder, see ModuleFinder
+
+	skipUnchanged  bool                                    // hash written files, see SetSkipUnchangedWrites
+	seenContent    map[string]map[string][sha256.Size]byte // file -> handler -> content last routed
+	indexedContent map[string][sha256.Size]byte            // file -> content last indexed by the cache
+
+	fileStamps map[string]int64 // file -> modification time when its package was loaded, see Reconcile
+
+	eventWindow  time.Duration          // see SetEventWindow
+	recentEvents map[string][]time.Time // handler -> times of its owned events within the window
+
+	loadErrors   map[string]string      // import path -> why its last reload failed, see BuildStatus
+	syntaxChecks map[string]syntaxCheck // file -> last full parse, see packageBuildStatus
+
+	overrides       []OwnershipOverride          // files pinned to handlers, see ForceOwnership
+	virtualHandlers map[string]func(string) bool // handler name -> matcher, see AddVirtualHandler
+
+	respectGitignore bool                   // see SetRespectGitignore
+	keepToolDirs     bool                   // tool-managed directories are indexed, see SetSkipToolDirs
+	ignoreMu         sync.Mutex             // guards ignoreFiles, read under the read lock
+	ignoreFiles      map[string]*ignoreFile // directory -> parsed .gitignore
+
+	externalDirs []string // out-of-root directories allowed to be routed, see AllowExternalDirs
+
+	changeProvider ChangeProvider // source of ImpactSince changes, nil for git, see SetChangeProvider
+
+	cacheFile      string     // key of the persisted package listing, see SetCacheFile
+	cacheStore     CacheStore // where cacheFile is kept, nil for the file itself, see SetCacheStore
+	allowUnchecked bool       // see SetAllowUnchecked
+	metrics        Metrics    // see Metrics
+
+	cacheStrategy  CacheStrategy     // requested strategy, "" for StrategyAuto, see SetCacheStrategy
+	lazyThreshold  int               // 0 = DefaultLazyThreshold, see SetLazyThreshold
+	activeStrategy CacheStrategy     // strategy of the last rebuild, see Capabilities
+	listedCount    int               // packages listed by the last rebuild
+	lazyPending    map[string]bool   // listed packages not loaded yet under StrategyLazy
+	lazyScope      *lazyScope        // what the running query needs loaded, nil for everything
+	lazyClosures   map[string]uint64 // handler main file -> graph version its closure was loaded at
+
+	closed  bool           // set by Close
+	closers []func() error // subsystem cleanup run by Close, see onClose
 }
 
 // New creates a new GoDepFind instance with the specified root directories
@@ -35,8 +134,10 @@ func New(rootDirs ...string) *GoDepFind {
 		dependencyGraph:   make(map[string][]string),
 		reverseDeps:       make(map[string][]string),
 		filePathToPackage: make(map[string]string),
+		testOnlyFiles:     make(map[string]bool),
 		fileToPackages:    make(map[string][]string),
 		mainPackages:      []string{},
+		toolchainErr:      lookupToolchain(),
 	}
 	finder.AddRoot(rootDirs...)
 	return finder
@@ -69,6 +170,22 @@ func (g *GoDepFind) AddRoot(paths ...string) {
 	}
 }
 
+// rootPath resolves a path relative to the primary root directory (the first
+// registered root, typically the app root) into a clean absolute path
+func (g *GoDepFind) rootPath(path string) string {
+	if !filepath.IsAbs(path) {
+		baseDir := "."
+		if len(g.rootDirs) > 0 {
+			baseDir = g.rootDirs[0]
+		}
+		path = filepath.Join(baseDir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
 // ThisFileIsMine decides whether the provided handler (identified by its
 // main file path relative to the module root) should handle an event for the
 // given file. It normalizes paths, validates the handler main file exists,
@@ -86,16 +203,26 @@ func (g *GoDepFind) AddRoot(paths ...string) {
 // Inputs:
 //   - mainInputFileRelativePath: handler main file (e.g. "pwa/main.server.go")
 //   - fileAbsPath: target file path (absolute or relative to module root)
-//   - event: one of "write","create","remove","rename" (drives cache ops)
+//   - event: one of the Event* constants; see RequiresCacheUpdate for which
+//     operations update the cache and which only query it
 //
 // Returns: (bool, error) — true when the handler should process the file.
 func (g *GoDepFind) ThisFileIsMine(mainInputFileRelativePath, fileAbsPath, event string) (bool, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	return g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event)
+	g.lazyScope = &lazyScope{handler: mainInputFileRelativePath, file: fileAbsPath}
+	defer func() { g.lazyScope = nil }()
+	decision, err := g.decide(mainInputFileRelativePath, fileAbsPath, event, true)
+	return decision.Owned, err
 }
 
 func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event string) (bool, error) {
+	return g.thisFileIsMineWith(mainInputFileRelativePath, fileAbsPath, event, true)
+}
+
+// thisFileIsMineWith routes an event; validate runs the Go file validator on
+// the target, see ThisFileIsMineUnchecked
+func (g *GoDepFind) thisFileIsMineWith(mainInputFileRelativePath, fileAbsPath, event string, validate bool) (bool, error) {
 	// 1. Basic input validation
 	if fileAbsPath == "" {
 		return false, fmt.Errorf("fileAbsPath cannot be empty")
@@ -119,6 +246,11 @@ func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event
 	}
 	fileAbsPath = absFilePath
 
+	// Virtual handlers have no main file: their matcher decides alone
+	if owned, virtual := g.virtualOwnership(mainInputFileRelativePath, fileAbsPath); virtual {
+		return owned, nil
+	}
+
 	// 3. CRITICAL: Verify handler's main file exists
 	handlerMainAbsPath := mainInputFileRelativePath
 	if !filepath.IsAbs(handlerMainAbsPath) {
@@ -140,8 +272,17 @@ func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event
 		return false, fmt.Errorf("cannot access handler main file %s: %w", mainInputFileRelativePath, err)
 	}
 
+	// A removed (or renamed-away) file no longer exists: ownership is decided
+	// from the last-known state before the cache forgets it
+	_, statErr := os.Stat(fileAbsPath)
+	if err := g.fsError(statErr); err != nil {
+		return false, err
+	}
+	_, tombstoned := g.removedFiles[fileAbsPath]
+	deleted := (event == EventRemove || event == EventRename || tombstoned) && os.IsNotExist(statErr)
+
 	// 4. Validate target file (skip if file doesn't exist or is being written)
-	if filepath.Ext(fileAbsPath) == ".go" {
+	if validate && filepath.Ext(fileAbsPath) == ".go" && !deleted {
 		validator := NewGoFileValidator()
 		if isValid, err := validator.IsValidGoFile(fileAbsPath); err != nil {
 			return false, fmt.Errorf("file validation failed: %w", err)
@@ -168,21 +309,53 @@ func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event
 
 	// 6. External dependency check
 	// If the file is outside our root directories, we assume it's part of an external
-	// local module (e.g. from a replace directive) and should be handled.
-	// We check if it's NOT a subpath of ANY rootDir.
-	isSubpath := false
-	for _, root := range g.rootDirs {
-		if strings.HasPrefix(fileAbsPath, root+string(filepath.Separator)) || fileAbsPath == root {
-			isSubpath = true
-			break
+	// local module (e.g. from a replace directive) and should be handled, unless
+	// it is toolchain code or outside the allowed external directories.
+	if !g.inRoots(fileAbsPath) {
+		if err := g.checkExternalFile(fileAbsPath); err != nil {
+			return false, err
 		}
-	}
-	if !isSubpath {
 		return true, nil
 	}
 
+	// Scoped finders never see files outside their subdirectory
+	if !g.inScope(fileAbsPath) {
+		return false, nil
+	}
+	if g.gitIgnored(fileAbsPath) || g.inToolDir(fileAbsPath) {
+		return false, nil
+	}
+
+	// Excluded vendored trees are not part of any handler (known once listed)
+	if err := g.ensureCacheInitialized(); err != nil {
+		return false, err
+	}
+	if g.isExcluded(fileAbsPath) || g.inNestedModule(fileAbsPath) {
+		return false, nil
+	}
+
+	// Queries answer from the cache as it is, without recording anything
+	if IsQueryEvent(event) {
+		return g.checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath)
+	}
+
 	// 7. CRITICAL: Always update cache for the file to capture dynamic dependency changes
 	// We do this before ownership check to ensure the dependency graph is up-to-date
+	g.recordEvent(mainInputFileRelativePath, fileAbsPath, event)
+	if deleted {
+		// Answer from the last-known mapping, then purge it
+		if err := g.ensureCacheInitialized(); err != nil {
+			return false, err
+		}
+		isMine, err := g.checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath)
+		if err != nil {
+			return false, err
+		}
+		if err := g.updateCacheForFileWithContext(fileAbsPath, event, mainInputFileRelativePath); err != nil {
+			return false, fmt.Errorf("cache update failed: %w", err)
+		}
+		return isMine, nil
+	}
 	if err := g.updateCacheForFileWithContext(fileAbsPath, event, mainInputFileRelativePath); err != nil {
 		return false, fmt.Errorf("cache update failed: %w", err)
 	}
@@ -193,62 +366,69 @@ func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event
 
 // checkPackageBasedOwnership determines ownership based on Go package dependencies
 func (g *GoDepFind) checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath string) (bool, error) {
-	// Find which package contains the target file
-	targetPkg, err := g.findPackageForFile(fileAbsPath)
-	if err != nil {
-		return false, err
-	}
-
-	// Fallback: empty cache (go list failed), but file is under a rootDir
-	// where the handler also exists -> assume it belongs
-	if targetPkg == "" {
-		for _, root := range g.rootDirs {
-			handlerMainAbs := filepath.Join(root, mainInputFileRelativePath)
-			if _, statErr := os.Stat(handlerMainAbs); statErr == nil {
-				if strings.HasPrefix(fileAbsPath, root+string(filepath.Separator)) {
-					return true, nil
-				}
-			}
-		}
-		return false, nil
-	}
-
-	// Check if target package should belong to this handler
-	return g.doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath), nil
+	decision, err := g.packageOwnership(mainInputFileRelativePath, fileAbsPath)
+	return decision.Owned, err
 }
 
 // findPackageForFile finds which package contains the given file
 func (g *GoDepFind) findPackageForFile(fileAbsPath string) (string, error) {
+	candidates, err := g.findPackagesForFile(fileAbsPath)
+	if err != nil || len(candidates) == 0 {
+		return "", err
+	}
+	return candidates[0], nil
+}
+
+// findPackagesForFile returns the packages that may contain the given file:
+// exactly one when the path is indexed, otherwise the filename-based
+// candidates allowed by the configured FilenameFallback
+func (g *GoDepFind) findPackagesForFile(fileAbsPath string) ([]string, error) {
 	// Ensure cache is initialized
 	if err := g.ensureCacheInitialized(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Try exact path lookup first (most reliable)
 	if pkg, exists := g.filePathToPackage[fileAbsPath]; exists {
-		return pkg, nil
+		return []string{pkg}, nil
+	}
+
+	// Removed files keep their last package for every handler asked
+	if pkg, removed := g.removedFiles[fileAbsPath]; removed {
+		return []string{pkg}, nil
+	}
+
+	// Build-tag variants excluded from the host build (db_js.go)
+	if pkg := g.variantPackage(fileAbsPath); pkg != "" {
+		return []string{pkg}, nil
 	}
 
 	// Fallback: try relative path lookup
 	if cwd, err := os.Getwd(); err == nil {
 		if relPath, err := filepath.Rel(cwd, fileAbsPath); err == nil {
 			if pkg, exists := g.filePathToPackage[relPath]; exists {
-				return pkg, nil
+				return []string{pkg}, nil
 			}
 		}
 	}
 
 	// Last resort: filename-based lookup (may be ambiguous)
-	fileName := filepath.Base(fileAbsPath)
-	if packages := g.fileToPackages[fileName]; len(packages) > 0 {
-		return packages[0], nil
+	packages := g.fileToPackages[filepath.Base(fileAbsPath)]
+	if len(packages) == 0 {
+		return nil, nil
+	}
+	switch g.filenameFallback {
+	case FallbackDisabled:
+		return nil, nil
+	case FallbackAllMatches:
+		return append([]string(nil), packages...), nil
+	default:
+		return packages[:1], nil
 	}
-
-	return "", nil
 }
 
 // doesPackageBelongToHandler determines if a package should be handled by this handler
-func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath string) bool {
+func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath string) (bool, error) {
 	handlerDir := filepath.Dir(mainInputFileRelativePath)
 
 	// Case 1: If target is a main package in the same directory as handler
@@ -260,13 +440,13 @@ func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativeP
 					for _, root := range g.rootDirs {
 						if relPkgDir, err := filepath.Rel(root, pkg.Dir); err == nil {
 							if filepath.Clean(relPkgDir) == filepath.Clean(handlerDir) {
-								return true
+								return true, nil
 							}
 						}
 					}
 				}
 				// Fallback: compare package name with handler directory
-				return filepath.Base(targetPkg) == filepath.Base(handlerDir)
+				return filepath.Base(targetPkg) == filepath.Base(handlerDir), nil
 			}
 		}
 	}
@@ -276,11 +456,13 @@ func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativeP
 	return g.handlerFileImportsPackage(mainInputFileRelativePath, targetPkg)
 }
 
-// handlerFileImportsPackage checks if a specific handler file imports the given package
-func (g *GoDepFind) handlerFileImportsPackage(handlerFileRelativePath, targetPkg string) bool {
+// handlerFileImportsPackage checks if a specific handler file imports the given package.
+// A handler file that cannot be read imports nothing, unless the
+// FSErrorPolicy fails the lookup.
+func (g *GoDepFind) handlerFileImportsPackage(handlerFileRelativePath, targetPkg string) (bool, error) {
 	// Ensure cache is initialized
 	if err := g.ensureCacheInitialized(); err != nil {
-		return false
+		return false, err
 	}
 
 	// Build the absolute path to the handler file
@@ -293,31 +475,34 @@ func (g *GoDepFind) handlerFileImportsPackage(handlerFileRelativePath, targetPkg
 		handlerAbsPath = filepath.Join(baseDir, handlerFileRelativePath)
 	}
 
-	// Parse the handler file to extract its imports
-	imports, err := g.parseFileImports(handlerAbsPath)
+	// Direct and transitive imports, cached per handler file
+	reach, err := g.handlerReach(handlerFileRelativePath, handlerAbsPath)
 	if err != nil {
-		return false
+		return false, g.fsError(err)
 	}
-
-	// Direct import check
-	for _, imp := range imports {
-		if imp == targetPkg {
-			return true
-		}
+	// Past the max depth the answer is unknown rather than "not owned"
+	if !reach[targetPkg] && g.closures[handlerAbsPath].truncated {
+		return false, &MaxDepthError{Path: handlerFileRelativePath, MaxDepth: g.maxDepth}
 	}
+	return reach[targetPkg], nil
+}
 
-	// Transitive import check - check if any direct import depends on targetPkg
-	for _, imp := range imports {
-		if g.cachedMainImportsPackage(imp, targetPkg) {
-			return true
-		}
+// parseFileImports extracts the import paths from a specific Go file
+func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
+	specs, err := g.parseFileImportSpecs(filePath)
+	if err != nil {
+		return nil, err
 	}
-
-	return false
+	imports := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		imports = append(imports, spec.Path)
+	}
+	return imports, nil
 }
 
-// parseFileImports extracts the import statements from a specific Go file
-func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
+// parseFileImportSpecs extracts the import statements from a specific Go
+// file, keeping aliases, dot and blank imports
+func (g *GoDepFind) parseFileImportSpecs(filePath string) ([]ImportSpec, error) {
 	// For now, use a simple file parsing approach
 	// This is a known limitation - we're parsing at file level but Go packages aggregate all files
 	// For the specific use case of main.server.go vs main.wasm.go, we need to parse the files individually
@@ -327,11 +512,11 @@ func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
 		return nil, err
 	}
 
-	var imports []string
+	var imports []ImportSpec
 	lines := strings.Split(string(content), "\n")
 	inImportBlock := false
 
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 
 		// Multi-line import block start (check this BEFORE single line import)
@@ -348,8 +533,9 @@ func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
 
 		// Import inside block
 		if inImportBlock {
-			if path := extractImportPath(line); path != "" {
-				imports = append(imports, path)
+			if spec := extractImportSpec(line); spec.Path != "" {
+				spec.Line = i + 1
+				imports = append(imports, spec)
 			}
 			continue
 		}
@@ -357,8 +543,9 @@ func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
 		// Single line import (check this AFTER import block detection)
 		if strings.HasPrefix(line, "import ") {
 			// Extract import path from 'import "path"'
-			if path := extractImportPath(line); path != "" {
-				imports = append(imports, path)
+			if spec := extractImportSpec(line); spec.Path != "" {
+				spec.Line = i + 1
+				imports = append(imports, spec)
 			}
 			continue
 		}
@@ -369,6 +556,11 @@ func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
 
 // extractImportPath extracts the import path from an import line
 func extractImportPath(line string) string {
+	return extractImportSpec(line).Path
+}
+
+// extractImportSpec extracts the import path and name from an import line
+func extractImportSpec(line string) ImportSpec {
 	// Remove comments
 	if idx := strings.Index(line, "//"); idx != -1 {
 		line = line[:idx]
@@ -377,7 +569,7 @@ func extractImportPath(line string) string {
 
 	// Skip empty lines
 	if line == "" {
-		return ""
+		return ImportSpec{}
 	}
 
 	// Handle different import formats:
@@ -394,14 +586,14 @@ func extractImportPath(line string) string {
 	// Find the quoted path
 	start := strings.Index(line, "\"")
 	if start == -1 {
-		return ""
+		return ImportSpec{}
 	}
 	end := strings.LastIndex(line, "\"")
 	if end == -1 || end <= start {
-		return ""
+		return ImportSpec{}
 	}
 
-	return line[start+1 : end]
+	return ImportSpec{Name: strings.TrimSpace(line[:start]), Path: line[start+1 : end]}
 }
 
 // SetTestImports enables or disables inclusion of test imports
@@ -412,145 +604,440 @@ func (g *GoDepFind) SetTestImports(enabled bool) {
 	g.testImports = enabled
 }
 
+// SetHandlerClaimsTests decides whether the handler identified by its main
+// file claims test-only files (_test.go, including external "foo_test"
+// packages) of the packages it depends on. Handlers without an explicit
+// setting claim them only when test imports are enabled.
+func (g *GoDepFind) SetHandlerClaimsTests(mainInputFileRelativePath string, claim bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.claimTests == nil {
+		g.claimTests = make(map[string]bool)
+	}
+	g.claimTests[handlerKey(mainInputFileRelativePath)] = claim
+}
+
+// SetHandlerOwnsSubtree makes the handler identified by its main file own
+// every file under its main's directory (e.g. cmd/app/internal/...), in
+// addition to the packages it imports. Useful for layouts keeping helper
+// subpackages next to the main that are not (yet) imported.
+func (g *GoDepFind) SetHandlerOwnsSubtree(mainInputFileRelativePath string, enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ownSubtree == nil {
+		g.ownSubtree = make(map[string]bool)
+	}
+	g.ownSubtree[handlerKey(mainInputFileRelativePath)] = enabled
+}
+
+// IsTestOnly reports whether filePath is indexed as a test-only file
+func (g *GoDepFind) IsTestOnly(filePath string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return false, err
+	}
+	return g.testOnlyFiles[g.rootPath(filePath)], nil
+}
+
+// claimsTests reports whether a handler claims test-only files
+func (g *GoDepFind) claimsTests(mainInputFileRelativePath string) bool {
+	if claim, ok := g.claimTests[handlerKey(mainInputFileRelativePath)]; ok {
+		return claim
+	}
+	return g.testImports
+}
+
+// SetHandlerTags evaluates files for the handler identified by its main file
+// under an explicit tag set (e.g. "js", "wasm", "tinygo") instead of the
+// host's: Go files whose name suffix or build constraint exclude them under
+// those tags are never owned by the handler. No tags removes the setting:
+// files are then evaluated for the target of the handler's main file.
+func (g *GoDepFind) SetHandlerTags(mainInputFileRelativePath string, tags ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.handlerTags == nil {
+		g.handlerTags = make(map[string]TagSet)
+	}
+	if len(tags) == 0 {
+		delete(g.handlerTags, handlerKey(mainInputFileRelativePath))
+		return
+	}
+	g.handlerTags[handlerKey(mainInputFileRelativePath)] = NewTagSet(tags...)
+}
+
+// excludedByHandlerTags reports whether the handler's build context excludes
+// a Go file, see handlerContext: a wasm handler never owns db_native.go and
+// a server handler never owns db_js.go. Files that cannot be read (e.g.
+// deleted) are not excluded.
+func (g *GoDepFind) excludedByHandlerTags(mainInputFileRelativePath, fileAbsPath string) bool {
+	if filepath.Ext(fileAbsPath) != ".go" {
+		return false
+	}
+	tags, ok := g.handlerContext(mainInputFileRelativePath)
+	if !ok {
+		return false
+	}
+	matched, err := tags.MatchFile(fileAbsPath)
+	return err == nil && !matched
+}
+
+// inHandlerSubtree reports whether a handler owning its subtree contains fileAbsPath
+func (g *GoDepFind) inHandlerSubtree(mainInputFileRelativePath, fileAbsPath string) bool {
+	if !g.ownSubtree[handlerKey(mainInputFileRelativePath)] {
+		return false
+	}
+	handlerDir := g.rootPath(filepath.Dir(mainInputFileRelativePath))
+	return strings.HasPrefix(fileAbsPath, handlerDir+string(filepath.Separator))
+}
+
+// handlerKey normalizes a handler main file path used as a configuration key
+func handlerKey(mainInputFileRelativePath string) string {
+	return filepath.ToSlash(filepath.Clean(mainInputFileRelativePath))
+}
+
+// FilenameFallback selects how files missing from the path index are mapped
+// to packages by their base name alone
+type FilenameFallback int
+
+const (
+	// FallbackFirstMatch uses the first package holding a file with the same name (default)
+	FallbackFirstMatch FilenameFallback = iota
+	// FallbackDisabled treats unindexed files as not found and never owned
+	FallbackDisabled
+	// FallbackAllMatches evaluates every package holding a file with the same
+	// name and reports ownership if any of them belongs to the handler
+	FallbackAllMatches
+)
+
+// SetFilenameFallback configures the filename-only lookup used for files the
+// path index does not know. Ambiguous names such as main.go make the default
+// FallbackFirstMatch guess wrong; FallbackDisabled or FallbackAllMatches avoid it.
+func (g *GoDepFind) SetFilenameFallback(mode FilenameFallback) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.filenameFallback = mode
+}
+
+// SetMaxDepth limits how many import levels transitive dependency walks may
+// descend. Ownership and reachability checks that cannot be answered within
+// it fail with *MaxDepthError instead of walking pathological graphs
+// indefinitely; a package found within the limit is still answered. Graph
+// queries (FindForwardDeps, FindImporters, Query) list the packages within
+// the limit, as with WithMaxDepth. Zero (the default) means unlimited.
+func (g *GoDepFind) SetMaxDepth(depth int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.maxDepth = depth
+	g.graphChanged() // cached handler closures were cut at the old depth
+}
+
+// MaxDepthError reports a dependency walk that exceeded the configured max depth
+type MaxDepthError struct {
+	Path     string // package (or handler main file) the walk started from
+	MaxDepth int
+}
+
+func (e *MaxDepthError) Error() string {
+	return fmt.Sprintf("import chain from %s exceeds max depth %d", e.Path, e.MaxDepth)
+}
+
 // listPackages returns the result of running "go list" with the specified path
 // It tolerates build constraint errors (e.g., WASM packages) and returns whatever packages
-// it can successfully list, only returning error if no packages are found at all
+// it can successfully list, only returning error if no packages are found at all.
+// Relative patterns such as "./..." are listed in every root and merged, so a
+// locally replaced library repo added with AddRoot joins the same graph.
 func (g *GoDepFind) listPackages(path string) ([]string, error) {
-	cmd := exec.Command("go", "list", path)
-	// Use the first root directory as the working directory for go list
-	// This might be imperfect if checking packages in secondary roots, but
-	// usually reasonable for "go list ./..." if that's what is being called.
-	// For specific paths, we might want to pick the most appropriate root.
-	cmd.Dir = "."
-	if len(g.rootDirs) > 0 {
-		cmd.Dir = g.rootDirs[0]
-		// Try to find if path belongs to a specific root to be more accurate
-		if filepath.IsAbs(path) {
-			for _, root := range g.rootDirs {
-				if strings.HasPrefix(path, root) {
-					cmd.Dir = root
-					break
-				}
+	if g.closed {
+		return nil, ErrClosed
+	}
+	path = g.scopedPattern(path)
+	if g.usesScanner() {
+		return g.listSyntheticPackages(path)
+	}
+	if err := g.checkModule(path); err != nil {
+		return nil, err
+	}
+	if g.toolchainErr != nil {
+		return nil, g.toolchainErr
+	}
+	return g.listPackagesIn(path, g.listDirs(path))
+}
+
+// listPackagesIn runs go list for a scoped pattern in each of dirs and merges
+// the packages found
+func (g *GoDepFind) listPackagesIn(path string, dirs []string) ([]string, error) {
+	var packages []string
+	var lastErr error
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		listed, err := g.goList(dir, path)
+		if err != nil {
+			lastErr = err
+			g.warn(Warning{Kind: WarnListFailed, Dir: dir, Message: fmt.Sprintf("go list %s: %v", path, err)})
+			continue
+		}
+		for _, pkg := range listed {
+			if !seen[pkg] {
+				seen[pkg] = true
+				packages = append(packages, pkg)
 			}
 		}
 	}
-	// Don't redirect stderr to os.Stderr to avoid polluting logs with build constraint warnings
-	out, err := cmd.Output()
 
-	// Parse the output even if the command failed
-	packages := strings.Fields(string(out))
+	packages = g.excludeVendoredTrees(packages)
+	packages = g.dropToolDirPackages(packages)
 
 	// If we got at least some packages, ignore the error
 	// This handles cases where some packages have build constraints (e.g., WASM)
+	// or a secondary root cannot be listed
 	if len(packages) > 0 {
 		return packages, nil
 	}
 
 	// Only return error if we couldn't list any packages
-	if err != nil {
-		return nil, err
+	if lastErr != nil {
+		return nil, lastErr
 	}
 
 	return packages, nil
 }
 
-// getPackages imports and returns a build.Package for each listed package
-func (g *GoDepFind) getPackages(paths []string) (map[string]*build.Package, error) {
-	packages := make(map[string]*build.Package)
-	for _, path := range paths {
-		var pkg *build.Package
-		var err error
-
-		// For module paths like "testproject/appAserver", we need to convert them to relative directory paths
-		// First, try to determine if this is a local module path
-		if strings.Contains(path, "/") {
-			// Extract the relative path from the module path
-			// For "testproject/appAserver", we want just "appAserver"
-			parts := strings.Split(path, "/")
-			if len(parts) >= 2 {
-				// Try to construct the relative path from the module root
-				relativePath := strings.Join(parts[1:], "/")
-
-				// Check against all roots
-				for _, root := range g.rootDirs {
-					fullPath := filepath.Join(root, relativePath)
-					// Check if this directory exists
-					if _, err := os.Stat(fullPath); err == nil {
-						pkg, err = build.ImportDir(fullPath, 0)
-						if err == nil {
-							packages[path] = pkg
-							break // Found it
-						}
-					}
-				}
-				if pkg != nil {
-					continue
-				}
+// listDirs returns the working directories "go list" must run in for path
+func (g *GoDepFind) listDirs(path string) []string {
+	if len(g.rootDirs) == 0 {
+		return []string{"."}
+	}
+	// Relative patterns are resolved in every root (the scope only applies to the primary one)
+	if strings.HasPrefix(path, ".") && g.scope == "" {
+		return g.rootDirs
+	}
+	// Try to find if path belongs to a specific root to be more accurate
+	if filepath.IsAbs(path) {
+		for _, root := range g.rootDirs {
+			if strings.HasPrefix(path, root) {
+				return []string{root}
 			}
 		}
+	}
+	return g.rootDirs[:1]
+}
 
-		// Fallback: try ImportDir with the full path relative to all roots
-		for _, root := range g.rootDirs {
-			fullPath := filepath.Join(root, path)
-			if _, err := os.Stat(fullPath); err == nil {
-				pkg, err = build.ImportDir(fullPath, 0)
-				if err == nil {
-					packages[path] = pkg
-					break
-				}
-			}
+// goList runs "go list" for pattern inside dir, recording each package
+// directory so getPackages can import it without guessing. Packages that
+// cannot be loaded are left out and reported through SkippedPackages instead
+// of failing the whole listing.
+func (g *GoDepFind) goList(dir, pattern string) ([]string, error) {
+	cmd := exec.Command("go", "list", "-e", "-json=ImportPath,Dir,Error,DepsErrors", pattern)
+	cmd.Dir = dir
+	// Don't redirect stderr to os.Stderr to avoid polluting logs with build
+	// constraint warnings; its lines are recorded as warnings instead
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			g.warn(Warning{Kind: WarnGoList, Dir: dir, Message: line})
+		}
+	}
+
+	// Parse the output even if the command failed
+	var packages []string
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var listed listedPackage
+		if decodeErr := decoder.Decode(&listed); decodeErr != nil {
+			break
 		}
-		if pkg != nil {
+		if listed.ImportPath == "" {
 			continue
 		}
+		if reason := g.listProblem(listed); reason != "" {
+			if strings.Contains(reason, "build constraints exclude all Go files") {
+				g.warn(Warning{Kind: WarnBuildConstraints, Package: listed.ImportPath, Dir: listed.Dir, Message: reason})
+			} else {
+				g.addSkipped(SkippedPackage{Path: listed.ImportPath, Dir: listed.Dir, Reason: reason})
+			}
+			continue
+		}
+		packages = append(packages, listed.ImportPath)
+		if listed.Dir != "" {
+			g.setPackageDir(listed.ImportPath, listed.Dir)
+		}
+	}
 
-		// Last resort: try build.Import (for standard library packages or fully qualified imports)
-		// We use the first root as srcDir context
-		srcDir := "."
-		if len(g.rootDirs) > 0 {
-			srcDir = g.rootDirs[0]
+	if len(packages) > 0 {
+		return packages, nil
+	}
+	return nil, err
+}
+
+// setPackageDir records the directory of a listed package
+func (g *GoDepFind) setPackageDir(importPath, dir string) {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	if g.packageDirs == nil {
+		g.packageDirs = make(map[string]string)
+	}
+	g.packageDirs[importPath] = dir
+}
+
+// packageDir returns the recorded directory of a listed package
+func (g *GoDepFind) packageDir(importPath string) (string, bool) {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	dir, ok := g.packageDirs[importPath]
+	return dir, ok
+}
+
+// getPackages imports and returns a build.Package for each listed package.
+// Packages that cannot be loaded are skipped and listed in a *MultiError
+// returned alongside the packages that were loaded.
+func (g *GoDepFind) getPackages(paths []string) (map[string]*build.Package, error) {
+	packages := make(map[string]*build.Package)
+	var failed *MultiError
+	for i, path := range paths {
+		if err := g.warming.step(i, len(paths)); err != nil {
+			return nil, err
 		}
-		pkg, err = build.Import(path, srcDir, 0)
+		pkg, dir, err := g.loadPackage(path)
 		if err != nil {
-			return nil, err
+			failed = addPackageError(failed, path, dir, err)
+			continue
 		}
 		packages[path] = pkg
 	}
+	if err := g.warming.step(len(paths), len(paths)); err != nil {
+		return nil, err
+	}
+	if failed != nil {
+		return packages, failed
+	}
 	return packages, nil
 }
 
-// imports returns true if path imports any of the packages in "any", transitively
-func (g *GoDepFind) imports(path string, packages map[string]*build.Package, any map[string]bool) bool {
+// loadPackage imports one package by import path, returning the directory
+// it was looked up in. Failures are recorded as skipped packages.
+func (g *GoDepFind) loadPackage(path string) (*build.Package, string, error) {
+	// Directories recorded while listing are authoritative
+	if dir, ok := g.packageDir(path); ok {
+		pkg, err := build.ImportDir(dir, 0)
+		if err != nil {
+			g.addSkipped(SkippedPackage{Path: path, Dir: dir, Reason: err.Error()})
+			return nil, dir, err
+		}
+		g.resolveLocalImports(pkg)
+		return pkg, dir, nil
+	}
+
+	// Otherwise trim the module path declared by a root's go.mod; the
+	// root directory name says nothing about the import path
+	if dir, ok := g.moduleDir(path); ok {
+		if pkg, err := build.ImportDir(dir, 0); err == nil {
+			g.resolveLocalImports(pkg)
+			return pkg, dir, nil
+		}
+	}
+
+	// Last resort: try build.Import (for standard library packages or fully qualified imports)
+	// We use the first root as srcDir context
+	srcDir := "."
+	if len(g.rootDirs) > 0 {
+		srcDir = g.rootDirs[0]
+	}
+	pkg, err := build.Import(path, srcDir, 0)
+	if err != nil {
+		g.addSkipped(SkippedPackage{Path: path, Reason: err.Error()})
+		return nil, "", err
+	}
+	return pkg, "", nil
+}
+
+// imports returns true if path imports any of the packages in "any", transitively.
+// The walk is iterative so deep or cyclic graphs cannot exhaust the stack.
+// Positive results are memoized in "any"; packages proven unable to reach a
+// target are memoized in "noMatch" so repeated calls share the work. A
+// *MaxDepthError is returned when the walk exceeds the configured max depth.
+func (g *GoDepFind) imports(path string, packages map[string]*build.Package, any, noMatch map[string]bool) (bool, error) {
+	return g.importsWith(path, func(p string) *build.Package { return packages[p] }, any, noMatch)
+}
+
+// importsWith is imports walking through the packages lookup returns, nil
+// for packages outside the search. The walk is breadth-first so each package
+// is reached at its minimum depth; a *MaxDepthError is only returned when no
+// target was found and the max depth left imports unexplored.
+func (g *GoDepFind) importsWith(path string, lookup func(string) *build.Package, any, noMatch map[string]bool) (bool, error) {
 	if any[path] {
-		return true
+		return true, nil
 	}
-	pkg, ok := packages[path]
-	if !ok || pkg == nil {
-		return false
+
+	type frame struct {
+		path  string
+		depth int
 	}
+	queue := []frame{{path, 0}}
+	visited := map[string]bool{path: true}
+	truncated := false
 
-	// Check test imports if enabled
-	if g.testImports {
-		for _, imp := range pkg.TestImports {
-			if any[imp] {
-				return true
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		pkg := lookup(current.path)
+		if pkg == nil {
+			continue
+		}
+
+		// Check test imports if enabled (direct only, as for go list -test)
+		if g.testImports {
+			for _, imp := range pkg.TestImports {
+				if any[imp] {
+					any[path] = true
+					return true, nil
+				}
+			}
+			for _, imp := range pkg.XTestImports {
+				if any[imp] {
+					any[path] = true
+					return true, nil
+				}
 			}
 		}
-		for _, imp := range pkg.XTestImports {
+
+		// Check regular imports
+		for _, imp := range pkg.Imports {
 			if any[imp] {
-				return true
+				any[path] = true
+				return true, nil
 			}
+			if visited[imp] || noMatch[imp] {
+				continue // cycle or already proven negative
+			}
+			if g.maxDepth > 0 && current.depth >= g.maxDepth {
+				truncated = true // imp would be walked past the max depth
+				continue
+			}
+			visited[imp] = true
+			queue = append(queue, frame{imp, current.depth + 1})
 		}
 	}
 
-	// Check regular imports
-	for _, imp := range pkg.Imports {
-		if g.imports(imp, packages, any) {
-			any[path] = true
-			return true
-		}
+	if truncated {
+		return false, &MaxDepthError{Path: path, MaxDepth: g.maxDepth}
 	}
-	return false
+	// Nothing reachable from path is a target
+	for p := range visited {
+		noMatch[p] = true
+	}
+	return false, nil
 }
 
 // FindReverseDeps finds packages in sourcePath that import any of the targetPaths
@@ -561,38 +1048,111 @@ func (g *GoDepFind) FindReverseDeps(sourcePath string, targetPaths []string) ([]
 }
 
 func (g *GoDepFind) findReverseDeps(sourcePath string, targetPaths []string) ([]string, error) {
-	// Build target map
+	var result []string
+	err := g.findReverseDepsFunc(sourcePath, targetPaths, func(pkg string) bool {
+		result = append(result, pkg)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindReverseDepsFunc is FindReverseDeps streaming each match to fn as soon
+// as it is found, in import path order, instead of building the full slice.
+// Source packages are only loaded when the search reaches them, and fn
+// returning false stops it: deciding a rebuild on the first match skips the
+// rest of a large pattern. fn runs while the finder lock is held and must
+// not call back into the finder.
+func (g *GoDepFind) FindReverseDepsFunc(sourcePath string, targetPaths []string, fn func(pkg string) bool) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.findReverseDepsFunc(sourcePath, targetPaths, fn)
+}
+
+func (g *GoDepFind) findReverseDepsFunc(sourcePath string, targetPaths []string, fn func(pkg string) bool) error {
+	targets, external, err := g.reverseDepTargets(targetPaths)
+	if err != nil {
+		return err
+	}
+
+	// Get source packages
+	paths, err := g.listPackages(sourcePath)
+	if err != nil {
+		return err
+	}
+	return g.scanReverseDeps(paths, targets, external, fn)
+}
+
+// reverseDepTargets lists the packages of the targets; packages of required
+// third-party modules are matched by import path as source packages load,
+// see externalTarget
+func (g *GoDepFind) reverseDepTargets(targetPaths []string) (map[string]bool, *externalMatcher, error) {
 	targets := make(map[string]bool)
+	external := &externalMatcher{}
 	for _, targetPath := range targetPaths {
+		if t, ok := g.externalTarget(targetPath); ok {
+			external.targets = append(external.targets, t)
+			continue
+		}
 		packages, err := g.listPackages(targetPath)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, path := range packages {
 			targets[path] = true
 		}
 	}
+	return targets, external, nil
+}
 
-	// Get source packages
-	paths, err := g.listPackages(sourcePath)
-	if err != nil {
-		return nil, err
-	}
+// scanReverseDeps calls fn, in import path order, for each of the source
+// packages that imports a target. targets is extended with the memoized
+// answers, so concurrent scans need their own copy.
+func (g *GoDepFind) scanReverseDeps(paths []string, targets map[string]bool, external *externalMatcher, fn func(pkg string) bool) error {
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
 
-	packages, err := g.getPackages(paths)
-	if err != nil {
-		return nil, err
+	// Load source packages lazily; failures are reported through
+	// SkippedPackages and leave the package out of the search
+	source := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		source[path] = true
+	}
+	loaded := make(map[string]*build.Package)
+	lookup := func(path string) *build.Package {
+		if !source[path] {
+			return nil
+		}
+		if pkg, done := loaded[path]; done {
+			return pkg
+		}
+		pkg, _, err := g.loadPackage(path)
+		if err != nil {
+			pkg = nil
+		} else {
+			external.markImports(pkg, g.testImports, targets)
+		}
+		loaded[path] = pkg
+		return pkg
 	}
 
 	// Find packages that import targets
-	var result []string
-	for path := range packages {
-		if g.imports(path, packages, targets) {
-			result = append(result, path)
+	noMatch := make(map[string]bool)
+	for _, path := range paths {
+		if lookup(path) == nil {
+			continue
+		}
+		found, err := g.importsWith(path, lookup, targets, noMatch)
+		if err != nil {
+			return err
+		}
+		if found && !fn(path) {
+			return nil
 		}
 	}
-
-	return result, nil
+	return nil
 }
 
 // GoFileComesFromMain finds which main packages depend on the given file (cached version)
@@ -620,7 +1180,11 @@ func (g *GoDepFind) goFileComesFromMain(fileName string) ([]string, error) {
 	var result []string
 	for _, mainPath := range g.mainPackages {
 		for _, filePkg := range candidatePackages {
-			if g.cachedMainImportsPackage(mainPath, filePkg) {
+			imports, err := g.cachedMainImportsPackage(mainPath, filePkg)
+			if err != nil {
+				return nil, err
+			}
+			if imports {
 				result = append(result, mainPath)
 				break // Don't add the same main package multiple times
 			}
@@ -694,13 +1258,13 @@ func (g *GoDepFind) matchesHandlerFile(mainPkg, handlerFile string) bool {
 
 // findMainPackages finds all packages with main function
 func (g *GoDepFind) findMainPackages() ([]string, error) {
-	allPaths, err := g.listPackages("./...")
+	allPaths, err := g.listScopePackages()
 	if err != nil {
 		return nil, err
 	}
 
 	packages, err := g.getPackages(allPaths)
-	if err != nil {
+	if err != nil && !isPartialFailure(err) {
 		return nil, err
 	}
 
@@ -746,7 +1310,7 @@ func (g *GoDepFind) findPackageContainingFileByPath(filePath string) (string, er
 		if pkg == nil {
 			continue
 		}
-		for _, file := range pkg.GoFiles {
+		for _, file := range buildFiles(pkg) {
 			candidate := file
 			if !filepath.IsAbs(candidate) {
 				candidate = filepath.Join(pkg.Dir, file)
@@ -773,18 +1337,19 @@ func (g *GoDepFind) findPackageContainingFileByPath(filePath string) (string, er
 					return pkgPath, nil
 				}
 			}
-			for _, file := range pkg.XTestGoFiles {
-				candidate := file
-				if !filepath.IsAbs(candidate) {
-					candidate = filepath.Join(pkg.Dir, file)
-				}
-				candAbs, err := filepath.Abs(candidate)
-				if err != nil {
-					continue
-				}
-				if candAbs == absPath {
-					return pkgPath, nil
-				}
+		}
+		// External test files (package foo_test) are always indexed
+		for _, file := range pkg.XTestGoFiles {
+			candidate := file
+			if !filepath.IsAbs(candidate) {
+				candidate = filepath.Join(pkg.Dir, file)
+			}
+			candAbs, err := filepath.Abs(candidate)
+			if err != nil {
+				continue
+			}
+			if candAbs == absPath {
+				return pkgPath, nil
 			}
 		}
 	}
@@ -3,6 +3,7 @@ package depfind
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -110,3 +111,46 @@ func TestThisFileIsMine_ExternalFile(t *testing.T) {
 		t.Error("Expected ThisFileIsMine to return true for external file")
 	}
 }
+
+func TestFindReverseDepsFunc(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module rdf\n\ngo 1.21\n",
+		"a/a.go":        "package a\n\nimport \"rdf/lib\"\n\nfunc A() { lib.Do() }\n",
+		"b/b.go":        "package b\n\nimport \"rdf/a\"\n\nfunc B() { a.A() }\n",
+		"c/c.go":        "package c\n\nimport \"rdf/lib\"\n\nfunc C() { lib.Do() }\n",
+		"d/d.go":        "package d\n",
+		"lib/lib.go":    "package lib\n\nfunc Do() {}\n",
+		"cmd/x/main.go": "package main\n\nimport \"rdf/c\"\n\nfunc main() { c.C() }\n",
+	})
+	finder := New(root)
+
+	var all []string
+	if err := finder.FindReverseDepsFunc("./...", []string{"rdf/lib"}, func(pkg string) bool {
+		all = append(all, pkg)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Targets matching the source pattern are reported too, as by FindReverseDeps
+	want := []string{"rdf/a", "rdf/b", "rdf/c", "rdf/cmd/x", "rdf/lib"}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("FindReverseDepsFunc = %v, want %v", all, want)
+	}
+
+	// Stopping at the first match
+	var first []string
+	if err := finder.FindReverseDepsFunc("./...", []string{"rdf/lib"}, func(pkg string) bool {
+		first = append(first, pkg)
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, []string{"rdf/a"}) {
+		t.Errorf("expected the walk to stop after rdf/a, got %v", first)
+	}
+
+	deps, err := finder.FindReverseDeps("./...", []string{"rdf/lib"})
+	if err != nil || !reflect.DeepEqual(deps, want) {
+		t.Errorf("FindReverseDeps = %v, %v, want %v", deps, err, want)
+	}
+}
@@ -0,0 +1,204 @@
+package depfind
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Node kinds reported in Graph
+const (
+	NodeLocal    = "local"    // package of the analyzed roots
+	NodeStdlib   = "stdlib"   // standard library package or the collapsed "std" node
+	NodeExternal = "external" // package or module outside the roots
+)
+
+// StdlibNode is the ID of the node standing for the whole standard library
+// when PruneOptions.CollapseStdlib is set
+const StdlibNode = "std"
+
+// Graph is an exportable view of the dependency graph
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a package (or collapsed group of packages) in a Graph
+type GraphNode struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Doc    string `json:"doc,omitempty"` // package synopsis of local nodes
+	Main   bool   `json:"main,omitempty"`
+	FanIn  int    `json:"fan_in"`
+	FanOut int    `json:"fan_out"`
+}
+
+// GraphEdge is a dependency From -> To in a Graph
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PruneOptions selects the reductions applied by Prune
+type PruneOptions struct {
+	CollapseStdlib   bool // merge every stdlib package into the StdlibNode
+	CollapseExternal bool // merge external packages into one node per module
+	MinFanIn         int  // drop leaves (no outgoing edges) imported by fewer packages; mains are kept
+}
+
+// Prune returns a reduced copy of the dependency graph for visualization, so
+// exports stay readable for repos with thousands of nodes. External module
+// roots are derived from the import path (host/owner/repo for well-known
+// hosts such as github.com). Nodes and edges are sorted by ID.
+func (g *GoDepFind) Prune(opts PruneOptions) (*Graph, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	return g.prune(opts), nil
+}
+
+func (g *GoDepFind) prune(opts PruneOptions) *Graph {
+	collapse := func(pkg string) string {
+		switch g.nodeKind(pkg) {
+		case NodeStdlib:
+			if opts.CollapseStdlib {
+				return StdlibNode
+			}
+		case NodeExternal:
+			if opts.CollapseExternal {
+				return externalModuleRoot(pkg)
+			}
+		}
+		return pkg
+	}
+
+	nodes := make(map[string]*GraphNode)
+	addNode := func(id, pkg string) *GraphNode {
+		if node, ok := nodes[id]; ok {
+			return node
+		}
+		kind := g.nodeKind(pkg)
+		if id == StdlibNode && opts.CollapseStdlib {
+			kind = NodeStdlib
+		}
+		node := &GraphNode{ID: id, Kind: kind}
+		if kind == NodeLocal {
+			node.Doc = packageSynopsis(g.packageCache[pkg])
+		}
+		nodes[id] = node
+		return node
+	}
+
+	edges := make(map[GraphEdge]bool)
+	for from, deps := range g.dependencyGraph {
+		fromID := collapse(from)
+		addNode(fromID, from)
+		for _, to := range deps {
+			toID := collapse(to)
+			addNode(toID, to)
+			if fromID != toID {
+				edges[GraphEdge{From: fromID, To: toID}] = true
+			}
+		}
+	}
+	for _, mainPkg := range g.mainPackages {
+		addNode(collapse(mainPkg), mainPkg).Main = true
+	}
+
+	for edge := range edges {
+		nodes[edge.From].FanOut++
+		nodes[edge.To].FanIn++
+	}
+
+	// Drop weakly used leaves
+	if opts.MinFanIn > 0 {
+		for id, node := range nodes {
+			if node.FanOut == 0 && !node.Main && node.FanIn < opts.MinFanIn {
+				delete(nodes, id)
+			}
+		}
+		for edge := range edges {
+			if nodes[edge.To] == nil {
+				delete(edges, edge)
+				nodes[edge.From].FanOut--
+			}
+		}
+	}
+
+	graph := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	for edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sortEdges(graph.Edges)
+	return graph
+}
+
+// nodeKind classifies a package path as local, stdlib or external
+func (g *GoDepFind) nodeKind(pkg string) string {
+	if _, ok := g.packageCache[pkg]; ok {
+		return NodeLocal
+	}
+	if isStdlibPath(pkg) {
+		return NodeStdlib
+	}
+	return NodeExternal
+}
+
+// stdlibPaths memoizes isStdlibPath; GOROOT does not change while running
+var stdlibPaths sync.Map // import path -> bool
+
+// isStdlibPath reports whether an import path is a standard library package
+// (and not a local package, which callers check first): a directory of
+// GOROOT/src. Module names without a dot ("myapp/shared") are therefore not
+// mistaken for the standard library. Without a GOROOT it falls back to the
+// first element having no dot.
+func isStdlibPath(pkg string) bool {
+	if pkg == "" || pkg == "C" {
+		return false
+	}
+	if std, ok := stdlibPaths.Load(pkg); ok {
+		return std.(bool)
+	}
+	var std bool
+	if goroot := build.Default.GOROOT; goroot != "" {
+		info, err := os.Stat(filepath.Join(goroot, "src", filepath.FromSlash(pkg)))
+		std = err == nil && info.IsDir() && pkg != "vendor" && !strings.HasPrefix(pkg, "vendor/")
+	} else {
+		first, _, _ := strings.Cut(pkg, "/")
+		std = !strings.Contains(first, ".")
+	}
+	stdlibPaths.Store(pkg, std)
+	return std
+}
+
+// externalModuleRoot guesses the module path of an external package
+func externalModuleRoot(pkg string) string {
+	parts := strings.Split(pkg, "/")
+	switch parts[0] {
+	case "github.com", "gitlab.com", "bitbucket.org", "golang.org", "google.golang.org", "go.googlesource.com":
+		if parts[0] == "google.golang.org" && len(parts) >= 2 {
+			return strings.Join(parts[:2], "/")
+		}
+		if len(parts) >= 3 {
+			return strings.Join(parts[:3], "/")
+		}
+	case "gopkg.in":
+		if len(parts) >= 2 {
+			return strings.Join(parts[:2], "/")
+		}
+	}
+	if len(parts) >= 3 {
+		return strings.Join(parts[:3], "/")
+	}
+	return pkg
+}
@@ -0,0 +1,66 @@
+package depfind
+
+import (
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	finder := New(".")
+	finder.cachedModule = true
+	finder.packageCache = map[string]*build.Package{
+		"app/cmd":  {Name: "main"},
+		"app/core": {Name: "core"},
+		"app/util": {Name: "util"},
+	}
+	finder.mainPackages = []string{"app/cmd"}
+	finder.dependencyGraph = map[string][]string{
+		"app/cmd":  {"app/core", "fmt", "github.com/acme/kit/log"},
+		"app/core": {"app/util", "strings", "net/http", "github.com/acme/kit/http"},
+		"app/util": {"strings"},
+	}
+
+	full, err := finder.Prune(PruneOptions{})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(full.Nodes) != 8 {
+		t.Errorf("expected 8 nodes without pruning, got %d", len(full.Nodes))
+	}
+
+	pruned, err := finder.Prune(PruneOptions{CollapseStdlib: true, CollapseExternal: true})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	var ids []string
+	for _, node := range pruned.Nodes {
+		ids = append(ids, node.ID)
+	}
+	if want := []string{"app/cmd", "app/core", "app/util", "github.com/acme/kit", "std"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("collapsed nodes: expected %v, got %v", want, ids)
+	}
+	for _, node := range pruned.Nodes {
+		if node.ID == "std" && (node.Kind != NodeStdlib || node.FanIn != 3) {
+			t.Errorf("unexpected std node: %+v", node)
+		}
+		if node.ID == "github.com/acme/kit" && (node.Kind != NodeExternal || node.FanIn != 2) {
+			t.Errorf("unexpected module node: %+v", node)
+		}
+	}
+
+	leaves, err := finder.Prune(PruneOptions{CollapseStdlib: true, CollapseExternal: true, MinFanIn: 3})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	for _, node := range leaves.Nodes {
+		if node.ID == "github.com/acme/kit" {
+			t.Error("expected leaf with fan-in 2 to be dropped")
+		}
+	}
+	for _, edge := range leaves.Edges {
+		if edge.To == "github.com/acme/kit" {
+			t.Errorf("dangling edge kept: %+v", edge)
+		}
+	}
+}
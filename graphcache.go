@@ -0,0 +1,178 @@
+package depfind
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCacheStale is returned by LoadCache for a graph saved for other roots,
+// scopes or toolchain, or before a go.mod/go.work change
+var ErrCacheStale = errors.New("depfind: cache file is stale")
+
+// graphCacheSnapshot is the persisted graph: the loaded packages, from which
+// the edges and file indices are derived again, and the file stamps and
+// content hashes they were loaded with
+type graphCacheSnapshot struct {
+	Key      string                    `json:"key"`      // roots, scopes and toolchain
+	Modules  map[string]string         `json:"modules"`  // go.mod/go.work -> sha256, "" when missing
+	Packages map[string]*build.Package `json:"packages"` // import path -> loaded package
+	Dirs     map[string]string         `json:"dirs"`     // import path -> directory, as listed
+	Files    map[string]int64          `json:"files"`    // indexed file (absolute) -> modification time
+	Hashes   map[string]string         `json:"hashes"`   // indexed file (absolute) -> sha256 of its content
+	Skipped  []SkippedPackage          `json:"skipped,omitempty"`
+	Excluded []string                  `json:"excluded,omitempty"` // vendored trees, see ExcludedDirs
+	Virtual  []VirtualEdge             `json:"virtual,omitempty"`  // see AddVirtualEdge
+}
+
+// SaveCache writes the whole cached graph to path (loaded packages, file
+// index, listing report, virtual edges), building it first when needed, so
+// a later process can start from it with LoadCache instead of scanning the
+// module. The file
+// shares the format of SetCacheFile (versioned, checksummed, written
+// atomically) but holds far more; keep it outside the roots as well.
+func (g *GoDepFind) SaveCache(path string) error {
+	return g.SaveCacheTo(FileCacheStore{}, path)
+}
+
+// SaveCacheTo writes the whole cached graph under key in store, see
+// SaveCache. Files are recorded with their content hash too, so a graph
+// saved on one machine can be loaded on another where a checkout of the
+// same commit gave every file a new modification time (e.g. a CI fleet
+// keying the store by commit). The roots must have the same absolute paths.
+func (g *GoDepFind) SaveCacheTo(store CacheStore, key string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+	snapshot := graphCacheSnapshot{
+		Key:      g.cacheKey(),
+		Modules:  moduleFileSums(g.rootDirs),
+		Packages: g.packageCache,
+		Dirs:     make(map[string]string),
+		Files:    g.fileStamps,
+		Hashes:   make(map[string]string, len(g.fileStamps)),
+		Virtual:  g.virtualEdgeList(),
+	}
+	for path := range g.fileStamps {
+		if sum, ok := writtenContentHash(path, EventWrite); ok {
+			snapshot.Hashes[path] = hex.EncodeToString(sum[:])
+		}
+	}
+	g.listMu.Lock()
+	maps.Copy(snapshot.Dirs, g.packageDirs)
+	for _, skipped := range g.skipped {
+		snapshot.Skipped = append(snapshot.Skipped, skipped)
+	}
+	snapshot.Excluded = slices.Clone(g.excludedDirs)
+	g.listMu.Unlock()
+	slices.SortFunc(snapshot.Skipped, func(a, b SkippedPackage) int { return strings.Compare(a.Path, b.Path) })
+
+	return putEnvelope(store, key, snapshot)
+}
+
+// LoadCache replaces the cache with the graph SaveCache wrote to path, then
+// reconciles it with the roots as Reconcile does: only the packages whose
+// files were added, removed or modified since are reloaded, and a new
+// package directory triggers a full rebuild. A file counts as modified when
+// both its modification time and its content changed. A cold start thus
+// costs a walk of the roots instead of a module scan. A missing file
+// (fs.ErrNotExist), a corrupt or outdated one, or ErrCacheStale leaves the
+// finder untouched: it builds its cache on the first query as usual.
+// Metrics reports the outcome.
+func (g *GoDepFind) LoadCache(path string) (*ReconcileResult, error) {
+	return g.LoadCacheFrom(FileCacheStore{}, path)
+}
+
+// LoadCacheFrom loads the graph SaveCacheTo stored under key in store, see
+// LoadCache
+func (g *GoDepFind) LoadCacheFrom(store CacheStore, key string) (*ReconcileResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil, ErrClosed
+	}
+	var snapshot graphCacheSnapshot
+	result, err := getEnvelope(store, key, &snapshot)
+	if result == CacheHit && (snapshot.Key != g.cacheKey() || !maps.Equal(snapshot.Modules, moduleFileSums(g.rootDirs))) {
+		result, err = CacheStale, ErrCacheStale
+	}
+	g.metrics.LastCacheLoad = result
+	g.metrics.LastCacheErr = errorDetail(err)
+	switch result {
+	case CacheHit:
+	case CacheMissing:
+		return nil, fmt.Errorf("cannot load cache %s: %w", key, fs.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("cannot load cache %s: %w", key, err)
+	}
+	g.metrics.CacheHits++
+
+	g.lastRebuild = time.Now()
+	g.rebuildPending = false
+	g.graphChanged()
+	g.resetListReport()
+	g.loadErrors = nil
+	g.detectNestedModules()
+	for pkgPath, dir := range snapshot.Dirs {
+		g.setPackageDir(pkgPath, dir)
+	}
+	for _, skipped := range snapshot.Skipped {
+		g.addSkipped(skipped)
+	}
+	g.listMu.Lock()
+	g.excludedDirs = slices.Clone(snapshot.Excluded)
+	g.listMu.Unlock()
+
+	// Edges declared on this finder win over the saved ones
+	for _, edge := range snapshot.Virtual {
+		if _, declared := g.virtualEdges[edge.From][edge.To]; declared || edge.From == "" || edge.To == "" {
+			continue
+		}
+		if g.virtualEdges == nil {
+			g.virtualEdges = make(map[string]map[string]string)
+		}
+		if g.virtualEdges[edge.From] == nil {
+			g.virtualEdges[edge.From] = make(map[string]string)
+		}
+		g.virtualEdges[edge.From][edge.To] = edge.Reason
+	}
+
+	packages := make(map[string]*build.Package, len(snapshot.Packages))
+	for pkgPath, pkg := range snapshot.Packages {
+		if pkg != nil {
+			packages[pkgPath] = pkg
+		}
+	}
+	g.commitPackages(packages)
+	g.activeStrategy, g.listedCount = StrategyFull, len(packages)
+	g.lazyPending, g.lazyClosures = nil, nil
+	// The stamps the packages were loaded with, so reconcile sees what
+	// changed since the file was written
+	g.fileStamps = snapshot.Files
+	if g.fileStamps == nil {
+		g.fileStamps = make(map[string]int64)
+	}
+	for path, stamp := range g.fileStamps {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().UnixNano() == stamp || snapshot.Hashes[path] == "" {
+			continue
+		}
+		if sum, ok := writtenContentHash(path, EventWrite); ok && hex.EncodeToString(sum[:]) == snapshot.Hashes[path] {
+			g.fileStamps[path] = info.ModTime().UnixNano() // touched, not modified
+		}
+	}
+	g.cachedModule = true
+
+	return g.reconcile()
+}
@@ -0,0 +1,233 @@
+package depfind
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module gc\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"gc/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+	})
+	cacheFile := filepath.Join(t.TempDir(), "graph.json")
+	libFile := filepath.Join(root, "lib", "lib.go")
+	utilFile := filepath.Join(root, "util", "util.go")
+
+	if err := New(root).SaveCache(cacheFile); err != nil {
+		t.Fatalf("SaveCache: %v", err)
+	}
+
+	finder := New(root)
+	result, err := finder.LoadCache(cacheFile)
+	if err != nil {
+		t.Fatalf("LoadCache: %v", err)
+	}
+	if result.Changed() || result.Rebuilt {
+		t.Errorf("expected an unchanged tree, got %+v", result)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if metrics := finder.Metrics(); metrics.FullRebuilds != 0 || metrics.CacheHits != 1 || metrics.LastCacheLoad != CacheHit {
+		t.Errorf("expected the graph to come from the file, got %+v", metrics)
+	}
+
+	// A file modified since the save is reloaded, without a full rebuild
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"gc/util\"\n\nfunc Do() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(libFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	finder = New(root)
+	result, err = finder.LoadCache(cacheFile)
+	if err != nil {
+		t.Fatalf("LoadCache: %v", err)
+	}
+	if len(result.Modified) != 1 || result.Modified[0] != libFile || result.Rebuilt {
+		t.Errorf("expected lib.go to be reloaded, got %+v", result)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", utilFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected util.go to be owned through the new import, got %v, %v", isMine, err)
+	}
+	if metrics := finder.Metrics(); metrics.FullRebuilds != 0 {
+		t.Errorf("expected no full rebuild, got %+v", metrics)
+	}
+
+	// A go.mod change makes the file stale; the finder builds as usual
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module gc\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	finder = New(root)
+	if _, err := finder.LoadCache(cacheFile); !errors.Is(err, ErrCacheStale) {
+		t.Errorf("expected ErrCacheStale, got %v", err)
+	}
+	if metrics := finder.Metrics(); metrics.LastCacheLoad != CacheStale {
+		t.Errorf("LastCacheLoad = %q, want %q", metrics.LastCacheLoad, CacheStale)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", utilFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected util.go to be owned after a rebuild, got %v, %v", isMine, err)
+	}
+
+	if _, err := New(root).LoadCache(filepath.Join(t.TempDir(), "none.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing file, got %v", err)
+	}
+}
+
+// memoryStore is a CacheStore shared by finders in a test, as a remote blob
+// store would be by machines
+type memoryStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("no blob %s: %w", key, fs.ErrNotExist)
+	}
+	return data, nil
+}
+
+func (s *memoryStore) Put(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blobs == nil {
+		s.blobs = make(map[string][]byte)
+	}
+	s.blobs[key] = data
+	return nil
+}
+
+func TestCacheStoreSharedAcrossCheckouts(t *testing.T) {
+	files := map[string]string{
+		"go.mod":      "module cs\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"cs/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	}
+	root := writeTree(t, files)
+	store := &memoryStore{}
+	if err := New(root).SaveCacheTo(store, "commit-abc"); err != nil {
+		t.Fatalf("SaveCacheTo: %v", err)
+	}
+
+	// A fresh checkout of the same commit: same content, new modification times
+	later := time.Now().Add(time.Hour)
+	for name := range files {
+		if err := os.Chtimes(filepath.Join(root, name), later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	finder := New(root)
+	result, err := finder.LoadCacheFrom(store, "commit-abc")
+	if err != nil {
+		t.Fatalf("LoadCacheFrom: %v", err)
+	}
+	if result.Changed() || result.Rebuilt {
+		t.Errorf("expected touched files to count as unchanged, got %+v", result)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib", "lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if _, err := finder.LoadCacheFrom(store, "commit-def"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for an unknown key, got %v", err)
+	}
+
+	// The listing cache goes through the store too
+	finder = New(root)
+	finder.SetCacheStore(store, "listing")
+	if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("listing"); err != nil {
+		t.Errorf("expected the listing to be stored, got %v", err)
+	}
+}
+
+func TestFileCacheStoreConcurrentPuts(t *testing.T) {
+	store := FileCacheStore{Dir: t.TempDir()}
+	blobs := make([][]byte, 8)
+	for i := range blobs {
+		blobs[i] = bytes.Repeat([]byte{byte('a' + i)}, 1<<16)
+	}
+
+	// Writers sharing a store never leave a torn blob behind
+	var wg sync.WaitGroup
+	for _, blob := range blobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if err := store.Put("graph", blob); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := store.Get("graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(blobs, func(blob []byte) bool { return bytes.Equal(blob, data) }) {
+		t.Errorf("expected one whole blob, got %d bytes starting with %q", len(data), data[:1])
+	}
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary file left, got %d entries", len(entries))
+	}
+}
+
+func TestSaveCacheKeepsVirtualEdges(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module gv\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nfunc main() {}\n",
+		"plugin/p.go":  "package plugin\n\nfunc P() {}\n",
+		"assets/as.go": "package assets\n",
+	})
+	cacheFile := filepath.Join(t.TempDir(), "graph.json")
+	pluginFile := filepath.Join(root, "plugin", "p.go")
+
+	saver := New(root)
+	saver.AddVirtualEdge("gv/app", "gv/plugin", "loaded at runtime")
+	if err := saver.SaveCache(cacheFile); err != nil {
+		t.Fatalf("SaveCache: %v", err)
+	}
+
+	finder := New(root)
+	finder.AddVirtualEdge("gv/app", "gv/assets", "embedded")
+	if _, err := finder.LoadCache(cacheFile); err != nil {
+		t.Fatalf("LoadCache: %v", err)
+	}
+	want := []VirtualEdge{
+		{From: "gv/app", To: "gv/assets", Reason: "embedded"},
+		{From: "gv/app", To: "gv/plugin", Reason: "loaded at runtime"},
+	}
+	if edges := finder.VirtualEdges(); fmt.Sprint(edges) != fmt.Sprint(want) {
+		t.Errorf("VirtualEdges after LoadCache = %v, want %v", edges, want)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", pluginFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected the saved virtual edge to route plugin/p.go, got %v, %v", isMine, err)
+	}
+	if metrics := finder.Metrics(); metrics.FullRebuilds != 0 {
+		t.Errorf("expected the graph to come from the file, got %+v", metrics)
+	}
+}
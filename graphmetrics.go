@@ -0,0 +1,121 @@
+package depfind
+
+import (
+	"sort"
+)
+
+// mostDependedOnLimit caps GraphMetrics.MostDependedOn
+const mostDependedOnLimit = 10
+
+// PackageMetrics are the graph metrics of one cached package
+type PackageMetrics struct {
+	Package string `json:"package"`
+	FanIn   int    `json:"fan_in"`  // cached packages importing it directly
+	FanOut  int    `json:"fan_out"` // distinct direct imports, stdlib and external included
+	Depth   int    `json:"depth"`   // longest chain of cached imports below it, 0 for a package importing none
+}
+
+// GraphMetrics summarizes the shape of the cached module graph, see
+// GoDepFind.GraphMetrics
+type GraphMetrics struct {
+	Packages       []PackageMetrics `json:"packages"`         // every cached package, sorted by path
+	MaxDepth       int              `json:"max_depth"`        // longest dependency chain
+	Deepest        []string         `json:"deepest"`          // packages at MaxDepth, sorted
+	MostDependedOn []PackageMetrics `json:"most_depended_on"` // highest fan-in first, at most 10, packages nobody imports left out
+}
+
+// GraphMetrics computes per-package fan-in, fan-out and depth over the
+// cached graph, for health dashboards that would otherwise load the module
+// again. Import cycles count once: the packages of a cycle share the depth
+// of the cycle as a whole (see DetectCycles).
+func (g *GoDepFind) GraphMetrics() (*GraphMetrics, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	cached := g.cachedPackageSet()
+	fanIn := make(map[string]int, len(cached))
+	for pkg := range cached {
+		for _, dep := range sortedUnique(g.dependencyGraph[pkg]) {
+			if cached[dep] && dep != pkg {
+				fanIn[dep]++
+			}
+		}
+	}
+	depth := g.packageDepths(cached)
+
+	metrics := &GraphMetrics{Packages: []PackageMetrics{}, Deepest: []string{}, MostDependedOn: []PackageMetrics{}}
+	for _, pkg := range sortedKeys(cached) {
+		m := PackageMetrics{
+			Package: pkg,
+			FanIn:   fanIn[pkg],
+			FanOut:  len(sortedUnique(g.dependencyGraph[pkg])),
+			Depth:   depth[pkg],
+		}
+		metrics.Packages = append(metrics.Packages, m)
+		switch {
+		case m.Depth > metrics.MaxDepth:
+			metrics.MaxDepth, metrics.Deepest = m.Depth, []string{pkg}
+		case m.Depth == metrics.MaxDepth:
+			metrics.Deepest = append(metrics.Deepest, pkg)
+		}
+		if m.FanIn > 0 {
+			metrics.MostDependedOn = append(metrics.MostDependedOn, m)
+		}
+	}
+	sort.SliceStable(metrics.MostDependedOn, func(i, j int) bool {
+		return metrics.MostDependedOn[i].FanIn > metrics.MostDependedOn[j].FanIn
+	})
+	if len(metrics.MostDependedOn) > mostDependedOnLimit {
+		metrics.MostDependedOn = metrics.MostDependedOn[:mostDependedOnLimit]
+	}
+	return metrics, nil
+}
+
+// packageDepths returns the length of the longest chain of cached imports
+// below each cached package, over the graph condensed by its cycles
+func (g *GoDepFind) packageDepths(cached map[string]bool) map[string]int {
+	component := make(map[string]string, len(cached)) // package -> representative of its cycle
+	members := make(map[string][]string)
+	for pkg := range cached {
+		component[pkg] = pkg
+		members[pkg] = []string{pkg}
+	}
+	for _, cycle := range g.stronglyConnected() {
+		for _, pkg := range cycle {
+			component[pkg] = cycle[0]
+		}
+		for _, pkg := range cycle[1:] {
+			delete(members, pkg)
+		}
+		members[cycle[0]] = cycle
+	}
+
+	memo := make(map[string]int, len(members))
+	var depthOf func(rep string) int
+	depthOf = func(rep string) int {
+		if d, ok := memo[rep]; ok {
+			return d
+		}
+		d := 0
+		for _, pkg := range members[rep] {
+			for _, dep := range g.dependencyGraph[pkg] {
+				if !cached[dep] || component[dep] == rep {
+					continue
+				}
+				d = max(d, depthOf(component[dep])+1)
+			}
+		}
+		memo[rep] = d
+		return d
+	}
+
+	depths := make(map[string]int, len(cached))
+	for pkg := range cached {
+		depths[pkg] = depthOf(component[pkg])
+	}
+	return depths
+}
@@ -0,0 +1,42 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraphMetrics(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module gm\n\ngo 1.21\n",
+		"cmd/main.go": "package main\n\nimport (\n\t_ \"gm/a\"\n\t_ \"gm/util\"\n)\n\nfunc main() {}\n",
+		"a/a.go":      "package a\n\nimport (\n\t_ \"fmt\"\n\t_ \"gm/b\"\n\t_ \"gm/util\"\n)\n",
+		"b/b.go":      "package b\n\nimport _ \"gm/c\"\n",
+		"c/c.go":      "package c\n\nimport (\n\t_ \"gm/b\"\n\t_ \"gm/util\"\n)\n",
+		"util/u.go":   "package util\n\nimport _ \"strings\"\n",
+	})
+	metrics, err := New(root).GraphMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PackageMetrics{
+		{Package: "gm/a", FanIn: 1, FanOut: 3, Depth: 2},
+		{Package: "gm/b", FanIn: 2, FanOut: 1, Depth: 1}, // b and c form a cycle above util
+		{Package: "gm/c", FanIn: 1, FanOut: 2, Depth: 1},
+		{Package: "gm/cmd", FanIn: 0, FanOut: 2, Depth: 3},
+		{Package: "gm/util", FanIn: 3, FanOut: 1, Depth: 0},
+	}
+	if !reflect.DeepEqual(metrics.Packages, want) {
+		t.Errorf("Packages = %+v, want %+v", metrics.Packages, want)
+	}
+	if metrics.MaxDepth != 3 || !reflect.DeepEqual(metrics.Deepest, []string{"gm/cmd"}) {
+		t.Errorf("MaxDepth = %d, Deepest = %v, want 3, [gm/cmd]", metrics.MaxDepth, metrics.Deepest)
+	}
+	var top []string
+	for _, m := range metrics.MostDependedOn {
+		top = append(top, m.Package)
+	}
+	if want := []string{"gm/util", "gm/b", "gm/a", "gm/c"}; !reflect.DeepEqual(top, want) {
+		t.Errorf("MostDependedOn = %v, want %v", top, want)
+	}
+}
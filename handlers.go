@@ -0,0 +1,209 @@
+package depfind
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HandlerDefinition is a suggested handler found by DiscoverHandlers: one
+// main file plus the build context it should be compiled with.
+type HandlerDefinition struct {
+	MainFile  string `json:"main_file"`            // relative to the primary root, e.g. "pwa/main.server.go"
+	Package   string `json:"package,omitempty"`    // import path of the main package when known
+	Group     string `json:"group"`                // directory shared by mains split by build tags
+	BuildTags string `json:"build_tags,omitempty"` // //go:build expression, "" when unconstrained
+	GOOS      string `json:"goos,omitempty"`       // target OS, "" for the host
+	GOARCH    string `json:"goarch,omitempty"`     // target arch, "" for the host
+}
+
+// IsWasm reports whether the handler targets WebAssembly
+func (h HandlerDefinition) IsWasm() bool {
+	return h.GOARCH == "wasm"
+}
+
+// DiscoverHandlers finds every file declaring func main in a main package
+// under the roots and returns one suggested handler per file. Mains sharing a
+// directory (the server vs wasm pattern selected by build tags) get the same
+// Group, and files only buildable for js/wasm get GOOS/GOARCH set accordingly.
+// Results are sorted by MainFile.
+func (g *GoDepFind) DiscoverHandlers() ([]HandlerDefinition, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.discoverHandlers()
+}
+
+func (g *GoDepFind) discoverHandlers() ([]HandlerDefinition, error) {
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	pkgByDir := make(map[string]string)
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil {
+			pkgByDir[pkg.Dir] = pkgPath
+		}
+	}
+
+	var handlers []HandlerDefinition
+	for _, root := range g.rootDirs {
+		start := root
+		if g.scope != "" && root == g.rootDirs[0] {
+			start = filepath.Join(root, filepath.FromSlash(g.scope))
+		}
+		err := filepath.WalkDir(start, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != start && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+					name == "testdata" || name == "vendor" || g.inToolDir(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			name := d.Name()
+			if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") || !g.matchesScopePatterns(path) || !declaresMainFunc(path) {
+				return nil
+			}
+
+			handlers = append(handlers, g.handlerDefinition(path, pkgByDir[filepath.Dir(path)]))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(handlers, func(i, j int) bool { return handlers[i].MainFile < handlers[j].MainFile })
+	return handlers, nil
+}
+
+// cachedHandlers is discoverHandlers restricted to the cached main
+// packages: only their files are parsed, instead of walking the roots. Main
+// files excluded from the host build (main.wasm.go) are included.
+func (g *GoDepFind) cachedHandlers() []HandlerDefinition {
+	var handlers []HandlerDefinition
+	for _, pkgPath := range g.mainPackages {
+		pkg := g.packageCache[pkgPath]
+		if pkg == nil {
+			continue
+		}
+		for _, name := range append(append([]string{}, pkg.GoFiles...), pkg.IgnoredGoFiles...) {
+			path := filepath.Join(pkg.Dir, name)
+			if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") || !g.inScope(path) || !declaresMainFunc(path) {
+				continue
+			}
+			handlers = append(handlers, g.handlerDefinition(path, pkgPath))
+		}
+	}
+	sort.Slice(handlers, func(i, j int) bool { return handlers[i].MainFile < handlers[j].MainFile })
+	return handlers
+}
+
+// handlerDefinition describes the main file at path, in package pkgPath
+func (g *GoDepFind) handlerDefinition(path, pkgPath string) HandlerDefinition {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	rel, err := filepath.Rel(g.rootDirs[0], path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = path // secondary root outside the primary one
+	}
+	group, _ := filepath.Rel(g.rootDirs[0], dir)
+
+	def := HandlerDefinition{
+		MainFile:  filepath.ToSlash(rel),
+		Package:   pkgPath,
+		Group:     filepath.ToSlash(group),
+		BuildTags: buildConstraint(path),
+	}
+	hostCtx := build.Default
+	wasmCtx := build.Default
+	wasmCtx.GOOS, wasmCtx.GOARCH = "js", "wasm"
+	hostOK, _ := hostCtx.MatchFile(dir, name)
+	wasmOK, _ := wasmCtx.MatchFile(dir, name)
+	if wasmOK && !hostOK {
+		def.GOOS, def.GOARCH = "js", "wasm"
+	}
+	return def
+}
+
+// DirectoryMain is one main file of a directory holding several, see
+// MainsInDirectory
+type DirectoryMain struct {
+	HandlerDefinition
+	Imports []string `json:"imports"` // direct imports of the file, sorted
+}
+
+// MainsInDirectory returns every file of dir (relative to the primary root
+// or absolute) declaring func main, with its build constraints, target and
+// direct imports, sorted by file. Directories splitting mains by build tags
+// (main.server.go / main.wasm.go) can be presented and checked with it: each
+// handler owns what its own file imports.
+func (g *GoDepFind) MainsInDirectory(dir string) ([]DirectoryMain, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	absDir := g.rootPath(dir)
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mains []DirectoryMain
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(absDir, name)
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") || !declaresMainFunc(path) {
+			continue
+		}
+		specs, err := g.parseFileImportSpecs(path)
+		if err != nil {
+			return nil, err
+		}
+		imports := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			imports = append(imports, g.resolveImport(spec.Path, absDir))
+		}
+		sort.Strings(imports)
+		mains = append(mains, DirectoryMain{
+			HandlerDefinition: g.handlerDefinition(path, g.packageInDir(absDir)),
+			Imports:           imports,
+		})
+	}
+	return mains, nil
+}
+
+// declaresMainFunc reports whether the file belongs to package main and
+// declares a top-level func main
+func declaresMainFunc(path string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil || file.Name.Name != "main" {
+		return false
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildConstraint returns the build constraint expression of a file, or ""
+// when none
+func buildConstraint(path string) string {
+	expr, err := fileConstraint(path)
+	if err != nil || expr == nil {
+		return ""
+	}
+	return expr.String()
+}
@@ -0,0 +1,70 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverHandlers(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module app\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nfunc main() {}\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nfunc main() {}\n",
+		"pwa/routes.go":      "package main\n",
+		"cmd/tool/main.go":   "package main\n\nfunc main() {}\n",
+		"lib/lib.go":         "package lib\n\nfunc main() {}\n",
+		"cmd/tool/x_test.go": "package main\n\nfunc main() {}\n",
+	})
+
+	handlers, err := New(root).DiscoverHandlers()
+	if err != nil {
+		t.Fatalf("DiscoverHandlers: %v", err)
+	}
+	if len(handlers) != 3 {
+		t.Fatalf("expected 3 handlers, got %d: %+v", len(handlers), handlers)
+	}
+
+	expected := []HandlerDefinition{
+		{MainFile: "cmd/tool/main.go", Package: "app/cmd/tool", Group: "cmd/tool"},
+		{MainFile: "pwa/main.server.go", Package: "app/pwa", Group: "pwa", BuildTags: "!wasm"},
+		{MainFile: "pwa/main.wasm.go", Package: "app/pwa", Group: "pwa", BuildTags: "wasm", GOOS: "js", GOARCH: "wasm"},
+	}
+	for i, want := range expected {
+		if handlers[i] != want {
+			t.Errorf("handler %d: expected %+v, got %+v", i, want, handlers[i])
+		}
+	}
+	if !handlers[2].IsWasm() || handlers[1].IsWasm() {
+		t.Error("IsWasm should only report the wasm main")
+	}
+}
+
+func TestMainsInDirectory(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module split\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport (\n\t\"net/http\"\n\n\t\"split/api\"\n)\n\nfunc main() { http.ListenAndServe(api.Addr, nil) }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"split/ui\"\n\nfunc main() { ui.Render() }\n",
+		"pwa/shared.go":      "package main\n",
+		"api/api.go":         "package api\n\nconst Addr = \":8080\"\n",
+		"ui/ui.go":           "package ui\n\nfunc Render() {}\n",
+	})
+	finder := New(root)
+
+	mains, err := finder.MainsInDirectory("pwa")
+	if err != nil {
+		t.Fatalf("MainsInDirectory: %v", err)
+	}
+	if len(mains) != 2 {
+		t.Fatalf("expected 2 mains, got %+v", mains)
+	}
+	server, wasm := mains[0], mains[1]
+	if server.MainFile != "pwa/main.server.go" || server.BuildTags != "!wasm" || !reflect.DeepEqual(server.Imports, []string{"net/http", "split/api"}) {
+		t.Errorf("unexpected server main: %+v", server)
+	}
+	if wasm.MainFile != "pwa/main.wasm.go" || wasm.BuildTags != "wasm" || !reflect.DeepEqual(wasm.Imports, []string{"split/ui"}) {
+		t.Errorf("unexpected wasm main: %+v", wasm)
+	}
+	if _, err := finder.MainsInDirectory("missing"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -1,6 +1,10 @@
 package depfind
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 // logf prints only when the test fails or is executed with -v.
 // Use instead of t.Logf for internal diagnostic logs.
@@ -10,3 +14,20 @@ func logf(t *testing.T, format string, args ...any) {
 		t.Logf(format, args...)
 	}
 }
+
+// writeTree creates the given files (relative path -> content) under a new
+// temporary directory and returns its path.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir %s: %v", rel, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	return root
+}
@@ -0,0 +1,166 @@
+package depfind
+
+import (
+	"slices"
+	"sort"
+)
+
+// AnalyzeFileImpact analyzes the impact of a file change for a handler: whether
+// the handler owns the file, which main packages, handlers and test packages
+// are affected, and the actions a dev server should take (rebuild handlers,
+// rerun tests). Invalid or half-written files are reported as "skipped".
+// Affected handlers are looked up among the main files of the cached main
+// packages and the handler asked about, without walking the roots.
+func (g *GoDepFind) AnalyzeFileImpact(mainInputFileRelativePath, fileName, filePath, event string) (*FileImpactResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Reuse centralized validation
+	shouldProcess, err := g.validateInputForProcessing(mainInputFileRelativePath, fileName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !shouldProcess {
+		return &FileImpactResult{
+			Status: "skipped",
+			Reason: "File is invalid, empty, or being written",
+			Impact: "none",
+		}, nil
+	}
+
+	// Perform impact analysis
+	mainPackages, err := g.goFileComesFromMain(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	belongs, err := g.thisFileIsMine(mainInputFileRelativePath, filePath, event)
+	if err != nil {
+		return nil, err
+	}
+
+	priority := PriorityNone
+	if belongs {
+		priority = g.ownershipPriority(mainInputFileRelativePath, filePath)
+	}
+
+	result := &FileImpactResult{
+		Status:           "analyzed",
+		BelongsToHandler: belongs,
+		AffectedMains:    mainPackages,
+		Impact:           calculateImpact(len(mainPackages), belongs),
+		Priority:         priority,
+	}
+
+	targetPkg, err := g.findPackageForFile(g.rootPath(filePath))
+	if err != nil || targetPkg == "" {
+		return result, nil
+	}
+	result.AffectedTests = g.affectedTestPackages(targetPkg)
+
+	// Handlers are the main files of the cached main packages, plus the one
+	// asked about: a main directory only building for another target
+	// (wasm) is not cached
+	handlers := g.cachedHandlers()
+	asked := handlerKey(mainInputFileRelativePath)
+	if !slices.ContainsFunc(handlers, func(h HandlerDefinition) bool { return h.MainFile == asked }) {
+		handlers = append(handlers, g.handlerDefinition(g.rootPath(mainInputFileRelativePath), ""))
+	}
+	for _, h := range handlers {
+		imports, err := g.handlerFileImportsPackage(h.MainFile, targetPkg)
+		if err != nil {
+			return nil, err
+		}
+		if h.Package == targetPkg || imports {
+			result.AffectedHandlers = append(result.AffectedHandlers, h.MainFile)
+		}
+	}
+
+	for _, h := range result.AffectedHandlers {
+		result.Actions = append(result.Actions, ImpactAction{Kind: ActionRebuild, Target: h})
+	}
+	for _, pkg := range result.AffectedTests {
+		result.Actions = append(result.Actions, ImpactAction{Kind: ActionTest, Target: pkg})
+	}
+
+	return result, nil
+}
+
+// FileImpactResult represents the result of file impact analysis
+type FileImpactResult struct {
+	Status           string         `json:"status"`
+	Reason           string         `json:"reason,omitempty"`
+	BelongsToHandler bool           `json:"belongs_to_handler"`
+	AffectedMains    []string       `json:"affected_mains"`
+	AffectedHandlers []string       `json:"affected_handlers,omitempty"` // handler main files to rebuild
+	AffectedTests    []string       `json:"affected_tests,omitempty"`    // packages whose tests should rerun
+	Actions          []ImpactAction `json:"actions,omitempty"`
+	Impact           string         `json:"impact"`
+	Priority         Priority       `json:"priority"`
+}
+
+// Impact action kinds
+const (
+	ActionRebuild = "rebuild" // rebuild the handler whose main file is Target
+	ActionTest    = "test"    // rerun the tests of package Target
+)
+
+// ImpactAction is a suggested follow-up step for a file change
+type ImpactAction struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+}
+
+// calculateImpact determines the impact level based on analysis results
+func calculateImpact(mainCount int, belongsToHandler bool) string {
+	if mainCount == 0 {
+		return "none"
+	}
+	if belongsToHandler && mainCount == 1 {
+		return "low"
+	}
+	if belongsToHandler && mainCount > 1 {
+		return "medium"
+	}
+	if !belongsToHandler && mainCount > 0 {
+		return "high" // File affects mains but doesn't belong to current handler
+	}
+	return "unknown"
+}
+
+// affectedTestPackages returns the packages with tests that exercise targetPkg:
+// the package itself and every package depending on it, directly, transitively
+// or from its test files. Results are sorted.
+func (g *GoDepFind) affectedTestPackages(targetPkg string) []string {
+	dependents := map[string]bool{targetPkg: true}
+	queue := []string{targetPkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.reverseDeps[current] {
+			if !dependents[dep] {
+				dependents[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	var result []string
+	for pkgPath, pkg := range g.packageCache {
+		if pkg == nil || len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) == 0 {
+			continue
+		}
+		affected := dependents[pkgPath]
+		for _, imp := range append(append([]string{}, pkg.TestImports...), pkg.XTestImports...) {
+			if affected {
+				break
+			}
+			affected = dependents[imp]
+		}
+		if affected {
+			result = append(result, pkgPath)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,80 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeFileImpactEnriched(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":              "module imp\n\ngo 1.21\n",
+		"app/main.go":         "package main\n\nimport \"imp/svc\"\n\nfunc main() { svc.Run() }\n",
+		"tool/main.go":        "package main\n\nfunc main() {}\n",
+		"svc/svc.go":          "package svc\n\nimport \"imp/store\"\n\nfunc Run() { store.Open() }\n",
+		"svc/svc_test.go":     "package svc\n\nimport \"testing\"\n\nfunc TestRun(t *testing.T) { Run() }\n",
+		"store/store.go":      "package store\n\nfunc Open() {}\n",
+		"store/store_test.go": "package store_test\n\nimport (\n\t\"testing\"\n\n\t\"imp/store\"\n)\n\nfunc TestOpen(t *testing.T) { store.Open() }\n",
+		"util/util.go":        "package util\n",
+		"util/util_test.go":   "package util\n\nimport (\n\t\"testing\"\n\n\t\"imp/store\"\n)\n\nfunc TestUtil(t *testing.T) { store.Open() }\n",
+		"unrelated/u.go":      "package unrelated\n",
+		"unrelated/u_test.go": "package unrelated\n\nimport \"testing\"\n\nfunc TestU(t *testing.T) {}\n",
+	})
+	finder := New(root)
+
+	result, err := finder.AnalyzeFileImpact("app/main.go", "store.go", filepath.Join(root, "store", "store.go"), "write")
+	if err != nil {
+		t.Fatalf("AnalyzeFileImpact: %v", err)
+	}
+
+	if !result.BelongsToHandler {
+		t.Error("expected store.go to belong to app/main.go")
+	}
+	if want := []string{"app/main.go"}; !reflect.DeepEqual(result.AffectedHandlers, want) {
+		t.Errorf("AffectedHandlers: expected %v, got %v", want, result.AffectedHandlers)
+	}
+	if want := []string{"imp/store", "imp/svc", "imp/util"}; !reflect.DeepEqual(result.AffectedTests, want) {
+		t.Errorf("AffectedTests: expected %v, got %v", want, result.AffectedTests)
+	}
+
+	wantActions := []ImpactAction{
+		{ActionRebuild, "app/main.go"},
+		{ActionTest, "imp/store"},
+		{ActionTest, "imp/svc"},
+		{ActionTest, "imp/util"},
+	}
+	if !reflect.DeepEqual(result.Actions, wantActions) {
+		t.Errorf("Actions: expected %v, got %v", wantActions, result.Actions)
+	}
+}
+
+func TestAnalyzeFileImpactCachedHandlers(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module imh\n\ngo 1.21\n",
+		"app/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"imh/store\"\n\nfunc main() { store.Open() }\n",
+		"app/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"imh/store\"\n\nfunc main() { store.Open() }\n",
+		"web/main.go":        "//go:build wasm\n\npackage main\n\nimport \"imh/store\"\n\nfunc main() { store.Open() }\n",
+		"store/store.go":     "package store\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+	storeFile := filepath.Join(root, "store", "store.go")
+
+	// Both files of the cached main package, host and wasm; the wasm-only
+	// directory is not cached
+	result, err := finder.AnalyzeFileImpact("app/main.server.go", "store.go", storeFile, EventWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app/main.server.go", "app/main.wasm.go"}; !reflect.DeepEqual(result.AffectedHandlers, want) {
+		t.Errorf("AffectedHandlers = %v, want %v", result.AffectedHandlers, want)
+	}
+
+	// The handler asked about is always considered
+	result, err = finder.AnalyzeFileImpact("web/main.go", "store.go", storeFile, EventWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app/main.server.go", "app/main.wasm.go", "web/main.go"}; !reflect.DeepEqual(result.AffectedHandlers, want) {
+		t.Errorf("AffectedHandlers = %v, want %v", result.AffectedHandlers, want)
+	}
+}
@@ -0,0 +1,178 @@
+package depfind
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImportSpec is one import declaration of a file as written in the source
+type ImportSpec struct {
+	Path string `json:"path"`           // import path as written
+	Name string `json:"name,omitempty"` // explicit name: an alias, "." or "_"; "" when absent
+	Line int    `json:"line"`           // 1-based line of the declaration
+}
+
+// IsDot reports whether the package is dot-imported: its exported
+// identifiers are used unqualified in the importing file
+func (s ImportSpec) IsDot() bool {
+	return s.Name == "."
+}
+
+// IsBlank reports whether the package is imported only for its side effects
+func (s ImportSpec) IsBlank() bool {
+	return s.Name == "_"
+}
+
+func (s ImportSpec) String() string {
+	if s.Name != "" {
+		return fmt.Sprintf("%s %q", s.Name, s.Path)
+	}
+	return fmt.Sprintf("%q", s.Path)
+}
+
+// FileImports returns the import declarations of a Go file with their
+// aliases, e.g. `db "testproject/database"`. Local and renamed imports are
+// resolved to current import paths.
+func (g *GoDepFind) FileImports(filePath string) ([]ImportSpec, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	absPath := g.rootPath(filePath)
+	specs, err := g.parseFileImportSpecs(absPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range specs {
+		specs[i].Path = g.resolveImport(specs[i].Path, filepath.Dir(absPath))
+	}
+	return specs, nil
+}
+
+// localName returns the identifier a file uses to refer to an import: the
+// alias when present, otherwise the imported package's name ("" for dot and
+// blank imports, whose identifiers are not qualified)
+func (g *GoDepFind) localName(spec ImportSpec) string {
+	switch {
+	case spec.IsDot(), spec.IsBlank():
+		return ""
+	case spec.Name != "":
+		return spec.Name
+	}
+	if pkg := g.packageCache[spec.Path]; pkg != nil && pkg.Name != "" {
+		return pkg.Name
+	}
+	return path.Base(spec.Path)
+}
+
+// qualifierPackages attributes a selector qualifier used in a file (the "db"
+// in db.Open) to the imports it may refer to. An unqualified identifier
+// ("" qualifier) may only come from dot imports, so all of them are returned.
+func (g *GoDepFind) qualifierPackages(specs []ImportSpec, qualifier string) []string {
+	var packages []string
+	for _, spec := range specs {
+		if qualifier == "" && spec.IsDot() || qualifier != "" && g.localName(spec) == qualifier {
+			packages = append(packages, spec.Path)
+		}
+	}
+	return packages
+}
+
+// FileEdge is a dependency of a package contributed by one of its files,
+// see EdgesFromFile
+type FileEdge struct {
+	From      string   `json:"from"`              // package containing the file
+	To        string   `json:"to"`                // imported package
+	Exclusive bool     `json:"exclusive"`         // no other file of the package imports To
+	AlsoIn    []string `json:"also_in,omitempty"` // other files of the package importing To, sorted
+}
+
+// EdgesFromFile returns the imports a file contributes to its package,
+// sorted by imported path, and for each one the other files of the package
+// (whatever their build constraints, tests excluded) that import it too. An
+// exclusive edge disappears when the file is deleted: removing
+// main.server.go drops the database dependency while main.wasm.go keeps dom.
+func (g *GoDepFind) EdgesFromFile(filePath string) ([]FileEdge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	absPath := g.rootPath(filePath)
+	pkgPath := g.filePathToPackage[absPath]
+	if pkgPath == "" {
+		pkgPath = g.packageInDir(filepath.Dir(absPath)) // excluded by build constraints on this host
+	}
+	if pkgPath == "" {
+		return nil, fmt.Errorf("file is not part of a known package: %s", filePath)
+	}
+
+	imports, err := g.resolvedFileImports(absPath)
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[string][]string) // import -> other files importing it
+	for _, peer := range g.packagePeerFiles(absPath) {
+		peerImports, err := g.resolvedFileImports(peer)
+		if err != nil {
+			continue
+		}
+		for _, imp := range peerImports {
+			peers[imp] = append(peers[imp], filepath.Base(peer))
+		}
+	}
+
+	edges := make([]FileEdge, 0, len(imports))
+	for _, imp := range imports {
+		edges = append(edges, FileEdge{From: pkgPath, To: imp, Exclusive: len(peers[imp]) == 0, AlsoIn: peers[imp]})
+	}
+	return edges, nil
+}
+
+// resolvedFileImports returns the distinct imports of a file, resolved and sorted
+func (g *GoDepFind) resolvedFileImports(absPath string) ([]string, error) {
+	specs, err := g.parseFileImportSpecs(absPath)
+	if err != nil {
+		return nil, err
+	}
+	imports := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		imports[g.resolveImport(spec.Path, filepath.Dir(absPath))] = true
+	}
+	return sortedKeys(imports), nil
+}
+
+// packagePeerFiles returns the other non-test Go files of the directory
+// declaring the same package as absPath, build constraints ignored, sorted
+func (g *GoDepFind) packagePeerFiles(absPath string) []string {
+	name := packageClause(absPath)
+	entries, err := os.ReadDir(filepath.Dir(absPath))
+	if err != nil || name == "" {
+		return nil
+	}
+	var peers []string
+	for _, entry := range entries {
+		peer := filepath.Join(filepath.Dir(absPath), entry.Name())
+		if entry.IsDir() || peer == absPath || filepath.Ext(peer) != ".go" || strings.HasSuffix(peer, "_test.go") {
+			continue
+		}
+		if packageClause(peer) == name {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// packageClause returns the package name declared by a Go file, or ""
+func packageClause(filePath string) string {
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	return file.Name.Name
+}
@@ -0,0 +1,112 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileImportsKeepsAliases(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod": "module al\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "fmt"
+
+import (
+	db "al/database"
+	. "al/helpers"
+	_ "al/plugins" // registers plugins
+	"al/version/v2"
+)
+
+func main() { fmt.Println(db.Open(), Help(), version.V) }
+`,
+		"database/db.go":  "package database\n\nfunc Open() int { return 1 }\n",
+		"helpers/h.go":    "package helpers\n\nfunc Help() int { return 2 }\n",
+		"plugins/p.go":    "package plugins\n",
+		"version/v2/v.go": "package version\n\nconst V = 2\n",
+	})
+	finder := New(root)
+
+	specs, err := finder.FileImports(filepath.Join(root, "app", "main.go"))
+	if err != nil {
+		t.Fatalf("FileImports: %v", err)
+	}
+	want := []ImportSpec{
+		{Path: "fmt", Line: 3},
+		{Path: "al/database", Name: "db", Line: 6},
+		{Path: "al/helpers", Name: ".", Line: 7},
+		{Path: "al/plugins", Name: "_", Line: 8},
+		{Path: "al/version/v2", Line: 9},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Fatalf("FileImports = %+v, want %+v", specs, want)
+	}
+	if specs[1].String() != `db "al/database"` {
+		t.Errorf("unexpected String(): %s", specs[1])
+	}
+	if !specs[2].IsDot() || !specs[3].IsBlank() || specs[1].IsDot() {
+		t.Error("unexpected dot/blank classification")
+	}
+
+	finder.mu.Lock()
+	defer finder.mu.Unlock()
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	attribution := map[string][]string{
+		"db":       {"al/database"},
+		"":         {"al/helpers"},    // unqualified identifiers come from dot imports
+		"version":  {"al/version/v2"}, // package name, not the last path element
+		"v2":       nil,
+		"database": nil, // hidden by the alias
+		"plugins":  nil, // blank imports are not referenced
+	}
+	for qualifier, want := range attribution {
+		if got := finder.qualifierPackages(specs, qualifier); !reflect.DeepEqual(got, want) {
+			t.Errorf("qualifierPackages(%q) = %v, want %v", qualifier, got, want)
+		}
+	}
+
+	// Aliased and dot imports still count for ownership
+	for _, file := range []string{"database/db.go", "helpers/h.go", "plugins/p.go"} {
+		if isMine, err := finder.thisFileIsMine("app/main.go", file, EventWrite); err != nil || !isMine {
+			t.Errorf("expected app/main.go to own %s, got %v, %v", file, isMine, err)
+		}
+	}
+}
+
+func TestEdgesFromFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module edges\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport (\n\t\"edges/database\"\n\t\"edges/shared\"\n)\n\nfunc main() { database.Open(); shared.X() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport (\n\t\"edges/dom\"\n\t\"edges/shared\"\n)\n\nfunc main() { dom.Render(); shared.X() }\n",
+		"database/db.go":     "package database\n\nfunc Open() {}\n",
+		"dom/dom.go":         "package dom\n\nfunc Render() {}\n",
+		"shared/shared.go":   "package shared\n\nfunc X() {}\n",
+	})
+	finder := New(root)
+
+	edges, err := finder.EdgesFromFile("pwa/main.server.go")
+	if err != nil {
+		t.Fatalf("EdgesFromFile: %v", err)
+	}
+	want := []FileEdge{
+		{From: "edges/pwa", To: "edges/database", Exclusive: true},
+		{From: "edges/pwa", To: "edges/shared", AlsoIn: []string{"main.wasm.go"}},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("EdgesFromFile(server) = %+v, want %+v", edges, want)
+	}
+
+	// Files excluded by build constraints on this host are still analyzed
+	edges, err = finder.EdgesFromFile("pwa/main.wasm.go")
+	if err != nil || len(edges) != 2 || edges[0].To != "edges/dom" || !edges[0].Exclusive {
+		t.Errorf("EdgesFromFile(wasm) = %+v, %v", edges, err)
+	}
+
+	if _, err := finder.EdgesFromFile("missing/file.go"); err == nil {
+		t.Error("expected an error for a file outside every package")
+	}
+}
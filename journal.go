@@ -0,0 +1,141 @@
+package depfind
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// JournalEntry is one routed file event persisted to the journal
+type JournalEntry struct {
+	Time    time.Time `json:"time"`
+	Handler string    `json:"handler,omitempty"`
+	File    string    `json:"file"`
+	Event   string    `json:"event"`
+}
+
+// SetJournal enables an append-only journal at path: every file event that
+// updates the cache is appended as one JSON line, so a restarted daemon can
+// catch up with Replay instead of rescanning. An empty path disables and
+// closes the journal.
+func (g *GoDepFind) SetJournal(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed && path != "" {
+		return ErrClosed
+	}
+
+	if g.journal != nil {
+		if err := g.journal.Close(); err != nil {
+			return fmt.Errorf("cannot close journal: %w", err)
+		}
+		g.journal = nil
+	}
+	g.lastJournalKey = ""
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open journal: %w", err)
+	}
+	g.journal = file
+	return nil
+}
+
+// ErrCacheNotBuilt is returned by Replay when there is no cache to apply the
+// journal onto
+var ErrCacheNotBuilt = errors.New("depfind: cache is not built, load a snapshot before replaying")
+
+// Replay applies the events recorded in a journal to the cache, in order.
+// The journal only makes sense on top of the cache it followed: restore it
+// first with LoadCache (or LoadCacheFrom), Replay never rebuilds the cache
+// and returns ErrCacheNotBuilt when there is none. A truncated last line
+// (crash mid-write) is ignored. Events that fail are skipped and reported
+// together in the returned error; the count holds the events applied
+// successfully.
+func (g *GoDepFind) Replay(journalPath string) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return 0, ErrClosed
+	}
+	if !g.cachedModule {
+		return 0, ErrCacheNotBuilt
+	}
+
+	file, err := os.Open(journalPath)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	var parseErr error
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if parseErr != nil {
+			// A corrupt line followed by more data is not a truncated tail
+			return 0, parseErr
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			parseErr = fmt.Errorf("journal line %d: %w", line, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("cannot read journal: %w", err)
+	}
+
+	applied := 0
+	var errs []error
+	for _, entry := range entries {
+		// A file removed then recreated is on disk again: removing it now
+		// would drop its package, which the later create does not restore
+		event := entry.Event
+		if event == EventRemove {
+			if info, err := os.Stat(entry.File); err == nil && !info.IsDir() {
+				event = EventCreate
+			}
+		}
+		if err := g.updateCacheForFileWithContext(entry.File, event, entry.Handler); err != nil {
+			errs = append(errs, fmt.Errorf("replay %s %s: %w", entry.Event, entry.File, err))
+			continue
+		}
+		applied++
+	}
+	return applied, errors.Join(errs...)
+}
+
+// recordEvent appends a cache-mutating event to the journal, if enabled.
+// Consecutive duplicates (the same event routed to several handlers) are
+// written once.
+func (g *GoDepFind) recordEvent(handler, fileAbsPath, event string) {
+	if g.journal == nil {
+		return
+	}
+	if !RequiresCacheUpdate(event) {
+		return // queries do not change the cache
+	}
+
+	key := event + "\x00" + fileAbsPath
+	if key == g.lastJournalKey {
+		return
+	}
+	g.lastJournalKey = key
+
+	data, err := json.Marshal(JournalEntry{Time: time.Now(), Handler: handler, File: fileAbsPath, Event: event})
+	if err != nil {
+		return
+	}
+	// Journal failures must never break routing; the cache stays correct in memory
+	g.journal.Write(append(data, '\n'))
+}
@@ -0,0 +1,175 @@
+package depfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplay(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module testmod\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"lib2/lib2.go": "package lib2\n\nfunc Do() {}\n",
+	})
+	journalPath := filepath.Join(t.TempDir(), "events.jsonl")
+
+	// Daemon that will restart: cache built before the change
+	restarted := New(root)
+	mains, err := restarted.GoFileComesFromMain("lib2.go")
+	if err != nil || len(mains) != 0 {
+		t.Fatalf("expected lib2 unused initially, got %v, %v", mains, err)
+	}
+
+	// Running daemon routes the change and journals it
+	running := New(root)
+	if err := running.SetJournal(journalPath); err != nil {
+		t.Fatalf("SetJournal: %v", err)
+	}
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"testmod/lib2\"\n\nfunc Do() { lib2.Do() }\n"), 0644); err != nil {
+		t.Fatalf("rewrite lib.go: %v", err)
+	}
+	for _, handler := range []string{"app/main.go", "app/main.go"} {
+		if _, err := running.ThisFileIsMine(handler, libFile, "write"); err != nil {
+			t.Fatalf("ThisFileIsMine: %v", err)
+		}
+	}
+	if err := running.SetJournal(""); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+
+	// Simulate a crash in the middle of writing the next entry
+	f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:00:00Z","fi`)
+	f.Close()
+
+	applied, err := restarted.Replay(journalPath)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("expected duplicate events to be journaled once, applied %d", applied)
+	}
+
+	mains, err = restarted.GoFileComesFromMain("lib2.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "testmod/app" {
+		t.Errorf("expected replay to add lib -> lib2 edge, got %v", mains)
+	}
+}
+
+func TestReplayOntoLoadedCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module testmod\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"lib2/lib2.go": "package lib2\n\nfunc Do() {}\n",
+	})
+	dir := t.TempDir()
+	journalPath := filepath.Join(dir, "events.jsonl")
+	cachePath := filepath.Join(dir, "graph.json")
+
+	running := New(root)
+	if err := running.SetJournal(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := running.SaveCache(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"testmod/lib2\"\n\nfunc Do() { lib2.Do() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := running.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	running.Close()
+
+	// Without a cache there is nothing to replay onto
+	restarted := New(root)
+	if _, err := restarted.Replay(journalPath); !errors.Is(err, ErrCacheNotBuilt) {
+		t.Fatalf("expected ErrCacheNotBuilt, got %v", err)
+	}
+	if restarted.Metrics().FullRebuilds != 0 {
+		t.Errorf("expected Replay not to rebuild the cache, got %+v", restarted.Metrics())
+	}
+
+	if _, err := restarted.LoadCache(cachePath); err != nil {
+		t.Fatalf("LoadCache: %v", err)
+	}
+	if applied, err := restarted.Replay(journalPath); err != nil || applied != 1 {
+		t.Fatalf("expected one event replayed, got %d, %v", applied, err)
+	}
+	mains, err := restarted.GoFileComesFromMain("lib2.go")
+	if err != nil || len(mains) != 1 || mains[0] != "testmod/app" {
+		t.Errorf("expected the lib -> lib2 edge, got %v, %v", mains, err)
+	}
+	if restarted.Metrics().FullRebuilds != 0 {
+		t.Errorf("expected the loaded cache to be replayed without a rebuild, got %+v", restarted.Metrics())
+	}
+}
+
+func TestReplayRemoveThenCreate(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module testmod\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"lib/h.go":     "package lib\n\nimport \"testmod/util\"\n\nfunc H() { util.U() }\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+	})
+	journalPath := filepath.Join(t.TempDir(), "events.jsonl")
+	hFile := filepath.Join(root, "lib", "h.go")
+	utilFile := filepath.Join(root, "util", "util.go")
+
+	live := New(root)
+	if err := live.SetJournal(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(hFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(hFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.ThisFileIsMine("app/main.go", hFile, EventRemove); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hFile, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.ThisFileIsMine("app/main.go", hFile, EventCreate); err != nil {
+		t.Fatal(err)
+	}
+	liveOwns, err := live.ThisFileIsMine("app/main.go", utilFile, EventWrite)
+	if err != nil || !liveOwns {
+		t.Fatalf("expected the live finder to own util.go, got %v, %v", liveOwns, err)
+	}
+	live.Close()
+
+	// The removed file is back on disk when the journal is replayed
+	restarted := New(root)
+	if _, err := restarted.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := restarted.Replay(journalPath); err != nil || applied != 3 {
+		t.Fatalf("expected three events replayed, got %d, %v", applied, err)
+	}
+	if isMine, err := restarted.ThisFileIsMine("app/main.go", utilFile, EventWrite); err != nil || isMine != liveOwns {
+		t.Errorf("expected replay to route util.go like the live finder (%v), got %v, %v", liveOwns, isMine, err)
+	}
+	if restarted.packageCache["testmod/lib"] == nil {
+		t.Error("expected lib to stay in the cache after replay")
+	}
+}
@@ -0,0 +1,79 @@
+package depfind
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrClosed is returned by queries made after Close
+var ErrClosed = errors.New("depfind: finder is closed")
+
+// Close releases the resources held by the finder: subsystems registered with
+// onClose are stopped in reverse order of registration (goroutines, flushes),
+// the journal is closed and the cache is dropped with everything derived from
+// it. Queries and mutators made afterwards return ErrClosed; methods without
+// an error result never bring the cache back. Close is idempotent; all
+// cleanup errors are joined.
+func (g *GoDepFind) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+
+	var errs []error
+	for i := len(g.closers) - 1; i >= 0; i-- {
+		if err := g.closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	g.closers = nil
+
+	if g.journal != nil {
+		if err := g.journal.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close journal: %w", err))
+		}
+		g.journal = nil
+	}
+
+	// Every piece of state derived from the graph goes with it; mutators
+	// called afterwards return ErrClosed or do nothing
+	g.cachedModule = false
+	g.packageCache = nil
+	g.dependencyGraph = nil
+	g.reverseDeps = nil
+	g.filePathToPackage = nil
+	g.testOnlyFiles = nil
+	g.removedFiles = nil
+	g.fileToPackages = nil
+	g.mainPackages = nil
+	g.virtualEdges = nil
+	g.renamedPackages = nil
+	g.moduleFinders = nil
+	g.lazyPending = nil
+	g.lazyClosures = nil
+	g.closures = nil
+	g.tagGraphs = nil
+	g.seenContent = nil
+	g.indexedContent = nil
+	g.fileStamps = nil
+	g.recentEvents = nil
+	g.loadErrors = nil
+	g.syntaxChecks = nil
+
+	g.listMu.Lock()
+	g.packageDirs = nil
+	g.skipped = nil
+	g.excludedDirs = nil
+	g.nestedModules = nil
+	g.listMu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// onClose registers cleanup for a subsystem started by the finder (mu held)
+func (g *GoDepFind) onClose(closer func() error) {
+	g.closers = append(g.closers, closer)
+}
@@ -0,0 +1,133 @@
+package depfind
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	finder := New("testproject")
+	if _, err := finder.ThisFileIsMine("appAserver/main.go", "appAserver/main.go", EventWrite); err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if err := finder.SetJournal(filepath.Join(t.TempDir(), "events.jsonl")); err != nil {
+		t.Fatalf("SetJournal: %v", err)
+	}
+
+	var order []string
+	finder.onClose(func() error { order = append(order, "first"); return nil })
+	finder.onClose(func() error { order = append(order, "second"); return errors.New("flush failed") })
+
+	err := finder.Close()
+	if err == nil || err.Error() != "flush failed" {
+		t.Fatalf("expected the subsystem error to be returned, got %v", err)
+	}
+	if len(order) != 2 || order[0] != "second" || order[1] != "first" {
+		t.Errorf("expected closers to run in reverse order, got %v", order)
+	}
+	if finder.journal != nil {
+		t.Error("expected the journal to be closed")
+	}
+
+	if err := finder.Close(); err != nil {
+		t.Errorf("expected a second Close to be a no-op, got %v", err)
+	}
+	if _, err := finder.ThisFileIsMine("appAserver/main.go", "appBcmd/main.go", EventWrite); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from ThisFileIsMine, got %v", err)
+	}
+	if _, err := finder.FindReverseDeps("./...", []string{"fmt"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from FindReverseDeps, got %v", err)
+	}
+	if err := finder.SetJournal(filepath.Join(t.TempDir(), "again.jsonl")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from SetJournal, got %v", err)
+	}
+}
+
+func TestMutatorsAfterClose(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module closed\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"closed/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	libFile := filepath.Join(root, "lib", "lib.go")
+	dir := t.TempDir()
+
+	finder := New(root)
+	finder.AddVirtualEdge("closed/app", "closed/lib", "plugin")
+	if _, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.SaveCache(filepath.Join(dir, "graph.json")); err != nil {
+		t.Fatal(err)
+	}
+	finder.Close()
+
+	// Mutators returning an error report ErrClosed
+	erring := map[string]func() error{
+		"AddReplaceRoots": func() error { _, err := finder.AddReplaceRoots(); return err },
+		"AddVirtualHandler": func() error {
+			return finder.AddVirtualHandler("assets", func(string) bool { return true })
+		},
+		"AddVirtualHandlerGlobs": func() error { return finder.AddVirtualHandlerGlobs("css", "*.css") },
+		"ApplyEvents":            func() error { return finder.ApplyEvents([]FileEvent{{Path: libFile, Event: EventWrite}}) },
+		"Decide":                 func() error { _, err := finder.Decide("app/main.go", libFile, EventWrite); return err },
+		"ForceOwnership":         func() error { return finder.ForceOwnership("lib/...", "app/main.go") },
+		"InitModule":             func() error { return finder.InitModule("closed/other") },
+		"InvalidateDir":          func() error { return finder.InvalidateDir("lib") },
+		"LoadCache":              func() error { _, err := finder.LoadCache(filepath.Join(dir, "graph.json")); return err },
+		"LoadOverrides":          func() error { return finder.LoadOverrides(filepath.Join(dir, "overrides.json")) },
+		"Rebuild":                func() error { return finder.Rebuild() },
+		"Reconcile":              func() error { _, err := finder.Reconcile(); return err },
+		"RefreshHandler":         func() error { _, err := finder.RefreshHandler("app/main.go"); return err },
+		"RenameDir":              func() error { _, err := finder.RenameDir("lib", "pkg"); return err },
+		"RenameFile":             func() error { return finder.RenameFile("lib/lib.go", "lib/do.go") },
+		"Replay":                 func() error { _, err := finder.Replay(filepath.Join(dir, "events.jsonl")); return err },
+		"SetJournal":             func() error { return finder.SetJournal(filepath.Join(dir, "events.jsonl")) },
+		"ThisFileIsMine":         func() error { _, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); return err },
+		"WarmCache":              func() error { return finder.WarmCache(context.Background(), nil) },
+	}
+	for name, call := range erring {
+		if err := call(); !errors.Is(err, ErrClosed) {
+			t.Errorf("%s: expected ErrClosed, got %v", name, err)
+		}
+	}
+
+	// The others neither panic nor bring state back
+	finder.AddRoot(dir)
+	finder.AddVirtualEdge("closed/lib", "closed/app", "")
+	finder.RemoveVirtualEdge("closed/app", "closed/lib")
+	finder.RemoveVirtualHandler("assets")
+	finder.RemoveOwnershipOverride("lib/...", "app/main.go")
+	finder.AllowExternalDirs(dir)
+	finder.OnPackageRenamed(func(PackageRenamed) {})
+	finder.SetAllowUnchecked(true)
+	finder.SetCacheFile(filepath.Join(dir, "listing.json"))
+	finder.SetCacheStrategy(StrategyLazy)
+	finder.SetEventWindow(time.Second)
+	finder.SetFSErrorPolicy(FailClosed)
+	finder.SetFilenameFallback(FallbackDisabled)
+	finder.SetHandlerClaimsTests("app/main.go", true)
+	finder.SetHandlerOwnsSubtree("app/main.go", true)
+	finder.SetHandlerTags("app/main.go", "dev")
+	finder.SetLazyThreshold(1)
+	finder.SetMaxDepth(3)
+	finder.SetModulePath("closed")
+	finder.SetRebuildInterval(time.Second)
+	finder.SetRespectGitignore(true)
+	finder.SetScannerFallback(true)
+	finder.SetScope([]string{"./lib/..."})
+	finder.SetSkipToolDirs(false)
+	finder.SetSkipUnchangedWrites(true)
+	finder.SetTestImports(true)
+	finder.SetWatchdog(time.Millisecond, nil)
+	if finder.cachedModule || finder.dependencyGraph != nil || finder.removedFiles != nil || finder.virtualEdges != nil {
+		t.Error("expected the graph state to stay dropped")
+	}
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		t.Errorf("expected go.mod untouched, got %v", err)
+	}
+}
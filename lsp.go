@@ -0,0 +1,327 @@
+package depfind
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// LSP custom methods served by LSPBridge. The same names with "." instead
+// of "/" (depfind.owners) are workspace/executeCommand commands.
+const (
+	LSPMethodOwners  = "depfind/owners"  // handlers compiling a document, see WhichMainsUseFile
+	LSPMethodImpact  = "depfind/impact"  // handlers to rebuild for changed documents, see RebuildPlan
+	LSPMethodExplain = "depfind/explain" // why a handler owns a document or not, see ExplainOwnership
+)
+
+// JSON-RPC error codes returned in LSPError
+const (
+	LSPParseError     = -32700
+	LSPInvalidParams  = -32602
+	LSPMethodNotFound = -32601
+	LSPRequestFailed  = -32803 // LSP RequestFailed: the query itself failed
+)
+
+// LSPError is a JSON-RPC error, to be returned as the "error" member of the
+// response
+type LSPError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *LSPError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// LSPTextDocument is an LSP TextDocumentIdentifier
+type LSPTextDocument struct {
+	URI string `json:"uri"`
+}
+
+// LSPOwnersParams are the params of depfind/owners
+type LSPOwnersParams struct {
+	TextDocument LSPTextDocument `json:"textDocument"`
+}
+
+// LSPOwnersResult is the result of depfind/owners
+type LSPOwnersResult struct {
+	Mains []string `json:"mains"` // handler main files, relative to the primary root, sorted
+}
+
+// LSPImpactParams are the params of depfind/impact
+type LSPImpactParams struct {
+	URIs []string `json:"uris"`
+}
+
+// LSPExplainParams are the params of depfind/explain
+type LSPExplainParams struct {
+	TextDocument LSPTextDocument `json:"textDocument"`
+	Main         string          `json:"main"` // handler main file, relative to the primary root
+}
+
+// LSPBridge serves depfind queries as LSP custom requests, so an editor
+// extension reuses the channel of an existing language server (a gopls
+// middleware, a client-side proxy) instead of a protocol of its own. It owns
+// no transport: the code reading the JSON-RPC stream forwards the requests
+// Handles accepts to Handle, and workspace/executeCommand requests for
+// Commands to ExecuteCommand, then writes back the result or the *LSPError.
+type LSPBridge struct {
+	finder *GoDepFind
+}
+
+// NewLSPBridge returns a bridge answering from finder
+func NewLSPBridge(finder *GoDepFind) *LSPBridge {
+	return &LSPBridge{finder: finder}
+}
+
+// Handles reports whether method is one of the custom methods served
+func (b *LSPBridge) Handles(method string) bool {
+	switch method {
+	case LSPMethodOwners, LSPMethodImpact, LSPMethodExplain:
+		return true
+	}
+	return false
+}
+
+// Commands returns the workspace/executeCommand commands served, to list in
+// the executeCommandProvider capability
+func (b *LSPBridge) Commands() []string {
+	return []string{lspCommand(LSPMethodExplain), lspCommand(LSPMethodImpact), lspCommand(LSPMethodOwners)}
+}
+
+// Handle answers a custom request. The result marshals to the JSON of the
+// response; errors are *LSPError.
+func (b *LSPBridge) Handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case LSPMethodOwners:
+		var p LSPOwnersParams
+		if err := decodeLSPParams(params, &p); err != nil {
+			return nil, err
+		}
+		path, err := lspPath(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		mains, err := b.finder.WhichMainsUseFile(path)
+		if err != nil {
+			return nil, &LSPError{Code: LSPRequestFailed, Message: err.Error()}
+		}
+		if mains == nil {
+			mains = []string{}
+		}
+		return &LSPOwnersResult{Mains: mains}, nil
+
+	case LSPMethodImpact:
+		var p LSPImpactParams
+		if err := decodeLSPParams(params, &p); err != nil {
+			return nil, err
+		}
+		paths := make([]string, 0, len(p.URIs))
+		for _, uri := range p.URIs {
+			path, err := lspPath(uri)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+		plan, err := b.finder.RebuildPlan(paths)
+		if err != nil {
+			return nil, &LSPError{Code: LSPRequestFailed, Message: err.Error()}
+		}
+		return plan, nil
+
+	case LSPMethodExplain:
+		var p LSPExplainParams
+		if err := decodeLSPParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Main == "" {
+			return nil, &LSPError{Code: LSPInvalidParams, Message: "missing main"}
+		}
+		path, err := lspPath(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := b.finder.ExplainOwnership(p.Main, path)
+		if err != nil {
+			return nil, &LSPError{Code: LSPRequestFailed, Message: err.Error()}
+		}
+		return exp, nil
+	}
+	return nil, &LSPError{Code: LSPMethodNotFound, Message: "unknown method " + method}
+}
+
+// ExecuteCommand answers the params of a workspace/executeCommand request
+// for one of Commands; the first argument holds the params of the method
+func (b *LSPBridge) ExecuteCommand(params json.RawMessage) (any, error) {
+	var p struct {
+		Command   string            `json:"command"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	if err := decodeLSPParams(params, &p); err != nil {
+		return nil, err
+	}
+	method := strings.Replace(p.Command, ".", "/", 1)
+	if !strings.HasPrefix(p.Command, "depfind.") || !b.Handles(method) {
+		return nil, &LSPError{Code: LSPInvalidParams, Message: "unknown command " + p.Command}
+	}
+	var args json.RawMessage
+	if len(p.Arguments) > 0 {
+		args = p.Arguments[0]
+	}
+	return b.Handle(method, args)
+}
+
+// lspMessage is a JSON-RPC request, notification or response
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // absent for notifications
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *LSPError       `json:"error,omitempty"`
+}
+
+// Serve answers JSON-RPC requests read from r on w, framed with
+// Content-Length headers as the LSP base protocol, until the exit
+// notification or the end of r. A tool written in any language can then
+// spawn a small Go program serving os.Stdin and os.Stdout and talk to it like
+// to a language server: initialize (which lists Commands), the custom
+// methods, workspace/executeCommand, shutdown and exit. Requests are
+// answered in order; notifications other than exit are ignored.
+func (b *LSPBridge) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readLSPFrame(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request lspMessage
+		if err := json.Unmarshal(body, &request); err != nil {
+			if err := writeLSPFrame(w, lspMessage{ID: json.RawMessage("null"), Error: &LSPError{Code: LSPParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if request.Method == "exit" {
+			return nil
+		}
+		if len(request.ID) == 0 {
+			continue // notification
+		}
+
+		response := lspMessage{ID: request.ID}
+		var result any
+		switch {
+		case request.Method == "initialize":
+			result = map[string]any{"capabilities": map[string]any{
+				"executeCommandProvider": map[string]any{"commands": b.Commands()},
+			}}
+		case request.Method == "shutdown":
+		case request.Method == "workspace/executeCommand":
+			result, err = b.ExecuteCommand(request.Params)
+		default:
+			result, err = b.Handle(request.Method, request.Params)
+		}
+		if err != nil {
+			var lspErr *LSPError
+			if !errors.As(err, &lspErr) {
+				lspErr = &LSPError{Code: LSPRequestFailed, Message: err.Error()}
+			}
+			response.Error = lspErr
+		} else if result == nil {
+			response.Result = json.RawMessage("null")
+		} else {
+			response.Result = result
+		}
+		if err := writeLSPFrame(w, response); err != nil {
+			return err
+		}
+	}
+}
+
+// readLSPFrame reads the headers of a message, then its body
+func readLSPFrame(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && line == "" && length == -1 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("cannot read message header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length == -1 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("cannot read message body: %w", err)
+	}
+	return body, nil
+}
+
+// writeLSPFrame writes a response with its Content-Length header
+func writeLSPFrame(w io.Writer, message lspMessage) error {
+	message.JSONRPC = "2.0"
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// lspCommand returns the executeCommand name of a custom method
+func lspCommand(method string) string {
+	return strings.Replace(method, "/", ".", 1)
+}
+
+// decodeLSPParams unmarshals request params, reporting bad JSON as invalid params
+func decodeLSPParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return &LSPError{Code: LSPInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &LSPError{Code: LSPInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// lspPath converts a file:// document URI to an absolute path
+func lspPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", &LSPError{Code: LSPInvalidParams, Message: "not a file URI: " + uri}
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/") // file:///C:/src -> C:/src
+	}
+	return filepath.FromSlash(path), nil
+}
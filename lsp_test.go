@@ -0,0 +1,141 @@
+package depfind
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLSPBridge(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":          "module lsp\n\ngo 1.21\n",
+		"app/main.go":     "package main\n\nimport \"lsp/lib\"\n\nfunc main() { lib.Do() }\n",
+		"tool/main.go":    "package main\n\nfunc main() {}\n",
+		"lib/lib.go":      "package lib\n\nfunc Do() {}\n",
+		"lib/lib_test.go": "package lib\n",
+	})
+	bridge := NewLSPBridge(New(root))
+	libURI := "file://" + filepath.ToSlash(filepath.Join(root, "lib/lib.go"))
+
+	call := func(method, params string) any {
+		t.Helper()
+		result, err := bridge.Handle(method, json.RawMessage(params))
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		return result
+	}
+
+	owners := call(LSPMethodOwners, `{"textDocument":{"uri":"`+libURI+`"}}`).(*LSPOwnersResult)
+	if want := []string{"app/main.go"}; !reflect.DeepEqual(owners.Mains, want) {
+		t.Errorf("owners = %v, want %v", owners.Mains, want)
+	}
+
+	plan := call(LSPMethodImpact, `{"uris":["`+libURI+`"]}`).(*Plan)
+	if want := []string{"app/main.go"}; !reflect.DeepEqual(plan.Rebuilds(), want) {
+		t.Errorf("impact = %v, want %v", plan.Rebuilds(), want)
+	}
+
+	exp := call(LSPMethodExplain, `{"textDocument":{"uri":"`+libURI+`"},"main":"tool/main.go"}`).(*Explanation)
+	if exp.Owned || exp.Package != "lsp/lib" {
+		t.Errorf("explain = %+v", exp)
+	}
+
+	// The same queries as workspace/executeCommand commands
+	result, err := bridge.ExecuteCommand(json.RawMessage(`{"command":"depfind.owners","arguments":[{"textDocument":{"uri":"` + libURI + `"}}]}`))
+	if err != nil || !reflect.DeepEqual(result.(*LSPOwnersResult).Mains, []string{"app/main.go"}) {
+		t.Errorf("executeCommand owners: %v, %v", result, err)
+	}
+	if want := []string{"depfind.explain", "depfind.impact", "depfind.owners"}; !reflect.DeepEqual(bridge.Commands(), want) {
+		t.Errorf("Commands = %v, want %v", bridge.Commands(), want)
+	}
+
+	// Errors carry JSON-RPC codes
+	for _, tt := range []struct {
+		method, params string
+		code           int
+	}{
+		{"depfind/unknown", `{}`, LSPMethodNotFound},
+		{LSPMethodOwners, `{"textDocument":{"uri":"https://example.com/x.go"}}`, LSPInvalidParams},
+		{LSPMethodOwners, `not json`, LSPInvalidParams},
+		{LSPMethodExplain, `{"textDocument":{"uri":"` + libURI + `"}}`, LSPInvalidParams},
+	} {
+		_, err := bridge.Handle(tt.method, json.RawMessage(tt.params))
+		var lspErr *LSPError
+		if !errors.As(err, &lspErr) || lspErr.Code != tt.code {
+			t.Errorf("%s %s: got %v, want code %d", tt.method, tt.params, err, tt.code)
+		}
+	}
+	if bridge.Handles("textDocument/hover") || !bridge.Handles(LSPMethodImpact) {
+		t.Error("Handles should accept the depfind methods only")
+	}
+}
+
+func TestLSPBridgeServe(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module lsps\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"lsps/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	libURI := "file://" + filepath.ToSlash(filepath.Join(root, "lib/lib.go"))
+
+	var in bytes.Buffer
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"depfind/owners","params":{"textDocument":{"uri":"` + libURI + `"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{}}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":"last","method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	var out bytes.Buffer
+	if err := NewLSPBridge(New(root)).Serve(&in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var responses []map[string]any
+	reader := bufio.NewReader(&out)
+	for {
+		body, err := readLSPFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var response map[string]any
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+	if len(responses) != 5 {
+		t.Fatalf("expected 5 responses (none for notifications nor after exit), got %d: %v", len(responses), responses)
+	}
+	commands := responses[0]["result"].(map[string]any)["capabilities"].(map[string]any)["executeCommandProvider"].(map[string]any)["commands"]
+	if !reflect.DeepEqual(commands, []any{"depfind.explain", "depfind.impact", "depfind.owners"}) {
+		t.Errorf("initialize commands = %v", commands)
+	}
+	if mains := responses[1]["result"].(map[string]any)["mains"]; !reflect.DeepEqual(mains, []any{"app/main.go"}) {
+		t.Errorf("owners = %v", mains)
+	}
+	if code := responses[2]["error"].(map[string]any)["code"]; code != float64(LSPMethodNotFound) {
+		t.Errorf("unknown method code = %v", code)
+	}
+	if code := responses[3]["error"].(map[string]any)["code"]; code != float64(LSPParseError) || responses[3]["id"] != nil {
+		t.Errorf("parse error response = %v", responses[3])
+	}
+	if last := responses[4]; last["id"] != "last" || last["result"] != nil || last["error"] != nil {
+		t.Errorf("shutdown response = %v", last)
+	}
+}
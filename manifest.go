@@ -0,0 +1,121 @@
+package depfind
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFormatVersion is the version of the manifests WriteManifest emits
+const ManifestFormatVersion = 1
+
+// Manifest lists the files a main compiles with their content hashes, see
+// WriteManifest
+type Manifest struct {
+	Version  int            `json:"version"`
+	Main     string         `json:"main"`     // main package or handler main file, as given
+	Checksum string         `json:"checksum"` // sha256 of the "path sha256\n" lines of Files, hex
+	Files    []ManifestFile `json:"files"`    // sorted by path
+}
+
+// ManifestFile is a file of a manifest
+type ManifestFile struct {
+	Path    string `json:"path"`    // relative to the primary root in slash form, absolute outside the roots
+	Package string `json:"package"` // import path
+	SHA256  string `json:"sha256"`  // of the content, hex
+}
+
+// WriteManifest writes to path the manifest of the files main compiles,
+// each with the sha256 of its content, stamped with a checksum of the whole
+// list. A build script can then detect changes by hashing the listed files
+// and comparing, without running depfind; regenerate the manifest in CI.
+// main is a main package ("example.com/app/cmd/server"), whose packages are
+// followed for the host, or a handler main file ("pwa/main.wasm.go"),
+// followed under its own build context as in WhichMainsUseFile so a wasm
+// main lists the files of its target only. Test files are left out. The
+// output is deterministic and written atomically.
+func (g *GoDepFind) WriteManifest(main, path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+	files, err := g.manifestFiles(main)
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{Version: ManifestFormatVersion, Main: main, Files: []ManifestFile{}}
+	sum := sha256.New()
+	for absPath, pkgPath := range files {
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("cannot hash %s: %w", absPath, err)
+		}
+		fileSum := sha256.Sum256(data)
+		entry := ManifestFile{Path: absPath, Package: pkgPath, SHA256: hex.EncodeToString(fileSum[:])}
+		if len(g.rootDirs) > 0 {
+			if rel, err := filepath.Rel(g.rootDirs[0], absPath); err == nil && isUnder(absPath, g.rootDirs[0]) {
+				entry.Path = filepath.ToSlash(rel)
+			}
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	for _, file := range manifest.Files {
+		fmt.Fprintf(sum, "%s %s\n", file.Path, file.SHA256)
+	}
+	manifest.Checksum = hex.EncodeToString(sum.Sum(nil))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	return nil
+}
+
+// manifestFiles returns the source files main compiles (absolute path ->
+// package), for a main package or a handler main file
+func (g *GoDepFind) manifestFiles(main string) (map[string]string, error) {
+	files := make(map[string]string)
+	if filepath.Ext(main) == ".go" {
+		tags, closure, err := g.handlerFileClosure(main)
+		if err != nil {
+			return nil, err
+		}
+		for pkgPath := range closure {
+			pkg := g.packageCache[pkgPath]
+			for _, name := range packageFileNames(pkg) {
+				absPath := filepath.Join(pkg.Dir, name)
+				if !sourceExts[filepath.Ext(name)] || strings.HasSuffix(name, "_test.go") {
+					continue
+				}
+				if matched, err := tags.MatchFile(absPath); err == nil && matched {
+					files[absPath] = pkgPath
+				}
+			}
+		}
+		return files, nil
+	}
+
+	if !g.isMainPackage(main) {
+		return nil, fmt.Errorf("%s is not a cached main package", main)
+	}
+	for pkgPath := range reachIn(g.dependencyGraph, []string{main}, 0) {
+		if pkg := g.packageCache[pkgPath]; pkg != nil {
+			for _, name := range buildFiles(pkg) {
+				files[filepath.Join(pkg.Dir, name)] = pkgPath
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,99 @@
+package depfind
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module mf\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"mf/db\"\n\nfunc main() { db.Open() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"mf/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":        "package main\n\nimport \"mf/util\"\n\nfunc main() { util.Do() }\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "package db\n\nimport \"mf/jsglue\"\n\nfunc open() { jsglue.Call() }\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nfunc open() {}\n",
+		"db/db_test.go":      "package db\n",
+		"jsglue/glue.go":     "package jsglue\n\nfunc Call() {}\n",
+		"util/util.go":       "package util\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	out := filepath.Join(t.TempDir(), "manifests", "wasm.json")
+
+	read := func() Manifest {
+		t.Helper()
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatal(err)
+		}
+		return manifest
+	}
+	paths := func(manifest Manifest) []string {
+		var paths []string
+		for _, file := range manifest.Files {
+			paths = append(paths, file.Path)
+		}
+		return paths
+	}
+
+	// A handler main file lists the files of its own target
+	if err := finder.WriteManifest("pwa/main.wasm.go", out); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	manifest := read()
+	if want := []string{"db/db.go", "db/db_js.go", "jsglue/glue.go", "pwa/main.wasm.go"}; !reflect.DeepEqual(paths(manifest), want) {
+		t.Errorf("files = %v, want %v", paths(manifest), want)
+	}
+	sum := sha256.New()
+	for _, file := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileSum := sha256.Sum256(data); hex.EncodeToString(fileSum[:]) != file.SHA256 {
+			t.Errorf("%s: wrong hash", file.Path)
+		}
+		fmt.Fprintf(sum, "%s %s\n", file.Path, file.SHA256)
+	}
+	if manifest.Checksum != hex.EncodeToString(sum.Sum(nil)) || manifest.Version != ManifestFormatVersion {
+		t.Errorf("unexpected stamp: %+v", manifest)
+	}
+
+	// A change shows in the checksum
+	if err := os.WriteFile(filepath.Join(root, "jsglue", "glue.go"), []byte("package jsglue\n\nfunc Call() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.WriteManifest("pwa/main.wasm.go", out); err != nil {
+		t.Fatal(err)
+	}
+	if read().Checksum == manifest.Checksum {
+		t.Error("expected the checksum to change with a file")
+	}
+
+	// A main package is followed for the host
+	if err := finder.WriteManifest("mf/cli", out); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	manifest = read()
+	if want := []string{"cli/main.go", "util/util.go"}; !reflect.DeepEqual(paths(manifest), want) {
+		t.Errorf("files = %v, want %v", paths(manifest), want)
+	}
+	if manifest.Files[1].Package != "mf/util" {
+		t.Errorf("package = %q, want mf/util", manifest.Files[1].Package)
+	}
+
+	if err := finder.WriteManifest("mf/util", out); err == nil {
+		t.Error("expected an error for a package that is not a main")
+	}
+}
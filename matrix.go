@@ -0,0 +1,117 @@
+package depfind
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Platform is a GOOS/GOARCH build target
+type Platform struct {
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+}
+
+// ParsePlatform parses "GOOS/GOARCH" ("linux/amd64", "js/wasm")
+func ParsePlatform(s string) (Platform, error) {
+	goos, goarch, ok := strings.Cut(s, "/")
+	if !ok || !knownOS[goos] || !knownArch[goarch] {
+		return Platform{}, fmt.Errorf("invalid platform %q, want GOOS/GOARCH", s)
+	}
+	return Platform{GOOS: goos, GOARCH: goarch}, nil
+}
+
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// tags returns the tags files are evaluated with for the platform: the
+// host's for the host platform, otherwise GOOS, GOARCH and the compiler (a
+// cross build has cgo disabled)
+func (p Platform) tags() TagSet {
+	if p.GOOS == build.Default.GOOS && p.GOARCH == build.Default.GOARCH {
+		return hostTagSet()
+	}
+	return NewTagSet(p.GOOS, p.GOARCH, build.Default.Compiler)
+}
+
+// PlatformDeps is the analysis of a package for one platform, see DependencyMatrix
+type PlatformDeps struct {
+	Platform Platform `json:"platform"`
+	Deps     []string `json:"deps"`  // forward dependencies, as FindForwardDeps
+	Mains    []string `json:"mains"` // main packages built for the platform that compile the package
+}
+
+// DependencyMatrix analyzes a package under several platforms in one call
+// ("linux/amd64", "js/wasm"), in the order given: its forward dependencies
+// and the main packages compiling it, both following the imports of the
+// files each platform builds (see WithBuildTags). A main package counts for
+// a platform when one of its non-test files builds for it. Options filter
+// Deps as for FindForwardDeps; WithBuildTags is overridden per platform.
+func (g *GoDepFind) DependencyMatrix(pkgPath string, platforms []string, opts ...Option) ([]PlatformDeps, error) {
+	parsed := make([]Platform, 0, len(platforms))
+	for _, s := range platforms {
+		p, err := ParsePlatform(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.packageCache[pkgPath]; !ok {
+		return nil, fmt.Errorf("package not found in cache: %s", pkgPath)
+	}
+
+	mains := append([]string(nil), g.mainPackages...)
+	sort.Strings(mains)
+	results := make([]PlatformDeps, 0, len(parsed))
+	for _, p := range parsed {
+		options := queryOptions{maxDepth: g.maxDepth}
+		for _, opt := range opts {
+			opt(&options)
+		}
+		options.tags = p.tags()
+
+		result := PlatformDeps{Platform: p, Deps: g.forwardDeps(pkgPath, options), Mains: []string{}}
+		if result.Deps == nil {
+			result.Deps = []string{}
+		}
+		edges := g.graphFor(options.tags).deps
+		for _, main := range mains {
+			if g.buildsFor(main, options.tags) && reachIn(edges, []string{main}, 0)[pkgPath] {
+				result.Mains = append(result.Mains, main)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// buildsFor reports whether a cached package has a non-test Go file the
+// tags include
+func (g *GoDepFind) buildsFor(pkgPath string, tags TagSet) bool {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return false
+	}
+	for _, list := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.IgnoredGoFiles} {
+		for _, name := range list {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			file := filepath.Join(pkg.Dir, name)
+			if matched, err := tags.MatchFile(file); err == nil && matched && packageClause(file) == pkg.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
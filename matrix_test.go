@@ -0,0 +1,50 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyMatrix(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module mx\n\ngo 1.21\n",
+		"app/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"mx/db\"\n\nfunc main() { db.Open() }\n",
+		"web/main.go":        "//go:build wasm\n\npackage main\n\nimport \"mx/db\"\n\nfunc main() { db.Open() }\n",
+		"web/host.go":        "//go:build !wasm\n\npackage main\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "package db\n\nimport \"mx/dom\"\n\nfunc open() { dom.Render() }\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nimport \"mx/sql\"\n\nfunc open() { sql.Connect() }\n",
+		"dom/dom.go":         "package dom\n\nfunc Render() {}\n",
+		"sql/sql.go":         "package sql\n\nimport \"fmt\"\n\nfunc Connect() { fmt.Println() }\n",
+	})
+	finder := New(root)
+
+	matrix, err := finder.DependencyMatrix("mx/db", []string{"linux/amd64", "js/wasm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlatformDeps{
+		{Platform: Platform{"linux", "amd64"}, Deps: []string{"fmt", "mx/sql"}, Mains: []string{"mx/app"}},
+		{Platform: Platform{"js", "wasm"}, Deps: []string{"mx/dom"}, Mains: []string{"mx/web"}},
+	}
+	if !reflect.DeepEqual(matrix, want) {
+		t.Errorf("DependencyMatrix(db) =\n%+v\nwant\n%+v", matrix, want)
+	}
+
+	// Options filter the dependencies of every platform
+	matrix, err = finder.DependencyMatrix("mx/sql", []string{"linux/amd64", "js/wasm"}, OnlyModuleLocal(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []PlatformDeps{
+		{Platform: Platform{"linux", "amd64"}, Deps: []string{}, Mains: []string{"mx/app"}},
+		{Platform: Platform{"js", "wasm"}, Deps: []string{}, Mains: []string{}},
+	}
+	if !reflect.DeepEqual(matrix, want) {
+		t.Errorf("DependencyMatrix(sql) =\n%+v\nwant\n%+v", matrix, want)
+	}
+
+	if _, err := finder.DependencyMatrix("mx/db", []string{"linux"}); err == nil {
+		t.Error("expected an error for a platform without GOARCH")
+	}
+}
@@ -0,0 +1,237 @@
+package depfind
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrNoModule is returned when the primary root is not inside a Go module and
+// no synthesized module path has been configured with SetModulePath.
+var ErrNoModule = errors.New("no go.mod found")
+
+// SetModulePath configures a synthesized module identity for roots that have
+// no go.mod (scratch projects, student exercises). When set and the primary
+// root is not inside a module, packages are discovered by walking the tree
+// instead of running "go list", import paths are derived as
+// modulePath + "/" + relative directory, and local relative imports
+// (e.g. "./lib") are mapped onto those paths.
+func (g *GoDepFind) SetModulePath(modulePath string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.modulePath = strings.TrimSuffix(modulePath, "/")
+	g.cachedModule = false
+}
+
+// InitModule writes a minimal go.mod declaring modulePath into the primary
+// root directory, for trees that should become real modules. It fails if a
+// go.mod already exists there. The cache is reset so the next query uses it.
+func (g *GoDepFind) InitModule(modulePath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	if modulePath == "" {
+		return fmt.Errorf("module path cannot be empty")
+	}
+	if len(g.rootDirs) == 0 {
+		return fmt.Errorf("no root directory configured")
+	}
+
+	goModPath := filepath.Join(g.rootDirs[0], "go.mod")
+	if _, err := os.Stat(goModPath); err == nil {
+		return fmt.Errorf("go.mod already exists: %s", goModPath)
+	}
+
+	content := fmt.Sprintf("module %s\n\ngo %s\n", modulePath, goDirectiveVersion())
+	if err := os.WriteFile(goModPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("cannot write go.mod: %w", err)
+	}
+
+	g.cachedModule = false
+	return nil
+}
+
+// goDirectiveVersion returns the running toolchain version in go.mod form
+func goDirectiveVersion() string {
+	version := runtime.Version()
+	if !strings.HasPrefix(version, "go1.") {
+		return "1.21" // devel or unknown toolchain
+	}
+	version = strings.TrimPrefix(version, "go")
+	if idx := strings.IndexAny(version, " -"); idx != -1 {
+		version = version[:idx]
+	}
+	return version
+}
+
+// findModuleRoot returns the directory containing the go.mod that governs dir,
+// or "" when dir is not inside a module
+func findModuleRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// moduleDir maps an import path of a root's module to its directory by
+// trimming the module path declared in go.mod (or set with SetModulePath),
+// e.g. "github.com/org/app/lib" in a root checked out as app-checkout
+func (g *GoDepFind) moduleDir(importPath string) (string, bool) {
+	for _, root := range g.rootDirs {
+		modRoot := findModuleRoot(root)
+		modulePath := ""
+		if modRoot != "" {
+			modulePath = readModulePath(filepath.Join(modRoot, "go.mod"))
+		}
+		if modulePath == "" && g.modulePath != "" {
+			modRoot, modulePath = root, g.modulePath
+		}
+		if modulePath == "" {
+			continue
+		}
+		rest, ok := strings.CutPrefix(importPath, modulePath)
+		if !ok || (rest != "" && rest[0] != '/') {
+			continue
+		}
+		dir := filepath.Join(modRoot, filepath.FromSlash(strings.TrimPrefix(rest, "/")))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// usesSyntheticModule reports whether packages must be discovered without go list
+func (g *GoDepFind) usesSyntheticModule() bool {
+	if g.modulePath == "" || len(g.rootDirs) == 0 {
+		return false
+	}
+	return findModuleRoot(g.rootDirs[0]) == ""
+}
+
+// checkModule returns ErrNoModule when a relative pattern cannot be listed
+// because the primary root is neither inside a module nor synthesized.
+func (g *GoDepFind) checkModule(pattern string) error {
+	if !strings.HasPrefix(pattern, ".") || len(g.rootDirs) == 0 {
+		return nil
+	}
+	if findModuleRoot(g.rootDirs[0]) != "" {
+		return nil
+	}
+	return fmt.Errorf("%w in %s (run InitModule or configure SetModulePath)", ErrNoModule, g.rootDirs[0])
+}
+
+// listSyntheticPackages walks the roots and returns import paths for every
+// directory containing Go files, relative to the governing go.mod or the
+// synthesized module path. Nested modules are not entered. Non-relative
+// patterns (stdlib or already qualified paths) are returned as is.
+func (g *GoDepFind) listSyntheticPackages(pattern string) ([]string, error) {
+	if !strings.HasPrefix(pattern, ".") {
+		return []string{pattern}, nil
+	}
+
+	recursive := strings.HasSuffix(pattern, "/...")
+	prefix := filepath.Clean(strings.TrimSuffix(pattern, "/..."))
+
+	var packages []string
+	for _, root := range g.rootDirs {
+		start := filepath.Join(root, prefix)
+		err := filepath.WalkDir(start, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil // unreadable entries are skipped, not fatal
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if path != start && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "testdata" || name == "vendor" || g.inToolDir(path)) {
+				return filepath.SkipDir
+			}
+			if !recursive && path != start {
+				return filepath.SkipDir
+			}
+			if path != start {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			pkg, err := build.ImportDir(path, 0)
+			if err != nil || pkg.Name == "" {
+				return nil
+			}
+			importPath := g.scannedImportPath(root, path)
+			g.setPackageDir(importPath, path)
+			packages = append(packages, importPath)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no Go packages found for %s", pattern)
+	}
+	return packages, nil
+}
+
+// syntheticImportPath derives the import path of dir under the synthesized module
+func (g *GoDepFind) syntheticImportPath(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return g.modulePath
+	}
+	return g.modulePath + "/" + filepath.ToSlash(rel)
+}
+
+// resolveImport maps a local relative import (e.g. "./lib") written in a file
+// located in fromDir onto the synthesized module path, and an import of a
+// package renamed in place onto its new path. Other imports are returned
+// unchanged.
+func (g *GoDepFind) resolveImport(imp, fromDir string) string {
+	if newPath, ok := g.renamedPackages[imp]; ok {
+		return newPath
+	}
+	if g.modulePath == "" || !build.IsLocalImport(imp) {
+		return imp
+	}
+	target := filepath.Join(fromDir, imp)
+	for _, root := range g.rootDirs {
+		if rel, err := filepath.Rel(root, target); err == nil && !strings.HasPrefix(rel, "..") {
+			return g.syntheticImportPath(root, target)
+		}
+	}
+	return imp
+}
+
+// resolveLocalImports rewrites relative and renamed imports of pkg onto
+// current module paths
+func (g *GoDepFind) resolveLocalImports(pkg *build.Package) {
+	if pkg == nil || (g.modulePath == "" && len(g.renamedPackages) == 0) {
+		return
+	}
+	for i, imp := range pkg.Imports {
+		pkg.Imports[i] = g.resolveImport(imp, pkg.Dir)
+	}
+	for i, imp := range pkg.TestImports {
+		pkg.TestImports[i] = g.resolveImport(imp, pkg.Dir)
+	}
+	for i, imp := range pkg.XTestImports {
+		pkg.XTestImports[i] = g.resolveImport(imp, pkg.Dir)
+	}
+}
@@ -0,0 +1,140 @@
+package depfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoModuleReturnsClearError(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	if findModuleRoot(root) != "" {
+		t.Skip("temporary directory is inside a module")
+	}
+
+	finder := New(root)
+	_, err := finder.FindReverseDeps("./...", []string{"fmt"})
+	if !errors.Is(err, ErrNoModule) {
+		t.Fatalf("expected ErrNoModule, got %v", err)
+	}
+}
+
+func TestSyntheticModuleMapsRelativeImports(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":       "package main\n\nimport \"./lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":    "package lib\n\nfunc Do() {}\n",
+		"other/main.go": "package main\n\nfunc main() {}\n",
+	})
+	if findModuleRoot(root) != "" {
+		t.Skip("temporary directory is inside a module")
+	}
+
+	finder := New(root)
+	finder.SetModulePath("scratch")
+
+	libFile := filepath.Join(root, "lib", "lib.go")
+	isMine, err := finder.ThisFileIsMine("main.go", libFile, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if !isMine {
+		t.Error("expected root main to own lib/lib.go through relative import")
+	}
+
+	isMine, err = finder.ThisFileIsMine("other/main.go", libFile, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if isMine {
+		t.Error("expected other/main.go NOT to own lib/lib.go")
+	}
+
+	mains, err := finder.GoFileComesFromMain("lib.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "scratch" {
+		t.Errorf("expected [scratch], got %v", mains)
+	}
+}
+
+func TestInitModule(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	finder := New(root)
+	if err := finder.InitModule("example.com/scratch"); err != nil {
+		t.Fatalf("InitModule: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatalf("read go.mod: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "module example.com/scratch\n") {
+		t.Errorf("unexpected go.mod content: %q", content)
+	}
+
+	if err := finder.InitModule("example.com/scratch"); err == nil {
+		t.Error("expected error when go.mod already exists")
+	}
+}
+
+func TestModulePathDiffersFromDirectoryName(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "app-checkout")
+	files := map[string]string{
+		"go.mod":           "module github.com/org/app\n\ngo 1.21\n",
+		"cmd/main.go":      "package main\n\nimport \"github.com/org/app/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":       "package lib\n\nfunc Do() {}\n",
+		"org/app/lib/x.go": "package decoy\n", // where splitting the import path would look
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	finder := New(root)
+
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if isMine, err := finder.ThisFileIsMine("cmd/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	info, err := finder.PackageInfo("github.com/org/app/lib")
+	if err != nil || info == nil || info.Dir != filepath.Join(root, "lib") {
+		t.Fatalf("unexpected package info: %+v, %v", info, err)
+	}
+
+	cases := map[string]string{
+		"github.com/org/app":         root,
+		"github.com/org/app/lib":     filepath.Join(root, "lib"),
+		"github.com/org/app/cmd":     filepath.Join(root, "cmd"),
+		"github.com/org/application": "",
+		"org/app/lib":                "",
+	}
+	for importPath, want := range cases {
+		dir, ok := finder.moduleDir(importPath)
+		if dir != want || ok != (want != "") {
+			t.Errorf("moduleDir(%s) = %q, %v, want %q", importPath, dir, ok, want)
+		}
+	}
+
+	// Without the directories recorded by go list, packages still resolve
+	// through the module path instead of splitting the import path
+	finder.packageDirs = nil
+	packages, err := finder.getPackages([]string{"github.com/org/app/lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg := packages["github.com/org/app/lib"]; pkg == nil || pkg.Name != "lib" || pkg.Dir != filepath.Join(root, "lib") {
+		t.Errorf("unexpected package: %+v", pkg)
+	}
+}
@@ -0,0 +1,98 @@
+package depfind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PackageError is the failure to load one package during a rebuild
+type PackageError struct {
+	Package string
+	Dir     string // empty when the package could not be located
+	Err     error
+}
+
+func (e *PackageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Package, e.Err)
+}
+
+func (e *PackageError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError lists the packages a rebuild could not load. The rest of the
+// cache was still built and committed, so queries keep working for every
+// other package.
+type MultiError struct {
+	Errors []*PackageError
+}
+
+func (e *MultiError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, pe := range e.Errors {
+		lines = append(lines, pe.Error())
+	}
+	return fmt.Sprintf("%d packages failed to load:\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// Unwrap exposes each package failure to errors.Is and errors.As
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, pe := range e.Errors {
+		errs[i] = pe
+	}
+	return errs
+}
+
+// Rebuild rebuilds the whole cache now. When some packages cannot be loaded
+// the others are still committed and a *MultiError lists the failures.
+func (g *GoDepFind) Rebuild() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	err := g.rebuildCache()
+	g.cachedModule = true
+	return err
+}
+
+// addPackageError records a package failure in a (possibly nil) MultiError
+func addPackageError(multi *MultiError, pkgPath, dir string, err error) *MultiError {
+	if multi == nil {
+		multi = &MultiError{}
+	}
+	multi.Errors = append(multi.Errors, &PackageError{Package: pkgPath, Dir: dir, Err: err})
+	return multi
+}
+
+// skippedError returns a *MultiError listing the packages skipped by the
+// last listing, or nil. Packages dropped with an excluded vendored tree are
+// intentional and not reported.
+func (g *GoDepFind) skippedError() error {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	var failed *MultiError
+	for _, s := range g.skipped {
+		if g.excludingTree(s.Dir) != "" {
+			continue
+		}
+		failed = addPackageError(failed, s.Path, s.Dir, errors.New(s.Reason))
+	}
+	if failed == nil {
+		return nil
+	}
+	sort.Slice(failed.Errors, func(i, j int) bool { return failed.Errors[i].Package < failed.Errors[j].Package })
+	return failed
+}
+
+// isPartialFailure reports whether err only lists packages that failed to
+// load, the others being usable
+func isPartialFailure(err error) bool {
+	var multi *MultiError
+	return errors.As(err, &multi)
+}
@@ -0,0 +1,47 @@
+package depfind
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRebuildMultiError(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module partial\n\ngo 1.21\n",
+		"app/main.go":    "package main\n\nimport \"partial/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":     "package lib\n\nfunc Do() {}\n",
+		"broken/a.go":    "package a\n",
+		"broken/b.go":    "package b\n",
+		"garbled/bad.go": "this is not go\n",
+	})
+	finder := New(root)
+
+	err := finder.Rebuild()
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected a *MultiError, got %v", err)
+	}
+	var failed []string
+	for _, pe := range multi.Errors {
+		failed = append(failed, pe.Package)
+		if pe.Err == nil || pe.Dir == "" {
+			t.Errorf("incomplete package error: %+v", pe)
+		}
+	}
+	if len(failed) != 2 || failed[0] != "partial/broken" || failed[1] != "partial/garbled" {
+		t.Errorf("failed packages = %v, want [partial/broken partial/garbled]", failed)
+	}
+	var pe *PackageError
+	if !errors.As(err, &pe) {
+		t.Error("expected package errors to unwrap")
+	}
+
+	// The rest of the cache was committed
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected working packages to stay routable, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "app/main.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected a main write to tolerate the partial rebuild, got %v, %v", isMine, err)
+	}
+}
@@ -0,0 +1,90 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMultiRootUnifiedGraph(t *testing.T) {
+	workspace := writeTree(t, map[string]string{
+		"app/go.mod":           "module app\n\ngo 1.21\n\nrequire example.com/lib v0.0.0\n\nreplace example.com/lib => ../lib\n",
+		"app/cmd/main.go":      "package main\n\nimport \"example.com/lib/util\"\n\nfunc main() { util.Do() }\n",
+		"lib/go.mod":           "module example.com/lib\n\ngo 1.21\n",
+		"lib/util/util.go":     "package util\n\nimport \"example.com/lib/inner\"\n\nfunc Do() { inner.Do() }\n",
+		"lib/inner/inner.go":   "package inner\n\nfunc Do() {}\n",
+		"lib/unused/unused.go": "package unused\n",
+	})
+	appRoot := filepath.Join(workspace, "app")
+	libRoot := filepath.Join(workspace, "lib")
+	finder := New(appRoot, libRoot)
+
+	tests := []struct {
+		file     string
+		expected bool
+	}{
+		{"util/util.go", true},
+		{"inner/inner.go", true},
+		{"unused/unused.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			isMine, err := finder.ThisFileIsMine("cmd/main.go", filepath.Join(libRoot, tt.file), "write")
+			if err != nil {
+				t.Fatalf("ThisFileIsMine: %v", err)
+			}
+			if isMine != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, isMine)
+			}
+		})
+	}
+
+	mains, err := finder.GoFileComesFromMain("inner.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "app/cmd" {
+		t.Errorf("expected [app/cmd], got %v", mains)
+	}
+}
+
+func TestAddReplaceRoots(t *testing.T) {
+	workspace := writeTree(t, map[string]string{
+		"app/go.mod": "module app\n\ngo 1.21\n\nrequire example.com/lib v0.0.0\n\n" +
+			"replace example.com/lib => ../lib // local checkout\n\n" +
+			"replace (\n\texample.com/remote => example.com/fork v1.2.3\n\t\"example.com/nomod\" => ./nomod\n)\n",
+		"app/cmd/main.go":   "package main\n\nimport \"example.com/lib/util\"\n\nfunc main() { util.Do() }\n",
+		"app/other/main.go": "package main\n\nfunc main() {}\n",
+		"app/nomod/x.go":    "package nomod\n",
+		"lib/go.mod":        "module example.com/lib\n\ngo 1.21\n",
+		"lib/util/util.go":  "package util\n\nfunc Do() {}\n",
+	})
+	appRoot := filepath.Join(workspace, "app")
+	libFile := filepath.Join(workspace, "lib", "util", "util.go")
+	finder := New(appRoot)
+
+	// Outside every root the library is assumed to belong to everyone
+	if isMine, err := finder.ThisFileIsMine("other/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected unregistered library files to be claimed, got %v, %v", isMine, err)
+	}
+
+	added, err := finder.AddReplaceRoots()
+	if err != nil {
+		t.Fatalf("AddReplaceRoots: %v", err)
+	}
+	want := []LocalReplace{{Path: "example.com/lib", Dir: filepath.Join(workspace, "lib")}}
+	if !reflect.DeepEqual(added, want) {
+		t.Fatalf("AddReplaceRoots = %+v, want %+v", added, want)
+	}
+
+	if isMine, err := finder.ThisFileIsMine("cmd/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected cmd/main.go to own the library file, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("other/main.go", libFile, EventWrite); err != nil || isMine {
+		t.Errorf("expected other/main.go NOT to own the library file once routed, got %v, %v", isMine, err)
+	}
+
+	if added, err := finder.AddReplaceRoots(); err != nil || len(added) != 0 {
+		t.Errorf("expected a second call to add nothing, got %v, %v", added, err)
+	}
+}
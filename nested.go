@@ -0,0 +1,124 @@
+package depfind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NestedModule is a module with its own go.mod inside a root (an example/
+// or testdata/ module). "go list ./..." does not descend into it, so its
+// files are kept out of the main graph instead of being guessed by name.
+type NestedModule struct {
+	Path string `json:"path"` // module path declared in its go.mod
+	Dir  string `json:"dir"`  // absolute directory holding the go.mod
+}
+
+// NestedModules returns the modules nested inside the roots, sorted by
+// directory. They are detected on every full rebuild.
+func (g *GoDepFind) NestedModules() ([]NestedModule, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	return append([]NestedModule(nil), g.nestedModules...), nil
+}
+
+// ModuleFinder returns a finder with its own graph for a nested module,
+// identified by its module path or by its directory relative to the primary
+// root (e.g. "example"). Finders are created once, inherit the
+// configuration of g but for the settings keyed by paths relative to its
+// primary root (per-handler settings, overrides, virtual handlers and scope
+// patterns), and are closed with it.
+func (g *GoDepFind) ModuleFinder(name string) (*GoDepFind, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	module, ok := g.lookupNestedModule(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown nested module: %s", name)
+	}
+	if finder, ok := g.moduleFinders[module.Dir]; ok {
+		return finder, nil
+	}
+
+	finder := New(module.Dir)
+	g.inheritConfig(finder, false)
+	if g.moduleFinders == nil {
+		g.moduleFinders = make(map[string]*GoDepFind)
+	}
+	g.moduleFinders[module.Dir] = finder
+	g.onClose(finder.Close)
+	return finder, nil
+}
+
+// lookupNestedModule finds a nested module by module path or relative directory
+func (g *GoDepFind) lookupNestedModule(name string) (NestedModule, bool) {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	dir := g.rootPath(name)
+	for _, module := range g.nestedModules {
+		if module.Path == name || module.Dir == dir {
+			return module, true
+		}
+	}
+	return NestedModule{}, false
+}
+
+// detectNestedModules records every directory below a root holding its own
+// go.mod. Hidden and "_" prefixed directories, node_modules and vendor are
+// not searched; modules nested in a nested module belong to it, and
+// directories registered as roots are not nested modules.
+func (g *GoDepFind) detectNestedModules() {
+	var modules []NestedModule
+	for _, root := range g.rootDirs {
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() || path == root {
+				return nil // unreadable entries are skipped, not fatal
+			}
+			if contains(g.rootDirs, path) {
+				return filepath.SkipDir // registered roots are part of the graph
+			}
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "node_modules" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			goMod := filepath.Join(path, "go.mod")
+			if _, err := os.Stat(goMod); err != nil {
+				return nil
+			}
+			modules = append(modules, NestedModule{Path: readModulePath(goMod), Dir: path})
+			return filepath.SkipDir
+		})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Dir < modules[j].Dir })
+
+	g.listMu.Lock()
+	g.nestedModules = modules
+	g.listMu.Unlock()
+}
+
+// inNestedModule reports whether a file lies in a nested module
+func (g *GoDepFind) inNestedModule(fileAbsPath string) bool {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	for _, module := range g.nestedModules {
+		if isUnder(fileAbsPath, module.Dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNestedModules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                      "module host\n\ngo 1.21\n",
+		"app/main.go":                 "package main\n\nimport \"host/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":                  "package lib\n\nfunc Do() {}\n",
+		"example/go.mod":              "module host/example\n\ngo 1.21\n",
+		"example/main.go":             "package main\n\nimport \"host/example/lib\"\n\nfunc main() { lib.Do() }\n",
+		"example/lib/lib.go":          "package lib\n\nfunc Do() {}\n",
+		"testdata/fixture/go.mod":     "module fixture\n\ngo 1.21\n",
+		"testdata/fixture/fixture.go": "package fixture\n",
+	})
+	finder := New(root)
+
+	modules, err := finder.NestedModules()
+	if err != nil {
+		t.Fatalf("NestedModules: %v", err)
+	}
+	want := []NestedModule{
+		{Path: "host/example", Dir: filepath.Join(root, "example")},
+		{Path: "fixture", Dir: filepath.Join(root, "testdata", "fixture")},
+	}
+	if !reflect.DeepEqual(modules, want) {
+		t.Errorf("NestedModules() = %+v, want %+v", modules, want)
+	}
+
+	// Files with the same name in a nested module are not claimed by the main graph
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "example/lib/lib.go"), EventWrite); err != nil || isMine {
+		t.Errorf("expected nested module file not to be claimed, got %v, %v", isMine, err)
+	}
+	if exp, err := finder.ExplainOwnership("app/main.go", "example/lib/lib.go"); err != nil || exp.Reason != ReasonNestedModule {
+		t.Errorf("expected nested-module explanation, got %+v, %v", exp, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected main module file to be claimed, got %v, %v", isMine, err)
+	}
+
+	// Nested modules have their own graph, by module path or directory
+	example, err := finder.ModuleFinder("host/example")
+	if err != nil {
+		t.Fatalf("ModuleFinder: %v", err)
+	}
+	if again, _ := finder.ModuleFinder("example"); again != example {
+		t.Error("expected the same finder by directory name")
+	}
+	if isMine, err := example.ThisFileIsMine("main.go", filepath.Join(root, "example/lib/lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected nested finder to claim its file, got %v, %v", isMine, err)
+	}
+	if _, err := finder.ModuleFinder("missing"); err == nil {
+		t.Error("expected an error for an unknown module")
+	}
+
+	finder.Close()
+	if _, err := example.ThisFileIsMine("main.go", filepath.Join(root, "example/lib/lib.go"), EventWrite); err == nil {
+		t.Error("expected nested finder to be closed with its parent")
+	}
+}
@@ -0,0 +1,142 @@
+package depfind
+
+import (
+	"crypto/sha256"
+	"os"
+)
+
+// SkipReason tells why an event was not routed to a handler
+type SkipReason string
+
+// SkipNoChange: the write left the file content identical to what the
+// handler last processed (e.g. format on save producing the same bytes)
+const SkipNoChange SkipReason = "no-change"
+
+// Decision is the routing outcome of an event for one handler
+type Decision struct {
+	Owned   bool       // the handler should process the event
+	Forced  bool       // an ownership override decided, see ForceOwnership
+	Skipped SkipReason // non-empty when the event was dropped before ownership was decided
+
+	// Package the file resolved to and whether it currently builds, filled
+	// for owned events so a handler can postpone rebuilding a package known
+	// to be broken, see BuildStatus
+	Package    string
+	Build      BuildStatus
+	BuildError string
+
+	// Recent is how many other events the handler was given within the
+	// event window, a hint to coalesce rebuilds, see SetEventWindow
+	Recent int
+}
+
+// SetSkipUnchangedWrites enables content hashing of written files. A write
+// whose content is identical to what a handler last processed is skipped
+// for that handler (ThisFileIsMine returns false, Decide reports
+// SkipNoChange), and the cache is only refreshed when the content differs
+// from what it indexed. Disabled by default.
+func (g *GoDepFind) SetSkipUnchangedWrites(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.skipUnchanged = enabled
+	if !enabled {
+		g.seenContent = nil
+		g.indexedContent = nil
+	}
+}
+
+// Decide is ThisFileIsMine reporting why an event was skipped, see Decision
+func (g *GoDepFind) Decide(mainInputFileRelativePath, fileAbsPath, event string) (Decision, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.decide(mainInputFileRelativePath, fileAbsPath, event, true)
+}
+
+// decide routes an event for one handler; validate is false only for
+// ThisFileIsMineUnchecked
+func (g *GoDepFind) decide(mainInputFileRelativePath, fileAbsPath, event string, validate bool) (Decision, error) {
+	if !g.skipUnchanged || fileAbsPath == "" || mainInputFileRelativePath == "" {
+		isMine, err := g.thisFileIsMineWith(mainInputFileRelativePath, fileAbsPath, event, validate)
+		return g.routedDecision(mainInputFileRelativePath, isMine, fileAbsPath), err
+	}
+
+	absPath := g.rootPath(fileAbsPath)
+	handler := handlerKey(mainInputFileRelativePath)
+	if IsMutationEvent(event) && event != EventWrite {
+		// Only writes are hashed: anything else (an atomic save renamed
+		// over the file, a create replacing it) may have changed the content
+		g.forgetContent(absPath)
+	}
+
+	sum, hashed := writtenContentHash(absPath, event)
+	if hashed {
+		if seen, ok := g.seenContent[absPath][handler]; ok && seen == sum {
+			return Decision{Skipped: SkipNoChange}, nil
+		}
+	}
+
+	isMine, err := g.thisFileIsMineWith(mainInputFileRelativePath, fileAbsPath, event, validate)
+	if err == nil && hashed {
+		if g.seenContent == nil {
+			g.seenContent = make(map[string]map[string][sha256.Size]byte)
+		}
+		if g.seenContent[absPath] == nil {
+			g.seenContent[absPath] = make(map[string][sha256.Size]byte)
+		}
+		g.seenContent[absPath][handler] = sum
+	}
+	return g.routedDecision(mainInputFileRelativePath, isMine, fileAbsPath), err
+}
+
+// routedDecision wraps the ownership answer for a routed event
+func (g *GoDepFind) routedDecision(mainInputFileRelativePath string, isMine bool, fileAbsPath string) Decision {
+	absPath := g.rootPath(fileAbsPath)
+	_, forced := g.forcedHandlers(absPath)
+	decision := Decision{Owned: isMine, Forced: forced}
+	if isMine {
+		decision.Package = g.changedFilePackage(absPath)
+		decision.Build, decision.BuildError = g.packageBuildStatus(decision.Package)
+		decision.Recent = g.noteHandlerEvent(mainInputFileRelativePath)
+	}
+	return decision
+}
+
+// indexedContentUnchanged reports whether a written file still has the
+// content the cache last indexed, recording the new content otherwise
+func (g *GoDepFind) indexedContentUnchanged(absPath string) bool {
+	if !g.skipUnchanged {
+		return false
+	}
+	sum, ok := writtenContentHash(absPath, EventWrite)
+	if !ok {
+		return false
+	}
+	if indexed, ok := g.indexedContent[absPath]; ok && indexed == sum {
+		return true
+	}
+	if g.indexedContent == nil {
+		g.indexedContent = make(map[string][sha256.Size]byte)
+	}
+	g.indexedContent[absPath] = sum
+	return false
+}
+
+// forgetContent drops the content memory of a file, so its next write
+// counts as a change for every handler and for the cache
+func (g *GoDepFind) forgetContent(absPath string) {
+	delete(g.seenContent, absPath)
+	delete(g.indexedContent, absPath)
+}
+
+// writtenContentHash hashes the content of a file on write events
+func writtenContentHash(absPath, event string) ([sha256.Size]byte, bool) {
+	if event != EventWrite {
+		return [sha256.Size]byte{}, false
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256(data), true
+}
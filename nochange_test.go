@@ -0,0 +1,107 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSkipUnchangedWrites(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module same\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"same/lib\"\n\nfunc main() { lib.Do() }\n",
+		"admin/main.go": "package main\n\nimport \"same/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":    "package lib\n\nfunc Do() {}\n",
+	})
+	libFile := filepath.Join(root, "lib/lib.go")
+	finder := New(root)
+	finder.SetSkipUnchangedWrites(true)
+
+	for _, handler := range []string{"app/main.go", "admin/main.go"} {
+		decision, err := finder.Decide(handler, libFile, EventWrite)
+		if err != nil || !decision.Owned || decision.Skipped != "" {
+			t.Fatalf("first write for %s: %+v, %v", handler, decision, err)
+		}
+	}
+
+	// Same bytes again: skipped for every handler that already saw them
+	for _, handler := range []string{"app/main.go", "admin/main.go"} {
+		decision, err := finder.Decide(handler, libFile, EventWrite)
+		if err != nil || decision.Owned || decision.Skipped != SkipNoChange {
+			t.Errorf("identical write for %s: %+v, %v", handler, decision, err)
+		}
+	}
+	if isMine, _ := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); isMine {
+		t.Error("expected ThisFileIsMine to drop an identical write")
+	}
+
+	// Changed content is routed again
+	if err := os.WriteFile(libFile, []byte("package lib\n\nfunc Do() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if decision, err := finder.Decide("app/main.go", libFile, EventWrite); err != nil || !decision.Owned {
+		t.Errorf("changed write: %+v, %v", decision, err)
+	}
+
+	// Disabled by default: identical writes are routed
+	plain := New(root)
+	for i := 0; i < 2; i++ {
+		if isMine, err := plain.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+			t.Errorf("write %d without hashing: %v, %v", i, isMine, err)
+		}
+	}
+}
+
+func TestSkipUnchangedWritesAfterOtherMutations(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module same\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"same/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+		"util/u.go":   "package util\n\nfunc U() {}\n",
+	})
+	libFile := filepath.Join(root, "lib/lib.go")
+	contentA := []byte("package lib\n\nfunc Do() {}\n")
+	contentB := []byte("package lib\n\nimport \"same/util\"\n\nfunc Do() { util.U() }\n")
+	finder := New(root)
+	finder.SetSkipUnchangedWrites(true)
+	write := func(content []byte) {
+		t.Helper()
+		if err := os.WriteFile(libFile, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	libImports := func() []string {
+		return finder.packageCache["same/lib"].Imports
+	}
+
+	if decision, err := finder.Decide("app/main.go", libFile, EventWrite); err != nil || !decision.Owned {
+		t.Fatalf("first write: %+v, %v", decision, err)
+	}
+
+	// A create (e.g. an atomic save) replaces the content; restoring the old
+	// bytes is a change again
+	write(contentB)
+	if decision, err := finder.Decide("app/main.go", libFile, EventCreate); err != nil || !decision.Owned {
+		t.Fatalf("create: %+v, %v", decision, err)
+	}
+	write(contentA)
+	if decision, err := finder.Decide("app/main.go", libFile, EventWrite); err != nil || !decision.Owned || decision.Skipped != "" {
+		t.Errorf("write restoring the content after a create: %+v, %v", decision, err)
+	}
+	if len(libImports()) != 0 {
+		t.Errorf("expected the cache to follow the restored content, got imports %v", libImports())
+	}
+
+	// Same through a batch applied without routing
+	write(contentB)
+	if err := finder.ApplyEvents([]FileEvent{{Path: libFile, Event: EventWrite}}); err != nil {
+		t.Fatal(err)
+	}
+	write(contentA)
+	if decision, err := finder.Decide("app/main.go", libFile, EventWrite); err != nil || !decision.Owned || decision.Skipped != "" {
+		t.Errorf("write restoring the content after a batch: %+v, %v", decision, err)
+	}
+	if len(libImports()) != 0 {
+		t.Errorf("expected the cache to follow the restored content, got imports %v", libImports())
+	}
+}
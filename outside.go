@@ -0,0 +1,83 @@
+package depfind
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NotInRootError reports a file outside every root that depfind refuses to
+// route: it lies in the Go toolchain or module cache, or outside the
+// directories allowed with AllowExternalDirs
+type NotInRootError struct {
+	Path string
+}
+
+func (e *NotInRootError) Error() string {
+	return fmt.Sprintf("file is outside the roots: %s", e.Path)
+}
+
+// AllowExternalDirs restricts which files outside the roots are routed. By
+// default such files are claimed by every handler, assuming a locally
+// replaced module (except GOROOT and the module cache, which are never
+// edited). Once directories are allowed, only files under them are claimed
+// and every other out-of-root file yields a *NotInRootError. Calling it with
+// no directories restores the default.
+func (g *GoDepFind) AllowExternalDirs(dirs ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.externalDirs = nil
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			g.externalDirs = append(g.externalDirs, abs)
+		}
+	}
+}
+
+// inRoots reports whether an absolute path lies inside one of the roots
+func (g *GoDepFind) inRoots(absPath string) bool {
+	_, ok := g.relToRoot(absPath)
+	return ok
+}
+
+// checkExternalFile returns a *NotInRootError when an absolute path outside
+// the roots must not be routed
+func (g *GoDepFind) checkExternalFile(absPath string) error {
+	if len(g.externalDirs) > 0 {
+		for _, dir := range g.externalDirs {
+			if isUnder(absPath, dir) {
+				return nil
+			}
+		}
+		return &NotInRootError{Path: absPath}
+	}
+	for _, dir := range toolchainDirs() {
+		if isUnder(absPath, dir) {
+			return &NotInRootError{Path: absPath}
+		}
+	}
+	return nil
+}
+
+// toolchainDirs returns GOROOT and the module cache, whose files are never
+// part of a project being edited
+func toolchainDirs() []string {
+	var dirs []string
+	if build.Default.GOROOT != "" {
+		dirs = append(dirs, filepath.Clean(build.Default.GOROOT))
+	}
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		dirs = append(dirs, filepath.Clean(modCache))
+	} else if gopath := filepath.SplitList(build.Default.GOPATH); len(gopath) > 0 && gopath[0] != "" {
+		dirs = append(dirs, filepath.Join(gopath[0], "pkg", "mod"))
+	}
+	return dirs
+}
+
+// isUnder reports whether path is dir or lies inside it
+func isUnder(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
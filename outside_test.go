@@ -0,0 +1,53 @@
+package depfind
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutsideRootFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module out\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	external := writeTree(t, map[string]string{"lib.go": "package lib\n"})
+	other := writeTree(t, map[string]string{"tmp.go": "package tmp\n"})
+	modCache := writeTree(t, map[string]string{"example.com/dep@v1.0.0/dep.go": "package dep\n"})
+	t.Setenv("GOMODCACHE", modCache)
+
+	finder := New(root)
+	var notInRoot *NotInRootError
+
+	// Default: external files are claimed, toolchain files are not
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(other, "tmp.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected external file to be claimed by default, got %v, %v", isMine, err)
+	}
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(modCache, "example.com/dep@v1.0.0/dep.go"), EventWrite)
+	if isMine || !errors.As(err, &notInRoot) {
+		t.Errorf("expected NotInRootError for a module cache file, got %v, %v", isMine, err)
+	}
+
+	// Allowlist: only allowed external directories are claimed
+	finder.AllowExternalDirs(external)
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(external, "lib.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected allowed external file to be claimed, got %v, %v", isMine, err)
+	}
+	isMine, err = finder.ThisFileIsMine("app/main.go", filepath.Join(other, "tmp.go"), EventWrite)
+	if isMine || !errors.As(err, &notInRoot) || notInRoot.Path != filepath.Join(other, "tmp.go") {
+		t.Errorf("expected NotInRootError outside the allowlist, got %v, %v", isMine, err)
+	}
+	if exp, err := finder.ExplainOwnership("app/main.go", filepath.Join(other, "tmp.go")); err != nil || exp.Reason != ReasonNotInRoot {
+		t.Errorf("expected not-in-root explanation, got %+v, %v", exp, err)
+	}
+
+	// Filename-based lookups never guess for outside files
+	if _, err := finder.FindReverseDepsForFile("app/main.go", "tmp.go", filepath.Join(other, "tmp.go")); !errors.As(err, &notInRoot) {
+		t.Errorf("expected NotInRootError from filename lookup, got %v", err)
+	}
+
+	finder.AllowExternalDirs()
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(other, "tmp.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected default restored, got %v, %v", isMine, err)
+	}
+}
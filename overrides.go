@@ -0,0 +1,163 @@
+package depfind
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OwnershipOverride pins the files matching Pattern to the handler whose main
+// file is Handler, for relationships dependency analysis cannot see
+// (reflection, plugins, runtime configuration)
+type OwnershipOverride struct {
+	Pattern string `json:"pattern"` // glob relative to the primary root; "dir/..." matches a subtree
+	Handler string `json:"handler"` // handler main file relative to the primary root
+}
+
+// ForceOwnership pins the files matching pathGlob to handlerMain. Overrides
+// are checked before graph-based resolution: a matching file is owned by the
+// handlers it is pinned to and by no other handler, whatever its imports.
+// pathGlob uses path.Match syntax relative to the primary root, and a
+// trailing "/..." matches a whole subtree (e.g. "plugins/...").
+func (g *GoDepFind) ForceOwnership(pathGlob, handlerMain string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	override, err := newOwnershipOverride(pathGlob, handlerMain)
+	if err != nil {
+		return err
+	}
+	for _, existing := range g.overrides {
+		if existing == override {
+			return nil
+		}
+	}
+	g.overrides = append(g.overrides, override)
+	return nil
+}
+
+// RemoveOwnershipOverride removes an override added with ForceOwnership
+func (g *GoDepFind) RemoveOwnershipOverride(pathGlob, handlerMain string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	override := OwnershipOverride{Pattern: normalizeOverridePattern(pathGlob), Handler: handlerKey(handlerMain)}
+	for i, existing := range g.overrides {
+		if existing == override {
+			g.overrides = append(g.overrides[:i], g.overrides[i+1:]...)
+			return
+		}
+	}
+}
+
+// OwnershipOverrides returns the overrides sorted by pattern then handler
+func (g *GoDepFind) OwnershipOverrides() []OwnershipOverride {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	overrides := append([]OwnershipOverride(nil), g.overrides...)
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Pattern != overrides[j].Pattern {
+			return overrides[i].Pattern < overrides[j].Pattern
+		}
+		return overrides[i].Handler < overrides[j].Handler
+	})
+	return overrides
+}
+
+// SaveOverrides writes the overrides as JSON to configPath, so they can be
+// committed next to the project and restored with LoadOverrides
+func (g *GoDepFind) SaveOverrides(configPath string) error {
+	data, err := json.MarshalIndent(g.OwnershipOverrides(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write overrides: %w", err)
+	}
+	return nil
+}
+
+// LoadOverrides replaces the overrides with the ones saved in configPath
+func (g *GoDepFind) LoadOverrides(configPath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("cannot read overrides: %w", err)
+	}
+	var saved []OwnershipOverride
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid overrides file %s: %w", configPath, err)
+	}
+
+	overrides := make([]OwnershipOverride, 0, len(saved))
+	for _, s := range saved {
+		override, err := newOwnershipOverride(s.Pattern, s.Handler)
+		if err != nil {
+			return fmt.Errorf("invalid overrides file %s: %w", configPath, err)
+		}
+		overrides = append(overrides, override)
+	}
+	g.overrides = overrides
+	return nil
+}
+
+// newOwnershipOverride validates and normalizes an override
+func newOwnershipOverride(pathGlob, handlerMain string) (OwnershipOverride, error) {
+	if pathGlob == "" || handlerMain == "" {
+		return OwnershipOverride{}, fmt.Errorf("override pattern and handler cannot be empty")
+	}
+	pattern := normalizeOverridePattern(pathGlob)
+	if _, err := path.Match(strings.TrimSuffix(pattern, "/..."), ""); err != nil {
+		return OwnershipOverride{}, fmt.Errorf("invalid override pattern %q: %w", pathGlob, err)
+	}
+	return OwnershipOverride{Pattern: pattern, Handler: handlerKey(handlerMain)}, nil
+}
+
+// normalizeOverridePattern converts a pattern to the slash form matched
+func normalizeOverridePattern(pathGlob string) string {
+	return strings.TrimPrefix(filepath.ToSlash(pathGlob), "./")
+}
+
+// forcedHandlers returns the handlers fileAbsPath is pinned to, sorted, and
+// whether any override matches it
+func (g *GoDepFind) forcedHandlers(fileAbsPath string) ([]string, bool) {
+	if len(g.overrides) == 0 || len(g.rootDirs) == 0 {
+		return nil, false
+	}
+	rel, err := filepath.Rel(g.rootDirs[0], fileAbsPath)
+	if err != nil {
+		return nil, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	var handlers []string
+	for _, override := range g.overrides {
+		if overrideMatches(override.Pattern, rel) && !contains(handlers, override.Handler) {
+			handlers = append(handlers, override.Handler)
+		}
+	}
+	sort.Strings(handlers)
+	return handlers, len(handlers) > 0
+}
+
+// overrideMatches reports whether the slash-separated relative path matches
+func overrideMatches(pattern, rel string) bool {
+	if tree, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return rel == tree || strings.HasPrefix(rel, tree+"/")
+	}
+	matched, _ := path.Match(pattern, rel)
+	return matched
+}
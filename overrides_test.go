@@ -0,0 +1,71 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestForceOwnership(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":               "module pin\n\ngo 1.21\n",
+		"app/main.go":          "package main\n\nimport \"pin/lib\"\n\nfunc main() { lib.Do() }\n",
+		"admin/main.go":        "package main\n\nfunc main() {}\n",
+		"lib/lib.go":           "package lib\n\nfunc Do() {}\n",
+		"plugins/auth/auth.go": "package auth\n",
+		"plugins/auth/load.go": "package auth\n",
+	})
+	finder := New(root)
+	authFile := filepath.Join(root, "plugins/auth/auth.go")
+
+	// Loaded at runtime: no handler imports the plugin
+	if isMine, _ := finder.ThisFileIsMine("admin/main.go", authFile, EventWrite); isMine {
+		t.Fatal("expected the plugin not to be owned before pinning")
+	}
+
+	if err := finder.ForceOwnership("plugins/...", "admin/main.go"); err != nil {
+		t.Fatalf("ForceOwnership: %v", err)
+	}
+	if err := finder.ForceOwnership("lib/*.go", "./admin/main.go"); err != nil {
+		t.Fatalf("ForceOwnership: %v", err)
+	}
+	if err := finder.ForceOwnership("lib/[", "admin/main.go"); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+
+	decision, err := finder.Decide("admin/main.go", authFile, EventWrite)
+	if err != nil || !decision.Owned || !decision.Forced {
+		t.Errorf("expected a forced claim, got %+v, %v", decision, err)
+	}
+
+	// Pinned files are owned by no other handler, whatever the imports say
+	if isMine, _ := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib.go"), EventWrite); isMine {
+		t.Error("expected a pinned file not to be owned by its importer")
+	}
+	exp, err := finder.ExplainOwnership("app/main.go", "lib/lib.go")
+	if err != nil || exp.Reason != ReasonForcedToOther || !reflect.DeepEqual(exp.OwnedBy, []string{"admin/main.go"}) {
+		t.Errorf("unexpected explanation: %+v, %v", exp, err)
+	}
+	if exp, _ := finder.ExplainOwnership("admin/main.go", "plugins/auth/load.go"); exp.Reason != ReasonForced || !exp.Owned {
+		t.Errorf("unexpected explanation: %+v", exp)
+	}
+
+	// Overrides round-trip through a config file
+	configPath := filepath.Join(t.TempDir(), "overrides.json")
+	if err := finder.SaveOverrides(configPath); err != nil {
+		t.Fatalf("SaveOverrides: %v", err)
+	}
+	restored := New(root)
+	if err := restored.LoadOverrides(configPath); err != nil {
+		t.Fatalf("LoadOverrides: %v", err)
+	}
+	want := []OwnershipOverride{{Pattern: "lib/*.go", Handler: "admin/main.go"}, {Pattern: "plugins/...", Handler: "admin/main.go"}}
+	if got := restored.OwnershipOverrides(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OwnershipOverrides() = %+v, want %+v", got, want)
+	}
+
+	finder.RemoveOwnershipOverride("lib/*.go", "admin/main.go")
+	if isMine, _ := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "lib/lib.go"), EventWrite); !isMine {
+		t.Error("expected graph-based ownership after removing the override")
+	}
+}
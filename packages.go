@@ -0,0 +1,214 @@
+package depfind
+
+import (
+	"fmt"
+	"go/build"
+	"go/doc"
+	"iter"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageInfo describes a package of the analyzed roots
+type PackageInfo struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+	Doc  string `json:"doc,omitempty"` // first sentence of the package doc comment
+	Main bool   `json:"main,omitempty"`
+
+	Variants []FileVariant `json:"variants,omitempty"` // files built only for some targets, host or not
+}
+
+// PackageInfo returns the description of a cached package, or nil when the
+// import path is not part of the analyzed roots
+func (g *GoDepFind) PackageInfo(importPath string) (*PackageInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	pkg := g.packageCache[importPath]
+	if pkg == nil {
+		return nil, nil
+	}
+	info := newPackageInfo(importPath, pkg)
+	return &info, nil
+}
+
+// PackageForFile returns the package a file belongs to, without applying
+// any ownership rule: its indexed package, the package of its build-tag
+// variant or, for a Go file created since the last rebuild, the package of
+// its directory. Unindexed names follow SetFilenameFallback. path is
+// absolute or relative to the primary root.
+func (g *GoDepFind) PackageForFile(path string) (PackageInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	absPath := g.rootPath(path)
+	pkgPath, err := g.findPackageForFile(absPath)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	if pkgPath == "" && filepath.Ext(absPath) == ".go" {
+		pkgPath = g.packageInDir(filepath.Dir(absPath))
+	}
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return PackageInfo{}, fmt.Errorf("file is not in any known package: %s", path)
+	}
+	return newPackageInfo(pkgPath, pkg), nil
+}
+
+// FilesForPackage returns the absolute paths of the files compiled into a
+// cached package (Go, assembly and .syso files), sorted: the forward
+// direction of the file index, to feed a watcher's include list. With
+// WithTestFiles its _test.go files are included. Gitignored files are left
+// out, like in the index.
+func (g *GoDepFind) FilesForPackage(pkgPath string, opts ...Option) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return nil, fmt.Errorf("package not found in cache: %s", pkgPath)
+	}
+	var options queryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	names := buildFiles(pkg)
+	if options.testFiles {
+		names = append(append(names, pkg.TestGoFiles...), pkg.XTestGoFiles...)
+	}
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		if path := filepath.Join(pkg.Dir, name); !g.gitIgnored(path) {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Packages returns the description of every cached package, sorted by path
+func (g *GoDepFind) Packages() ([]PackageInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	infos := make([]PackageInfo, 0, len(g.packageCache))
+	for importPath, pkg := range g.packageCache {
+		if pkg != nil {
+			infos = append(infos, newPackageInfo(importPath, pkg))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos, nil
+}
+
+// MainPackageInfo describes a main package of the analyzed roots, see
+// MainPackages
+type MainPackageInfo struct {
+	Path        string              `json:"path"`
+	Dir         string              `json:"dir"`
+	GoFiles     []string            `json:"go_files"`              // files built for the host, sorted
+	Constraints map[string]string   `json:"constraints,omitempty"` // GoFiles entry -> //go:build expression, constrained files only
+	MainFiles   []HandlerDefinition `json:"main_files"`            // files declaring func main, host or not, sorted
+}
+
+// MainPackages returns the cached main packages sorted by path, with their
+// files, build constraints and one HandlerDefinition per file declaring
+// func main (a wasm-only main.wasm.go next to main.server.go included), so
+// watchers can register handlers without walking the tree themselves.
+func (g *GoDepFind) MainPackages() ([]MainPackageInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	mains := make([]MainPackageInfo, 0, len(g.mainPackages))
+	for _, pkgPath := range g.mainPackages {
+		pkg := g.packageCache[pkgPath]
+		if pkg == nil {
+			continue
+		}
+		info := MainPackageInfo{Path: pkgPath, Dir: pkg.Dir, GoFiles: sortedUnique(pkg.GoFiles)}
+		for _, name := range info.GoFiles {
+			if expr := buildConstraint(filepath.Join(pkg.Dir, name)); expr != "" {
+				if info.Constraints == nil {
+					info.Constraints = make(map[string]string)
+				}
+				info.Constraints[name] = expr
+			}
+		}
+		for _, name := range sortedUnique(append(append([]string(nil), pkg.GoFiles...), pkg.IgnoredGoFiles...)) {
+			path := filepath.Join(pkg.Dir, name)
+			if filepath.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") && declaresMainFunc(path) {
+				info.MainFiles = append(info.MainFiles, g.handlerDefinition(path, pkgPath))
+			}
+		}
+		mains = append(mains, info)
+	}
+	sort.Slice(mains, func(i, j int) bool { return mains[i].Path < mains[j].Path })
+	return mains, nil
+}
+
+func newPackageInfo(importPath string, pkg *build.Package) PackageInfo {
+	return PackageInfo{
+		Path: importPath,
+		Name: pkg.Name,
+		Dir:  pkg.Dir,
+		Doc:  packageSynopsis(pkg),
+		Main: pkg.Name == "main",
+
+		Variants: packageVariants(pkg),
+	}
+}
+
+// packageSynopsis returns the first sentence of the package doc comment
+// extracted when the package was loaded, e.g. "Package database provides
+// connection pooling for the PWA server."
+func packageSynopsis(pkg *build.Package) string {
+	if pkg == nil || pkg.Doc == "" {
+		return ""
+	}
+	return new(doc.Package).Synopsis(pkg.Doc)
+}
+
+// IndexedFiles iterates over every file in the index as absolute path ->
+// owning package, sorted by path, so tools can reconcile their own watch list
+// against depfind's view. It iterates over a snapshot taken when called; the
+// finder stays usable (and unlocked) while iterating. Nothing is yielded when
+// the cache cannot be built.
+func (g *GoDepFind) IndexedFiles() iter.Seq2[string, string] {
+	g.mu.Lock()
+	var paths []string
+	var snapshot map[string]string
+	if err := g.ensureCacheInitialized(); err == nil {
+		snapshot = make(map[string]string, len(g.filePathToPackage))
+		for path, pkg := range g.filePathToPackage {
+			snapshot[path] = pkg
+			paths = append(paths, path)
+		}
+	}
+	g.mu.Unlock()
+	sort.Strings(paths)
+
+	return func(yield func(string, string) bool) {
+		for _, path := range paths {
+			if !yield(path, snapshot[path]) {
+				return
+			}
+		}
+	}
+}
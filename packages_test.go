@@ -0,0 +1,197 @@
+package depfind
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPackageInfo(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":          "module pi\n\ngo 1.21\n",
+		"app/main.go":     "// Command app serves the PWA.\npackage main\n\nimport \"pi/database\"\n\nfunc main() { database.Open() }\n",
+		"database/doc.go": "// Package database provides connection pooling for the PWA server.\n// It wraps database/sql.\npackage database\n",
+		"database/db.go":  "package database\n\nfunc Open() {}\n",
+		"util/util.go":    "package util\n",
+	})
+	finder := New(root)
+
+	info, err := finder.PackageInfo("pi/database")
+	if err != nil || info == nil {
+		t.Fatalf("PackageInfo: %v, %v", info, err)
+	}
+	if info.Name != "database" || info.Main {
+		t.Errorf("unexpected package info: %+v", info)
+	}
+	if want := "Package database provides connection pooling for the PWA server."; info.Doc != want {
+		t.Errorf("Doc = %q, want %q", info.Doc, want)
+	}
+	if info, err := finder.PackageInfo("fmt"); err != nil || info != nil {
+		t.Errorf("expected nil for packages outside the roots, got %+v, %v", info, err)
+	}
+
+	infos, err := finder.Packages()
+	if err != nil {
+		t.Fatalf("Packages: %v", err)
+	}
+	if len(infos) != 3 || infos[0].Path != "pi/app" || !infos[0].Main || infos[0].Doc != "Command app serves the PWA." {
+		t.Errorf("unexpected packages: %+v", infos)
+	}
+	if infos[2].Path != "pi/util" || infos[2].Doc != "" {
+		t.Errorf("expected an undocumented package to have no doc, got %+v", infos[2])
+	}
+
+	graph, err := finder.Prune(PruneOptions{CollapseStdlib: true})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	for _, node := range graph.Nodes {
+		if node.ID == "pi/database" && node.Doc != info.Doc {
+			t.Errorf("expected the graph node to carry the package doc, got %q", node.Doc)
+		}
+	}
+}
+
+func TestIndexedFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module ix\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"lib/a.go":    "package lib\n",
+		"lib/b.go":    "package lib\n",
+	})
+	finder := New(root)
+
+	var got []string
+	for path, pkg := range finder.IndexedFiles() {
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel)+"="+pkg)
+	}
+	want := []string{"app/main.go=ix/app", "lib/a.go=ix/lib", "lib/b.go=ix/lib"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IndexedFiles = %v, want %v", got, want)
+	}
+
+	// Breaking early stops the iteration, and the finder is not left locked
+	for range finder.IndexedFiles() {
+		break
+	}
+	if _, err := finder.Packages(); err != nil {
+		t.Errorf("Packages after iteration: %v", err)
+	}
+}
+
+func TestMainPackages(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module mp\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nfunc main() {}\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nfunc main() {}\n",
+		"pwa/routes.go":      "package main\n",
+		"cli/main.go":        "package main\n\nfunc main() {}\n",
+		"lib/lib.go":         "package lib\n",
+	})
+	finder := New(root)
+
+	mains, err := finder.MainPackages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mains) != 2 || mains[0].Path != "mp/cli" || mains[1].Path != "mp/pwa" {
+		t.Fatalf("unexpected main packages: %+v", mains)
+	}
+	pwa := mains[1]
+	if pwa.Dir != filepath.Join(root, "pwa") {
+		t.Errorf("Dir = %s", pwa.Dir)
+	}
+	if want := []string{"main.server.go", "routes.go"}; !reflect.DeepEqual(pwa.GoFiles, want) {
+		t.Errorf("GoFiles = %v, want %v", pwa.GoFiles, want)
+	}
+	if want := map[string]string{"main.server.go": "!wasm"}; !reflect.DeepEqual(pwa.Constraints, want) {
+		t.Errorf("Constraints = %v, want %v", pwa.Constraints, want)
+	}
+	if len(pwa.MainFiles) != 2 {
+		t.Fatalf("expected both main files, got %+v", pwa.MainFiles)
+	}
+	if server, wasm := pwa.MainFiles[0], pwa.MainFiles[1]; server.MainFile != "pwa/main.server.go" || server.IsWasm() ||
+		wasm.MainFile != "pwa/main.wasm.go" || !wasm.IsWasm() || wasm.BuildTags != "wasm" {
+		t.Errorf("unexpected main files: %+v", pwa.MainFiles)
+	}
+	if mains[0].Constraints != nil || len(mains[0].MainFiles) != 1 {
+		t.Errorf("unexpected cli info: %+v", mains[0])
+	}
+}
+
+func TestPackageForFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module pf\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"pf/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":      "package db\n\nfunc Open() {}\n",
+		"db/db_js.go":   "package db\n",
+		"docs/notes.md": "notes\n",
+	})
+	finder := New(root)
+
+	cases := map[string]string{
+		filepath.Join(root, "db", "db.go"): "pf/db",
+		"db/db_js.go":                      "pf/db",
+		"app/main.go":                      "pf/app",
+	}
+	for path, want := range cases {
+		info, err := finder.PackageForFile(path)
+		if err != nil || info.Path != want {
+			t.Errorf("PackageForFile(%s) = %+v, %v, want %s", path, info, err, want)
+		}
+	}
+	if info, _ := finder.PackageForFile("app/main.go"); !info.Main || info.Name != "main" || info.Dir != filepath.Join(root, "app") {
+		t.Errorf("unexpected main package info: %+v", info)
+	}
+
+	// A Go file created after the last rebuild belongs to its directory's package
+	newFile := filepath.Join(root, "db", "pool.go")
+	if err := os.WriteFile(newFile, []byte("package db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := finder.PackageForFile(newFile); err != nil || info.Path != "pf/db" {
+		t.Errorf("PackageForFile(new file) = %+v, %v", info, err)
+	}
+
+	if _, err := finder.PackageForFile("docs/notes.md"); err == nil {
+		t.Error("expected an error for a file outside every package")
+	}
+}
+
+func TestFilesForPackage(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module ff\n\ngo 1.21\n",
+		"db/db.go":       "package db\n",
+		"db/pool.go":     "package db\n",
+		"db/asm_amd64.s": "",
+		"db/db_test.go":  "package db\n",
+		"db/x_test.go":   "package db_test\n",
+		"db/db_js.go":    "package db\n",
+		"db/notes.md":    "notes\n",
+	})
+	finder := New(root)
+	dir := filepath.Join(root, "db")
+
+	files, err := finder.FilesForPackage("ff/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "db.go"), filepath.Join(dir, "pool.go")}
+	if build.Default.GOARCH == "amd64" {
+		want = append([]string{filepath.Join(dir, "asm_amd64.s")}, want...)
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("FilesForPackage = %v, want %v", files, want)
+	}
+
+	files, _ = finder.FilesForPackage("ff/db", WithTestFiles())
+	if len(files) != len(want)+2 || !contains(files, filepath.Join(dir, "x_test.go")) {
+		t.Errorf("FilesForPackage with tests = %v", files)
+	}
+	if _, err := finder.FilesForPackage("ff/missing"); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+}
@@ -0,0 +1,319 @@
+package depfind
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CacheFormatVersion is the version of the on-disk cache layout. Files
+// written with another version are ignored and rewritten. Version 2 added
+// the virtual edges of SaveCache.
+const CacheFormatVersion = 2
+
+// CacheLoadResult tells which path the last full rebuild took with the
+// on-disk cache, see SetCacheFile and Metrics
+type CacheLoadResult string
+
+const (
+	CacheDisabled        CacheLoadResult = ""                 // no cache file configured
+	CacheHit             CacheLoadResult = "hit"              // listing restored from disk
+	CacheMissing         CacheLoadResult = "missing"          // no file yet, rebuilt and written
+	CacheVersionMismatch CacheLoadResult = "version-mismatch" // written by another format version, rebuilt
+	CacheCorrupt         CacheLoadResult = "corrupt"          // unreadable, truncated or bad checksum, rebuilt
+	CacheStale           CacheLoadResult = "stale"            // the tree or toolchain changed since, rebuilt
+)
+
+// Metrics are counters about the work done by the finder
+type Metrics struct {
+	FullRebuilds  int             `json:"full_rebuilds"`              // complete cache rebuilds
+	CacheHits     int             `json:"cache_hits"`                 // rebuilds served by the on-disk cache
+	CacheMisses   int             `json:"cache_misses"`               // rebuilds that had to list packages again
+	LastCacheLoad CacheLoadResult `json:"last_cache_load,omitempty"`  // outcome of the last on-disk cache read
+	LastCacheErr  string          `json:"last_cache_error,omitempty"` // why the last read or write failed
+}
+
+// Metrics returns the counters collected since the finder was created
+func (g *GoDepFind) Metrics() Metrics {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.metrics
+}
+
+// SetCacheFile persists the package listing of full rebuilds to path, so a
+// cold start restores it instead of running go list over the whole module.
+// The file is versioned and checksummed; a missing, corrupt, outdated or
+// stale file (a directory of the roots, a file of a skipped package or the
+// version of the go command changed) is never an error: the finder lists
+// packages again and rewrites it. Metrics reports which path was taken. Keep
+// the file outside the roots or in a hidden directory (".depfind/cache.json"):
+// writing it must not touch the tree it describes. An empty path disables the
+// cache file.
+func (g *GoDepFind) SetCacheFile(path string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cacheFile = path
+	g.cacheStore = nil
+}
+
+// SetCacheStore persists the package listing as SetCacheFile does, under key
+// in store. The listing is validated by directory modification times, which
+// rarely survive a checkout on another machine: share the whole graph with
+// SaveCacheTo instead. A nil store or an empty key disables the cache.
+func (g *GoDepFind) SetCacheStore(store CacheStore, key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if store == nil {
+		key = ""
+	}
+	g.cacheFile = key
+	g.cacheStore = store
+}
+
+// listingStore returns the store of the package listing
+func (g *GoDepFind) listingStore() CacheStore {
+	if g.cacheStore == nil {
+		return FileCacheStore{}
+	}
+	return g.cacheStore
+}
+
+// cacheEnvelope is the on-disk layout: the payload with its version and checksum
+type cacheEnvelope struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"` // sha256 of Payload, hex
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// cacheSnapshot is the persisted listing and what it was computed from
+type cacheSnapshot struct {
+	Key      string            `json:"key"`      // roots, scopes and toolchain
+	Stamps   map[string]int64  `json:"stamps"`   // directory or go.mod -> modification time
+	Packages map[string]string `json:"packages"` // import path -> directory
+	Skipped  []SkippedPackage  `json:"skipped,omitempty"`
+	Excluded []string          `json:"excluded,omitempty"` // vendored trees, see ExcludedDirs
+
+	// SkippedStamps holds the modification time of every file in the
+	// directories of skipped packages: fixing one in place changes no
+	// directory, but must list it again
+	SkippedStamps map[string]int64 `json:"skipped_stamps,omitempty"`
+}
+
+// listAllPackages lists every package of the roots for a full rebuild,
+// through the cache file when one is configured
+func (g *GoDepFind) listAllPackages() ([]string, error) {
+	g.metrics.FullRebuilds++
+	if g.cacheFile == "" || g.usesScanner() {
+		return g.listScopePackages()
+	}
+
+	snapshot, result, err := g.readCacheFile()
+	g.metrics.LastCacheLoad = result
+	g.metrics.LastCacheErr = errorDetail(err)
+	if result == CacheHit {
+		g.metrics.CacheHits++
+		return g.restoreSnapshot(snapshot), nil
+	}
+	g.metrics.CacheMisses++
+
+	packages, err := g.listScopePackages()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.writeCacheFile(packages); err != nil {
+		g.metrics.LastCacheErr = err.Error()
+	}
+	return packages, nil
+}
+
+// readCacheFile loads and validates the cache file
+func (g *GoDepFind) readCacheFile() (*cacheSnapshot, CacheLoadResult, error) {
+	var snapshot cacheSnapshot
+	if result, err := getEnvelope(g.listingStore(), g.cacheFile, &snapshot); result != CacheHit {
+		return nil, result, err
+	}
+
+	if snapshot.Key != g.cacheKey() || !maps.Equal(snapshot.Stamps, g.treeStamps()) ||
+		!maps.Equal(snapshot.SkippedStamps, skippedFileStamps(snapshot.Skipped)) {
+		return nil, CacheStale, nil
+	}
+	return &snapshot, CacheHit, nil
+}
+
+// writeCacheFile persists the listing just computed, atomically
+func (g *GoDepFind) writeCacheFile(packages []string) error {
+	// Created before the stamps are taken, so its own creation does not
+	// make the file stale
+	if store, ok := g.listingStore().(FileCacheStore); ok {
+		if err := os.MkdirAll(filepath.Dir(store.path(g.cacheFile)), 0755); err != nil {
+			return fmt.Errorf("cannot write cache file: %w", err)
+		}
+	}
+	snapshot := cacheSnapshot{
+		Key:      g.cacheKey(),
+		Stamps:   g.treeStamps(),
+		Packages: make(map[string]string, len(packages)),
+	}
+	g.listMu.Lock()
+	for _, pkgPath := range packages {
+		snapshot.Packages[pkgPath] = g.packageDirs[pkgPath]
+	}
+	for _, skipped := range g.skipped {
+		snapshot.Skipped = append(snapshot.Skipped, skipped)
+	}
+	snapshot.Excluded = slices.Clone(g.excludedDirs)
+	g.listMu.Unlock()
+	slices.SortFunc(snapshot.Skipped, func(a, b SkippedPackage) int { return strings.Compare(a.Path, b.Path) })
+	snapshot.SkippedStamps = skippedFileStamps(snapshot.Skipped)
+
+	return putEnvelope(g.listingStore(), g.cacheFile, snapshot)
+}
+
+// getEnvelope loads a versioned, checksummed blob of store into payload;
+// anything but CacheHit comes with the reason, except CacheMissing
+func getEnvelope(store CacheStore, key string, payload any) (CacheLoadResult, error) {
+	data, err := store.Get(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return CacheMissing, nil
+	}
+	if err != nil {
+		return CacheCorrupt, err
+	}
+
+	var envelope cacheEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return CacheCorrupt, err
+	}
+	if envelope.Version != CacheFormatVersion {
+		return CacheVersionMismatch, fmt.Errorf("cache format version %d, want %d", envelope.Version, CacheFormatVersion)
+	}
+	if payloadChecksum(envelope.Payload) != envelope.Checksum {
+		return CacheCorrupt, fmt.Errorf("cache checksum mismatch")
+	}
+	if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+		return CacheCorrupt, err
+	}
+	return CacheHit, nil
+}
+
+// putEnvelope stores payload under key with its version and checksum
+func putEnvelope(store CacheStore, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(cacheEnvelope{Version: CacheFormatVersion, Checksum: payloadChecksum(data), Payload: data})
+	if err != nil {
+		return err
+	}
+	return store.Put(key, data)
+}
+
+// restoreSnapshot records a persisted listing as if go list had produced it
+func (g *GoDepFind) restoreSnapshot(snapshot *cacheSnapshot) []string {
+	packages := make([]string, 0, len(snapshot.Packages))
+	for pkgPath, dir := range snapshot.Packages {
+		packages = append(packages, pkgPath)
+		if dir != "" {
+			g.setPackageDir(pkgPath, dir)
+		}
+	}
+	slices.Sort(packages)
+	for _, skipped := range snapshot.Skipped {
+		g.addSkipped(skipped)
+	}
+	g.listMu.Lock()
+	g.excludedDirs = slices.Clone(snapshot.Excluded)
+	g.listMu.Unlock()
+	return packages
+}
+
+// cacheKey identifies what a listing depends on besides the tree itself,
+// including the version of the go command that lists the primary root
+func (g *GoDepFind) cacheKey() string {
+	var toolchain string
+	if len(g.rootDirs) > 0 {
+		toolchain = goCommandVersion(g.rootDirs[0])
+	}
+	key := append([]string{toolchain, g.scope, strings.Join(g.scopePatterns, ","), strconv.FormatBool(g.keepToolDirs)}, g.rootDirs...)
+	return strings.Join(key, "\x00")
+}
+
+// skippedFileStamps returns the modification times of the files in the
+// directories of skipped packages, nil when there are none
+func skippedFileStamps(skipped []SkippedPackage) map[string]int64 {
+	var stamps map[string]int64
+	for _, s := range skipped {
+		if s.Dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(s.Dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if info, err := entry.Info(); err == nil {
+				if stamps == nil {
+					stamps = make(map[string]int64)
+				}
+				stamps[filepath.Join(s.Dir, entry.Name())] = info.ModTime().UnixNano()
+			}
+		}
+	}
+	return stamps
+}
+
+// treeStamps returns the modification times of the directories go list
+// would visit and of the go.mod/go.work files of the roots: adding,
+// removing or renaming a file or package changes one of them
+func (g *GoDepFind) treeStamps() map[string]int64 {
+	stamps := g.moduleFileStamps()
+	for _, root := range g.rootDirs {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			if info, err := d.Info(); err == nil {
+				stamps[path] = info.ModTime().UnixNano()
+			}
+			return nil
+		})
+	}
+	return stamps
+}
+
+// moduleFileStamps returns the modification times of the go.mod and go.work
+// files of the roots
+func (g *GoDepFind) moduleFileStamps() map[string]int64 {
+	stamps := make(map[string]int64)
+	for _, root := range g.rootDirs {
+		for _, name := range []string{"go.mod", "go.work"} {
+			if info, err := os.Stat(filepath.Join(root, name)); err == nil {
+				stamps[filepath.Join(root, name)] = info.ModTime().UnixNano()
+			}
+		}
+	}
+	return stamps
+}
+
+// payloadChecksum returns the hex sha256 of a cache payload
+func payloadChecksum(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
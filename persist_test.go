@@ -0,0 +1,138 @@
+package depfind
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module pc\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"pc/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	cacheFile := filepath.Join(root, ".depfind", "cache.json")
+	libFile := filepath.Join(root, "lib", "lib.go")
+
+	load := func(want CacheLoadResult) *GoDepFind {
+		t.Helper()
+		finder := New(root)
+		finder.SetCacheFile(cacheFile)
+		if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+			t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+		}
+		metrics := finder.Metrics()
+		if metrics.LastCacheLoad != want {
+			t.Fatalf("LastCacheLoad = %q, want %q (%s)", metrics.LastCacheLoad, want, metrics.LastCacheErr)
+		}
+		if metrics.FullRebuilds != 1 {
+			t.Errorf("FullRebuilds = %d, want 1", metrics.FullRebuilds)
+		}
+		return finder
+	}
+
+	load(CacheMissing)
+	if finder := load(CacheHit); finder.Metrics().CacheHits != 1 {
+		t.Errorf("expected a cache hit to be counted, got %+v", finder.Metrics())
+	}
+
+	// Corruption and other versions are rebuilt and rewritten
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope cacheEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	flipped := "0"
+	if envelope.Checksum[0] == '0' {
+		flipped = "1"
+	}
+	envelope.Checksum = flipped + envelope.Checksum[1:]
+	writeEnvelope(t, cacheFile, envelope)
+	load(CacheCorrupt)
+	load(CacheHit)
+
+	if err := os.WriteFile(cacheFile, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	load(CacheCorrupt)
+
+	envelope.Version = CacheFormatVersion + 1
+	writeEnvelope(t, cacheFile, envelope)
+	load(CacheVersionMismatch)
+	load(CacheHit)
+
+	// A new package makes the listing stale
+	if err := os.MkdirAll(filepath.Join(root, "extra"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "extra", "extra.go"), []byte("package extra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	finder := load(CacheStale)
+	if info, err := finder.PackageInfo("pc/extra"); err != nil || info == nil {
+		t.Errorf("expected the new package after a stale cache, got %v, %v", info, err)
+	}
+	load(CacheHit)
+}
+
+func TestCacheFileSkippedPackageFixedInPlace(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module pc\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"lib/a.go":    "package lib\n",
+		"lib/b.go":    "package other\n",
+	})
+	cacheFile := filepath.Join(root, ".depfind", "cache.json")
+
+	load := func(want CacheLoadResult) *GoDepFind {
+		t.Helper()
+		finder := New(root)
+		finder.SetCacheFile(cacheFile)
+		if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+			t.Fatal(err)
+		}
+		if result := finder.Metrics().LastCacheLoad; result != want {
+			t.Fatalf("LastCacheLoad = %q, want %q", result, want)
+		}
+		return finder
+	}
+	load(CacheMissing)
+	if skipped := load(CacheHit).SkippedPackages(); len(skipped) != 1 || skipped[0].Path != "pc/lib" {
+		t.Fatalf("expected pc/lib to be skipped, got %+v", skipped)
+	}
+
+	// Fixing a file changes no directory, but the package is listed again
+	libFile := filepath.Join(root, "lib", "b.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(libFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	finder := load(CacheStale)
+	if skipped := finder.SkippedPackages(); len(skipped) != 0 {
+		t.Errorf("expected no skipped package once fixed, got %+v", skipped)
+	}
+	if info, err := finder.PackageInfo("pc/lib"); err != nil || info == nil {
+		t.Errorf("expected pc/lib to be loaded, got %v, %v", info, err)
+	}
+	load(CacheHit)
+}
+
+func writeEnvelope(t *testing.T, path string, envelope cacheEnvelope) {
+	t.Helper()
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
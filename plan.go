@@ -0,0 +1,99 @@
+package depfind
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Plan tells which handlers a batch of changed files requires to rebuild,
+// see RebuildPlan
+type Plan struct {
+	Handlers []PlanEntry `json:"handlers"` // every discovered and virtual handler, sorted by main file
+}
+
+// PlanEntry is the verdict for one handler
+type PlanEntry struct {
+	MainFile string   `json:"main_file"`          // relative to the primary root, the name of a virtual handler
+	Virtual  bool     `json:"virtual,omitempty"`  // registered with AddVirtualHandler
+	Package  string   `json:"package,omitempty"`  // import path of the main package when known
+	Rebuild  bool     `json:"rebuild"`            // at least one changed file is owned
+	Files    []string `json:"files,omitempty"`    // owned changed files, absolute, sorted
+	Packages []string `json:"packages,omitempty"` // packages of those files, sorted: why it rebuilds
+}
+
+// Rebuilds returns the main files of the handlers to rebuild, sorted
+func (p *Plan) Rebuilds() []string {
+	var mains []string
+	for _, entry := range p.Handlers {
+		if entry.Rebuild {
+			mains = append(mains, entry.MainFile)
+		}
+	}
+	return mains
+}
+
+// RebuildPlan decides, for every handler DiscoverHandlers finds, whether the
+// changed files require a rebuild, with the same rules as ThisFileIsMine and
+// in a single pass over the cache. It is a query: the cache is used as it
+// is, so route the events themselves through ThisFileIsMine (or call
+// Rebuild) first when the changes may have altered imports. Only source
+// files (.go, .s, .syso) are planned, except for virtual handlers (see
+// AddVirtualHandler) which are given every file; paths may be absolute or
+// relative to the primary root.
+func (g *GoDepFind) RebuildPlan(changedFiles []string) (*Plan, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(changedFiles))
+	for _, file := range changedFiles {
+		if sourceExts[filepath.Ext(file)] {
+			files = append(files, g.rootPath(file))
+		}
+	}
+	files = sortedUnique(files)
+
+	plan := &Plan{Handlers: make([]PlanEntry, 0, len(handlers))}
+	for _, h := range handlers {
+		entry := PlanEntry{MainFile: h.MainFile, Package: h.Package}
+		packages := make(map[string]bool)
+		for _, file := range files {
+			owned, err := g.thisFileIsMineWith(h.MainFile, file, EventQuery, false)
+			if err != nil {
+				return nil, err
+			}
+			if !owned {
+				continue
+			}
+			entry.Files = append(entry.Files, file)
+			if pkgPath := g.changedFilePackage(file); pkgPath != "" {
+				packages[pkgPath] = true
+			}
+		}
+		entry.Rebuild = len(entry.Files) > 0
+		if len(packages) > 0 {
+			entry.Packages = sortedKeys(packages)
+		}
+		plan.Handlers = append(plan.Handlers, entry)
+	}
+	for _, name := range g.virtualHandlerNames() {
+		entry := PlanEntry{MainFile: name, Virtual: true}
+		for _, file := range changedFiles {
+			if owned, _ := g.virtualOwnership(name, g.rootPath(file)); owned && !contains(entry.Files, g.rootPath(file)) {
+				entry.Files = append(entry.Files, g.rootPath(file))
+			}
+		}
+		sort.Strings(entry.Files)
+		entry.Rebuild = len(entry.Files) > 0
+		plan.Handlers = append(plan.Handlers, entry)
+	}
+	sort.Slice(plan.Handlers, func(i, j int) bool { return plan.Handlers[i].MainFile < plan.Handlers[j].MainFile })
+	return plan, nil
+}
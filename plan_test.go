@@ -0,0 +1,60 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRebuildPlan(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module rp\n\ngo 1.21\n",
+		"pwa/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"rp/db\"\n\nfunc main() { db.Open() }\n",
+		"pwa/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"rp/ui\"\n\nfunc main() { ui.Draw() }\n",
+		"cli/main.go":        "package main\n\nimport \"rp/util\"\n\nfunc main() { util.Do() }\n",
+		"db/db.go":           "package db\n\nimport \"rp/util\"\n\nfunc Open() { util.Do() }\n",
+		"ui/ui.go":           "package ui\n\nfunc Draw() {}\n",
+		"util/util.go":       "package util\n\nfunc Do() {}\n",
+		"docs/notes.md":      "# notes\n",
+	})
+	finder := New(root)
+
+	plan, err := finder.RebuildPlan([]string{
+		filepath.Join(root, "util", "util.go"),
+		"db/db.go",
+		"docs/notes.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlanEntry{
+		{MainFile: "cli/main.go", Package: "rp/cli", Rebuild: true,
+			Files: []string{filepath.Join(root, "util", "util.go")}, Packages: []string{"rp/util"}},
+		{MainFile: "pwa/main.server.go", Package: "rp/pwa", Rebuild: true,
+			Files: []string{filepath.Join(root, "db", "db.go"), filepath.Join(root, "util", "util.go")}, Packages: []string{"rp/db", "rp/util"}},
+		{MainFile: "pwa/main.wasm.go", Package: "rp/pwa"},
+	}
+	if !reflect.DeepEqual(plan.Handlers, want) {
+		t.Errorf("RebuildPlan =\n%+v\nwant\n%+v", plan.Handlers, want)
+	}
+	if got := plan.Rebuilds(); !reflect.DeepEqual(got, []string{"cli/main.go", "pwa/main.server.go"}) {
+		t.Errorf("Rebuilds() = %v", got)
+	}
+
+	// Same answers as ThisFileIsMine per handler per file
+	for _, entry := range plan.Handlers {
+		for _, file := range []string{filepath.Join(root, "ui", "ui.go"), filepath.Join(root, "db", "db.go")} {
+			isMine, err := finder.ThisFileIsMine(entry.MainFile, file, EventQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inPlan, err := finder.RebuildPlan([]string{file})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := contains(inPlan.Rebuilds(), entry.MainFile); got != isMine {
+				t.Errorf("%s / %s: plan says %v, ThisFileIsMine %v", entry.MainFile, file, got, isMine)
+			}
+		}
+	}
+}
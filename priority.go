@@ -0,0 +1,83 @@
+package depfind
+
+import "path/filepath"
+
+// Priority ranks how strongly a handler is affected by a file change, so dev
+// servers can schedule rebuilds of the most affected binaries first when a
+// shared package changes.
+type Priority int
+
+const (
+	// PriorityNone means the handler is not affected by the file
+	PriorityNone Priority = iota
+	// PriorityTransitive means the file's package is reached through other imports
+	PriorityTransitive
+	// PriorityDirect means the handler main file imports the file's package
+	PriorityDirect
+	// PriorityMainFile means the file is the handler main file or part of its main package
+	PriorityMainFile
+)
+
+// String returns the lowercase name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityNone:
+		return "none"
+	case PriorityTransitive:
+		return "transitive"
+	case PriorityDirect:
+		return "direct"
+	case PriorityMainFile:
+		return "main"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the priority by name so JSON results stay readable
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// OwnershipPriority reports how strongly the handler is affected by a change to
+// the given file. It applies the same rules as ThisFileIsMine without
+// updating the cache, and returns PriorityNone when the handler does not own
+// the file.
+func (g *GoDepFind) OwnershipPriority(mainInputFileRelativePath, fileAbsPath string) (Priority, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	belongs, err := g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, EventQuery)
+	if err != nil || !belongs {
+		return PriorityNone, err
+	}
+	return g.ownershipPriority(mainInputFileRelativePath, fileAbsPath), nil
+}
+
+// ownershipPriority ranks a file already known to belong to the handler
+func (g *GoDepFind) ownershipPriority(mainInputFileRelativePath, fileAbsPath string) Priority {
+	fileAbsPath = g.rootPath(fileAbsPath)
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	if g.isSameFile(fileAbsPath, handlerAbsPath) {
+		return PriorityMainFile
+	}
+
+	targetPkg, err := g.findPackageForFile(fileAbsPath)
+	if err != nil || targetPkg == "" {
+		// External or unindexed file: ownership was assumed, depth is unknown
+		return PriorityTransitive
+	}
+	if g.isMainPackage(targetPkg) {
+		// Owned main packages always share the handler directory
+		return PriorityMainFile
+	}
+
+	if imports, err := g.parseFileImports(handlerAbsPath); err == nil {
+		handlerDir := filepath.Dir(handlerAbsPath)
+		for _, imp := range imports {
+			if g.resolveImport(imp, handlerDir) == targetPkg {
+				return PriorityDirect
+			}
+		}
+	}
+	return PriorityTransitive
+}
@@ -0,0 +1,51 @@
+package depfind
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestOwnershipPriority(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module prio\n\ngo 1.21\n",
+		"app/main.go":        "package main\n\nimport \"prio/service\"\n\nfunc main() { service.Run() }\n",
+		"app/flags.go":       "package main\n",
+		"service/service.go": "package service\n\nimport \"prio/store\"\n\nfunc Run() { store.Open() }\n",
+		"store/store.go":     "package store\n\nfunc Open() {}\n",
+		"unused/unused.go":   "package unused\n",
+	})
+	finder := New(root)
+
+	tests := []struct {
+		file     string
+		expected Priority
+	}{
+		{"app/main.go", PriorityMainFile},
+		{"app/flags.go", PriorityMainFile},
+		{"service/service.go", PriorityDirect},
+		{"store/store.go", PriorityTransitive},
+		{"unused/unused.go", PriorityNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := finder.OwnershipPriority("app/main.go", filepath.Join(root, tt.file))
+			if err != nil {
+				t.Fatalf("OwnershipPriority: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	result, err := finder.AnalyzeFileImpact("app/main.go", "store.go", filepath.Join(root, "store", "store.go"), "write")
+	if err != nil {
+		t.Fatalf("AnalyzeFileImpact: %v", err)
+	}
+	if result.Priority != PriorityTransitive {
+		t.Errorf("expected transitive priority in impact result, got %v", result.Priority)
+	}
+	data, _ := json.Marshal(result)
+	logf(t, "impact result: %s", data)
+}
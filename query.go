@@ -0,0 +1,282 @@
+package depfind
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query evaluates a graph selection written in a small set language and
+// returns the selected packages, sorted. Questions such as "what does main A
+// pull in that main B does not" are then one expression instead of a new
+// method each:
+//
+//	main(example.com/app/server) -> * & !(main(example.com/app/cli) -> *) & !stdlib
+//
+// Operands select sets of packages:
+//
+//	example.com/app/db       a cached package, "/..." patterns allowed
+//	main(example.com/app)    a main package; an error if it is not one
+//	mains                    every main package
+//	local, stdlib, external  packages of the roots, of the standard library, of other modules
+//	X -> *                   X and everything it imports transitively
+//	* -> X                   X and every cached package importing it transitively
+//
+// and combine with !X (every known package but X), X & Y, X | Y and
+// parentheses, from the tightest binding to the loosest. Options apply to
+// the arrows (WithMaxDepth, WithBuildTags) and filter the result
+// (IncludeStdlib, OnlyModuleLocal).
+func (g *GoDepFind) Query(expr string, opts ...Option) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	options := queryOptions{maxDepth: g.maxDepth}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	tokens, err := tokenizeQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &queryEval{g: g, tokens: tokens, maxDepth: options.maxDepth}
+	q.deps, q.reverse = g.queryEdges(options)
+	q.universe = make(map[string]bool)
+	for pkgPath := range g.packageCache {
+		q.universe[pkgPath] = true
+	}
+	for from, deps := range q.deps {
+		q.universe[from] = true
+		for _, dep := range deps {
+			q.universe[dep] = true
+		}
+	}
+
+	selected, err := q.union()
+	if err != nil {
+		return nil, err
+	}
+	if tok := q.peek(); tok.text != "" {
+		return nil, fmt.Errorf("query: unexpected %q at %d", tok.text, tok.pos)
+	}
+	for pkgPath := range selected {
+		if options.excludes(g.nodeKind(pkgPath)) {
+			delete(selected, pkgPath)
+		}
+	}
+	return sortedKeys(selected), nil
+}
+
+// queryToken is an operator or a word of a query, pos is its byte offset
+type queryToken struct {
+	text string
+	pos  int
+}
+
+// tokenizeQuery splits a query into operators ( ) & | ! * -> and words
+// (package paths, set names)
+func tokenizeQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(expr[i:], "->"):
+			tokens = append(tokens, queryToken{text: "->", pos: i})
+			i += 2
+		case strings.ContainsRune("()&|!*", rune(c)):
+			tokens = append(tokens, queryToken{text: string(c), pos: i})
+			i++
+		default:
+			start := i
+			for i < len(expr) && !unicode.IsSpace(rune(expr[i])) && !strings.ContainsRune("()&|!*", rune(expr[i])) &&
+				!strings.HasPrefix(expr[i:], "->") {
+				i++
+			}
+			tokens = append(tokens, queryToken{text: expr[start:i], pos: start})
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query: empty expression")
+	}
+	return tokens, nil
+}
+
+// queryEval evaluates a tokenized query by recursive descent (mu held)
+type queryEval struct {
+	g        *GoDepFind
+	tokens   []queryToken
+	next     int
+	deps     map[string][]string
+	reverse  map[string][]string
+	universe map[string]bool
+	maxDepth int
+}
+
+func (q *queryEval) peek() queryToken {
+	if q.next < len(q.tokens) {
+		return q.tokens[q.next]
+	}
+	return queryToken{pos: -1}
+}
+
+// expect consumes the next token, which must be text
+func (q *queryEval) expect(text string) error {
+	tok := q.peek()
+	if tok.text != text {
+		if tok.text == "" {
+			return fmt.Errorf("query: expected %q at end of expression", text)
+		}
+		return fmt.Errorf("query: expected %q at %d, got %q", text, tok.pos, tok.text)
+	}
+	q.next++
+	return nil
+}
+
+// union parses X | Y | ...
+func (q *queryEval) union() (map[string]bool, error) {
+	set, err := q.intersection()
+	for err == nil && q.peek().text == "|" {
+		q.next++
+		var other map[string]bool
+		if other, err = q.intersection(); err == nil {
+			for pkgPath := range other {
+				set[pkgPath] = true
+			}
+		}
+	}
+	return set, err
+}
+
+// intersection parses X & Y & ...
+func (q *queryEval) intersection() (map[string]bool, error) {
+	set, err := q.unary()
+	for err == nil && q.peek().text == "&" {
+		q.next++
+		var other map[string]bool
+		if other, err = q.unary(); err == nil {
+			for pkgPath := range set {
+				if !other[pkgPath] {
+					delete(set, pkgPath)
+				}
+			}
+		}
+	}
+	return set, err
+}
+
+// unary parses !X, * -> X and X -> *
+func (q *queryEval) unary() (map[string]bool, error) {
+	switch q.peek().text {
+	case "!":
+		q.next++
+		excluded, err := q.unary()
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool)
+		for pkgPath := range q.universe {
+			if !excluded[pkgPath] {
+				set[pkgPath] = true
+			}
+		}
+		return set, nil
+	case "*":
+		q.next++
+		if err := q.expect("->"); err != nil {
+			return nil, err
+		}
+		targets, err := q.primary()
+		if err != nil {
+			return nil, err
+		}
+		return reachIn(q.reverse, sortedKeys(targets), q.maxDepth), nil
+	}
+
+	set, err := q.primary()
+	if err != nil {
+		return nil, err
+	}
+	if q.peek().text == "->" {
+		q.next++
+		if err := q.expect("*"); err != nil {
+			return nil, err
+		}
+		set = reachIn(q.deps, sortedKeys(set), q.maxDepth)
+	}
+	return set, nil
+}
+
+// primary parses a parenthesized expression, a set name, main(path) or a
+// package path
+func (q *queryEval) primary() (map[string]bool, error) {
+	tok := q.peek()
+	switch tok.text {
+	case "":
+		return nil, fmt.Errorf("query: unexpected end of expression")
+	case "(":
+		q.next++
+		set, err := q.union()
+		if err != nil {
+			return nil, err
+		}
+		return set, q.expect(")")
+	case ")", "&", "|", "->", "*", "!":
+		return nil, fmt.Errorf("query: unexpected %q at %d", tok.text, tok.pos)
+	}
+	q.next++
+
+	switch tok.text {
+	case "mains":
+		set := make(map[string]bool)
+		for _, mainPkg := range q.g.mainPackages {
+			set[mainPkg] = true
+		}
+		return set, nil
+	case NodeLocal, NodeStdlib, NodeExternal:
+		set := make(map[string]bool)
+		for pkgPath := range q.universe {
+			if q.g.nodeKind(pkgPath) == tok.text {
+				set[pkgPath] = true
+			}
+		}
+		return set, nil
+	case "main":
+		if err := q.expect("("); err != nil {
+			return nil, err
+		}
+		arg := q.peek()
+		q.next++
+		if err := q.expect(")"); err != nil {
+			return nil, err
+		}
+		if !contains(q.g.mainPackages, arg.text) {
+			return nil, fmt.Errorf("query: %s is not a main package", arg.text)
+		}
+		return map[string]bool{arg.text: true}, nil
+	}
+	return q.packages(tok.text)
+}
+
+// packages matches a package path or a "/..." pattern against the known
+// packages; nothing matching is an error
+func (q *queryEval) packages(pattern string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		for pkgPath := range q.universe {
+			if pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/") {
+				set[pkgPath] = true
+			}
+		}
+	} else if q.universe[pattern] {
+		set[pattern] = true
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("query: no known package matches %s", pattern)
+	}
+	return set, nil
+}
@@ -0,0 +1,61 @@
+package depfind
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":            "module qy\n\ngo 1.21\n",
+		"server/main.go":    "package main\n\nimport (\n\t_ \"fmt\"\n\t_ \"qy/db\"\n\t_ \"qy/util\"\n)\n\nfunc main() {}\n",
+		"cli/main.go":       "package main\n\nimport _ \"qy/util\"\n\nfunc main() {}\n",
+		"db/db.go":          "package db\n\nimport (\n\t_ \"qy/db/sqlite\"\n\t_ \"strings\"\n)\n",
+		"db/sqlite/lite.go": "package sqlite\n",
+		"util/util.go":      "package util\n",
+	})
+	finder := New(root)
+
+	cases := map[string][]string{
+		"main(qy/server) -> * & !stdlib":                      {"qy/db", "qy/db/sqlite", "qy/server", "qy/util"},
+		"main(qy/server) -> * & !(main(qy/cli) -> *) & local": {"qy/db", "qy/db/sqlite", "qy/server"},
+		"* -> qy/util":            {"qy/cli", "qy/server", "qy/util"},
+		"(* -> qy/util) & mains":  {"qy/cli", "qy/server"},
+		"qy/db/... | qy/util":     {"qy/db", "qy/db/sqlite", "qy/util"},
+		"qy/server -> * & stdlib": {"fmt", "strings"},
+		"!local & !stdlib":        {},
+		"mains & !main(qy/cli)":   {"qy/server"},
+	}
+	for expr, want := range cases {
+		got, err := finder.Query(expr)
+		if err != nil {
+			t.Errorf("Query(%q): %v", expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Query(%q) = %v, want %v", expr, got, want)
+		}
+	}
+
+	if got, err := finder.Query("main(qy/server) -> *", WithMaxDepth(1), OnlyModuleLocal(true)); err != nil ||
+		!reflect.DeepEqual(got, []string{"qy/db", "qy/server", "qy/util"}) {
+		t.Errorf("Query with options = %v, %v", got, err)
+	}
+
+	errors := map[string]string{
+		"":                 "empty expression",
+		"main(qy/db) -> *": "not a main package",
+		"qy/missing":       "no known package",
+		"(qy/db":           "expected \")\"",
+		"qy/db -> qy/util": "expected \"*\"",
+		"qy/db qy/util":    "unexpected \"qy/util\"",
+		"* -> !qy/db":      "unexpected \"!\"",
+		"qy/db & ":         "unexpected end",
+	}
+	for expr, want := range errors {
+		if _, err := finder.Query(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Query(%q) error = %v, want %q", expr, err, want)
+		}
+	}
+}
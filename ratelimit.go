@@ -0,0 +1,63 @@
+package depfind
+
+import "time"
+
+// SetRebuildInterval limits full cache rebuilds triggered by main-file writes
+// to at most one per interval. A write arriving sooner marks the cache dirty:
+// queries keep being served from the previous snapshot and a single rebuild
+// runs when the interval has elapsed, however many saves happened meanwhile.
+// Zero (the default) rebuilds on every main-file write.
+func (g *GoDepFind) SetRebuildInterval(interval time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.rebuildInterval = interval
+}
+
+// rescanMainPackageDependencies rescans the dependencies of a main package.
+// Rebuilding the whole cache keeps dependencyGraph, file mappings and
+// mainPackages consistent; rebuilds are rate limited, see SetRebuildInterval.
+func (g *GoDepFind) rescanMainPackageDependencies(mainInputFileRelativePath string) error {
+	if g.rebuildInterval > 0 && !g.lastRebuild.IsZero() {
+		if wait := g.rebuildInterval - time.Since(g.lastRebuild); wait > 0 {
+			g.scheduleRebuild(wait)
+			return nil
+		}
+	}
+	if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+		return err
+	}
+	return nil // failed packages are reported as skipped
+}
+
+// scheduleRebuild marks the cache dirty and arms a single deferred rebuild (mu held)
+func (g *GoDepFind) scheduleRebuild(wait time.Duration) {
+	if g.rebuildPending {
+		return
+	}
+	g.rebuildPending = true
+
+	if g.rebuildTimer == nil {
+		g.rebuildTimer = time.AfterFunc(wait, g.runPendingRebuild)
+		g.onClose(func() error {
+			g.rebuildTimer.Stop()
+			return nil
+		})
+		return
+	}
+	g.rebuildTimer.Reset(wait)
+}
+
+// runPendingRebuild performs the deferred rebuild, unless another rebuild
+// already refreshed the cache or the finder was closed
+func (g *GoDepFind) runPendingRebuild() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.rebuildPending || g.closed {
+		return
+	}
+	if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+		g.warn(Warning{Kind: WarnCacheRebuild, Message: err.Error()})
+	}
+}
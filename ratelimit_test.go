@@ -0,0 +1,84 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRebuildRateLimit(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module rl\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	mainFile := filepath.Join(root, "app", "main.go")
+	finder := New(root)
+	defer finder.Close()
+	finder.SetRebuildInterval(200 * time.Millisecond)
+
+	finder.mu.Lock()
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	first := finder.lastRebuild
+
+	// Rapid saves that add an import: the snapshot is kept, one rebuild is deferred
+	if err := os.WriteFile(mainFile, []byte("package main\n\nimport \"rl/lib\"\n\nfunc main() { lib.Do() }\n"), 0644); err != nil {
+		t.Fatalf("write main: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := finder.updateCacheForFileWithContext(mainFile, EventWrite, "app/main.go"); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if !finder.lastRebuild.Equal(first) {
+		t.Error("expected no full rebuild within the interval")
+	}
+	if !finder.rebuildPending {
+		t.Error("expected a pending rebuild")
+	}
+	if imports, _ := finder.cachedMainImportsPackage("rl/app", "rl/lib"); imports {
+		t.Error("expected queries to be served from the previous snapshot")
+	}
+	finder.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		finder.mu.RLock()
+		rebuilt := !finder.lastRebuild.Equal(first) && !finder.rebuildPending
+		finder.mu.RUnlock()
+		if rebuilt {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the deferred rebuild to run after the interval")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	finder.mu.RLock()
+	defer finder.mu.RUnlock()
+	if imports, _ := finder.cachedMainImportsPackage("rl/app", "rl/lib"); !imports {
+		t.Error("expected the deferred rebuild to pick up the new import")
+	}
+}
+
+func TestRebuildWithoutRateLimit(t *testing.T) {
+	finder := New("testproject")
+	finder.mu.Lock()
+	defer finder.mu.Unlock()
+
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	first := finder.lastRebuild
+	time.Sleep(time.Millisecond)
+	if err := finder.rescanMainPackageDependencies("appAserver/main.go"); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if finder.lastRebuild.Equal(first) || finder.rebuildPending {
+		t.Error("expected an immediate rebuild when no interval is configured")
+	}
+}
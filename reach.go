@@ -0,0 +1,263 @@
+package depfind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReachableFromAny returns the union of the packages reachable from roots:
+// the roots themselves and everything they import transitively (standard
+// library and external modules included unless filtered with
+// IncludeStdlib or OnlyModuleLocal), sorted. A root is an import path or a
+// pattern ending in "/..." matching cached packages, e.g.
+// "example.com/app/cmd/..." for every binary under cmd/.
+func (g *GoDepFind) ReachableFromAny(roots []string, opts ...Option) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var options queryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	deps, _ := g.queryEdges(options)
+	reach, err := g.reachFromPatterns(roots, deps)
+	if err != nil {
+		return nil, err
+	}
+	for pkgPath := range reach {
+		if options.excludes(g.nodeKind(pkgPath)) {
+			delete(reach, pkgPath)
+		}
+	}
+	return sortedKeys(reach), nil
+}
+
+// UnreachableFromAll returns the module's packages that none of roots reach,
+// sorted: the complement of ReachableFromAny within the cached packages.
+func (g *GoDepFind) UnreachableFromAll(roots []string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	reach, err := g.reachFromPatterns(roots, g.dependencyGraph)
+	if err != nil {
+		return nil, err
+	}
+	return g.unreachable(reach), nil
+}
+
+// UnreachablePackages returns the module's packages that no main package
+// imports, even transitively, sorted: candidates for dead-code cleanup.
+// Main packages themselves are reachable. The max depth does not apply, so
+// deep dependencies are never reported as dead.
+func (g *GoDepFind) UnreachablePackages() ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	mains := append([]string(nil), g.mainPackages...)
+	sort.Strings(mains)
+	return g.unreachable(g.reachWithin(mains, 0)), nil
+}
+
+// unreachable returns the cached packages missing from reach, sorted
+func (g *GoDepFind) unreachable(reach map[string]bool) []string {
+	var unreachable []string
+	for pkgPath := range g.packageCache {
+		if !reach[pkgPath] {
+			unreachable = append(unreachable, pkgPath)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable
+}
+
+// reachFromPatterns expands root patterns against the cached packages and
+// returns everything they reach through edges. Unknown roots are an error.
+func (g *GoDepFind) reachFromPatterns(patterns []string, edges map[string][]string) (map[string]bool, error) {
+	var roots []string
+	for _, pattern := range patterns {
+		matched := false
+		if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+			for pkgPath := range g.packageCache {
+				if pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/") {
+					roots = append(roots, pkgPath)
+					matched = true
+				}
+			}
+		} else if _, ok := g.packageCache[pattern]; ok {
+			roots = append(roots, pattern)
+			matched = true
+		}
+		if !matched {
+			return nil, fmt.Errorf("no cached package matches root %s", pattern)
+		}
+	}
+	sort.Strings(roots) // deterministic depths when a max depth applies
+	reach, truncated := reachDepth(edges, roots, g.maxDepth)
+	if truncated {
+		return nil, &MaxDepthError{Path: roots[0], MaxDepth: g.maxDepth}
+	}
+	return reach, nil
+}
+
+// sortedKeys returns the keys of a set, sorted
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Option tunes a graph query such as FindForwardDeps or FindImporters
+type Option func(*queryOptions)
+
+// queryOptions holds the settings applied by Option values
+type queryOptions struct {
+	maxDepth     int
+	skipStdlib   bool
+	skipExternal bool
+	testFiles    bool
+	tags         TagSet // graph of a build configuration, see WithBuildTags
+}
+
+// WithMaxDepth limits the query to packages at most depth imports away;
+// zero means unlimited. Without it the finder's SetMaxDepth applies.
+func WithMaxDepth(depth int) Option {
+	return func(o *queryOptions) { o.maxDepth = depth }
+}
+
+// WithoutStdlib leaves standard library packages out of the result
+func WithoutStdlib() Option {
+	return func(o *queryOptions) { o.skipStdlib = true }
+}
+
+// WithoutExternal leaves packages of other modules out of the result
+func WithoutExternal() Option {
+	return func(o *queryOptions) { o.skipExternal = true }
+}
+
+// IncludeStdlib keeps (the default) or leaves out standard library packages.
+// The standard library is told apart by GOROOT, not by the shape of the
+// import path, so module names without a dot are never filtered as stdlib.
+func IncludeStdlib(include bool) Option {
+	return func(o *queryOptions) { o.skipStdlib = !include }
+}
+
+// OnlyModuleLocal restricts the result to packages of the roots, leaving
+// out the standard library and other modules; false keeps both
+func OnlyModuleLocal(only bool) Option {
+	return func(o *queryOptions) { o.skipStdlib, o.skipExternal = only, only }
+}
+
+// excludes reports whether the options filter out a package of the given
+// node kind, see nodeKind
+func (o queryOptions) excludes(kind string) bool {
+	return kind == NodeStdlib && o.skipStdlib || kind == NodeExternal && o.skipExternal
+}
+
+// WithTestFiles includes _test.go files in FilesForPackage
+func WithTestFiles() Option {
+	return func(o *queryOptions) { o.testFiles = true }
+}
+
+// FindForwardDeps returns the transitive import set of pkgPath from the
+// dependency cache, sorted and without pkgPath itself: what a main pulls in
+// when it is compiled. Packages outside the module are leaves of the cached
+// graph, so their own imports are not listed.
+func (g *GoDepFind) FindForwardDeps(pkgPath string, opts ...Option) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.packageCache[pkgPath]; !ok {
+		return nil, fmt.Errorf("package not found in cache: %s", pkgPath)
+	}
+
+	options := queryOptions{maxDepth: g.maxDepth}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return g.forwardDeps(pkgPath, options), nil
+}
+
+// forwardDeps is FindForwardDeps for a cached package
+func (g *GoDepFind) forwardDeps(pkgPath string, options queryOptions) []string {
+	var deps []string
+	edges, _ := g.queryEdges(options)
+	for dep := range reachIn(edges, []string{pkgPath}, options.maxDepth) {
+		if dep == pkgPath {
+			continue
+		}
+		if options.excludes(g.nodeKind(dep)) {
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// FindImporters returns the packages importing pkgPath from the dependency
+// cache, sorted and without pkgPath itself: the reverse of FindForwardDeps.
+// WithMaxDepth(1) answers "what imports it directly", WithMaxDepth(2)
+// "within two hops"; without a depth the whole transitive set is returned.
+// Importers are always cached packages, so the stdlib and external filters
+// do not apply.
+func (g *GoDepFind) FindImporters(pkgPath string, opts ...Option) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.packageCache[pkgPath]; !ok {
+		if _, imported := g.reverseDeps[pkgPath]; !imported {
+			return nil, fmt.Errorf("package not found in cache: %s", pkgPath)
+		}
+	}
+
+	options := queryOptions{maxDepth: g.maxDepth}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	_, reverse := g.queryEdges(options)
+
+	// Breadth-first so each importer is reached at its minimum depth
+	depth := map[string]int{pkgPath: 0}
+	queue := []string{pkgPath}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if options.maxDepth > 0 && depth[current] >= options.maxDepth {
+			continue
+		}
+		for _, importer := range reverse[current] {
+			if _, seen := depth[importer]; !seen {
+				depth[importer] = depth[current] + 1
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	importers := make([]string, 0, len(depth))
+	for importer := range depth {
+		if importer != pkgPath {
+			importers = append(importers, importer)
+		}
+	}
+	sort.Strings(importers)
+	return importers, nil
+}
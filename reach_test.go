@@ -0,0 +1,183 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReachabilitySetOperations(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module multi\n\ngo 1.21\n",
+		"cmd/api/main.go":    "package main\n\nimport \"multi/db\"\n\nfunc main() { db.Open() }\n",
+		"cmd/worker/main.go": "package main\n\nimport \"multi/queue\"\n\nfunc main() { queue.Run() }\n",
+		"tools/gen/main.go":  "package main\n\nimport \"multi/codegen\"\n\nfunc main() { codegen.Gen() }\n",
+		"db/db.go":           "package db\n\nimport \"multi/shared\"\n\nfunc Open() { shared.Do() }\n",
+		"queue/queue.go":     "package queue\n\nimport \"multi/shared\"\n\nfunc Run() { shared.Do() }\n",
+		"shared/shared.go":   "package shared\n\nfunc Do() {}\n",
+		"codegen/codegen.go": "package codegen\n\nfunc Gen() {}\n",
+	})
+	finder := New(root)
+
+	reach, err := finder.ReachableFromAny([]string{"multi/cmd/..."})
+	if err != nil {
+		t.Fatalf("ReachableFromAny: %v", err)
+	}
+	want := []string{"multi/cmd/api", "multi/cmd/worker", "multi/db", "multi/queue", "multi/shared"}
+	if !reflect.DeepEqual(reach, want) {
+		t.Errorf("ReachableFromAny = %v, want %v", reach, want)
+	}
+
+	unreachable, err := finder.UnreachableFromAll([]string{"multi/cmd/api", "multi/cmd/worker"})
+	if err != nil {
+		t.Fatalf("UnreachableFromAll: %v", err)
+	}
+	if want := []string{"multi/codegen", "multi/tools/gen"}; !reflect.DeepEqual(unreachable, want) {
+		t.Errorf("UnreachableFromAll = %v, want %v", unreachable, want)
+	}
+
+	if _, err := finder.ReachableFromAny([]string{"multi/missing"}); err == nil {
+		t.Error("expected an error for an unknown root")
+	}
+}
+
+func TestUnreachablePackages(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module dead\n\ngo 1.21\n",
+		"cmd/api/main.go":    "package main\n\nimport \"dead/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":           "package db\n\nimport \"dead/shared\"\n\nfunc Open() { shared.Do() }\n",
+		"shared/shared.go":   "package shared\n\nfunc Do() {}\n",
+		"legacy/legacy.go":   "package legacy\n\nimport \"dead/oldutil\"\n\nfunc Old() { oldutil.Do() }\n",
+		"oldutil/oldutil.go": "package oldutil\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	finder.SetMaxDepth(1)
+
+	unreachable, err := finder.UnreachablePackages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dead/legacy", "dead/oldutil"}; !reflect.DeepEqual(unreachable, want) {
+		t.Errorf("UnreachablePackages = %v, want %v", unreachable, want)
+	}
+}
+
+func TestFindForwardDeps(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module fwd\n\ngo 1.21\n",
+		"app/main.go":      "package main\n\nimport (\n\t\"fmt\"\n\n\t\"fwd/db\"\n)\n\nfunc main() { fmt.Println(db.Open()) }\n",
+		"db/db.go":         "package db\n\nimport \"fwd/driver\"\n\nfunc Open() string { return driver.Name }\n",
+		"driver/driver.go": "package driver\n\nimport \"strings\"\n\nvar Name = strings.ToUpper(\"x\")\n",
+	})
+	finder := New(root)
+
+	deps, err := finder.FindForwardDeps("fwd/app", WithoutStdlib())
+	if err != nil {
+		t.Fatalf("FindForwardDeps: %v", err)
+	}
+	if want := []string{"fwd/db", "fwd/driver"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("FindForwardDeps = %v, want %v", deps, want)
+	}
+
+	deps, _ = finder.FindForwardDeps("fwd/app", WithMaxDepth(1))
+	if want := []string{"fmt", "fwd/db"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("FindForwardDeps depth 1 = %v, want %v", deps, want)
+	}
+
+	deps, _ = finder.FindForwardDeps("fwd/db")
+	if want := []string{"fwd/driver", "strings"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("FindForwardDeps(db) = %v, want %v", deps, want)
+	}
+
+	if _, err := finder.FindForwardDeps("fwd/missing"); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+}
+
+func TestFindImporters(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module rev\n\ngo 1.21\n",
+		"app/main.go":      "package main\n\nimport \"rev/db\"\n\nfunc main() { db.Open() }\n",
+		"tool/main.go":     "package main\n\nimport \"rev/driver\"\n\nfunc main() { _ = driver.Name }\n",
+		"db/db.go":         "package db\n\nimport \"rev/driver\"\n\nfunc Open() string { return driver.Name }\n",
+		"driver/driver.go": "package driver\n\nimport \"strings\"\n\nvar Name = strings.ToUpper(\"x\")\n",
+	})
+	finder := New(root)
+
+	cases := []struct {
+		depth int
+		want  []string
+	}{
+		{1, []string{"rev/db", "rev/tool"}},
+		{2, []string{"rev/app", "rev/db", "rev/tool"}},
+		{0, []string{"rev/app", "rev/db", "rev/tool"}},
+	}
+	for _, tc := range cases {
+		importers, err := finder.FindImporters("rev/driver", WithMaxDepth(tc.depth))
+		if err != nil {
+			t.Fatalf("FindImporters depth %d: %v", tc.depth, err)
+		}
+		if !reflect.DeepEqual(importers, tc.want) {
+			t.Errorf("FindImporters depth %d = %v, want %v", tc.depth, importers, tc.want)
+		}
+	}
+
+	importers, err := finder.FindImporters("strings", WithMaxDepth(1))
+	if err != nil || !reflect.DeepEqual(importers, []string{"rev/driver"}) {
+		t.Errorf("FindImporters(strings) = %v, %v", importers, err)
+	}
+	if _, err := finder.FindImporters("rev/missing"); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+}
+
+func TestStdlibFilterOptions(t *testing.T) {
+	// "shared" is another module whose name has no dot: it must not be
+	// mistaken for the standard library
+	root := writeTree(t, map[string]string{
+		"go.mod":               "module filt\n\ngo 1.21\n\nrequire shared v0.0.0\n\nreplace shared => ./_shared\n",
+		"app/main.go":          "package main\n\nimport (\n\t\"fmt\"\n\n\t\"filt/db\"\n\t\"shared/util\"\n)\n\nfunc main() { fmt.Println(db.Open(), util.X) }\n",
+		"db/db.go":             "package db\n\nimport \"net/http\"\n\nfunc Open() string { return http.MethodGet }\n",
+		"_shared/go.mod":       "module shared\n\ngo 1.21\n",
+		"_shared/util/util.go": "package util\n\nvar X = 1\n",
+	})
+	finder := New(root)
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"default", nil, []string{"filt/db", "fmt", "net/http", "shared/util"}},
+		{"without stdlib", []Option{IncludeStdlib(false)}, []string{"filt/db", "shared/util"}},
+		{"module local", []Option{OnlyModuleLocal(true)}, []string{"filt/db"}},
+		{"module local reset", []Option{OnlyModuleLocal(true), OnlyModuleLocal(false)}, []string{"filt/db", "fmt", "net/http", "shared/util"}},
+	}
+	for _, tt := range tests {
+		deps, err := finder.FindForwardDeps("filt/app", tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(deps, tt.want) {
+			t.Errorf("%s: FindForwardDeps = %v, want %v", tt.name, deps, tt.want)
+		}
+	}
+
+	reach, err := finder.ReachableFromAny([]string{"filt/app"}, OnlyModuleLocal(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"filt/app", "filt/db"}; !reflect.DeepEqual(reach, want) {
+		t.Errorf("ReachableFromAny(OnlyModuleLocal) = %v, want %v", reach, want)
+	}
+
+	var walked []string
+	if err := finder.Walk("filt/app", Forward, func(pkg string, depth int) bool {
+		walked = append(walked, pkg)
+		return true
+	}, IncludeStdlib(false)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"filt/app", "filt/db", "shared/util"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk(IncludeStdlib(false)) = %v, want %v", walked, want)
+	}
+}
@@ -0,0 +1,224 @@
+package depfind
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReconcileResult is what Reconcile found different from the cache and applied
+type ReconcileResult struct {
+	Created  []string `json:"created,omitempty"`  // absolute paths, sorted
+	Removed  []string `json:"removed,omitempty"`  // absolute paths, sorted
+	Modified []string `json:"modified,omitempty"` // absolute paths, sorted
+	Rebuilt  bool     `json:"rebuilt"`            // a package appeared or could not be reloaded: the cache was rebuilt
+}
+
+// Changed reports whether Reconcile found any difference
+func (r *ReconcileResult) Changed() bool {
+	return len(r.Created)+len(r.Removed)+len(r.Modified) > 0
+}
+
+// Reconcile walks the roots, compares their source files with what the
+// cache loaded (new, removed, and modified by modification time) and applies
+// the differences, so events a watcher dropped (inotify queue overflow) are
+// caught up without restarting. Each affected package is reloaded once; a
+// new package directory triggers a full rebuild. Files that belong to no
+// loadable package are ignored.
+func (g *GoDepFind) Reconcile() (*ReconcileResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	return g.reconcile()
+}
+
+func (g *GoDepFind) reconcile() (*ReconcileResult, error) {
+	result := &ReconcileResult{}
+	onDisk := g.sourceFileStamps()
+	affected := make(map[string]bool) // package directories to reload
+	for path, stamp := range g.fileStamps {
+		current, exists := onDisk[path]
+		switch {
+		case !exists:
+			result.Removed = append(result.Removed, path)
+		case current != stamp:
+			result.Modified = append(result.Modified, path)
+		default:
+			continue
+		}
+		affected[filepath.Dir(path)] = true
+	}
+	for path, stamp := range onDisk {
+		if _, known := g.fileStamps[path]; known {
+			continue
+		}
+		dir := filepath.Dir(path)
+		if g.packageInDir(dir) == "" {
+			if pkg, err := build.ImportDir(dir, 0); err != nil || len(pkg.GoFiles) == 0 {
+				g.fileStamps[path] = stamp // in no package, nothing to load
+				continue
+			}
+			result.Rebuilt = true
+		}
+		result.Created = append(result.Created, path)
+		affected[dir] = true
+	}
+	sort.Strings(result.Created)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Modified)
+
+	for _, paths := range [][]string{result.Created, result.Removed, result.Modified} {
+		for _, path := range paths {
+			g.forgetContent(path)
+		}
+	}
+	if !result.Rebuilt {
+		for _, dir := range sortedKeys(affected) {
+			pkgPath := g.packageInDir(dir)
+			if pkgPath == "" {
+				continue // an invalidated package, reloaded by the rebuild below
+			}
+			if err := g.reloadPackage(pkgPath, g.packageCache[pkgPath]); err != nil {
+				result.Rebuilt = true
+				break
+			}
+		}
+	}
+	if result.Rebuilt {
+		if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// InvalidateDir reloads every package under dir (absolute, or relative to
+// the primary root) in one call, for a generator rewriting a whole folder
+// without per-file events. Removed files, new files and rewritten imports
+// are picked up; a package appearing or vanishing under dir triggers one
+// full rebuild. The content memory of the files under dir is forgotten, so
+// the next write of each counts as a change (see SetSkipUnchangedWrites).
+// A dir outside the roots yields a *NotInRootError.
+func (g *GoDepFind) InvalidateDir(dir string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+	absDir := g.rootPath(dir)
+	if !g.inRoots(absDir) {
+		return &NotInRootError{Path: absDir}
+	}
+
+	for path := range g.seenContent {
+		if isUnder(path, absDir) {
+			g.forgetContent(path)
+		}
+	}
+	for path := range g.indexedContent {
+		if isUnder(path, absDir) {
+			g.forgetContent(path)
+		}
+	}
+
+	rebuild := false
+	onDisk := make(map[string]bool)
+	for path := range g.sourceFileStampsUnder([]string{absDir}) {
+		onDisk[filepath.Dir(path)] = true
+	}
+	for _, pkgDir := range sortedKeys(onDisk) {
+		if g.packageInDir(pkgDir) == "" && hasGoFiles(pkgDir) {
+			rebuild = true // a new package
+		}
+	}
+	for _, pkgPath := range sortedKeys(g.cachedPackageSet()) {
+		pkg := g.packageCache[pkgPath]
+		if rebuild || pkg == nil || !isUnder(pkg.Dir, absDir) {
+			continue
+		}
+		if !hasGoFiles(pkg.Dir) || g.reloadPackage(pkgPath, pkg) != nil {
+			rebuild = true
+		}
+	}
+	if rebuild {
+		if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceFileStamps returns the modification times of the source files the
+// roots hold in scope, skipping what is never part of the module: hidden,
+// "_" prefixed and ignored directories, excluded vendored trees, nested
+// modules and gitignored paths
+func (g *GoDepFind) sourceFileStamps() map[string]int64 {
+	return g.sourceFileStampsUnder(g.rootDirs)
+}
+
+// sourceFileStampsUnder is sourceFileStamps for the trees of dirs
+func (g *GoDepFind) sourceFileStampsUnder(dirs []string) map[string]int64 {
+	stamps := make(map[string]int64)
+	for _, root := range dirs {
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || ignoredDirNames[name] ||
+					g.isExcluded(path) || g.inNestedModule(path) || g.gitIgnored(path) || g.inToolDir(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !sourceExts[filepath.Ext(name)] || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				!g.inScope(path) || g.gitIgnored(path) {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				stamps[path] = info.ModTime().UnixNano()
+			}
+			return nil
+		})
+	}
+	return stamps
+}
+
+// stampPackage records the modification times of every file of a loaded
+// package, build-tag excluded ones included, see Reconcile
+func (g *GoDepFind) stampPackage(pkg *build.Package) {
+	if g.fileStamps == nil {
+		g.fileStamps = make(map[string]int64)
+	}
+	for _, name := range packageFileNames(pkg) {
+		path := filepath.Join(pkg.Dir, name)
+		if info, err := os.Stat(path); err == nil {
+			g.fileStamps[path] = info.ModTime().UnixNano()
+		}
+	}
+}
+
+// unstampPackage forgets the files of a package no longer loaded as is
+func (g *GoDepFind) unstampPackage(pkg *build.Package) {
+	for _, name := range packageFileNames(pkg) {
+		delete(g.fileStamps, filepath.Join(pkg.Dir, name))
+	}
+}
+
+// packageFileNames returns the names of every source file go/build found in
+// the directory of pkg
+func packageFileNames(pkg *build.Package) []string {
+	var names []string
+	for _, list := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.IgnoredGoFiles, pkg.InvalidGoFiles,
+		pkg.TestGoFiles, pkg.XTestGoFiles, pkg.SFiles, pkg.SysoFiles} {
+		names = append(names, list...)
+	}
+	return names
+}
@@ -0,0 +1,162 @@
+package depfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcileCatchesMissedEvents(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module rc\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"rc/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"lib/old.go":   "package lib\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+		"docs/x.md":    "# not source\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("lib.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	// Events the watcher never delivered
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"rc/util\"\n\nfunc Do() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(libFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	newFile := filepath.Join(root, "lib", "new.go")
+	if err := os.WriteFile(newFile, []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(root, "lib", "old.go")
+	if err := os.Remove(oldFile); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := finder.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ReconcileResult{Created: []string{newFile}, Removed: []string{oldFile}, Modified: []string{libFile}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Reconcile = %+v, want %+v", result, want)
+	}
+
+	util := filepath.Join(root, "util", "util.go")
+	if isMine, err := finder.ThisFileIsMine("app/main.go", util, EventQuery); err != nil || !isMine {
+		t.Errorf("expected the new import to be picked up, got %v, %v", isMine, err)
+	}
+	if mains, err := finder.GoFileComesFromMain("new.go"); err != nil || len(mains) != 1 {
+		t.Errorf("expected new.go to be indexed, got %v, %v", mains, err)
+	}
+	if mains, err := finder.GoFileComesFromMain("old.go"); err != nil || len(mains) != 0 {
+		t.Errorf("expected old.go to be forgotten, got %v, %v", mains, err)
+	}
+
+	// Nothing left to catch up
+	if result, err := finder.Reconcile(); err != nil || result.Changed() || result.Rebuilt {
+		t.Errorf("expected a clean second pass, got %+v, %v", result, err)
+	}
+}
+
+func TestReconcileRebuildsForNewPackage(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module rn\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	pkgFile := filepath.Join(root, "feature", "feature.go")
+	if err := os.MkdirAll(filepath.Dir(pkgFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pkgFile, []byte("package feature\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := finder.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Rebuilt || !reflect.DeepEqual(result.Created, []string{pkgFile}) {
+		t.Errorf("expected a rebuild for the new package, got %+v", result)
+	}
+	if _, ok := finder.packageCache["rn/feature"]; !ok {
+		t.Error("expected the new package to be loaded")
+	}
+}
+
+func TestInvalidateDir(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module inv\n\ngo 1.21\n",
+		"app/main.go":    "package main\n\nimport \"inv/gen\"\n\nfunc main() { gen.A() }\n",
+		"gen/a.go":       "package gen\n\nfunc A() {}\n",
+		"gen/b.go":       "package gen\n\nfunc B() {}\n",
+		"gen/sub/sub.go": "package sub\n",
+		"util/util.go":   "package util\n\nfunc U() {}\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generator rewrites gen/ without any event
+	if err := os.WriteFile(filepath.Join(root, "gen", "a.go"), []byte("package gen\n\nimport \"inv/util\"\n\nfunc A() { util.U() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "gen", "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "gen", "c.go"), []byte("package gen\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.InvalidateDir("gen"); err != nil {
+		t.Fatalf("InvalidateDir: %v", err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "util", "util.go"), EventQuery); err != nil || !isMine {
+		t.Errorf("expected util.go to be owned through the rewritten import, got %v, %v", isMine, err)
+	}
+	finder.mu.RLock()
+	indexedB := finder.filePathToPackage[filepath.Join(root, "gen", "b.go")]
+	indexedC := finder.filePathToPackage[filepath.Join(root, "gen", "c.go")]
+	finder.mu.RUnlock()
+	if indexedB != "" || indexedC != "inv/gen" {
+		t.Errorf("expected b.go dropped and c.go indexed, got %q and %q", indexedB, indexedC)
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 1 {
+		t.Errorf("expected the packages to be reloaded in place, got %d full rebuilds", rebuilds)
+	}
+
+	// A vanished package costs a rebuild
+	if err := os.RemoveAll(filepath.Join(root, "gen", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.InvalidateDir(filepath.Join(root, "gen")); err != nil {
+		t.Fatalf("InvalidateDir: %v", err)
+	}
+	finder.mu.RLock()
+	_, cached := finder.packageCache["inv/gen/sub"]
+	finder.mu.RUnlock()
+	if cached {
+		t.Error("expected inv/gen/sub to be dropped")
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 2 {
+		t.Errorf("expected one rebuild for the vanished package, got %d full rebuilds", rebuilds)
+	}
+
+	var notInRoot *NotInRootError
+	if err := finder.InvalidateDir(t.TempDir()); !errors.As(err, &notInRoot) {
+		t.Errorf("expected a *NotInRootError, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module rh\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"rh/a\"\n\nfunc main() { a.A() }\n",
+		"a/a.go":      "package a\n\nimport \"rh/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go":      "package b\n\nfunc B() {}\n",
+		"c/c.go":      "package c\n\nimport \"rh/d\"\n\nfunc C() { d.D() }\n",
+		"d/d.go":      "package d\n\nfunc D() {}\n",
+	})
+	finder := New(root)
+	cFile := filepath.Join(root, "c", "c.go")
+
+	if isMine, err := finder.ThisFileIsMine("app/main.go", cFile, EventCheck); err != nil || isMine {
+		t.Fatalf("expected c NOT to be owned before the refresh, got %v, %v", isMine, err)
+	}
+	finder.mu.RLock()
+	rebuilt := finder.lastRebuild
+	finder.mu.RUnlock()
+
+	mainSrc := "package main\n\nimport \"rh/c\"\n\nfunc main() { c.C() }\n"
+	if err := os.WriteFile(filepath.Join(root, "app", "main.go"), []byte(mainSrc), 0644); err != nil {
+		t.Fatalf("write main: %v", err)
+	}
+	diff, err := finder.RefreshHandler("app/main.go")
+	if err != nil {
+		t.Fatalf("RefreshHandler: %v", err)
+	}
+	want := HandlerDiff{Gained: []string{"rh/c", "rh/d"}, Lost: []string{"rh/a", "rh/b"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("RefreshHandler = %+v, want %+v", diff, want)
+	}
+	if !diff.Changed() {
+		t.Error("expected the diff to report a change")
+	}
+
+	finder.mu.RLock()
+	if !finder.lastRebuild.Equal(rebuilt) {
+		t.Error("expected no full rebuild")
+	}
+	finder.mu.RUnlock()
+	if isMine, err := finder.ThisFileIsMine("app/main.go", cFile, EventCheck); err != nil || !isMine {
+		t.Errorf("expected c to be owned after the refresh, got %v, %v", isMine, err)
+	}
+
+	diff, err = finder.RefreshHandler("app/main.go")
+	if err != nil || diff.Changed() {
+		t.Errorf("expected an unchanged main to produce an empty diff, got %+v, %v", diff, err)
+	}
+	if _, err := finder.RefreshHandler("missing/main.go"); err == nil {
+		t.Error("expected an error for a missing handler")
+	}
+}
@@ -0,0 +1,96 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemovedFileOwnershipFromLastKnownState(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module rm\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"rm/db\"\n\nfunc main() { db.Open() }\n",
+		"other/main.go": "package main\n\nfunc main() {}\n",
+		"db/db.go":      "package db\n\nimport \"rm/driver\"\n\nfunc Open() { driver.Load() }\n",
+		"db/legacy.go":  "package db\n",
+		"driver/d.go":   "package driver\n\nfunc Load() {}\n",
+	})
+	finder := New(root)
+	legacy := filepath.Join(root, "db", "legacy.go")
+
+	// Prime the cache while the file exists
+	if _, err := finder.ThisFileIsMine("app/main.go", legacy, "write"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+	if err := os.Remove(legacy); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	isMine, err := finder.ThisFileIsMine("other/main.go", legacy, "remove")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine other: %v", err)
+	}
+	if isMine {
+		t.Error("expected other/main.go NOT to own the removed file")
+	}
+
+	isMine, err = finder.ThisFileIsMine("app/main.go", legacy, "remove")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine app: %v", err)
+	}
+	if !isMine {
+		t.Error("expected app/main.go to be notified about the removed file")
+	}
+
+	// The package survives without the file, keeping its edges
+	isMine, err = finder.ThisFileIsMine("app/main.go", filepath.Join(root, "driver", "d.go"), "write")
+	if err != nil || !isMine {
+		t.Errorf("expected driver to stay owned after removal, got %v, %v", isMine, err)
+	}
+	if _, indexed := finder.filePathToPackage[legacy]; indexed {
+		t.Error("expected removed file to be purged from the index")
+	}
+}
+
+func TestRemovedFileOwnerAskedFirst(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module rm\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"rm/db\"\n\nfunc main() { db.Open() }\n",
+		"other/main.go": "package main\n\nfunc main() {}\n",
+		"db/db.go":      "package db\n\nfunc Open() {}\n",
+		"db/legacy.go":  "package db\n",
+	})
+	finder := New(root)
+	legacy := filepath.Join(root, "db", "legacy.go")
+	if _, err := finder.ThisFileIsMine("app/main.go", legacy, EventWrite); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+	if err := os.Remove(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	// The owner purges the index first; the others still answer from the
+	// last-known package instead of the path fallback
+	for _, tc := range []struct {
+		handler string
+		want    bool
+	}{{"app/main.go", true}, {"other/main.go", false}, {"app/main.go", true}} {
+		if isMine, err := finder.ThisFileIsMine(tc.handler, legacy, EventRemove); err != nil || isMine != tc.want {
+			t.Errorf("%s: expected %v, got %v, %v", tc.handler, tc.want, isMine, err)
+		}
+	}
+	if isMine, err := finder.ThisFileIsMine("other/main.go", legacy, EventQuery); err != nil || isMine {
+		t.Errorf("expected a query on the removed file to keep its owner, got %v, %v", isMine, err)
+	}
+
+	// Once the file is back it is indexed again
+	if err := os.WriteFile(legacy, []byte("package db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", legacy, EventCreate); err != nil || !isMine {
+		t.Errorf("expected the recreated file to be owned, got %v, %v", isMine, err)
+	}
+	if _, removed := finder.removedFiles[legacy]; removed {
+		t.Error("expected the tombstone to be dropped once the file exists again")
+	}
+}
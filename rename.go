@@ -0,0 +1,269 @@
+package depfind
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PackageRenamed describes a package whose directory was moved, e.g.
+// modules/database -> modules/db. The graph is updated in place so ownership
+// keeps working without a full rescan.
+type PackageRenamed struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	OldDir  string `json:"old_dir"`
+	NewDir  string `json:"new_dir"`
+}
+
+// OnPackageRenamed registers a callback invoked for each package detected as
+// renamed, either through RenameDir or automatically when a create event hits
+// a directory holding the same files as a package whose directory vanished.
+// The callback runs while the finder lock is held and must not call back into
+// the finder.
+func (g *GoDepFind) OnPackageRenamed(fn func(PackageRenamed)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.onPackageRenamed = fn
+}
+
+// RenameDir tells the finder that oldDir was renamed to newDir. Every cached
+// package in oldDir or below is moved to its new import path in place.
+func (g *GoDepFind) RenameDir(oldDir, newDir string) ([]PackageRenamed, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	return g.renamePackageDir(g.rootPath(oldDir), g.rootPath(newDir))
+}
+
+// RenameFile tells the finder that the file oldPath was renamed to newPath,
+// for watchers reporting both names of a rename. The path and file name
+// indices drop the old name and learn the new one in the same step, whether
+// the file stayed in its package or moved to another one.
+func (g *GoDepFind) RenameFile(oldPath, newPath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+	oldAbs, newAbs := g.rootPath(oldPath), g.rootPath(newPath)
+	g.forgetContent(oldAbs)
+	g.forgetContent(newAbs) // the rename may have replaced an existing file
+
+	oldPkg := g.filePathToPackage[oldAbs]
+	if oldPkg != "" {
+		if err := g.handleFileRemove(oldAbs); err != nil {
+			return err
+		}
+	}
+	// Moved within its package: removing the old name reloaded the package,
+	// which indexed the new one
+	if oldPkg != "" && g.filePathToPackage[newAbs] == oldPkg {
+		return nil
+	}
+	if pkgPath := g.packageInDir(filepath.Dir(newAbs)); pkgPath != "" {
+		return g.reloadPackage(pkgPath, g.packageCache[pkgPath])
+	}
+	return g.handleFileCreate(newAbs)
+}
+
+// detectPackageRename checks whether dir holds exactly the files of a cached
+// package whose directory no longer exists, and if so applies the rename.
+// When several vanished packages hold the same file names, the one with the
+// same package name and imports is picked; a rename still ambiguous after
+// that is not applied, the directory is then loaded as a new package.
+func (g *GoDepFind) detectPackageRename(dir string) (bool, error) {
+	for _, pkg := range g.packageCache {
+		if pkg != nil && pkg.Dir == dir {
+			return false, nil // already known
+		}
+	}
+
+	newPkg, err := build.ImportDir(dir, 0)
+	if err != nil || len(newPkg.GoFiles) == 0 {
+		return false, nil
+	}
+
+	var candidates []*build.Package
+	for _, pkg := range g.packageCache {
+		if pkg == nil {
+			continue
+		}
+		if _, err := os.Stat(pkg.Dir); err == nil {
+			continue // old directory still present, not a rename
+		}
+		if sameFileSet(pkg.GoFiles, newPkg.GoFiles) {
+			candidates = append(candidates, pkg)
+		}
+	}
+	if len(candidates) > 1 {
+		candidates = slices.DeleteFunc(candidates, func(pkg *build.Package) bool {
+			return pkg.Name != newPkg.Name || !sameFileSet(pkg.Imports, newPkg.Imports)
+		})
+	}
+	if len(candidates) != 1 {
+		return false, nil // no vanished package, or no way to tell which one moved
+	}
+	renames, err := g.renamePackageDir(candidates[0].Dir, dir)
+	return len(renames) > 0, err
+}
+
+// renamePackageDir moves all cached packages under oldDir to newDir
+func (g *GoDepFind) renamePackageDir(oldDir, newDir string) ([]PackageRenamed, error) {
+	renamed := make(map[string]string) // old import path -> new import path
+	var events []PackageRenamed
+
+	for pkgPath, pkg := range g.packageCache {
+		if pkg == nil || (pkg.Dir != oldDir && !strings.HasPrefix(pkg.Dir, oldDir+string(filepath.Separator))) {
+			continue
+		}
+		rel, err := filepath.Rel(oldDir, pkg.Dir)
+		if err != nil {
+			continue
+		}
+		newPkgDir := filepath.Join(newDir, rel)
+		newPath, err := g.movedImportPath(pkgPath, pkg.Dir, newPkgDir)
+		if err != nil {
+			return nil, err
+		}
+		renamed[pkgPath] = newPath
+		events = append(events, PackageRenamed{OldPath: pkgPath, NewPath: newPath, OldDir: pkg.Dir, NewDir: newPkgDir})
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	// Re-import from the new location before touching the graph
+	newPackages := make(map[string]*build.Package)
+	for _, ev := range events {
+		pkg, err := g.importPackageFromDir(ev.NewDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import renamed package %s: %w", ev.NewPath, err)
+		}
+		for i, imp := range pkg.Imports {
+			if newPath, ok := renamed[imp]; ok {
+				pkg.Imports[i] = newPath
+			}
+		}
+		newPackages[ev.NewPath] = pkg
+	}
+
+	rename := func(paths []string) []string {
+		for i, p := range paths {
+			if newPath, ok := renamed[p]; ok {
+				paths[i] = newPath
+			}
+		}
+		return paths
+	}
+
+	oldPackages := make(map[string]*build.Package, len(events))
+	for _, ev := range events {
+		oldPackages[ev.OldPath] = g.packageCache[ev.OldPath]
+	}
+
+	g.graphChanged()
+	for _, ev := range events {
+		g.unstampPackage(g.packageCache[ev.OldPath])
+		g.stampPackage(newPackages[ev.NewPath])
+		delete(g.packageCache, ev.OldPath)
+		g.packageCache[ev.NewPath] = newPackages[ev.NewPath]
+
+		if deps, ok := g.dependencyGraph[ev.OldPath]; ok {
+			delete(g.dependencyGraph, ev.OldPath)
+			g.dependencyGraph[ev.NewPath] = deps
+		}
+		if deps, ok := g.reverseDeps[ev.OldPath]; ok {
+			delete(g.reverseDeps, ev.OldPath)
+			g.reverseDeps[ev.NewPath] = deps
+		}
+		g.setPackageDir(ev.NewPath, ev.NewDir)
+	}
+
+	// Rewrite edges pointing at renamed packages
+	for pkgPath, deps := range g.dependencyGraph {
+		g.dependencyGraph[pkgPath] = rename(deps)
+	}
+	for pkgPath, deps := range g.reverseDeps {
+		g.reverseDeps[pkgPath] = rename(deps)
+	}
+	g.mainPackages = rename(g.mainPackages)
+
+	// Importers not yet rewritten still name the old path in their sources
+	if g.renamedPackages == nil {
+		g.renamedPackages = make(map[string]string)
+	}
+	for oldPath, newPath := range renamed {
+		g.renamedPackages[oldPath] = newPath
+	}
+	for fileName, pkgs := range g.fileToPackages {
+		g.fileToPackages[fileName] = rename(pkgs)
+	}
+
+	// Move file path mappings: every indexed file, assembly, variant and
+	// test file included, as a reload does
+	for filePath, pkgPath := range g.filePathToPackage {
+		if _, ok := renamed[pkgPath]; ok {
+			delete(g.filePathToPackage, filePath)
+			delete(g.testOnlyFiles, filePath)
+		}
+	}
+	for _, ev := range events {
+		for absPath := range g.indexedFiles(oldPackages[ev.OldPath]) {
+			delete(g.filePathToPackage, absPath)
+			delete(g.testOnlyFiles, absPath)
+		}
+		for absPath, testOnly := range g.indexedFiles(newPackages[ev.NewPath]) {
+			g.indexFile(absPath, ev.NewPath, testOnly)
+		}
+	}
+
+	if g.onPackageRenamed != nil {
+		for _, ev := range events {
+			g.onPackageRenamed(ev)
+		}
+	}
+	return events, nil
+}
+
+// movedImportPath derives the import path of a package moved from oldDir to
+// newDir by swapping the directory suffix of its current import path
+func (g *GoDepFind) movedImportPath(pkgPath, oldDir, newDir string) (string, error) {
+	for _, root := range g.rootDirs {
+		oldRel, err1 := filepath.Rel(root, oldDir)
+		newRel, err2 := filepath.Rel(root, newDir)
+		if err1 != nil || err2 != nil || strings.HasPrefix(oldRel, "..") || strings.HasPrefix(newRel, "..") {
+			continue
+		}
+		oldRel, newRel = filepath.ToSlash(oldRel), filepath.ToSlash(newRel)
+		if strings.HasSuffix(pkgPath, "/"+oldRel) {
+			return strings.TrimSuffix(pkgPath, oldRel) + newRel, nil
+		}
+	}
+	return "", fmt.Errorf("cannot derive new import path for %s moved to %s", pkgPath, newDir)
+}
+
+// sameFileSet reports whether two file name lists hold the same names
+func sameFileSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, f := range a {
+		set[f] = true
+	}
+	for _, f := range b {
+		if !set[f] {
+			return false
+		}
+	}
+	return true
+}
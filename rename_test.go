@@ -0,0 +1,265 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageRenameDetectedOnCreate(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                    "module testmod\n\ngo 1.21\n",
+		"app/main.go":               "package main\n\nimport \"testmod/modules/database\"\n\nfunc main() { database.Ping() }\n",
+		"modules/database/db.go":    "package database\n\nfunc Ping() {}\n",
+		"modules/database/query.go": "package database\n",
+	})
+	finder := New(root)
+
+	var events []PackageRenamed
+	finder.OnPackageRenamed(func(ev PackageRenamed) {
+		events = append(events, ev)
+	})
+
+	// Prime the cache with the original layout
+	if _, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "modules", "database", "db.go"), "write"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	if err := os.Rename(filepath.Join(root, "modules", "database"), filepath.Join(root, "modules", "db")); err != nil {
+		t.Fatalf("rename dir: %v", err)
+	}
+
+	newFile := filepath.Join(root, "modules", "db", "db.go")
+	isMine, err := finder.ThisFileIsMine("app/main.go", newFile, "create")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine after rename: %v", err)
+	}
+	if !isMine {
+		t.Error("expected renamed package to stay owned by app/main.go")
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 rename event, got %d: %v", len(events), events)
+	}
+	if events[0].OldPath != "testmod/modules/database" || events[0].NewPath != "testmod/modules/db" {
+		t.Errorf("unexpected rename event: %+v", events[0])
+	}
+
+	mains, err := finder.GoFileComesFromMain("query.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "testmod/app" {
+		t.Errorf("expected [testmod/app], got %v", mains)
+	}
+}
+
+func TestRenameDirExplicit(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":              "module testmod\n\ngo 1.21\n",
+		"app/main.go":         "package main\n\nimport \"testmod/lib/util\"\n\nfunc main() { util.Do() }\n",
+		"lib/util/util.go":    "package util\n\nimport \"testmod/lib/util/inner\"\n\nfunc Do() { inner.Do() }\n",
+		"lib/util/inner/i.go": "package inner\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("util.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	if err := os.Rename(filepath.Join(root, "lib"), filepath.Join(root, "pkg")); err != nil {
+		t.Fatalf("rename dir: %v", err)
+	}
+	events, err := finder.RenameDir("lib", "pkg")
+	if err != nil {
+		t.Fatalf("RenameDir: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected nested packages to be renamed too, got %v", events)
+	}
+
+	mains, err := finder.GoFileComesFromMain("i.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 {
+		t.Errorf("expected transitive edge to survive rename, got %v", mains)
+	}
+}
+
+func TestRenameDirIndexesEveryFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module rd\n\ngo 1.21\n",
+		"app/main.go":        "package main\n\nimport \"rd/lib/db\"\n\nfunc main() { db.Open() }\n",
+		"lib/db/db.go":       "package db\n\nfunc Open() {}\n",
+		"lib/db/db_amd64.s":  "#include \"textflag.h\"\n",
+		"lib/db/ext_test.go": "package db_test\n\nimport \"rd/lib/db\"\n\nvar _ = db.Open\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("db.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	oldDir, newDir := filepath.Join(root, "lib", "db"), filepath.Join(root, "lib", "store")
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatalf("rename dir: %v", err)
+	}
+	if _, err := finder.RenameDir("lib/db", "lib/store"); err != nil {
+		t.Fatalf("RenameDir: %v", err)
+	}
+
+	for _, name := range []string{"db.go", "db_amd64.s", "ext_test.go"} {
+		if got := finder.filePathToPackage[filepath.Join(newDir, name)]; got != "rd/lib/store" {
+			t.Errorf("%s mapped to %q after rename, want rd/lib/store", name, got)
+		}
+		if pkg, ok := finder.filePathToPackage[filepath.Join(oldDir, name)]; ok {
+			t.Errorf("old path of %s still mapped to %q", name, pkg)
+		}
+	}
+	if !finder.testOnlyFiles[filepath.Join(newDir, "ext_test.go")] {
+		t.Error("renamed external test file not marked test-only")
+	}
+	if finder.testOnlyFiles[filepath.Join(oldDir, "ext_test.go")] {
+		t.Error("old external test file still marked test-only")
+	}
+}
+
+func TestFileRenameKeepsFilenameIndex(t *testing.T) {
+	for _, reported := range []string{"old", "new"} {
+		t.Run(reported, func(t *testing.T) {
+			root := writeTree(t, map[string]string{
+				"go.mod":      "module rn\n\ngo 1.21\n",
+				"app/main.go": "package main\n\nimport \"rn/lib\"\n\nfunc main() { lib.Do() }\n",
+				"lib/a.go":    "package lib\n\nfunc Do() {}\n",
+				"lib/c.go":    "package lib\n",
+			})
+			finder := New(root)
+			oldFile, newFile := filepath.Join(root, "lib", "a.go"), filepath.Join(root, "lib", "b.go")
+			if _, err := finder.GoFileComesFromMain("a.go"); err != nil {
+				t.Fatalf("prime cache: %v", err)
+			}
+
+			if err := os.Rename(oldFile, newFile); err != nil {
+				t.Fatal(err)
+			}
+			// A single rename event, on whichever name the watcher reports
+			path := oldFile
+			if reported == "new" {
+				path = newFile
+			}
+			if isMine, err := finder.ThisFileIsMine("app/main.go", path, EventRename); err != nil || !isMine {
+				t.Fatalf("expected the renamed file to be owned, got %v, %v", isMine, err)
+			}
+
+			if mains, err := finder.GoFileComesFromMain("b.go"); err != nil || len(mains) != 1 || mains[0] != "rn/app" {
+				t.Errorf("expected b.go to resolve to [rn/app], got %v, %v", mains, err)
+			}
+			if mains, err := finder.GoFileComesFromMain("a.go"); err != nil || len(mains) != 0 {
+				t.Errorf("expected a.go to be forgotten, got %v, %v", mains, err)
+			}
+			if _, ok := finder.fileToPackages["a.go"]; ok {
+				t.Errorf("expected no filename entry left for a.go, got %v", finder.fileToPackages["a.go"])
+			}
+			if finder.filePathToPackage[oldFile] != "" || finder.filePathToPackage[newFile] != "rn/lib" {
+				t.Errorf("unexpected path index: %v", finder.filePathToPackage)
+			}
+		})
+	}
+}
+
+func TestRenameFileAcrossPackages(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module rf\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"rf/lib\"\n\nfunc main() { lib.Do() }\n",
+		"cli/main.go":  "package main\n\nimport \"rf/util\"\n\nfunc main() { util.U() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() {}\n",
+		"lib/extra.go": "package lib\n\nfunc Extra() {}\n",
+		"util/util.go": "package util\n\nfunc U() {}\n",
+	})
+	finder := New(root)
+	if _, err := finder.GoFileComesFromMain("extra.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	oldFile, newFile := filepath.Join(root, "lib", "extra.go"), filepath.Join(root, "util", "more.go")
+	if err := os.WriteFile(newFile, []byte("package util\n\nfunc Extra() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(oldFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.RenameFile("lib/extra.go", "util/more.go"); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+
+	if mains, err := finder.GoFileComesFromMain("more.go"); err != nil || len(mains) != 1 || mains[0] != "rf/cli" {
+		t.Errorf("expected more.go to resolve to [rf/cli], got %v, %v", mains, err)
+	}
+	if _, ok := finder.fileToPackages["extra.go"]; ok {
+		t.Errorf("expected extra.go to be forgotten, got %v", finder.fileToPackages["extra.go"])
+	}
+	if isMine, err := finder.ThisFileIsMine("cli/main.go", newFile, EventQuery); err != nil || !isMine {
+		t.Errorf("expected cli to own the moved file, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", newFile, EventQuery); err != nil || isMine {
+		t.Errorf("expected app not to own the moved file, got %v, %v", isMine, err)
+	}
+}
+
+func TestPackageRenameAmbiguous(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module testmod\n\ngo 1.21\n",
+		"app/main.go":      "package main\n\nimport (\n\t\"testmod/a/util\"\n\t\"testmod/b/util\"\n)\n\nfunc main() {}\n",
+		"a/util/util.go":   "package util\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n",
+		"b/util/util.go":   "package util\n",
+		"c/util/util.go":   "package util\n",
+		"d/helper/util.go": "package util\n",
+	})
+	finder := New(root)
+	var events []PackageRenamed
+	finder.OnPackageRenamed(func(ev PackageRenamed) {
+		events = append(events, ev)
+	})
+	if _, err := finder.GoFileComesFromMain("util.go"); err != nil {
+		t.Fatalf("prime cache: %v", err)
+	}
+
+	// Both util packages vanish; the imports tell which one moved
+	for _, dir := range []string{"a", "b"} {
+		if err := os.RemoveAll(filepath.Join(root, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := filepath.Join(root, "x", "util", "util.go")
+	if err := os.MkdirAll(filepath.Dir(moved), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(moved, []byte("package util\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", moved, EventCreate); err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if len(events) != 1 || events[0].OldPath != "testmod/a/util" || events[0].NewPath != "testmod/x/util" {
+		t.Fatalf("expected a/util to be renamed to x/util, got %+v", events)
+	}
+
+	// b/util, c/util and d/helper then vanish with the same files: no rename
+	for _, dir := range []string{"c", "d"} {
+		if err := os.RemoveAll(filepath.Join(root, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copied := filepath.Join(root, "y", "util", "util.go")
+	if err := os.MkdirAll(filepath.Dir(copied), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(copied, []byte("package util\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", copied, EventCreate); err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected an ambiguous move not to be reported as a rename, got %+v", events)
+	}
+}
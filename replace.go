@@ -0,0 +1,120 @@
+package depfind
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LocalReplace is a go.mod replace directive pointing at a local directory
+type LocalReplace struct {
+	Path string `json:"path"` // replaced module path, e.g. "example.com/lib"
+	Dir  string `json:"dir"`  // absolute directory of the local copy
+}
+
+// AddReplaceRoots registers as extra roots the local directories that the
+// primary module's go.mod replaces modules with (replace example.com/lib =>
+// ../lib), so the library joins the graph: saving a library file rebuilds the
+// app mains importing it. Directories without a go.mod are ignored. It
+// returns the replaces whose directories were added.
+func (g *GoDepFind) AddReplaceRoots() ([]LocalReplace, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil, ErrClosed
+	}
+	if len(g.rootDirs) == 0 {
+		return nil, fmt.Errorf("no root directory configured")
+	}
+	modRoot := findModuleRoot(g.rootDirs[0])
+	if modRoot == "" {
+		return nil, fmt.Errorf("%w in %s", ErrNoModule, g.rootDirs[0])
+	}
+	replaces, err := localReplaces(filepath.Join(modRoot, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	var added []LocalReplace
+	for _, r := range replaces {
+		if _, err := os.Stat(filepath.Join(r.Dir, "go.mod")); err != nil {
+			continue
+		}
+		if contains(g.rootDirs, r.Dir) {
+			continue
+		}
+		g.rootDirs = append(g.rootDirs, r.Dir)
+		added = append(added, r)
+	}
+	if len(added) > 0 {
+		g.cachedModule = false // the new roots must be listed
+	}
+	return added, nil
+}
+
+// localReplaces parses the replace directives of a go.mod file whose target
+// is a local directory, resolving it against the go.mod directory
+func localReplaces(goModPath string) ([]LocalReplace, error) {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read go.mod: %w", err)
+	}
+	defer file.Close()
+
+	var replaces []LocalReplace
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		switch {
+		case line == "replace (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case !inBlock:
+			rest, ok := strings.CutPrefix(line, "replace ")
+			if !ok {
+				continue
+			}
+			line = rest
+		}
+
+		oldSide, newSide, ok := strings.Cut(line, "=>")
+		if !ok {
+			continue
+		}
+		oldFields, newFields := strings.Fields(oldSide), strings.Fields(newSide)
+		if len(oldFields) == 0 || len(newFields) != 1 {
+			continue // a module version on the right means a remote replacement
+		}
+		target := unquoteModField(newFields[0])
+		if !filepath.IsAbs(target) && !strings.HasPrefix(target, "./") && !strings.HasPrefix(target, "../") {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(goModPath), target)
+		}
+		replaces = append(replaces, LocalReplace{Path: unquoteModField(oldFields[0]), Dir: filepath.Clean(target)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read go.mod: %w", err)
+	}
+	return replaces, nil
+}
+
+// unquoteModField removes the optional quotes around a go.mod token
+func unquoteModField(field string) string {
+	if unquoted, err := strconv.Unquote(field); err == nil {
+		return unquoted
+	}
+	return field
+}
@@ -0,0 +1,216 @@
+package depfind
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SafeToDelete reports whether removing pathOrPkg (a Go file, a package
+// directory or an import path) would leave every main package compiling.
+// When it would not, the packages that would break and are built into a
+// main are returned, sorted. Removing a package breaks its importers;
+// removing one file of a package breaks the files referring to its
+// top-level declarations (uses through methods are not tracked). Paths
+// unknown to the graph are safe to delete.
+func (g *GoDepFind) SafeToDelete(pathOrPkg string) (bool, []string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return false, nil, err
+	}
+
+	var dependents []string
+	if _, ok := g.packageCache[pathOrPkg]; ok {
+		dependents = g.packageImporters(pathOrPkg)
+	} else {
+		absPath := g.rootPath(pathOrPkg)
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return false, nil, err
+		}
+		if info.IsDir() {
+			if pkgPath := g.packageInDir(absPath); pkgPath != "" {
+				dependents = g.packageImporters(pkgPath)
+			}
+		} else if pkgPath := g.filePathToPackage[absPath]; pkgPath != "" {
+			dependents = g.fileDependents(absPath, pkgPath)
+		}
+	}
+
+	var breaking []string
+	for _, pkgPath := range dependents {
+		built, err := g.builtIntoMain(pkgPath)
+		if err != nil {
+			return false, nil, err
+		}
+		if built {
+			breaking = append(breaking, pkgPath)
+		}
+	}
+	sort.Strings(breaking)
+	return len(breaking) == 0, breaking, nil
+}
+
+// packageInDir returns the cached package located in dir, or ""
+func (g *GoDepFind) packageInDir(dir string) string {
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil && pkg.Dir == dir {
+			return pkgPath
+		}
+	}
+	return ""
+}
+
+// packageImporters returns the packages importing pkgPath, deduplicated
+func (g *GoDepFind) packageImporters(pkgPath string) []string {
+	var importers []string
+	for _, importer := range g.reverseDeps[pkgPath] {
+		if !contains(importers, importer) {
+			importers = append(importers, importer)
+		}
+	}
+	return importers
+}
+
+// builtIntoMain reports whether pkgPath is a main package or one imported by a main
+func (g *GoDepFind) builtIntoMain(pkgPath string) (bool, error) {
+	if g.isMainPackage(pkgPath) {
+		return true, nil
+	}
+	for _, mainPkg := range g.mainPackages {
+		if imports, err := g.cachedMainImportsPackage(mainPkg, pkgPath); err != nil || imports {
+			return imports, err
+		}
+	}
+	return false, nil
+}
+
+// fileDependents returns the packages referring to a top-level declaration
+// of filePath: its own package through the other files, and importers
+// through qualified (or dot-imported) exported names. When filePath is the
+// only file of its package, removing it removes the package.
+func (g *GoDepFind) fileDependents(filePath, pkgPath string) []string {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return nil
+	}
+	var siblings []string
+	for _, name := range buildFiles(pkg) {
+		if other := filepath.Join(pkg.Dir, name); other != filePath && filepath.Ext(name) == ".go" {
+			siblings = append(siblings, other)
+		}
+	}
+	if len(siblings) == 0 {
+		return g.packageImporters(pkgPath)
+	}
+
+	declared := topLevelNames(filePath)
+	if len(declared) == 0 {
+		return nil
+	}
+
+	var dependents []string
+	for _, sibling := range siblings {
+		if refersTo(sibling, func(qualifier, name string) bool { return qualifier == "" && declared[name] }) {
+			dependents = append(dependents, pkgPath)
+			break
+		}
+	}
+
+	for _, importer := range g.packageImporters(pkgPath) {
+		importerPkg := g.packageCache[importer]
+		if importerPkg == nil {
+			continue
+		}
+		for _, name := range importerPkg.GoFiles {
+			file := filepath.Join(importerPkg.Dir, name)
+			specs, err := g.parseFileImportSpecs(file)
+			if err != nil {
+				continue
+			}
+			for i := range specs {
+				specs[i].Path = g.resolveImport(specs[i].Path, importerPkg.Dir)
+			}
+			uses := func(qualifier, name string) bool {
+				return ast.IsExported(name) && declared[name] && contains(g.qualifierPackages(specs, qualifier), pkgPath)
+			}
+			if refersTo(file, uses) {
+				dependents = append(dependents, importer)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// topLevelNames returns the package-level identifiers declared in a Go file
+// (functions, types, variables and constants; methods are not included)
+func topLevelNames(filePath string) map[string]bool {
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name != "init" && d.Name.Name != "_" {
+				names[d.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names[s.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						if ident.Name != "_" {
+							names[ident.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// refersTo reports whether a Go file uses an identifier accepted by match.
+// Qualified identifiers (db.Open) are matched with their qualifier, bare
+// identifiers with an empty one.
+func refersTo(filePath string, match func(qualifier, name string) bool) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return false
+	}
+	found := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := node.X.(*ast.Ident); ok && match(x.Name, node.Sel.Name) {
+				found = true
+			}
+			ast.Inspect(node.X, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok && match("", ident.Name) {
+					found = true
+				}
+				return !found
+			})
+			return false // the selected name is a field or method, not a package-level identifier
+		case *ast.Ident:
+			if match("", node.Name) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
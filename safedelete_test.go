@@ -0,0 +1,48 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSafeToDelete(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module del\n\ngo 1.21\n",
+		"app/main.go":    "package main\n\nimport \"del/lib\"\n\nfunc main() { lib.Open() }\n",
+		"lib/open.go":    "package lib\n\nfunc Open() { helper() }\n",
+		"lib/helper.go":  "package lib\n\nfunc helper() {}\n",
+		"lib/unused.go":  "package lib\n\nfunc Unused() {}\n",
+		"dead/dead.go":   "package dead\n\nimport \"del/util\"\n\nfunc X() { util.Y() }\n",
+		"util/util.go":   "package util\n\nfunc Y() {}\n",
+		"orphan/orph.go": "package orphan\n",
+	})
+	finder := New(root)
+
+	cases := []struct {
+		target     string
+		safe       bool
+		dependents []string
+	}{
+		{"del/lib", false, []string{"del/app"}},
+		{"lib", false, []string{"del/app"}},
+		{"lib/open.go", false, []string{"del/app"}},
+		{"lib/helper.go", false, []string{"del/lib"}},
+		{"lib/unused.go", true, nil},
+		{"util/util.go", true, nil}, // only imported by a package no main uses
+		{"orphan", true, nil},
+		{"app/main.go", true, nil},
+	}
+	for _, tc := range cases {
+		safe, dependents, err := finder.SafeToDelete(tc.target)
+		if err != nil {
+			t.Fatalf("SafeToDelete(%s): %v", tc.target, err)
+		}
+		if safe != tc.safe || !reflect.DeepEqual(dependents, tc.dependents) {
+			t.Errorf("SafeToDelete(%s) = %v, %v; want %v, %v", tc.target, safe, dependents, tc.safe, tc.dependents)
+		}
+	}
+
+	if _, _, err := finder.SafeToDelete("missing/file.go"); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
@@ -0,0 +1,228 @@
+package depfind
+
+import (
+	"fmt"
+	"go/build"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ScopedFinder returns a new finder restricted to packages under subdir
+// (relative to the primary root), e.g. "services/foo". It shares the
+// configuration of g, but for its cache file and watchdog, and keeps its own
+// cache, which only loads the scoped packages. Relative patterns such as "./..." are interpreted inside the
+// scope, and files outside it are never owned by scoped handlers.
+func (g *GoDepFind) ScopedFinder(subdir string) *GoDepFind {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	scope := filepath.ToSlash(filepath.Clean(subdir))
+	scope = strings.TrimPrefix(scope, "./")
+	if scope == "." {
+		scope = ""
+	}
+	if g.scope != "" && scope != "" {
+		scope = g.scope + "/" + scope
+	} else if scope == "" {
+		scope = g.scope
+	}
+
+	finder := &GoDepFind{
+		rootDirs:          append([]string(nil), g.rootDirs...),
+		scope:             scope,
+		packageCache:      make(map[string]*build.Package),
+		dependencyGraph:   make(map[string][]string),
+		reverseDeps:       make(map[string][]string),
+		filePathToPackage: make(map[string]string),
+		testOnlyFiles:     make(map[string]bool),
+		fileToPackages:    make(map[string][]string),
+		mainPackages:      []string{},
+	}
+	g.inheritConfig(finder, true)
+	return finder
+}
+
+// inheritConfig copies the configuration of g into a finder derived from it
+// (ScopedFinder, ModuleFinder). sameRoots is false when the finder has
+// other roots: settings keyed by paths relative to the primary root
+// (per-handler settings, overrides, virtual handlers and scope patterns)
+// would not mean the same files there and are left out. The journal is
+// reopened so each finder closes its own handle. The cache file and the
+// watchdog are not inherited: each finder lists different packages.
+func (g *GoDepFind) inheritConfig(finder *GoDepFind, sameRoots bool) {
+	finder.testImports = g.testImports
+	finder.maxDepth = g.maxDepth
+	finder.filenameFallback = g.filenameFallback
+	finder.fsErrorPolicy = g.fsErrorPolicy
+	finder.modulePath = g.modulePath
+	finder.toolchainErr = g.toolchainErr
+	finder.scannerFallback = g.scannerFallback
+	finder.logger = g.logger
+	finder.onPackageRenamed = g.onPackageRenamed
+	finder.rebuildInterval = g.rebuildInterval
+	finder.skipUnchanged = g.skipUnchanged
+	finder.eventWindow = g.eventWindow
+	finder.respectGitignore = g.respectGitignore
+	finder.keepToolDirs = g.keepToolDirs
+	finder.externalDirs = slices.Clone(g.externalDirs)
+	finder.changeProvider = g.changeProvider
+	finder.allowUnchecked = g.allowUnchecked
+	finder.cacheStrategy = g.cacheStrategy
+	finder.lazyThreshold = g.lazyThreshold
+
+	if g.virtualEdges != nil {
+		finder.virtualEdges = make(map[string]map[string]string, len(g.virtualEdges))
+		for from, targets := range g.virtualEdges {
+			finder.virtualEdges[from] = maps.Clone(targets)
+		}
+	}
+	if g.journal != nil {
+		file, err := os.OpenFile(g.journal.Name(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			g.warn(Warning{Kind: WarnFSError, Message: fmt.Sprintf("cannot open journal: %v", err)})
+		} else {
+			finder.journal = file
+		}
+	}
+
+	if sameRoots {
+		finder.claimTests = maps.Clone(g.claimTests)
+		finder.ownSubtree = maps.Clone(g.ownSubtree)
+		finder.handlerTags = maps.Clone(g.handlerTags)
+		finder.overrides = slices.Clone(g.overrides)
+		finder.virtualHandlers = maps.Clone(g.virtualHandlers)
+		finder.scopePatterns = slices.Clone(g.scopePatterns)
+	}
+}
+
+// Scope returns the subdirectory this finder is restricted to, or "" when it
+// sees the whole module
+func (g *GoDepFind) Scope() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.scope
+}
+
+// SetScope restricts the cache to the packages matching patterns, relative
+// to the roots like "./...": {"./services/payments/...", "./pkg/..."} loads
+// one service of a monorepo and the libraries it shares, nothing else.
+// Files of other packages are out of scope: never indexed, never owned and
+// not watch relevant, and handlers outside the patterns are not discovered.
+// Patterns are "." or a directory, optionally ending in "/..." for its
+// subtree. Nil restores the whole module; the cache is rebuilt on the next
+// query.
+func (g *GoDepFind) SetScope(patterns []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var normalized []string
+	for _, pattern := range patterns {
+		pattern = path.Clean(filepath.ToSlash(pattern))
+		if pattern != "." && pattern != "..." {
+			pattern = "./" + pattern
+		} else if pattern == "..." {
+			pattern = "./..."
+		}
+		if !slices.Contains(normalized, pattern) {
+			normalized = append(normalized, pattern)
+		}
+	}
+	if !slices.Equal(normalized, g.scopePatterns) {
+		g.scopePatterns = normalized
+		g.cachedModule = false
+	}
+}
+
+// listScopePackages lists the packages of the SetScope patterns, or of the
+// whole module without any. A pattern matching nothing is not an error as
+// long as another one matches.
+func (g *GoDepFind) listScopePackages() ([]string, error) {
+	if len(g.scopePatterns) == 0 {
+		return g.listPackages("./...")
+	}
+	var packages []string
+	var lastErr error
+	seen := make(map[string]bool)
+	for _, pattern := range g.scopePatterns {
+		listed, err := g.listPackages(pattern)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, pkg := range listed {
+			if !seen[pkg] {
+				seen[pkg] = true
+				packages = append(packages, pkg)
+			}
+		}
+	}
+	if len(packages) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return packages, nil
+}
+
+// matchesScopePatterns reports whether a path inside a root matches one of
+// the SetScope patterns: a file by its directory, a directory by itself
+func (g *GoDepFind) matchesScopePatterns(absPath string) bool {
+	if len(g.scopePatterns) == 0 {
+		return true
+	}
+	rel, ok := g.relToRoot(absPath)
+	if !ok {
+		return true // outside the roots, see AllowExternalDirs
+	}
+	if g.scope != "" {
+		if scoped, err := filepath.Rel(filepath.FromSlash(g.scope), rel); err == nil && !strings.HasPrefix(scoped, "..") {
+			rel = scoped
+		}
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		rel = filepath.Dir(rel)
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range g.scopePatterns {
+		pattern = strings.TrimPrefix(pattern, "./")
+		if pattern == "..." {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+			if rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+				return true
+			}
+		} else if rel == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedPattern rewrites a relative package pattern so it is resolved inside the scope
+func (g *GoDepFind) scopedPattern(pattern string) string {
+	if g.scope == "" || !strings.HasPrefix(pattern, ".") {
+		return pattern
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(pattern, "."), "/")
+	if rest == "" {
+		return "./" + g.scope
+	}
+	return "./" + g.scope + "/" + rest
+}
+
+// inScope reports whether an absolute file path lies inside the scope and
+// matches the SetScope patterns
+func (g *GoDepFind) inScope(fileAbsPath string) bool {
+	if !g.matchesScopePatterns(fileAbsPath) {
+		return false
+	}
+	if g.scope == "" || len(g.rootDirs) == 0 {
+		return true
+	}
+	scopeDir := filepath.Join(g.rootDirs[0], filepath.FromSlash(g.scope))
+	return fileAbsPath == scopeDir || strings.HasPrefix(fileAbsPath, scopeDir+string(filepath.Separator))
+}
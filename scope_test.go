@@ -0,0 +1,217 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopedFinder(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                           "module mono\n\ngo 1.21\n",
+		"services/foo/main.go":             "package main\n\nimport \"mono/services/foo/internal/store\"\n\nfunc main() { store.Open() }\n",
+		"services/foo/internal/store/s.go": "package store\n\nfunc Open() {}\n",
+		"services/bar/main.go":             "package main\n\nimport \"mono/services/bar/api\"\n\nfunc main() { api.Serve() }\n",
+		"services/bar/api/api.go":          "package api\n\nfunc Serve() {}\n",
+	})
+
+	scoped := New(root).ScopedFinder("services/foo")
+	if scoped.Scope() != "services/foo" {
+		t.Fatalf("unexpected scope %q", scoped.Scope())
+	}
+
+	isMine, err := scoped.ThisFileIsMine("services/foo/main.go", filepath.Join(root, "services/foo/internal/store/s.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if !isMine {
+		t.Error("expected scoped handler to own its store package")
+	}
+
+	isMine, err = scoped.ThisFileIsMine("services/foo/main.go", filepath.Join(root, "services/bar/api/api.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if isMine {
+		t.Error("expected file outside scope not to be owned")
+	}
+
+	for pkgPath := range scoped.packageCache {
+		if !strings.HasPrefix(pkgPath, "mono/services/foo") {
+			t.Errorf("scoped cache loaded package outside scope: %s", pkgPath)
+		}
+	}
+
+	mains, err := scoped.GoFileComesFromMain("api.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 0 {
+		t.Errorf("expected no mains for out-of-scope file, got %v", mains)
+	}
+
+	deps, err := scoped.FindReverseDeps("./...", []string{"mono/services/foo/internal/store"})
+	if err != nil {
+		t.Fatalf("FindReverseDeps: %v", err)
+	}
+	if len(deps) != 2 {
+		logf(t, "reverse deps: %v", deps)
+	}
+	for _, dep := range deps {
+		if !strings.HasPrefix(dep, "mono/services/foo") {
+			t.Errorf("reverse dep outside scope: %s", dep)
+		}
+	}
+}
+
+func TestSetScopePatterns(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                        "module mono\n\ngo 1.21\n",
+		"services/payments/main.go":     "package main\n\nimport \"mono/pkg/money\"\n\nfunc main() { money.Add() }\n",
+		"services/payments/ledger/l.go": "package ledger\n",
+		"services/orders/main.go":       "package main\n\nimport \"mono/pkg/money\"\n\nfunc main() { money.Add() }\n",
+		"pkg/money/money.go":            "package money\n\nfunc Add() {}\n",
+		"tools/gen/gen.go":              "package gen\n",
+	})
+	finder := New(root)
+	finder.SetScope([]string{"./services/payments/...", "pkg/..."})
+
+	handlers, err := finder.DiscoverHandlers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handlers) != 1 || handlers[0].MainFile != "services/payments/main.go" {
+		t.Errorf("expected only the payments handler, got %v", handlers)
+	}
+	for pkgPath := range finder.packageCache {
+		if !strings.HasPrefix(pkgPath, "mono/services/payments") && !strings.HasPrefix(pkgPath, "mono/pkg/") {
+			t.Errorf("cache loaded package outside the patterns: %s", pkgPath)
+		}
+	}
+	if _, ok := finder.packageCache["mono/services/payments/ledger"]; !ok {
+		t.Error("expected the payments subtree to be loaded")
+	}
+
+	money := filepath.Join(root, "pkg", "money", "money.go")
+	if isMine, err := finder.ThisFileIsMine("services/payments/main.go", money, EventWrite); err != nil || !isMine {
+		t.Errorf("expected payments to own money.go, got %v, %v", isMine, err)
+	}
+	gen := filepath.Join(root, "tools", "gen", "gen.go")
+	if isMine, err := finder.ThisFileIsMine("services/payments/main.go", gen, EventWrite); err != nil || isMine {
+		t.Errorf("expected a file outside the patterns not to be owned, got %v, %v", isMine, err)
+	}
+	if finder.IsWatchRelevant(gen) || !finder.IsWatchRelevant(money) {
+		t.Error("expected watch relevance to follow the patterns")
+	}
+
+	// Nil restores the whole module
+	finder.SetScope(nil)
+	handlers, err = finder.DiscoverHandlers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handlers) != 2 {
+		t.Errorf("expected both handlers without a scope, got %v", handlers)
+	}
+	if _, ok := finder.packageCache["mono/tools/gen"]; !ok {
+		t.Error("expected the whole module to be loaded again")
+	}
+}
+
+func TestDerivedFindersInheritConfig(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module host\n\ngo 1.21\n",
+		"app/main.go":        "package main\n\nfunc main() {}\n",
+		"example/go.mod":     "module host/example\n\ngo 1.21\n",
+		"example/main.go":    "package main\n\nfunc main() {}\n",
+		"example/lib/lib.go": "package lib\n",
+	})
+	finder := New(root)
+	defer finder.Close()
+
+	// Every setting a caller can change
+	finder.SetTestImports(true)
+	finder.SetHandlerClaimsTests("app/main.go", true)
+	finder.SetHandlerOwnsSubtree("app/main.go", true)
+	finder.SetHandlerTags("app/main.go", "dev")
+	finder.SetMaxDepth(8)
+	finder.SetScope([]string{"./..."})
+	finder.SetFilenameFallback(FallbackAllMatches)
+	finder.SetFSErrorPolicy(FailClosed)
+	finder.AddVirtualEdge("host/app", "host/lib", "plugin")
+	finder.SetModulePath("host")
+	finder.SetScannerFallback(true)
+	finder.SetLogger(func(...any) {})
+	finder.OnPackageRenamed(func(PackageRenamed) {})
+	if err := finder.SetJournal(filepath.Join(t.TempDir(), "journal")); err != nil {
+		t.Fatal(err)
+	}
+	finder.SetRebuildInterval(time.Second)
+	finder.SetSkipUnchangedWrites(true)
+	finder.SetEventWindow(time.Second)
+	if err := finder.ForceOwnership("plugins/...", "app/main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.AddVirtualHandlerGlobs("assets", "web/*.css"); err != nil {
+		t.Fatal(err)
+	}
+	finder.SetRespectGitignore(true)
+	finder.SetSkipToolDirs(false)
+	finder.AllowExternalDirs(t.TempDir())
+	finder.SetChangeProvider(staticChanges{})
+	finder.SetAllowUnchecked(true)
+	finder.SetCacheStrategy(StrategyFull)
+	finder.SetLazyThreshold(10)
+
+	// Fields holding state, or settings each finder keeps to itself
+	notInherited := map[string]bool{
+		"mu": true, "rootDirs": true, "scope": true, "toolchainErr": true,
+		"cachedModule": true, "packageCache": true, "dependencyGraph": true, "reverseDeps": true,
+		"filePathToPackage": true, "testOnlyFiles": true, "removedFiles": true, "fileToPackages": true,
+		"mainPackages": true, "listMu": true, "packageDirs": true, "skipped": true, "excludedDirs": true,
+		"nestedModules": true, "warnings": true, "renamedPackages": true, "lastJournalKey": true,
+		"lastRebuild": true, "rebuildPending": true, "rebuildTimer": true, "warming": true,
+		"watchdogStop": true, "watchdogCloser": true, "watchdogBaseline": true, "watchdogStatus": true,
+		"closures": true, "graphVersion": true, "tagGraphs": true, "moduleFinders": true,
+		"seenContent": true, "indexedContent": true, "fileStamps": true, "recentEvents": true,
+		"loadErrors": true, "syntaxChecks": true, "ignoreMu": true, "ignoreFiles": true,
+		"cacheFile": true, "cacheStore": true, "metrics": true, "activeStrategy": true,
+		"listedCount": true, "lazyPending": true, "lazyScope": true, "lazyClosures": true,
+		"closed": true, "closers": true,
+	}
+	// Settings keyed by paths relative to the primary root
+	rootRelative := map[string]bool{
+		"claimTests": true, "ownSubtree": true, "handlerTags": true,
+		"overrides": true, "virtualHandlers": true, "scopePatterns": true,
+	}
+
+	scoped := finder.ScopedFinder("app")
+	defer scoped.Close()
+	nested, err := finder.ModuleFinder("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := reflect.ValueOf(finder).Elem()
+	for i := 0; i < parent.NumField(); i++ {
+		name := parent.Type().Field(i).Name
+		if notInherited[name] {
+			continue
+		}
+		if parent.Field(i).IsZero() {
+			t.Errorf("%s is not configured by this test", name)
+			continue
+		}
+		if reflect.ValueOf(scoped).Elem().Field(i).IsZero() {
+			t.Errorf("%s is not inherited by ScopedFinder", name)
+		}
+		if inherited := !reflect.ValueOf(nested).Elem().Field(i).IsZero(); inherited == rootRelative[name] {
+			t.Errorf("expected ModuleFinder to inherit %s: %v, got %v", name, !rootRelative[name], inherited)
+		}
+	}
+	if scoped.journal == finder.journal || nested.journal == finder.journal {
+		t.Error("expected derived finders to hold their own journal handle")
+	}
+}
@@ -0,0 +1,168 @@
+package depfind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphSnapshot is a frozen copy of the cached graph, see Snapshot
+type GraphSnapshot struct {
+	Packages []string          `json:"packages"` // cached packages, sorted
+	Edges    []GraphEdge       `json:"edges"`    // imports of the cached packages, virtual edges included, sorted
+	Files    map[string]string `json:"files"`    // indexed file (absolute) -> package
+}
+
+// GraphDiff is what changed between two snapshots, see DiffSnapshots
+type GraphDiff struct {
+	AddedPackages   []string    `json:"added_packages,omitempty"`
+	RemovedPackages []string    `json:"removed_packages,omitempty"`
+	AddedEdges      []GraphEdge `json:"added_edges,omitempty"`
+	RemovedEdges    []GraphEdge `json:"removed_edges,omitempty"`
+	AddedFiles      []string    `json:"added_files,omitempty"`
+	RemovedFiles    []string    `json:"removed_files,omitempty"`
+	MovedFiles      []string    `json:"moved_files,omitempty"` // indexed in both, under another package
+}
+
+// Snapshot copies the current state of the cache (packages, import edges
+// and the file index) so it can be compared with a later one, e.g. before
+// and after routing a save. The cache is built first when needed; when that
+// fails the snapshot holds whatever was cached.
+func (g *GoDepFind) Snapshot() *GraphSnapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.ensureCacheInitialized()
+	snapshot := &GraphSnapshot{
+		Packages: sortedKeys(g.cachedPackageSet()),
+		Edges:    []GraphEdge{},
+		Files:    make(map[string]string, len(g.filePathToPackage)),
+	}
+	for _, from := range snapshot.Packages {
+		for _, to := range sortedUnique(g.packageImports(from, false)) {
+			snapshot.Edges = append(snapshot.Edges, GraphEdge{From: from, To: to})
+		}
+	}
+	for file, pkgPath := range g.filePathToPackage {
+		snapshot.Files[file] = pkgPath
+	}
+	return snapshot
+}
+
+// DiffSnapshots reports what b added to and removed from a; a nil snapshot
+// counts as empty. Lists are sorted.
+func DiffSnapshots(a, b *GraphSnapshot) *GraphDiff {
+	if a == nil {
+		a = &GraphSnapshot{}
+	}
+	if b == nil {
+		b = &GraphSnapshot{}
+	}
+
+	diff := &GraphDiff{}
+	diff.AddedPackages, diff.RemovedPackages = diffLists(a.Packages, b.Packages)
+
+	before, after := make(map[GraphEdge]bool, len(a.Edges)), make(map[GraphEdge]bool, len(b.Edges))
+	for _, edge := range a.Edges {
+		before[edge] = true
+	}
+	for _, edge := range b.Edges {
+		after[edge] = true
+		if !before[edge] {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+	for _, edge := range a.Edges {
+		if !after[edge] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+	sortEdges(diff.AddedEdges)
+	sortEdges(diff.RemovedEdges)
+
+	for file, pkgPath := range b.Files {
+		previous, ok := a.Files[file]
+		switch {
+		case !ok:
+			diff.AddedFiles = append(diff.AddedFiles, file)
+		case previous != pkgPath:
+			diff.MovedFiles = append(diff.MovedFiles, file)
+		}
+	}
+	for file := range a.Files {
+		if _, ok := b.Files[file]; !ok {
+			diff.RemovedFiles = append(diff.RemovedFiles, file)
+		}
+	}
+	sort.Strings(diff.AddedFiles)
+	sort.Strings(diff.RemovedFiles)
+	sort.Strings(diff.MovedFiles)
+	return diff
+}
+
+// Empty reports whether the snapshots were identical
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedPackages)+len(d.RemovedPackages)+len(d.AddedEdges)+len(d.RemovedEdges)+
+		len(d.AddedFiles)+len(d.RemovedFiles)+len(d.MovedFiles) == 0
+}
+
+// String renders the diff one change per line, for display during
+// development: "+ dependency app/pwa -> app/database"
+func (d *GraphDiff) String() string {
+	var b strings.Builder
+	for _, pkgPath := range d.AddedPackages {
+		fmt.Fprintf(&b, "+ package %s\n", pkgPath)
+	}
+	for _, pkgPath := range d.RemovedPackages {
+		fmt.Fprintf(&b, "- package %s\n", pkgPath)
+	}
+	for _, edge := range d.AddedEdges {
+		fmt.Fprintf(&b, "+ dependency %s -> %s\n", edge.From, edge.To)
+	}
+	for _, edge := range d.RemovedEdges {
+		fmt.Fprintf(&b, "- dependency %s -> %s\n", edge.From, edge.To)
+	}
+	for _, file := range d.AddedFiles {
+		fmt.Fprintf(&b, "+ file %s\n", file)
+	}
+	for _, file := range d.RemovedFiles {
+		fmt.Fprintf(&b, "- file %s\n", file)
+	}
+	for _, file := range d.MovedFiles {
+		fmt.Fprintf(&b, "~ file %s\n", file)
+	}
+	return b.String()
+}
+
+// diffLists returns the items of b missing from a, and those of a missing
+// from b, sorted
+func diffLists(a, b []string) (added, removed []string) {
+	before, after := make(map[string]bool, len(a)), make(map[string]bool, len(b))
+	for _, item := range a {
+		before[item] = true
+	}
+	for _, item := range b {
+		after[item] = true
+		if !before[item] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range a {
+		if !after[item] {
+			removed = append(removed, item)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// sortEdges sorts edges by origin then target
+func sortEdges(edges []GraphEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
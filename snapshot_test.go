@@ -0,0 +1,56 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":         "module sn\n\ngo 1.21\n",
+		"pwa/main.go":    "package main\n\nimport _ \"sn/pwa/ui\"\n\nfunc main() {}\n",
+		"pwa/ui/ui.go":   "package ui\n",
+		"database/db.go": "package database\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+
+	before := finder.Snapshot()
+	if diff := DiffSnapshots(before, finder.Snapshot()); !diff.Empty() {
+		t.Fatalf("expected identical snapshots, got:\n%s", diff)
+	}
+
+	uiFile := filepath.Join(root, "pwa", "ui", "ui.go")
+	if err := os.WriteFile(uiFile, []byte("package ui\n\nimport _ \"sn/database\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("pwa/main.go", uiFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	extra := filepath.Join(root, "database", "extra.go")
+	if err := os.WriteFile(extra, []byte("package database\n\nfunc Extra() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := DiffSnapshots(before, finder.Snapshot())
+	if want := []GraphEdge{{From: "sn/pwa/ui", To: "sn/database"}}; !reflect.DeepEqual(diff.AddedEdges, want) {
+		t.Errorf("AddedEdges = %v, want %v", diff.AddedEdges, want)
+	}
+	if want := []string{extra}; !reflect.DeepEqual(diff.AddedFiles, want) {
+		t.Errorf("AddedFiles = %v, want %v", diff.AddedFiles, want)
+	}
+	if len(diff.AddedPackages)+len(diff.RemovedPackages)+len(diff.RemovedEdges)+len(diff.RemovedFiles)+len(diff.MovedFiles) != 0 {
+		t.Errorf("unexpected changes:\n%s", diff)
+	}
+	if want := "+ dependency sn/pwa/ui -> sn/database\n+ file " + extra + "\n"; diff.String() != want {
+		t.Errorf("String() = %q, want %q", diff.String(), want)
+	}
+
+	if removed := DiffSnapshots(before, nil); !reflect.DeepEqual(removed.RemovedPackages, before.Packages) {
+		t.Errorf("expected a nil snapshot to remove every package, got %v", removed.RemovedPackages)
+	}
+}
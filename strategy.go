@@ -0,0 +1,251 @@
+package depfind
+
+import (
+	"go/build"
+	"maps"
+	"path/filepath"
+	"sort"
+)
+
+// CacheStrategy is how much of the module the cache loads, see
+// SetCacheStrategy
+type CacheStrategy string
+
+const (
+	// StrategyAuto picks StrategyFull or StrategyLazy at each rebuild from
+	// the number of listed packages, see SetLazyThreshold
+	StrategyAuto CacheStrategy = "auto"
+	// StrategyFull loads every listed package when the cache is built
+	StrategyFull CacheStrategy = "full"
+	// StrategyLazy lists the packages but only loads, per handler, those its
+	// main file compiles
+	StrategyLazy CacheStrategy = "lazy"
+)
+
+// DefaultLazyThreshold is the number of listed packages above which
+// StrategyAuto loads lazily
+const DefaultLazyThreshold = 2000
+
+// Capabilities describes how the finder caches the module, see
+// GoDepFind.Capabilities
+type Capabilities struct {
+	Strategy  CacheStrategy `json:"strategy"`  // StrategyFull or StrategyLazy, as chosen by the last rebuild
+	Requested CacheStrategy `json:"requested"` // StrategyAuto unless overridden by SetCacheStrategy
+	Threshold int           `json:"threshold"` // listed packages above which StrategyAuto loads lazily
+	Packages  int           `json:"packages"`  // packages listed by the last rebuild
+	Loaded    int           `json:"loaded"`    // packages loaded so far
+}
+
+// lazyScope is what a query needs loaded under StrategyLazy: the closure of
+// a handler main file and the package of a file, either may be empty
+type lazyScope struct {
+	handler string
+	file    string
+}
+
+// SetCacheStrategy overrides how much of the module the cache loads.
+// StrategyFull (the behavior of small modules) loads every package up
+// front, so any query answers from memory. StrategyLazy, meant for modules
+// of thousands of packages, only lists them: ThisFileIsMine then loads the
+// packages its handler compiles and the package of the file, on first use,
+// so a dev server routing events for a few mains never parses the rest.
+// Every other query, as well as WarmCache, Rebuild and SaveCache, loads the
+// packages still missing first. StrategyAuto (the default) picks lazy
+// loading when the module lists more packages than SetLazyThreshold.
+// Changing the strategy rebuilds the cache on the next query.
+func (g *GoDepFind) SetCacheStrategy(strategy CacheStrategy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if strategy == StrategyAuto {
+		strategy = ""
+	}
+	if strategy != g.cacheStrategy {
+		g.cacheStrategy = strategy
+		g.cachedModule = false
+	}
+}
+
+// SetLazyThreshold sets the number of listed packages above which
+// StrategyAuto loads lazily; 0 restores DefaultLazyThreshold. It applies
+// from the next rebuild.
+func (g *GoDepFind) SetLazyThreshold(packages int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lazyThreshold = packages
+}
+
+// Capabilities reports the cache strategy in use, with the package counts
+// it was chosen from. The module is listed first if the cache is not built,
+// without loading any package under StrategyLazy.
+func (g *GoDepFind) Capabilities() (Capabilities, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lazyScope = &lazyScope{}
+	defer func() { g.lazyScope = nil }()
+	if err := g.ensureCacheInitialized(); err != nil {
+		return Capabilities{}, err
+	}
+	requested := g.cacheStrategy
+	if requested == "" {
+		requested = StrategyAuto
+	}
+	return Capabilities{
+		Strategy:  g.activeStrategy,
+		Requested: requested,
+		Threshold: g.threshold(),
+		Packages:  g.listedCount,
+		Loaded:    len(g.packageCache),
+	}, nil
+}
+
+// threshold returns the package count above which StrategyAuto loads lazily
+func (g *GoDepFind) threshold() int {
+	if g.lazyThreshold > 0 {
+		return g.lazyThreshold
+	}
+	return DefaultLazyThreshold
+}
+
+// chooseStrategy resolves the requested strategy for a rebuild listing
+// count packages. Lazy loading needs the directories of the listing to find
+// the package of a file, so without them every package is loaded.
+func (g *GoDepFind) chooseStrategy(count int) CacheStrategy {
+	g.listMu.Lock()
+	listed := len(g.packageDirs) > 0
+	g.listMu.Unlock()
+
+	switch {
+	case !listed || g.cacheStrategy == StrategyFull:
+		return StrategyFull
+	case g.cacheStrategy == StrategyLazy || count > g.threshold():
+		return StrategyLazy
+	default:
+		return StrategyFull
+	}
+}
+
+// settleLazy loads what the running query needs among the listed packages
+// not loaded yet: everything, or under a lazyScope the package of its file
+// and the closure of its handler
+func (g *GoDepFind) settleLazy() error {
+	if len(g.lazyPending) == 0 {
+		return nil
+	}
+	if g.lazyScope == nil {
+		pending := sortedKeys(g.lazyPending)
+		return g.loadLazy(pending)
+	}
+	if g.lazyScope.file != "" {
+		if pkgPath := g.listedPackageInDir(filepath.Dir(g.rootPath(g.lazyScope.file))); g.lazyPending[pkgPath] {
+			if err := g.loadLazy([]string{pkgPath}); err != nil {
+				return err
+			}
+		}
+	}
+	if g.lazyScope.handler != "" {
+		return g.loadHandlerClosure(g.lazyScope.handler)
+	}
+	return nil
+}
+
+// loadHandlerClosure loads the packages a handler main file compiles, under
+// its build context, walking the imports from its package. The walk is
+// repeated only after the dependency graph changed.
+func (g *GoDepFind) loadHandlerClosure(mainInputFileRelativePath string) error {
+	mainAbs := g.rootPath(mainInputFileRelativePath)
+	if version, ok := g.lazyClosures[mainAbs]; ok && version == g.graphVersion {
+		return nil
+	}
+	start := g.listedPackageInDir(filepath.Dir(mainAbs))
+	if start == "" {
+		return nil // not a listed package: ownership falls back to paths
+	}
+	tags, ok := g.handlerContext(mainInputFileRelativePath)
+	if !ok {
+		tags = hostTagSet()
+	}
+
+	seen := map[string]bool{start: true}
+	wave := []string{start}
+	for len(wave) > 0 {
+		var missing []string
+		for _, pkgPath := range wave {
+			if g.lazyPending[pkgPath] {
+				missing = append(missing, pkgPath)
+			}
+		}
+		if len(missing) > 0 {
+			if err := g.loadLazy(missing); err != nil {
+				return err
+			}
+		}
+		var next []string
+		for _, pkgPath := range wave {
+			for _, imp := range g.lazyImports(pkgPath, tags) {
+				if !seen[imp] && (g.lazyPending[imp] || g.packageCache[imp] != nil) {
+					seen[imp] = true
+					next = append(next, imp)
+				}
+			}
+		}
+		sort.Strings(next)
+		wave = next
+	}
+
+	if g.lazyClosures == nil {
+		g.lazyClosures = make(map[string]uint64)
+	}
+	g.lazyClosures[mainAbs] = g.graphVersion
+	return nil
+}
+
+// lazyImports returns the imports of a cached package the closure of a
+// handler built with tags may follow
+func (g *GoDepFind) lazyImports(pkgPath string, tags TagSet) []string {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return nil
+	}
+	imports := append(append([]string{}, pkg.Imports...), g.taggedImports(pkgPath, tags)...)
+	imports = append(imports, g.virtualTargets(pkgPath)...)
+	if g.testImports {
+		imports = append(append(imports, pkg.TestImports...), pkg.XTestImports...)
+	}
+	return imports
+}
+
+// loadLazy loads listed packages into the cache. Packages that fail to
+// load are reported as skipped, as by a rebuild.
+func (g *GoDepFind) loadLazy(paths []string) error {
+	loaded, err := g.getPackages(paths)
+	if err != nil && !isPartialFailure(err) {
+		return err
+	}
+	for _, pkgPath := range paths {
+		delete(g.lazyPending, pkgPath)
+	}
+
+	packages := make(map[string]*build.Package, len(g.packageCache)+len(loaded))
+	maps.Copy(packages, g.packageCache)
+	maps.Copy(packages, loaded)
+	renamed := g.renamedPackages
+	g.commitPackages(packages)
+	g.renamedPackages = renamed
+	g.graphChanged()
+	return nil
+}
+
+// listedPackageInDir returns the listed package whose directory is dir
+func (g *GoDepFind) listedPackageInDir(dir string) string {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	for pkgPath, pkgDir := range g.packageDirs {
+		if pkgDir == dir {
+			return pkgPath
+		}
+	}
+	return ""
+}
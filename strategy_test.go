@@ -0,0 +1,112 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheStrategy(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module cs\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"cs/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":    "package lib\n\nimport \"cs/util\"\n\nfunc Do() { util.U() }\n",
+		"util/u.go":     "package util\n\nfunc U() {}\n",
+		"other/main.go": "package main\n\nimport \"cs/extra\"\n\nfunc main() { extra.E() }\n",
+		"extra/e.go":    "package extra\n\nfunc E() {}\n",
+	})
+	libFile := filepath.Join(root, "lib", "lib.go")
+	extraFile := filepath.Join(root, "extra", "e.go")
+
+	// A small module is loaded up front
+	finder := New(root)
+	caps, err := finder.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Strategy != StrategyFull || caps.Requested != StrategyAuto || caps.Threshold != DefaultLazyThreshold ||
+		caps.Packages != 5 || caps.Loaded != 5 {
+		t.Errorf("expected a full cache of 5 packages, got %+v", caps)
+	}
+
+	// Above the threshold only the listing is done until a handler asks
+	finder = New(root)
+	finder.SetLazyThreshold(3)
+	if caps, err = finder.Capabilities(); err != nil || caps.Strategy != StrategyLazy || caps.Packages != 5 || caps.Loaded != 0 {
+		t.Fatalf("expected a lazy cache with nothing loaded, got %+v, %v", caps, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", extraFile, EventWrite); err != nil || isMine {
+		t.Errorf("expected extra/e.go not to be owned, got %v, %v", isMine, err)
+	}
+	caps, _ = finder.Capabilities()
+	for _, pkgPath := range []string{"cs/app", "cs/lib", "cs/util", "cs/extra"} {
+		if finder.packageCache[pkgPath] == nil {
+			t.Errorf("expected %s to be loaded", pkgPath)
+		}
+	}
+	if finder.packageCache["cs/other"] != nil || caps.Loaded != 4 {
+		t.Errorf("expected the other main to stay unloaded, got %+v", caps)
+	}
+
+	// Other queries load the rest
+	mains, err := finder.GoFileComesFromMain("e.go")
+	if err != nil || len(mains) != 1 || mains[0] != "cs/other" {
+		t.Errorf("expected cs/other to import extra, got %v, %v", mains, err)
+	}
+	if caps, _ = finder.Capabilities(); caps.Loaded != 5 {
+		t.Errorf("expected every package loaded, got %+v", caps)
+	}
+
+	// A manual override wins over the threshold
+	finder.SetCacheStrategy(StrategyFull)
+	if caps, err = finder.Capabilities(); err != nil || caps.Strategy != StrategyFull || caps.Requested != StrategyFull || caps.Loaded != 5 {
+		t.Errorf("expected the full strategy to be forced, got %+v, %v", caps, err)
+	}
+	finder = New(root)
+	finder.SetCacheStrategy(StrategyLazy)
+	if caps, err = finder.Capabilities(); err != nil || caps.Strategy != StrategyLazy || caps.Loaded != 0 {
+		t.Errorf("expected the lazy strategy to be forced, got %+v, %v", caps, err)
+	}
+}
+
+func TestLazyClosureFollowsReloads(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module cs\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"cs/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":    "package lib\n\nfunc Do() {}\n",
+		"mid/mid.go":    "package mid\n\nimport \"cs/extra\"\n\nfunc M() { extra.E() }\n",
+		"extra/e.go":    "package extra\n\nfunc E() {}\n",
+		"other/main.go": "package main\n\nimport \"cs/extra\"\n\nfunc main() { extra.E() }\n",
+	})
+	libFile := filepath.Join(root, "lib", "lib.go")
+	extraFile := filepath.Join(root, "extra", "e.go")
+
+	finder := New(root)
+	finder.SetCacheStrategy(StrategyLazy)
+	if isMine, err := finder.ThisFileIsMine("other/main.go", extraFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected extra/e.go to be owned by other, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if finder.packageCache["cs/mid"] != nil {
+		t.Fatal("expected mid to stay unloaded")
+	}
+
+	// lib starts importing a package still unloaded: the closure grows
+	if err := os.WriteFile(libFile, []byte("package lib\n\nimport \"cs/mid\"\n\nfunc Do() { mid.M() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to stay owned, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", extraFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected extra/e.go to be owned through mid, got %v, %v", isMine, err)
+	}
+	if finder.packageCache["cs/mid"] == nil {
+		t.Error("expected mid to be loaded with the closure")
+	}
+}
@@ -0,0 +1,43 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHandlerOwnsSubtree(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                         "module st\n\ngo 1.21\n",
+		"cmd/app/main.go":                "package main\n\nfunc main() {}\n",
+		"cmd/app/internal/helper/h.go":   "package helper\n",
+		"cmd/app/internal/helper/gen.go": "package helper\n\nfunc Gen() {}\n",
+		"cmd/tool/main.go":               "package main\n\nfunc main() {}\n",
+		"cmd/apps/x.go":                  "package apps\n",
+	})
+	helper := filepath.Join(root, "cmd", "app", "internal", "helper", "h.go")
+	sibling := filepath.Join(root, "cmd", "apps", "x.go")
+
+	finder := New(root)
+	if isMine, err := finder.ThisFileIsMine("cmd/app/main.go", helper, EventWrite); err != nil || isMine {
+		t.Fatalf("expected an unimported helper NOT to be owned by default, got %v, %v", isMine, err)
+	}
+
+	finder.SetHandlerOwnsSubtree("./cmd/app/main.go", true)
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"cmd/app/main.go", helper, true},
+		{"cmd/app/main.go", sibling, false}, // prefix match must stop at the directory boundary
+		{"cmd/tool/main.go", helper, false}, // the option is per handler
+	}
+	for _, tc := range cases {
+		isMine, err := finder.ThisFileIsMine(tc.handler, tc.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s): %v", tc.handler, tc.file, err)
+		}
+		if isMine != tc.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tc.handler, tc.file, isMine, tc.want)
+		}
+	}
+}
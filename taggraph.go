@@ -0,0 +1,84 @@
+package depfind
+
+import (
+	"sort"
+	"strings"
+)
+
+// tagGraph is the dependency graph of the cached packages under one build
+// configuration: the imports of the files its tags include, see graphFor
+type tagGraph struct {
+	version uint64              // graphVersion it was built for
+	deps    map[string][]string // package -> imports
+	reverse map[string][]string // package -> importers, sorted
+}
+
+// WithBuildTags queries the dependency graph of a build configuration
+// instead of the shared one loaded for the host: imports are those of the
+// files the tags include ("js", "wasm" for a wasm build; GOOS, GOARCH and
+// custom tags such as "tinygo"). The tags are the complete configuration,
+// the host's are not added. Each configuration's graph is built on first
+// use and kept until the shared graph changes.
+func WithBuildTags(tags ...string) Option {
+	return func(o *queryOptions) { o.tags = NewTagSet(tags...) }
+}
+
+// queryEdges returns the forward and reverse edges a query follows
+func (g *GoDepFind) queryEdges(options queryOptions) (deps, reverse map[string][]string) {
+	if options.tags == nil {
+		return g.dependencyGraph, g.reverseDeps
+	}
+	graph := g.graphFor(options.tags)
+	return graph.deps, graph.reverse
+}
+
+// handlerEdges returns the graph a handler's imports are followed in and its
+// key: the shared graph ("") for handlers built for the host, the graph of
+// their build configuration otherwise (SetHandlerTags, wasm mains), so a
+// wasm handler follows the imports of db_js.go and not those of db_native.go
+func (g *GoDepFind) handlerEdges(mainInputFileRelativePath string) (map[string][]string, string) {
+	tags, ok := g.handlerContext(mainInputFileRelativePath)
+	if !ok {
+		return g.dependencyGraph, ""
+	}
+	key := tags.key()
+	if key == hostTagSet().key() {
+		return g.dependencyGraph, ""
+	}
+	return g.graphFor(tags).deps, key
+}
+
+// graphFor returns the dependency graph of the cached packages under tags,
+// rebuilt when the shared graph changed since it was computed
+func (g *GoDepFind) graphFor(tags TagSet) *tagGraph {
+	key := tags.key()
+	if graph := g.tagGraphs[key]; graph != nil && graph.version == g.graphVersion {
+		return graph
+	}
+
+	graph := &tagGraph{
+		version: g.graphVersion,
+		deps:    make(map[string][]string, len(g.packageCache)),
+		reverse: make(map[string][]string),
+	}
+	for pkgPath := range g.packageCache {
+		imports := append(g.taggedImports(pkgPath, tags), g.virtualTargets(pkgPath)...)
+		graph.deps[pkgPath] = imports
+		for _, imp := range imports {
+			graph.reverse[imp] = append(graph.reverse[imp], pkgPath)
+		}
+	}
+	for _, importers := range graph.reverse {
+		sort.Strings(importers)
+	}
+	if g.tagGraphs == nil {
+		g.tagGraphs = make(map[string]*tagGraph)
+	}
+	g.tagGraphs[key] = graph
+	return graph
+}
+
+// key identifies a tag set among the cached graphs
+func (s TagSet) key() string {
+	return strings.Join(sortedKeys(s), ",")
+}
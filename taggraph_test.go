@@ -0,0 +1,107 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildTagGraphs(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module tg\n\ngo 1.21\n",
+		"app/main.server.go": "//go:build !wasm\n\npackage main\n\nimport \"tg/db\"\n\nfunc main() { db.Open() }\n",
+		"app/main.wasm.go":   "//go:build wasm\n\npackage main\n\nimport \"tg/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":           "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":        "package db\n\nimport \"tg/dom\"\n\nfunc open() { dom.Render() }\n",
+		"db/db_native.go":    "//go:build !wasm\n\npackage db\n\nimport \"tg/sql\"\n\nfunc open() { sql.Connect() }\n",
+		"dom/dom.go":         "package dom\n\nfunc Render() {}\n",
+		"sql/sql.go":         "package sql\n\nfunc Connect() {}\n",
+	})
+	finder := New(root)
+
+	// Queries select the graph of a build configuration
+	host, err := finder.FindForwardDeps("tg/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tg/sql"}; !reflect.DeepEqual(host, want) {
+		t.Errorf("host FindForwardDeps = %v, want %v", host, want)
+	}
+	wasm, err := finder.FindForwardDeps("tg/db", WithBuildTags("js", "wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tg/dom"}; !reflect.DeepEqual(wasm, want) {
+		t.Errorf("wasm FindForwardDeps = %v, want %v", wasm, want)
+	}
+	importers, err := finder.FindImporters("tg/dom", WithBuildTags("js", "wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tg/app", "tg/db"}; !reflect.DeepEqual(importers, want) {
+		t.Errorf("wasm FindImporters(dom) = %v, want %v", importers, want)
+	}
+	if importers, _ := finder.FindImporters("tg/sql", WithBuildTags("js", "wasm")); len(importers) != 0 {
+		t.Errorf("sql has no importers in the wasm graph, got %v", importers)
+	}
+
+	// Each handler follows the imports of its own build configuration
+	domFile := filepath.Join(root, "dom/dom.go")
+	sqlFile := filepath.Join(root, "sql/sql.go")
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"app/main.server.go", sqlFile, true},
+		{"app/main.server.go", domFile, false},
+		{"app/main.wasm.go", domFile, true},
+		{"app/main.wasm.go", sqlFile, false},
+	}
+	for _, tc := range cases {
+		isMine, err := finder.ThisFileIsMine(tc.handler, tc.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s): %v", tc.handler, filepath.Base(tc.file), err)
+		}
+		if isMine != tc.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tc.handler, filepath.Base(tc.file), isMine, tc.want)
+		}
+	}
+}
+
+func TestTagGraphsFollowReloads(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":           "module rm\n\ngo 1.21\n",
+		"app/main.wasm.go": "//go:build wasm\n\npackage main\n\nimport \"rm/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":         "package db\n\nfunc Open() {}\n",
+		"db/db_js.go":      "package db\n",
+		"x/x.go":           "package x\n",
+	})
+	finder := New(root)
+	xFile := filepath.Join(root, "x", "x.go")
+	if deps, err := finder.FindForwardDeps("rm/db", WithBuildTags("js", "wasm")); err != nil || len(deps) != 0 {
+		t.Fatalf("expected no wasm deps, got %v, %v", deps, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.wasm.go", xFile, EventWrite); err != nil || isMine {
+		t.Fatalf("expected x.go not to be owned yet, got %v, %v", isMine, err)
+	}
+
+	// Only the js file changes: the host imports of db stay the same
+	jsFile := filepath.Join(root, "db", "db_js.go")
+	if err := os.WriteFile(jsFile, []byte("package db\n\nimport _ \"rm/x\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.wasm.go", jsFile, EventWrite); err != nil {
+		t.Fatal(err)
+	}
+	deps, err := finder.FindForwardDeps("rm/db", WithBuildTags("js", "wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"rm/x"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("wasm FindForwardDeps after the edit = %v, want %v", deps, want)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.wasm.go", xFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected the wasm handler to own x.go after the edit, got %v, %v", isMine, err)
+	}
+}
@@ -0,0 +1,157 @@
+package depfind
+
+import (
+	"bufio"
+	"fmt"
+	"go/build/constraint"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch mirror the GOOS/GOARCH values go/build recognizes in
+// file name suffixes (name_GOOS_GOARCH.go)
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
+
+// unixOS are the GOOS values satisfying the "unix" constraint
+var unixOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "linux": true, "netbsd": true,
+	"openbsd": true, "solaris": true,
+}
+
+// impliedOS lists GOOS values that also satisfy another OS tag
+var impliedOS = map[string]string{"android": "linux", "ios": "darwin", "illumos": "solaris"}
+
+// TagSet is an explicit set of build tags (GOOS, GOARCH, compiler and custom
+// tags such as "wasm" and "tinygo") used to evaluate build constraints
+// independently of the host. "unix" and the OS implied by android, ios and
+// illumos are derived from the set; release tags (go1.N) always hold.
+type TagSet map[string]bool
+
+// NewTagSet returns a TagSet holding tags plus the tags they imply
+func NewTagSet(tags ...string) TagSet {
+	set := make(TagSet, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+		if implied, ok := impliedOS[tag]; ok {
+			set[implied] = true
+		}
+		if unixOS[tag] {
+			set["unix"] = true
+		}
+	}
+	return set
+}
+
+// Has reports whether tag is satisfied by the set
+func (s TagSet) Has(tag string) bool {
+	return s[tag] || strings.HasPrefix(tag, "go1.")
+}
+
+// Eval evaluates a build constraint expression ("wasm && !tinygo") or a
+// //go:build / // +build line against the set
+func (s TagSet) Eval(expr string) (bool, error) {
+	line := strings.TrimSpace(expr)
+	if !constraint.IsGoBuild(line) && !constraint.IsPlusBuild(line) {
+		line = "//go:build " + line
+	}
+	parsed, err := constraint.Parse(line)
+	if err != nil {
+		return false, fmt.Errorf("invalid build constraint %q: %w", expr, err)
+	}
+	return parsed.Eval(s.Has), nil
+}
+
+// MatchFile reports whether the Go file at path is included under the set:
+// its GOOS/GOARCH file name suffix and its build constraint must both hold.
+// Test files are matched like any other file.
+func (s TagSet) MatchFile(path string) (bool, error) {
+	if !s.matchFileName(filepath.Base(path)) {
+		return false, nil
+	}
+	expr, err := fileConstraint(path)
+	if err != nil {
+		return false, err
+	}
+	if expr == nil {
+		return true, nil
+	}
+	return expr.Eval(s.Has), nil
+}
+
+// matchFileName applies the name_GOOS_GOARCH.go convention
+func (s TagSet) matchFileName(name string) bool {
+	goos, goarch := fileNameTarget(name)
+	return (goos == "" || s.Has(goos)) && (goarch == "" || s.Has(goarch))
+}
+
+// fileNameTarget returns the GOOS and GOARCH required by a file name suffix
+// (name_GOOS_GOARCH.go), "" when the name does not constrain them. A file
+// named after an OS ("linux.go") is unconstrained.
+func fileNameTarget(name string) (goos, goarch string) {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.TrimSuffix(name, "_test")
+	idx := strings.Index(name, "_")
+	if idx == -1 {
+		return "", ""
+	}
+	parts := strings.Split(name[idx:], "_")
+	n := len(parts)
+	switch {
+	case n >= 2 && knownOS[parts[n-2]] && knownArch[parts[n-1]]:
+		return parts[n-2], parts[n-1]
+	case knownOS[parts[n-1]]:
+		return parts[n-1], ""
+	case knownArch[parts[n-1]]:
+		return "", parts[n-1]
+	}
+	return "", ""
+}
+
+// fileConstraint returns the build constraint of a Go file: its //go:build
+// line, or the conjunction of its // +build lines; nil when unconstrained
+func fileConstraint(path string) (constraint.Expr, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var plusBuild constraint.Expr
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		switch {
+		case constraint.IsGoBuild(line):
+			return constraint.Parse(line)
+		case constraint.IsPlusBuild(line):
+			expr, err := constraint.Parse(line)
+			if err != nil {
+				return nil, err
+			}
+			if plusBuild == nil {
+				plusBuild = expr
+			} else {
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	return plusBuild, scanner.Err()
+}
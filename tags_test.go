@@ -0,0 +1,105 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTagSetEval(t *testing.T) {
+	wasm := NewTagSet("js", "wasm", "tinygo")
+	linux := NewTagSet("android", "arm64")
+
+	cases := []struct {
+		set  TagSet
+		expr string
+		want bool
+	}{
+		{wasm, "wasm && tinygo", true},
+		{wasm, "!wasm", false},
+		{wasm, "//go:build js && !windows", true},
+		{wasm, "// +build linux,amd64 js", true},
+		{wasm, "unix", false},
+		{wasm, "go1.21", true},
+		{linux, "linux && unix", true}, // implied by android
+		{linux, "wasm || tinygo", false},
+	}
+	for _, tc := range cases {
+		got, err := tc.set.Eval(tc.expr)
+		if err != nil {
+			t.Fatalf("Eval(%q): %v", tc.expr, err)
+		}
+		if got != tc.want {
+			t.Errorf("Eval(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+	if _, err := wasm.Eval("wasm &&"); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestTagSetMatchFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"plain.go":           "package p\n",
+		"linux.go":           "package p\n",
+		"sys_js.go":          "package p\n",
+		"sys_js_wasm.go":     "package p\n",
+		"sys_linux_amd64.go": "package p\n",
+		"sys_wasm_test.go":   "package p\n",
+		"tiny.go":            "// Copyright notice\n\n//go:build tinygo\n\npackage p\n",
+		"legacy.go":          "// +build js\n// +build !tinygo\n\npackage p\n",
+		"late.go":            "package p\n\n//go:build ignore\n",
+	})
+	wasm := NewTagSet("js", "wasm", "tinygo")
+	want := map[string]bool{
+		"plain.go":           true,
+		"linux.go":           true,
+		"sys_js.go":          true,
+		"sys_js_wasm.go":     true,
+		"sys_linux_amd64.go": false,
+		"sys_wasm_test.go":   true,
+		"tiny.go":            true,
+		"legacy.go":          false,
+		"late.go":            true, // constraints after the package clause are ignored
+	}
+	for name, expected := range want {
+		got, err := wasm.MatchFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatalf("MatchFile(%s): %v", name, err)
+		}
+		if got != expected {
+			t.Errorf("MatchFile(%s) = %v, want %v", name, got, expected)
+		}
+	}
+	if _, err := wasm.MatchFile(filepath.Join(root, "missing.go")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestHandlerTags(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module tg\n\ngo 1.21\n",
+		"web/main.go":  "package main\n\nimport \"tg/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":   "package lib\n\nfunc Do() { host() }\n",
+		"lib/host.go":  "//go:build !tinygo\n\npackage lib\n\nfunc host() {}\n",
+		"lib/other.go": "package lib\n",
+	})
+	hostFile := filepath.Join(root, "lib", "host.go")
+	finder := New(root)
+
+	if isMine, err := finder.ThisFileIsMine("web/main.go", hostFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected host.go to be owned under host tags, got %v, %v", isMine, err)
+	}
+
+	finder.SetHandlerTags("web/main.go", "js", "wasm", "tinygo")
+	if isMine, err := finder.ThisFileIsMine("web/main.go", hostFile, EventWrite); err != nil || isMine {
+		t.Errorf("expected host.go to be excluded under tinygo tags, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMine("web/main.go", filepath.Join(root, "lib", "other.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected other.go to stay owned, got %v, %v", isMine, err)
+	}
+
+	finder.SetHandlerTags("web/main.go")
+	if isMine, err := finder.ThisFileIsMine("web/main.go", hostFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected clearing the tags to restore ownership, got %v, %v", isMine, err)
+	}
+}
@@ -0,0 +1,117 @@
+package depfind
+
+import (
+	"fmt"
+	"sort"
+)
+
+// EdgeKind tells how a dependency is established
+type EdgeKind string
+
+const (
+	ImportEdge EdgeKind = "import" // imported by the package sources, compiled into binaries
+	TestImport EdgeKind = "test"   // imported only by _test.go files, see SetTestImports
+)
+
+// DepEdge is a dependency From -> To and how it is established. For
+// MainsDependingOn, From is a main package and To the package it depends on,
+// possibly through other packages.
+type DepEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// DependencyEdges returns the direct dependencies of a cached package,
+// sorted by target. With SetTestImports(true), the packages only its test
+// files import are TestImport edges; a package imported by both its sources
+// and its tests is an ImportEdge.
+func (g *GoDepFind) DependencyEdges(pkgPath string) ([]DepEdge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.packageCache[pkgPath]; !ok {
+		return nil, fmt.Errorf("package not found in cache: %s", pkgPath)
+	}
+
+	production := make(map[string]bool)
+	for _, imp := range g.packageImports(pkgPath, false) {
+		production[imp] = true
+	}
+	var edges []DepEdge
+	for _, to := range sortedUnique(g.packageImports(pkgPath, true)) {
+		if to == pkgPath {
+			continue // an external test package importing the package under test
+		}
+		kind := TestImport
+		if production[to] {
+			kind = ImportEdge
+		}
+		edges = append(edges, DepEdge{From: pkgPath, To: to, Kind: kind})
+	}
+	return edges, nil
+}
+
+// MainsDependingOn returns the main packages depending on pkgPath, sorted:
+// an ImportEdge when the package is compiled into the binary, a TestImport
+// when only test imports lead to it (a main whose tests, or the tests of a
+// package it imports, use it). CI can then rebuild binaries for ImportEdge
+// dependents and only rerun tests for TestImport ones. A main package
+// depends on itself. Without SetTestImports(true) every dependent is an
+// ImportEdge.
+func (g *GoDepFind) MainsDependingOn(pkgPath string) ([]DepEdge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	mains := append([]string(nil), g.mainPackages...)
+	sort.Strings(mains)
+	var edges []DepEdge
+	for _, main := range mains {
+		switch {
+		case g.importReach(main, false)[pkgPath]:
+			edges = append(edges, DepEdge{From: main, To: pkgPath, Kind: ImportEdge})
+		case g.importReach(main, true)[pkgPath]:
+			edges = append(edges, DepEdge{From: main, To: pkgPath, Kind: TestImport})
+		}
+	}
+	return edges, nil
+}
+
+// packageImports returns the imports of a package, virtual edges included,
+// and with tests those of its test files when SetTestImports is enabled
+func (g *GoDepFind) packageImports(pkgPath string, tests bool) []string {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return g.dependencyGraph[pkgPath]
+	}
+	imports := append(append([]string(nil), pkg.Imports...), g.virtualTargets(pkgPath)...)
+	if tests && g.testImports {
+		imports = append(append(imports, pkg.TestImports...), pkg.XTestImports...)
+	}
+	return imports
+}
+
+// importReach returns root and every package it imports transitively, see
+// packageImports
+func (g *GoDepFind) importReach(root string, tests bool) map[string]bool {
+	reach := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.packageImports(current, tests) {
+			if !reach[dep] {
+				reach[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return reach
+}
@@ -0,0 +1,59 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTestOnlyDependencies(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":               "module td\n\ngo 1.21\n",
+		"app/main.go":          "package main\n\nimport \"td/lib\"\n\nfunc main() { lib.Do() }\n",
+		"app/main_test.go":     "package main\n\nimport (\n\t\"testing\"\n\n\t\"td/fixtures\"\n)\n\nfunc TestMain(t *testing.T) { fixtures.Load() }\n",
+		"lib/lib.go":           "package lib\n\nfunc Do() {}\n",
+		"lib/lib_test.go":      "package lib_test\n\nimport (\n\t\"testing\"\n\n\t\"td/lib\"\n\t\"td/mock\"\n)\n\nfunc TestDo(t *testing.T) { lib.Do(); mock.New() }\n",
+		"fixtures/fixtures.go": "package fixtures\n\nfunc Load() {}\n",
+		"mock/mock.go":         "package mock\n\nfunc New() {}\n",
+		"tool/main.go":         "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	finder.SetTestImports(true)
+
+	tests := []struct {
+		pkg  string
+		want []DepEdge
+	}{
+		{"td/lib", []DepEdge{{From: "td/app", To: "td/lib", Kind: ImportEdge}}},
+		{"td/fixtures", []DepEdge{{From: "td/app", To: "td/fixtures", Kind: TestImport}}},
+		{"td/mock", []DepEdge{{From: "td/app", To: "td/mock", Kind: TestImport}}}, // through the tests of lib
+		{"td/tool", []DepEdge{{From: "td/tool", To: "td/tool", Kind: ImportEdge}}},
+	}
+	for _, tt := range tests {
+		got, err := finder.MainsDependingOn(tt.pkg)
+		if err != nil {
+			t.Fatalf("MainsDependingOn(%s): %v", tt.pkg, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MainsDependingOn(%s) = %+v, want %+v", tt.pkg, got, tt.want)
+		}
+	}
+
+	edges, err := finder.DependencyEdges("td/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DepEdge{
+		{From: "td/app", To: "td/fixtures", Kind: TestImport},
+		{From: "td/app", To: "td/lib", Kind: ImportEdge},
+		{From: "td/app", To: "testing", Kind: TestImport},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("DependencyEdges = %+v, want %+v", edges, want)
+	}
+
+	// Without test imports the test-only packages are not dependencies
+	plain := New(root)
+	if got, err := plain.MainsDependingOn("td/fixtures"); err != nil || len(got) != 0 {
+		t.Errorf("MainsDependingOn without test imports = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,97 @@
+package depfind
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrToolchainMissing is returned when the go command is not on PATH and the
+// pure-Go scanner fallback is not enabled
+var ErrToolchainMissing = errors.New("go toolchain not found in PATH")
+
+// lookupToolchain reports whether the go command can be run
+func lookupToolchain() error {
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("%w: %v", ErrToolchainMissing, err)
+	}
+	return nil
+}
+
+// SetScannerFallback enables discovering packages by walking the roots with
+// go/build when the go command is missing, instead of failing. Import paths
+// are derived from the governing go.mod (or SetModulePath). The scanner does
+// not evaluate go.work files or replace directives.
+func (g *GoDepFind) SetScannerFallback(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.scannerFallback = enabled
+	g.cachedModule = false
+}
+
+// Check verifies up front that queries can run: the go toolchain must be on
+// PATH (or the scanner fallback enabled) and the primary root must be inside
+// a module (or have a synthesized module path). It returns ErrToolchainMissing
+// or ErrNoModule, so callers can fail fast at startup rather than inside the
+// first ThisFileIsMine call of an event storm.
+func (g *GoDepFind) Check() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.toolchainErr != nil && !g.usesScanner() {
+		return g.toolchainErr
+	}
+	if g.usesSyntheticModule() {
+		return nil
+	}
+	return g.checkModule("./...")
+}
+
+// usesScanner reports whether packages are discovered by walking the roots
+// instead of running go list
+func (g *GoDepFind) usesScanner() bool {
+	return g.usesSyntheticModule() || (g.scannerFallback && g.toolchainErr != nil)
+}
+
+// scannedImportPath derives the import path of a scanned directory from the
+// go.mod governing it, falling back to the synthesized module path
+func (g *GoDepFind) scannedImportPath(root, dir string) string {
+	if modRoot := findModuleRoot(dir); modRoot != "" {
+		if modulePath := readModulePath(filepath.Join(modRoot, "go.mod")); modulePath != "" {
+			rel, err := filepath.Rel(modRoot, dir)
+			if err != nil || rel == "." {
+				return modulePath
+			}
+			return modulePath + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return g.syntheticImportPath(root, dir)
+}
+
+// readModulePath returns the module path declared in a go.mod file, or ""
+func readModulePath(goModPath string) string {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		return unquoteModField(strings.TrimSpace(rest))
+	}
+	return ""
+}
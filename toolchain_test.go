@@ -0,0 +1,85 @@
+package depfind
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingToolchain(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":          "module example.com/tool // comment\n\ngo 1.21\n",
+		"app/main.go":     "package main\n\nimport \"example.com/tool/lib\"\n\nfunc main() { lib.Do() }\n",
+		"other/main.go":   "package main\n\nfunc main() {}\n",
+		"lib/lib.go":      "package lib\n\nfunc Do() {}\n",
+		"nested/go.mod":   "module example.com/nested\n\ngo 1.21\n",
+		"nested/n/n.go":   "package n\n",
+		"nested/main.go":  "package main\n\nfunc main() {}\n",
+		"testdata/x/x.go": "package x\n",
+	})
+	t.Setenv("PATH", t.TempDir())
+
+	finder := New(root)
+	if err := finder.Check(); !errors.Is(err, ErrToolchainMissing) {
+		t.Fatalf("expected ErrToolchainMissing from Check, got %v", err)
+	}
+	if _, err := finder.FindReverseDeps("./...", []string{"example.com/tool/lib"}); !errors.Is(err, ErrToolchainMissing) {
+		t.Fatalf("expected ErrToolchainMissing from queries, got %v", err)
+	}
+
+	finder.SetScannerFallback(true)
+	if err := finder.Check(); err != nil {
+		t.Fatalf("expected Check to pass with scanner fallback, got %v", err)
+	}
+
+	libFile := filepath.Join(root, "lib", "lib.go")
+	isMine, err := finder.ThisFileIsMine("app/main.go", libFile, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if !isMine {
+		t.Error("expected app/main.go to own lib/lib.go via the scanner")
+	}
+	isMine, err = finder.ThisFileIsMine("other/main.go", libFile, "write")
+	if err != nil || isMine {
+		t.Errorf("expected other/main.go NOT to own lib/lib.go, got %v, %v", isMine, err)
+	}
+
+	for _, pkg := range []string{"example.com/nested", "example.com/tool/nested", "example.com/nested/n"} {
+		if _, found := finder.packageCache[pkg]; found {
+			t.Errorf("expected nested module package %s to be left out", pkg)
+		}
+	}
+}
+
+func TestCheckWithToolchain(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module example.com/ok\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	if err := New(root).Check(); err != nil {
+		t.Fatalf("expected Check to pass, got %v", err)
+	}
+	if err := New(t.TempDir()).Check(); !errors.Is(err, ErrNoModule) {
+		t.Fatalf("expected ErrNoModule outside a module, got %v", err)
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a/go.mod": "// header\nmodule \"example.com/quoted\"\n",
+		"b/go.mod": "go 1.21\n",
+		"c/go.mod": "modulefoo x\nmodule\texample.com/tab\n",
+	})
+	cases := map[string]string{
+		"a/go.mod":       "example.com/quoted",
+		"b/go.mod":       "",
+		"c/go.mod":       "example.com/tab",
+		"missing/go.mod": "",
+	}
+	for rel, want := range cases {
+		if got := readModulePath(filepath.Join(root, rel)); got != want {
+			t.Errorf("readModulePath(%s) = %q, want %q", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toolDirNames are directories managed by tools rather than written as Go
+// sources: VCS and editor metadata, JS dependencies and build output. Stray
+// .go files there (editor backups, copied sources) are not packages of the
+// module.
+var toolDirNames = map[string]bool{
+	".git":         true,
+	".idea":        true,
+	".vscode":      true,
+	"node_modules": true,
+	"dist":         true,
+	"bin":          true,
+}
+
+// SetSkipToolDirs controls whether tool-managed directories are left out of
+// the cache and of event routing: .git, .idea, .vscode, node_modules, dist
+// and bin anywhere in a root, plus the go build cache (GOCACHE) and GOTMPDIR
+// when they lie inside one. Files there are never indexed nor owned by a
+// handler, and are not watch-relevant. Enabled by default; disable it for a
+// module keeping real packages under such a name (e.g. cmd/.../bin). The
+// cache is rebuilt on the next query.
+func (g *GoDepFind) SetSkipToolDirs(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.keepToolDirs == enabled {
+		g.keepToolDirs = !enabled
+		g.cachedModule = false
+	}
+}
+
+// inToolDir reports whether an absolute path lies in a tool-managed
+// directory of a root, see SetSkipToolDirs
+func (g *GoDepFind) inToolDir(absPath string) bool {
+	if g.keepToolDirs {
+		return false
+	}
+	for _, dir := range toolManagedDirs() {
+		if isUnder(absPath, dir) {
+			return true
+		}
+	}
+	rel, ok := g.relToRoot(absPath)
+	if !ok || rel == "." {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if toolDirNames[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// dropToolDirPackages removes the listed packages whose directory is
+// tool-managed
+func (g *GoDepFind) dropToolDirPackages(packages []string) []string {
+	if g.keepToolDirs {
+		return packages
+	}
+	kept := packages[:0]
+	for _, pkgPath := range packages {
+		if dir, ok := g.packageDir(pkgPath); ok && g.inToolDir(dir) {
+			continue
+		}
+		kept = append(kept, pkgPath)
+	}
+	return kept
+}
+
+// toolManagedDirs returns the go build cache and GOTMPDIR, which only hold
+// files the go command writes
+func toolManagedDirs() []string {
+	var dirs []string
+	if cache := os.Getenv("GOCACHE"); cache != "" && cache != "off" {
+		dirs = append(dirs, filepath.Clean(cache))
+	} else if userCache, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, filepath.Join(userCache, "go-build"))
+	}
+	if tmp := os.Getenv("GOTMPDIR"); tmp != "" {
+		dirs = append(dirs, filepath.Clean(tmp))
+	}
+	return dirs
+}
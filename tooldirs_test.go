@@ -0,0 +1,57 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSkipToolDirs(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                  "module td\n\ngo 1.21\n",
+		"app/main.go":             "package main\n\nimport \"td/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":              "package lib\n\nfunc Do() {}\n",
+		"dist/lib.go":             "package lib\n\nfunc Do() {}\n",
+		"bin/tool/main.go":        "package main\n\nfunc main() {}\n",
+		"web/node_modules/x/x.go": "package x\n",
+	})
+	finder := New(root)
+
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range []string{"td/dist", "td/bin/tool", "td/web/node_modules/x"} {
+		if _, cached := finder.packageCache[pkg]; cached {
+			t.Errorf("expected %s not to be cached", pkg)
+		}
+	}
+	if _, cached := finder.packageCache["td/lib"]; !cached {
+		t.Error("expected td/lib to be cached")
+	}
+
+	stray := filepath.Join(root, "dist", "lib.go")
+	if isMine, err := finder.ThisFileIsMine("app/main.go", stray, EventWrite); err != nil || isMine {
+		t.Errorf("expected a stray copy in dist/ not to be routed, got %v, %v", isMine, err)
+	}
+	if finder.IsWatchRelevant(stray) || finder.IsWatchRelevant(filepath.Join(root, "bin", "tool", "main.go")) {
+		t.Error("expected tool-managed directories not to be watch-relevant")
+	}
+	if exp, _ := finder.ExplainOwnership("app/main.go", stray); exp == nil || exp.Reason != ReasonToolDir {
+		t.Errorf("expected tool-dir explanation, got %+v", exp)
+	}
+	handlers, err := finder.DiscoverHandlers()
+	if err != nil || len(handlers) != 1 || handlers[0].MainFile != "app/main.go" {
+		t.Errorf("expected only app/main.go to be discovered, got %+v, %v", handlers, err)
+	}
+
+	// Opt-out
+	finder.SetSkipToolDirs(false)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatal(err)
+	}
+	if _, cached := finder.packageCache["td/bin/tool"]; !cached {
+		t.Error("expected td/bin/tool to be cached once tool directories are kept")
+	}
+	if !finder.IsWatchRelevant(stray) {
+		t.Error("expected dist/ to be watch-relevant once tool directories are kept")
+	}
+}
@@ -0,0 +1,164 @@
+package depfind
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// chainPackages builds p0 -> p1 -> ... -> p(n-1), optionally closing a cycle back to p0
+func chainPackages(n int, cyclic bool) map[string]*build.Package {
+	packages := make(map[string]*build.Package)
+	for i := 0; i < n; i++ {
+		pkg := &build.Package{Name: fmt.Sprintf("p%d", i)}
+		if i+1 < n {
+			pkg.Imports = []string{fmt.Sprintf("p%d", i+1)}
+		} else if cyclic {
+			pkg.Imports = []string{"p0"}
+		}
+		packages[pkg.Name] = pkg
+	}
+	return packages
+}
+
+func TestImportsHandlesCycles(t *testing.T) {
+	finder := New(".")
+	packages := chainPackages(50, true)
+
+	found, err := finder.imports("p0", packages, map[string]bool{"missing": true}, map[string]bool{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Error("expected no match in cyclic graph without target")
+	}
+
+	found, err = finder.imports("p10", packages, map[string]bool{"p5": true}, map[string]bool{})
+	if err != nil || !found {
+		t.Errorf("expected p10 to reach p5 through the cycle, got %v, %v", found, err)
+	}
+}
+
+func TestImportsDeepChainWithoutRecursion(t *testing.T) {
+	finder := New(".")
+	packages := chainPackages(200000, false)
+
+	found, err := finder.imports("p0", packages, map[string]bool{"p199999": true}, map[string]bool{})
+	if err != nil || !found {
+		t.Errorf("expected deep chain to resolve, got %v, %v", found, err)
+	}
+}
+
+func TestImportsMaxDepth(t *testing.T) {
+	finder := New(".")
+	finder.SetMaxDepth(5)
+	packages := chainPackages(20, false)
+
+	_, err := finder.imports("p0", packages, map[string]bool{"p19": true}, map[string]bool{})
+	var depthErr *MaxDepthError
+	if !errors.As(err, &depthErr) {
+		t.Fatalf("expected *MaxDepthError, got %v", err)
+	}
+	if depthErr.Path != "p0" || depthErr.MaxDepth != 5 {
+		t.Errorf("unexpected error fields: %+v", depthErr)
+	}
+
+	found, err := finder.imports("p0", packages, map[string]bool{"p3": true}, map[string]bool{})
+	if err != nil || !found {
+		t.Errorf("expected shallow target within max depth, got %v, %v", found, err)
+	}
+}
+
+func TestCachedImportsCycle(t *testing.T) {
+	finder := New(".")
+	finder.dependencyGraph = map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a", "d"},
+	}
+	if imports, err := finder.cachedMainImportsPackage("a", "d"); err != nil || !imports {
+		t.Errorf("expected a to reach d, got %v, %v", imports, err)
+	}
+	if imports, err := finder.cachedMainImportsPackage("a", "x"); err != nil || imports {
+		t.Errorf("expected a not to reach x, got %v, %v", imports, err)
+	}
+}
+
+func TestCachedImportsMaxDepth(t *testing.T) {
+	finder := New(".")
+	finder.SetMaxDepth(2)
+	// The deep path a -> b -> c is walked first; c still counts at depth 1
+	finder.dependencyGraph = map[string][]string{
+		"a": {"c", "b"},
+		"b": {"c"},
+		"c": {"d"},
+		"d": {"e"},
+	}
+	if imports, err := finder.cachedMainImportsPackage("a", "d"); err != nil || !imports {
+		t.Errorf("expected a to reach d within the max depth, got %v, %v", imports, err)
+	}
+	_, err := finder.cachedMainImportsPackage("a", "e")
+	var depthErr *MaxDepthError
+	if !errors.As(err, &depthErr) || depthErr.Path != "a" || depthErr.MaxDepth != 2 {
+		t.Errorf("expected *MaxDepthError past the max depth, got %v", err)
+	}
+	if imports, err := finder.cachedMainImportsPackage("d", "x"); err != nil || imports {
+		t.Errorf("expected a walk within the max depth to answer false, got %v, %v", imports, err)
+	}
+}
+
+func TestOwnershipMaxDepth(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module md\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"md/a\"\n\nfunc main() { a.A() }\n",
+		"a/a.go":      "package a\n\nimport \"md/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go":      "package b\n\nimport \"md/c\"\n\nfunc B() { c.C() }\n",
+		"c/c.go":      "package c\n\nfunc C() {}\n",
+	})
+	finder := New(root)
+	finder.SetMaxDepth(1)
+	// The handler's imports are the first level
+	if isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "b", "b.go"), EventWrite); err != nil || !isMine {
+		t.Errorf("expected b.go to be owned within the max depth, got %v, %v", isMine, err)
+	}
+	_, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "c", "c.go"), EventWrite)
+	var depthErr *MaxDepthError
+	if !errors.As(err, &depthErr) {
+		t.Errorf("expected *MaxDepthError for a package past the max depth, got %v", err)
+	}
+	if _, err := finder.UnreachableFromAll([]string{"md/app"}); !errors.As(err, &depthErr) {
+		t.Errorf("expected *MaxDepthError from a cut reachability walk, got %v", err)
+	}
+}
+
+func TestFindReverseDepsMaxDepthImportOrder(t *testing.T) {
+	// a -> {short, deep}, short -> t, deep -> c -> d: a reaches t at depth 2
+	// whichever branch sorts first
+	for _, names := range [][2]string{{"s", "z"}, {"z", "s"}} {
+		short, deep := names[0], names[1]
+		root := writeTree(t, map[string]string{
+			"go.mod":                    "module od\n\ngo 1.21\n",
+			"a/a.go":                    fmt.Sprintf("package a\n\nimport (\n\t_ \"od/%s\"\n\t_ \"od/%s\"\n)\n", short, deep),
+			short + "/" + short + ".go": fmt.Sprintf("package %s\n\nimport _ \"od/t\"\n", short),
+			deep + "/" + deep + ".go":   fmt.Sprintf("package %s\n\nimport _ \"od/c\"\n", deep),
+			"c/c.go":                    "package c\n\nimport _ \"od/d\"\n",
+			"d/d.go":                    "package d\n",
+			"t/t.go":                    "package t\n",
+		})
+		finder := New(root)
+		finder.SetMaxDepth(2)
+		deps, err := finder.FindReverseDeps("./...", []string{"od/t"})
+		if err != nil {
+			t.Fatalf("short=%s deep=%s: FindReverseDeps: %v", short, deep, err)
+		}
+		want := []string{"od/a", "od/" + short, "od/t"}
+		sort.Strings(deps)
+		sort.Strings(want)
+		if fmt.Sprint(deps) != fmt.Sprint(want) {
+			t.Errorf("short=%s deep=%s: got %v, want %v", short, deep, deps, want)
+		}
+	}
+}
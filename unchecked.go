@@ -0,0 +1,31 @@
+package depfind
+
+import "errors"
+
+// ErrUncheckedDisabled is returned by ThisFileIsMineUnchecked unless
+// SetAllowUnchecked enabled it
+var ErrUncheckedDisabled = errors.New("depfind: unchecked ownership is disabled, see SetAllowUnchecked")
+
+// SetAllowUnchecked enables ThisFileIsMineUnchecked. Disabled by default so
+// the validated ThisFileIsMine stays the only path unless a caller opts in.
+func (g *GoDepFind) SetAllowUnchecked(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.allowUnchecked = enabled
+}
+
+// ThisFileIsMineUnchecked is ThisFileIsMine without the Go file validator:
+// the target is not parsed to reject empty or half-written files. It is
+// meant for trusted callers that already know the file is complete (e.g. a
+// post-format hook) and want the lower latency. It returns
+// ErrUncheckedDisabled unless SetAllowUnchecked(true) was called.
+func (g *GoDepFind) ThisFileIsMineUnchecked(mainInputFileRelativePath, fileAbsPath, event string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.allowUnchecked {
+		return false, ErrUncheckedDisabled
+	}
+	decision, err := g.decide(mainInputFileRelativePath, fileAbsPath, event, false)
+	return decision.Owned, err
+}
@@ -0,0 +1,38 @@
+package depfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThisFileIsMineUnchecked(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module uc\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"uc/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	libFile := filepath.Join(root, "lib", "lib.go")
+
+	if _, err := finder.ThisFileIsMineUnchecked("app/main.go", libFile, EventWrite); !errors.Is(err, ErrUncheckedDisabled) {
+		t.Fatalf("expected ErrUncheckedDisabled by default, got %v", err)
+	}
+
+	finder.SetAllowUnchecked(true)
+	if isMine, err := finder.ThisFileIsMineUnchecked("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Fatalf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+
+	// A file the validator rejects is still routed when unchecked
+	if err := os.WriteFile(libFile, []byte("package lib\n\nfunc Do() {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || isMine {
+		t.Errorf("expected the validated path to skip the half-written file, got %v, %v", isMine, err)
+	}
+	if isMine, err := finder.ThisFileIsMineUnchecked("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected the unchecked path to route the file, got %v, %v", isMine, err)
+	}
+}
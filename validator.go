@@ -61,6 +61,13 @@ func (g *GoDepFind) validateInputForProcessing(mainInputFileRelativePath, fileNa
 		return false, fmt.Errorf("handler main file path cannot be empty")
 	}
 
+	// Filename-based lookups cannot answer for files outside the roots
+	if filePath != "" {
+		if absPath := g.rootPath(filePath); !g.inRoots(absPath) {
+			return false, &NotInRootError{Path: absPath}
+		}
+	}
+
 	// Validate Go file before processing (if we have a file path)
 	if filePath != "" && filepath.Ext(fileName) == ".go" {
 		validator := NewGoFileValidator()
@@ -0,0 +1,134 @@
+package depfind
+
+import (
+	"go/build"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileVariant is a Go file of a package that is only built for some targets,
+// by its GOOS/GOARCH name suffix (db_js.go) or its build constraint
+// (//go:build !wasm), see PackageInfo.Variants
+type FileVariant struct {
+	File       string `json:"file"`                 // base name, e.g. "db_js.go"
+	Constraint string `json:"constraint,omitempty"` // //go:build expression, "" when only the name constrains it
+	GOOS       string `json:"goos,omitempty"`       // OS required by the name suffix
+	GOARCH     string `json:"goarch,omitempty"`     // arch required by the name suffix
+	Host       bool   `json:"host"`                 // built for the host, i.e. part of the indexed package
+}
+
+// Context describes the targets the file is built for: the GOOS/GOARCH of
+// its name suffix ("js/wasm", "windows", "wasm"), then its constraint
+// ("js/wasm, !tinygo")
+func (v FileVariant) Context() string {
+	var parts []string
+	switch {
+	case v.GOOS != "" && v.GOARCH != "":
+		parts = append(parts, v.GOOS+"/"+v.GOARCH)
+	case v.GOOS != "":
+		parts = append(parts, v.GOOS)
+	case v.GOARCH != "":
+		parts = append(parts, v.GOARCH)
+	}
+	if v.Constraint != "" {
+		parts = append(parts, v.Constraint)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fileVariant returns the variant of the cached package pkgPath that
+// fileAbsPath is, nil when the file is built for every target
+func (g *GoDepFind) fileVariant(pkgPath, fileAbsPath string) *FileVariant {
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil || pkg.Dir != filepath.Dir(fileAbsPath) {
+		return nil
+	}
+	name := filepath.Base(fileAbsPath)
+	for _, variant := range packageVariants(pkg) {
+		if variant.File == name {
+			return &variant
+		}
+	}
+	return nil
+}
+
+// packageVariants returns the constrained non-test Go files of a package,
+// built for the host or not, sorted by file
+func packageVariants(pkg *build.Package) []FileVariant {
+	var variants []FileVariant
+	add := func(names []string, host bool) {
+		for _, name := range names {
+			if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			goos, goarch := fileNameTarget(name)
+			expr := buildConstraint(filepath.Join(pkg.Dir, name))
+			if expr == "" && goos == "" && goarch == "" {
+				continue // ignored for another reason (e.g. another package clause)
+			}
+			variants = append(variants, FileVariant{File: name, Constraint: expr, GOOS: goos, GOARCH: goarch, Host: host})
+		}
+	}
+	add(pkg.GoFiles, true)
+	add(pkg.IgnoredGoFiles, false)
+	sort.Slice(variants, func(i, j int) bool { return variants[i].File < variants[j].File })
+	return variants
+}
+
+// variantPackage returns the cached package whose directory holds fileAbsPath
+// as a Go file excluded from the host build by its name or constraint, so
+// variants such as db_js.go resolve to their package, or ""
+func (g *GoDepFind) variantPackage(fileAbsPath string) string {
+	if filepath.Ext(fileAbsPath) != ".go" {
+		return ""
+	}
+	pkgPath := g.packageInDir(filepath.Dir(fileAbsPath))
+	if pkgPath == "" {
+		return ""
+	}
+	name := filepath.Base(fileAbsPath)
+	for _, variant := range packageVariants(g.packageCache[pkgPath]) {
+		if variant.File == name && !variant.Host {
+			return pkgPath
+		}
+	}
+	return ""
+}
+
+// handlerContext returns the tags the handler's files are evaluated with:
+// the ones set with SetHandlerTags, otherwise the target its main file
+// builds for (js/wasm for a wasm-only main, the host otherwise). The second
+// result is false when the main file cannot be found.
+func (g *GoDepFind) handlerContext(mainInputFileRelativePath string) (TagSet, bool) {
+	if tags, ok := g.handlerTags[handlerKey(mainInputFileRelativePath)]; ok {
+		return tags, true
+	}
+	mainAbs := g.rootPath(mainInputFileRelativePath)
+	host := hostTagSet()
+	if matched, err := host.MatchFile(mainAbs); err != nil {
+		return nil, false
+	} else if matched {
+		return host, true
+	}
+	if matched, _ := wasmTagSet().MatchFile(mainAbs); matched {
+		return wasmTagSet(), true
+	}
+	return host, true
+}
+
+// hostTagSet returns the tags of the default build context
+func hostTagSet() TagSet {
+	ctx := build.Default
+	tags := []string{ctx.GOOS, ctx.GOARCH, ctx.Compiler}
+	if ctx.CgoEnabled {
+		tags = append(tags, "cgo")
+	}
+	tags = append(tags, ctx.BuildTags...)
+	return NewTagSet(append(tags, ctx.ToolTags...)...)
+}
+
+// wasmTagSet returns the tags of a js/wasm build
+func wasmTagSet() TagSet {
+	return NewTagSet("js", "wasm", build.Default.Compiler)
+}
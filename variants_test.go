@@ -0,0 +1,127 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildTagVariants(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":               "module vt\n\ngo 1.21\n",
+		"app/main.server.go":   "//go:build !wasm\n\npackage main\n\nimport \"vt/db\"\n\nfunc main() { db.Open() }\n",
+		"app/main.wasm.go":     "//go:build wasm\n\npackage main\n\nimport \"vt/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":             "package db\n\nfunc Open() { open() }\n",
+		"db/db_js.go":          "package db\n\nfunc open() {}\n",
+		"db/db_native.go":      "//go:build !wasm\n\npackage db\n\nfunc open() {}\n",
+		"db/db_native_test.go": "//go:build !wasm\n\npackage db\n",
+	})
+	finder := New(root)
+
+	info, err := finder.PackageInfo("vt/db")
+	if err != nil || info == nil {
+		t.Fatalf("PackageInfo: %v, %v", info, err)
+	}
+	want := []FileVariant{
+		{File: "db_js.go", GOOS: "js"},
+		{File: "db_native.go", Constraint: "!wasm", Host: true},
+	}
+	if !reflect.DeepEqual(info.Variants, want) {
+		t.Errorf("Variants = %+v, want %+v", info.Variants, want)
+	}
+
+	jsFile := filepath.Join(root, "db", "db_js.go")
+	nativeFile := filepath.Join(root, "db", "db_native.go")
+	shared := filepath.Join(root, "db", "db.go")
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"app/main.server.go", jsFile, false},
+		{"app/main.server.go", nativeFile, true},
+		{"app/main.server.go", shared, true},
+		{"app/main.wasm.go", jsFile, true},
+		{"app/main.wasm.go", nativeFile, false},
+		{"app/main.wasm.go", shared, true},
+	}
+	for _, tc := range cases {
+		isMine, err := finder.ThisFileIsMine(tc.handler, tc.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s): %v", tc.handler, filepath.Base(tc.file), err)
+		}
+		if isMine != tc.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tc.handler, filepath.Base(tc.file), isMine, tc.want)
+		}
+	}
+
+	exp, err := finder.ExplainOwnership("app/main.server.go", jsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Reason != ReasonExcludedByTags {
+		t.Errorf("expected db_js.go to be excluded by the server target, got %s", exp.Reason)
+	}
+	if pkgs, err := finder.findPackagesForFile(jsFile); err != nil || !reflect.DeepEqual(pkgs, []string{"vt/db"}) {
+		t.Errorf("expected db_js.go to resolve to its package, got %v, %v", pkgs, err)
+	}
+}
+
+func TestFileSuffixVariantsIndexed(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":               "module sv\n\ngo 1.21\n",
+		"app/main.server.go":   "//go:build !wasm\n\npackage main\n\nimport \"sv/util\"\n\nfunc main() { util.Do() }\n",
+		"app/main.wasm.go":     "//go:build wasm\n\npackage main\n\nimport \"sv/util\"\n\nfunc main() { util.Do() }\n",
+		"util/util.go":         "package util\n\nfunc Do() { do() }\n",
+		"util/util_js_wasm.go": "package util\n\nfunc do() {}\n",
+		"util/util_windows.go": "package util\n\nfunc do() {}\n",
+		"util/util_other.go":   "//go:build !js && !windows\n\npackage util\n\nfunc do() {}\n",
+	})
+	finder := New(root)
+	wasmFile := filepath.Join(root, "util", "util_js_wasm.go")
+
+	// Variants the host build excludes are in the file index
+	for _, name := range []string{"util_js_wasm.go", "util_windows.go"} {
+		finder.mu.Lock()
+		finder.ensureCacheInitialized()
+		pkgPath := finder.filePathToPackage[filepath.Join(root, "util", name)]
+		byName := finder.fileToPackages[name]
+		finder.mu.Unlock()
+		if pkgPath != "sv/util" || !reflect.DeepEqual(byName, []string{"sv/util"}) {
+			t.Errorf("%s: indexed as %q, by name %v", name, pkgPath, byName)
+		}
+	}
+
+	// Ownership answers state the context they hold under
+	exp, err := finder.ExplainOwnership("app/main.wasm.go", wasmFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.Owned || exp.Variant == nil || exp.Variant.Context() != "js/wasm" {
+		t.Fatalf("wasm main: %+v", exp)
+	}
+	if want := " [built for js/wasm only]"; !strings.HasSuffix(exp.String(), want) {
+		t.Errorf("String() = %q, want suffix %q", exp.String(), want)
+	}
+	exp, err = finder.ExplainOwnership("app/main.server.go", wasmFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Owned || exp.Reason != ReasonExcludedByTags || exp.Variant == nil || exp.Variant.Context() != "js/wasm" {
+		t.Errorf("server main: %+v", exp)
+	}
+	exp, err = finder.ExplainOwnership("app/main.server.go", filepath.Join(root, "util", "util_other.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.Owned || exp.Variant == nil || exp.Variant.Context() != "!js && !windows" {
+		t.Errorf("constrained file: %+v", exp)
+	}
+	exp, err = finder.ExplainOwnership("app/main.server.go", filepath.Join(root, "util", "util.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Variant != nil {
+		t.Errorf("expected no variant for a file built everywhere, got %+v", exp.Variant)
+	}
+}
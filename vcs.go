@@ -0,0 +1,195 @@
+package depfind
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileChange is one file changed since a revision, see ChangeProvider
+type FileChange struct {
+	Path  string `json:"path"`  // absolute path
+	Event string `json:"event"` // EventCreate, EventWrite or EventRemove
+}
+
+// ChangeProvider lists the files changed since a revision. GitChanges is the
+// default; other version control systems (Mercurial, Jujutsu) and test
+// harnesses plug in their own with SetChangeProvider. A rename is reported
+// as the removal of the old path and the creation of the new one.
+type ChangeProvider interface {
+	Changes(since string) ([]FileChange, error)
+}
+
+// GitChanges reads changes from the git repository containing Dir: the
+// working tree (committed, staged or not) against the since revision, plus
+// untracked files that are not ignored. An empty since means HEAD.
+type GitChanges struct {
+	Dir string
+}
+
+// Changes implements ChangeProvider
+func (c GitChanges) Changes(since string) ([]FileChange, error) {
+	if since == "" {
+		since = "HEAD"
+	}
+	top, err := c.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	top = strings.TrimSpace(top)
+
+	diff, err := c.git("diff", "--name-status", "-M", since, "--")
+	if err != nil {
+		return nil, err
+	}
+	var changes []FileChange
+	add := func(rel, event string) {
+		changes = append(changes, FileChange{Path: filepath.Join(top, filepath.FromSlash(rel)), Event: event})
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		switch fields[0][0] {
+		case 'A', 'C':
+			add(fields[len(fields)-1], EventCreate)
+		case 'D':
+			add(fields[1], EventRemove)
+		case 'R':
+			add(fields[1], EventRemove)
+			add(fields[2], EventCreate)
+		default:
+			add(fields[1], EventWrite)
+		}
+	}
+
+	untracked, err := c.git("ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range strings.Split(untracked, "\n") {
+		if rel != "" {
+			add(rel, EventCreate)
+		}
+	}
+	return changes, nil
+}
+
+// git runs a git command in Dir and returns its output
+func (c GitChanges) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// SetChangeProvider replaces the source of changes used by ImpactSince; nil
+// restores GitChanges on the primary root
+func (g *GoDepFind) SetChangeProvider(provider ChangeProvider) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.changeProvider = provider
+}
+
+// ChangeImpact is what the changes since a revision affect, see ImpactSince
+type ChangeImpact struct {
+	Changes  []FileChange `json:"changes"`
+	Packages []string     `json:"packages,omitempty"` // packages holding changed files
+	Handlers []string     `json:"handlers,omitempty"` // handler main files to rebuild
+	Tests    []string     `json:"tests,omitempty"`    // packages whose tests should rerun
+}
+
+// ImpactSince asks the change provider for the files changed since a
+// revision and returns the packages they belong to, the handlers to rebuild
+// and the packages whose tests should rerun, all sorted. Files outside the
+// roots or in no package are listed in Changes only.
+func (g *GoDepFind) ImpactSince(since string) (*ChangeImpact, error) {
+	g.mu.RLock()
+	provider := g.changeProvider
+	if provider == nil && len(g.rootDirs) > 0 {
+		provider = GitChanges{Dir: g.rootDirs[0]}
+	}
+	g.mu.RUnlock()
+	if provider == nil {
+		return nil, fmt.Errorf("no change provider")
+	}
+
+	// The provider may shell out; it runs without holding the finder lock
+	changes, err := provider.Changes(since)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	impact := &ChangeImpact{Changes: changes}
+	packages := make(map[string]bool)
+	tests := make(map[string]bool)
+	for _, change := range changes {
+		pkgPath := g.changedFilePackage(change.Path)
+		if pkgPath == "" || packages[pkgPath] {
+			continue
+		}
+		packages[pkgPath] = true
+		for _, test := range g.affectedTestPackages(pkgPath) {
+			tests[test] = true
+		}
+	}
+	impact.Packages = sortedKeys(packages)
+	impact.Tests = sortedKeys(tests)
+
+	if len(packages) == 0 {
+		return impact, nil
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range handlers {
+		for _, pkgPath := range impact.Packages {
+			imports, err := g.handlerFileImportsPackage(h.MainFile, pkgPath)
+			if err != nil {
+				return nil, err
+			}
+			if h.Package == pkgPath || imports {
+				impact.Handlers = append(impact.Handlers, h.MainFile)
+				break
+			}
+		}
+	}
+	sort.Strings(impact.Handlers)
+	return impact, nil
+}
+
+// changedFilePackage returns the package of a changed file: indexed (a
+// removed file is still in the index until the cache is updated), a build
+// variant or, for a Go file created since the last rebuild, the package of
+// its directory
+func (g *GoDepFind) changedFilePackage(fileAbsPath string) string {
+	if !g.inRoots(fileAbsPath) {
+		return ""
+	}
+	if pkgPath := g.filePathToPackage[fileAbsPath]; pkgPath != "" {
+		return pkgPath
+	}
+	if pkgPath := g.variantPackage(fileAbsPath); pkgPath != "" {
+		return pkgPath
+	}
+	if filepath.Ext(fileAbsPath) == ".go" {
+		return g.packageInDir(filepath.Dir(fileAbsPath))
+	}
+	return ""
+}
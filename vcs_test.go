@@ -0,0 +1,110 @@
+package depfind
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// staticChanges is a ChangeProvider returning a fixed change set
+type staticChanges []FileChange
+
+func (c staticChanges) Changes(string) ([]FileChange, error) { return c, nil }
+
+func impactTree(t *testing.T) string {
+	return writeTree(t, map[string]string{
+		"go.mod":          "module vc\n\ngo 1.21\n",
+		"server/main.go":  "package main\n\nimport \"vc/db\"\n\nfunc main() { db.Open() }\n",
+		"cli/main.go":     "package main\n\nimport \"vc/util\"\n\nfunc main() { util.Do() }\n",
+		"db/db.go":        "package db\n\nfunc Open() {}\n",
+		"db/db_test.go":   "package db\n",
+		"util/util.go":    "package util\n\nfunc Do() {}\n",
+		"docs/readme.txt": "notes\n",
+	})
+}
+
+func TestImpactSinceProvider(t *testing.T) {
+	root := impactTree(t)
+	finder := New(root)
+	finder.SetChangeProvider(staticChanges{
+		{Path: filepath.Join(root, "db", "db.go"), Event: EventWrite},
+		{Path: filepath.Join(root, "db", "new.go"), Event: EventCreate},
+		{Path: filepath.Join(root, "docs", "readme.txt"), Event: EventWrite},
+	})
+
+	impact, err := finder.ImpactSince("anything")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impact.Changes) != 3 {
+		t.Errorf("expected the 3 changes to be reported, got %v", impact.Changes)
+	}
+	if want := []string{"vc/db"}; !reflect.DeepEqual(impact.Packages, want) {
+		t.Errorf("Packages = %v, want %v", impact.Packages, want)
+	}
+	if want := []string{"server/main.go"}; !reflect.DeepEqual(impact.Handlers, want) {
+		t.Errorf("Handlers = %v, want %v", impact.Handlers, want)
+	}
+	if want := []string{"vc/db"}; !reflect.DeepEqual(impact.Tests, want) {
+		t.Errorf("Tests = %v, want %v", impact.Tests, want)
+	}
+}
+
+func TestGitChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := impactTree(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	if err := os.WriteFile(filepath.Join(root, "util", "util.go"), []byte("package util\n\nfunc Do() { _ = 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "util", "extra.go"), []byte("package util\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "docs", "readme.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := GitChanges{Dir: filepath.Join(root, "util")}.Changes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		filepath.Join(root, "docs", "readme.txt"): EventRemove,
+		filepath.Join(root, "util", "util.go"):    EventWrite,
+		filepath.Join(root, "util", "extra.go"):   EventCreate,
+	}
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.Path] = change.Event
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes = %v, want %v", got, want)
+	}
+
+	impact, err := New(root).ImpactSince("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cli/main.go"}; !reflect.DeepEqual(impact.Handlers, want) {
+		t.Errorf("Handlers = %v, want %v", impact.Handlers, want)
+	}
+
+	if _, err := (GitChanges{Dir: root}).Changes("no-such-revision"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+}
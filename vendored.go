@@ -0,0 +1,167 @@
+package depfind
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vendoredDirNames are directory names conventionally holding third-party
+// code that is not linked through go.mod. Names as generic as "external"
+// are left out: modules keep their own packages there (internal/external,
+// pkg/external) and one broken file must not drop them all.
+var vendoredDirNames = map[string]bool{
+	"third_party": true,
+	"third-party": true,
+	"3rdparty":    true,
+	"vendor":      true,
+}
+
+// SkippedPackage reports a package left out of the graph because it could
+// not be loaded, or because it lives in an excluded vendored tree
+type SkippedPackage struct {
+	Path   string `json:"path"`
+	Dir    string `json:"dir"`
+	Reason string `json:"reason"`
+}
+
+// listedPackage is the subset of "go list -json" output depfind reads
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Error      *struct{ Err string }
+	DepsErrors []*struct{ Err string }
+}
+
+// SkippedPackages returns the packages left out of the graph, sorted by path.
+// Broken packages are always skipped; when one lies in a vendored tree
+// (third_party/, 3rdparty/, ...) the whole tree is excluded, so stray .go
+// files neither fail the listing nor pollute ownership.
+func (g *GoDepFind) SkippedPackages() []SkippedPackage {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	result := make([]SkippedPackage, 0, len(g.skipped))
+	for _, s := range g.skipped {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// ExcludedDirs returns the vendored trees automatically excluded from the graph
+func (g *GoDepFind) ExcludedDirs() []string {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	return append([]string(nil), g.excludedDirs...)
+}
+
+// listProblem returns why a listed package cannot join the graph, or "".
+// Missing dependencies only disqualify packages inside vendored trees: in
+// the module's own code they are the user's to fix and keep their edges.
+// Import cycles do not either: the package still loads from source and the
+// cycle is reported by DetectCycles.
+func (g *GoDepFind) listProblem(listed listedPackage) string {
+	if listed.Error != nil && !strings.Contains(listed.Error.Err, "import cycle not allowed") {
+		return listed.Error.Err
+	}
+	if len(listed.DepsErrors) > 0 && listed.DepsErrors[0] != nil && g.vendoredTreeRoot(listed.Dir) != "" {
+		return listed.DepsErrors[0].Err
+	}
+	return ""
+}
+
+// addSkipped records a skipped package
+func (g *GoDepFind) addSkipped(skipped SkippedPackage) {
+	g.listMu.Lock()
+	if g.skipped == nil {
+		g.skipped = make(map[string]SkippedPackage)
+	}
+	g.skipped[skipped.Path] = skipped
+	g.listMu.Unlock()
+
+	g.warn(Warning{Kind: WarnPackageSkipped, Package: skipped.Path, Dir: skipped.Dir, Message: skipped.Reason})
+}
+
+// resetListReport clears skipped packages, excluded trees and warnings before a rebuild
+func (g *GoDepFind) resetListReport() {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	g.skipped = nil
+	g.excludedDirs = nil
+	g.warnings = nil
+}
+
+// excludeVendoredTrees excludes every vendored tree holding a skipped package
+// and drops the listed packages inside those trees
+func (g *GoDepFind) excludeVendoredTrees(packages []string) []string {
+	g.listMu.Lock()
+	for _, s := range g.skipped {
+		if tree := g.vendoredTreeRoot(s.Dir); tree != "" && !contains(g.excludedDirs, tree) {
+			g.excludedDirs = append(g.excludedDirs, tree)
+		}
+	}
+	if len(g.excludedDirs) == 0 {
+		g.listMu.Unlock()
+		return packages
+	}
+
+	kept := packages[:0]
+	var dropped []SkippedPackage
+	for _, pkgPath := range packages {
+		dir := g.packageDirs[pkgPath]
+		if tree := g.excludingTree(dir); tree != "" {
+			dropped = append(dropped, SkippedPackage{Path: pkgPath, Dir: dir, Reason: "inside excluded vendored tree " + tree})
+			continue
+		}
+		kept = append(kept, pkgPath)
+	}
+	g.listMu.Unlock()
+
+	for _, s := range dropped {
+		g.addSkipped(s)
+	}
+	return kept
+}
+
+// isExcluded reports whether a file lies in an excluded vendored tree
+func (g *GoDepFind) isExcluded(fileAbsPath string) bool {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	return g.excludingTree(fileAbsPath) != ""
+}
+
+// excludingTree returns the excluded tree containing path, or "" (listMu held)
+func (g *GoDepFind) excludingTree(path string) string {
+	if path == "" {
+		return ""
+	}
+	for _, tree := range g.excludedDirs {
+		if path == tree || strings.HasPrefix(path, tree+string(filepath.Separator)) {
+			return tree
+		}
+	}
+	return ""
+}
+
+// vendoredTreeRoot returns the top vendored directory (e.g. <root>/third_party)
+// containing dir, or "" when dir is not inside one
+func (g *GoDepFind) vendoredTreeRoot(dir string) string {
+	for _, root := range g.rootDirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		current := root
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+			if vendoredDirNames[part] {
+				return current
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestVendoredTreeExcluded(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                     "module vt\n\ngo 1.21\n",
+		"app/main.go":                "package main\n\nimport \"vt/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":                 "package lib\n\nfunc Do() {}\n",
+		"third_party/bar/bar.go":     "package bar\n",
+		"third_party/bar/baz.go":     "package baz\n",
+		"third_party/bar/sub/sub.go": "package sub\n",
+	})
+	finder := New(root)
+
+	mains, err := finder.GoFileComesFromMain("lib.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "vt/app" {
+		t.Fatalf("expected broken vendored tree not to break the cache, got %v", mains)
+	}
+
+	excluded := finder.ExcludedDirs()
+	if len(excluded) != 1 || excluded[0] != filepath.Join(root, "third_party") {
+		t.Errorf("expected third_party to be excluded, got %v", excluded)
+	}
+
+	skipped := finder.SkippedPackages()
+	if len(skipped) != 2 || skipped[0].Path != "vt/third_party/bar" || skipped[1].Path != "vt/third_party/bar/sub" {
+		t.Fatalf("unexpected skipped packages: %+v", skipped)
+	}
+	logf(t, "skip reasons: %q, %q", skipped[0].Reason, skipped[1].Reason)
+
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "third_party", "bar", "sub", "sub.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine: %v", err)
+	}
+	if isMine {
+		t.Error("expected files in excluded tree not to be owned")
+	}
+}
+
+func TestExternalDirIsNotVendored(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                 "module vt\n\ngo 1.21\n",
+		"app/main.go":            "package main\n\nimport \"vt/external/api\"\n\nfunc main() { api.Call() }\n",
+		"external/api/api.go":    "package api\n\nfunc Call() {}\n",
+		"external/broken/a.go":   "package a\n",
+		"external/broken/b.go":   "package b\n",
+		"third_party/bar/bar.go": "package bar\n",
+		"third_party/bar/baz.go": "package baz\n",
+	})
+	finder := New(root)
+
+	// A broken package under external/ is skipped alone, its siblings are the module's own
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "external", "api", "api.go"), EventWrite)
+	if err != nil || !isMine {
+		t.Errorf("expected external/api to be owned, got %v, %v", isMine, err)
+	}
+	excluded := finder.ExcludedDirs()
+	if len(excluded) != 1 || excluded[0] != filepath.Join(root, "third_party") {
+		t.Errorf("expected only third_party to be excluded, got %v", excluded)
+	}
+	skipped := finder.SkippedPackages()
+	if len(skipped) != 2 || skipped[0].Path != "vt/external/broken" || skipped[1].Path != "vt/third_party/bar" {
+		t.Errorf("unexpected skipped packages: %+v", skipped)
+	}
+}
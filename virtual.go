@@ -0,0 +1,120 @@
+package depfind
+
+import "sort"
+
+// VirtualEdge is a dependency injected by tooling that imports do not show,
+// e.g. code loaded via plugins, templ/sqlc generation or JS glue loaded by a
+// wasm main. From and To are package import paths.
+type VirtualEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AddVirtualEdge declares that package from depends on package to. Routing
+// and impact analysis honor the edge like a regular import, and it survives
+// cache rebuilds and package refreshes. Adding an existing edge updates its
+// reason; empty paths are ignored, as is every edge once the finder is
+// closed.
+func (g *GoDepFind) AddVirtualEdge(from, to string, reason string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed || from == "" || to == "" || from == to {
+		return
+	}
+	if g.virtualEdges == nil {
+		g.virtualEdges = make(map[string]map[string]string)
+	}
+	if g.virtualEdges[from] == nil {
+		g.virtualEdges[from] = make(map[string]string)
+	}
+	g.virtualEdges[from][to] = reason
+
+	if g.cachedModule {
+		g.addGraphEdge(from, to)
+	}
+}
+
+// RemoveVirtualEdge removes an edge added with AddVirtualEdge. Real imports
+// between the same packages are kept. It does nothing once the finder is
+// closed.
+func (g *GoDepFind) RemoveVirtualEdge(from, to string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return
+	}
+	if _, ok := g.virtualEdges[from][to]; !ok {
+		return
+	}
+	delete(g.virtualEdges[from], to)
+	g.graphChanged()
+	if len(g.virtualEdges[from]) == 0 {
+		delete(g.virtualEdges, from)
+	}
+
+	if pkg := g.packageCache[from]; pkg != nil && contains(pkg.Imports, to) {
+		return
+	}
+	if !g.cachedModule {
+		return // the next rebuild does not apply the edge
+	}
+	g.dependencyGraph[from] = removeString(g.dependencyGraph[from], to)
+	g.removeReverseDep(to, from)
+}
+
+// VirtualEdges returns the injected edges sorted by From then To
+func (g *GoDepFind) VirtualEdges() []VirtualEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.virtualEdgeList()
+}
+
+// virtualEdgeList is VirtualEdges without locking
+func (g *GoDepFind) virtualEdgeList() []VirtualEdge {
+	var edges []VirtualEdge
+	for from, targets := range g.virtualEdges {
+		for to, reason := range targets {
+			edges = append(edges, VirtualEdge{From: from, To: to, Reason: reason})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// virtualTargets returns the packages from depends on through virtual edges
+func (g *GoDepFind) virtualTargets(from string) []string {
+	var targets []string
+	for to := range g.virtualEdges[from] {
+		targets = append(targets, to)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// applyVirtualEdges merges every virtual edge into a freshly built graph
+func (g *GoDepFind) applyVirtualEdges() {
+	for from, targets := range g.virtualEdges {
+		for to := range targets {
+			g.addGraphEdge(from, to)
+		}
+	}
+}
+
+// addGraphEdge adds from -> to to the dependency graph and reverse deps
+func (g *GoDepFind) addGraphEdge(from, to string) {
+	g.graphChanged()
+	if !contains(g.dependencyGraph[from], to) {
+		// Copy so package Imports slices shared with the graph stay untouched
+		deps := append([]string(nil), g.dependencyGraph[from]...)
+		g.dependencyGraph[from] = append(deps, to)
+	}
+	g.addReverseDep(to, from)
+}
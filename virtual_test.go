@@ -0,0 +1,76 @@
+package depfind
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestVirtualEdges(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module virt\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport \"virt/ui\"\n\nfunc main() { ui.Render() }\n",
+		"ui/ui.go":     "package ui\n\nfunc Render() {}\n",
+		"glue/glue.go": "package glue\n",
+		"plugin/p.go":  "package plugin\n",
+	})
+	finder := New(root)
+	glueFile := filepath.Join(root, "glue", "glue.go")
+	pluginFile := filepath.Join(root, "plugin", "p.go")
+
+	isMine, err := finder.ThisFileIsMine("app/main.go", glueFile, "write")
+	if err != nil || isMine {
+		t.Fatalf("expected glue not owned before virtual edge, got %v, %v", isMine, err)
+	}
+
+	finder.AddVirtualEdge("virt/ui", "virt/glue", "wasm JS glue")
+	finder.AddVirtualEdge("virt/app", "virt/plugin", "loaded as plugin")
+
+	for _, file := range []string{glueFile, pluginFile} {
+		isMine, err = finder.ThisFileIsMine("app/main.go", file, "write")
+		if err != nil || !isMine {
+			t.Errorf("expected %s owned through virtual edge, got %v, %v", file, isMine, err)
+		}
+	}
+
+	// Refreshing ui (write event) and a full rebuild must keep the edges
+	if _, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "ui", "ui.go"), "write"); err != nil {
+		t.Fatalf("refresh ui: %v", err)
+	}
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatalf("rebuildCache: %v", err)
+	}
+	mains, err := finder.GoFileComesFromMain("glue.go")
+	if err != nil || len(mains) != 1 {
+		t.Errorf("expected virtual edge to survive refresh and rebuild, got %v, %v", mains, err)
+	}
+
+	if edges := finder.VirtualEdges(); len(edges) != 2 || edges[0].From != "virt/app" || edges[1].Reason != "wasm JS glue" {
+		t.Errorf("unexpected edges: %+v", edges)
+	}
+
+	finder.RemoveVirtualEdge("virt/ui", "virt/glue")
+	mains, err = finder.GoFileComesFromMain("glue.go")
+	if err != nil || len(mains) != 0 {
+		t.Errorf("expected removed edge to stop routing, got %v, %v", mains, err)
+	}
+}
+
+func TestRemoveVirtualEdgeAfterClose(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module virt\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"ui/ui.go":    "package ui\n",
+	})
+	finder := New(root)
+	finder.AddVirtualEdge("virt/app", "virt/ui", "plugin")
+	if _, err := finder.GoFileComesFromMain("ui.go"); err != nil {
+		t.Fatal(err)
+	}
+	finder.Close()
+
+	finder.RemoveVirtualEdge("virt/app", "virt/ui")
+	if _, err := finder.GoFileComesFromMain("ui.go"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package depfind
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AddVirtualHandler registers a handler not backed by a Go main file, for
+// routing groups such as "docs", "infra" or "assets". ThisFileIsMine and
+// Decide called with name as the handler answer from match, given the path
+// relative to the primary root in slash form ("docs/index.md"); files outside
+// the primary root are never owned. Virtual handlers are listed in
+// RebuildPlan next to the discovered ones. They do not take files away from
+// Go handlers: a file can be owned by both. Registering a name again
+// replaces its matcher. match runs with the finder locked and must not call
+// it.
+func (g *GoDepFind) AddVirtualHandler(name string, match func(relPath string) bool) error {
+	if name == "" || match == nil {
+		return fmt.Errorf("virtual handler name and matcher cannot be empty")
+	}
+	if filepath.Ext(name) == ".go" {
+		return fmt.Errorf("virtual handler name %q looks like a main file", name)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	if g.virtualHandlers == nil {
+		g.virtualHandlers = make(map[string]func(string) bool)
+	}
+	g.virtualHandlers[handlerKey(name)] = match
+	return nil
+}
+
+// AddVirtualHandlerGlobs registers a virtual handler owning the files
+// matching any of globs, in the syntax of ForceOwnership ("docs/...",
+// "assets/*.css")
+func (g *GoDepFind) AddVirtualHandlerGlobs(name string, globs ...string) error {
+	if len(globs) == 0 {
+		return fmt.Errorf("virtual handler %s needs at least one glob", name)
+	}
+	patterns := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		pattern := normalizeOverridePattern(glob)
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/..."), ""); err != nil {
+			return fmt.Errorf("invalid virtual handler glob %q: %w", glob, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return g.AddVirtualHandler(name, func(relPath string) bool {
+		for _, pattern := range patterns {
+			if overrideMatches(pattern, relPath) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RemoveVirtualHandler unregisters a virtual handler
+func (g *GoDepFind) RemoveVirtualHandler(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.virtualHandlers, handlerKey(name))
+}
+
+// VirtualHandlers returns the names of the virtual handlers, sorted
+func (g *GoDepFind) VirtualHandlers() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.virtualHandlerNames()
+}
+
+func (g *GoDepFind) virtualHandlerNames() []string {
+	names := make([]string, 0, len(g.virtualHandlers))
+	for name := range g.virtualHandlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// virtualOwnership answers for a virtual handler: whether name is one, and
+// whether it owns the absolute fileAbsPath
+func (g *GoDepFind) virtualOwnership(name, fileAbsPath string) (owned, virtual bool) {
+	match, virtual := g.virtualHandlers[handlerKey(name)]
+	if !virtual {
+		return false, false
+	}
+	if len(g.rootDirs) == 0 {
+		return false, true
+	}
+	rel, err := filepath.Rel(g.rootDirs[0], fileAbsPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false, true
+	}
+	return match(filepath.ToSlash(rel)), true
+}
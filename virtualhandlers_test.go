@@ -0,0 +1,78 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVirtualHandlers(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":            "module vh\n\ngo 1.21\n",
+		"app/main.go":       "package main\n\nfunc main() {}\n",
+		"docs/index.md":     "# docs\n",
+		"docs/api/ref.md":   "# ref\n",
+		"assets/site.css":   "body {}\n",
+		"infra/deploy.yaml": "kind: Deployment\n",
+	})
+	finder := New(root)
+
+	if err := finder.AddVirtualHandlerGlobs("docs", "docs/..."); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.AddVirtualHandler("infra", func(rel string) bool { return strings.HasSuffix(rel, ".yaml") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.AddVirtualHandler("cmd/main.go", func(string) bool { return true }); err == nil {
+		t.Error("expected a name looking like a main file to be rejected")
+	}
+	if err := finder.AddVirtualHandlerGlobs("assets", "assets/[.css"); err == nil {
+		t.Error("expected an invalid glob to be rejected")
+	}
+	if got := finder.VirtualHandlers(); !reflect.DeepEqual(got, []string{"docs", "infra"}) {
+		t.Errorf("VirtualHandlers() = %v", got)
+	}
+
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"docs", "docs/api/ref.md", true},
+		{"docs", "assets/site.css", false},
+		{"infra", "infra/deploy.yaml", true},
+		{"docs", "app/main.go", false},
+		{"docs", filepath.Join(filepath.Dir(root), "outside.md"), false},
+	}
+	for _, c := range cases {
+		owned, err := finder.ThisFileIsMine(c.handler, c.file, "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s): %v", c.handler, c.file, err)
+		}
+		if owned != c.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", c.handler, c.file, owned, c.want)
+		}
+	}
+
+	plan, err := finder.RebuildPlan([]string{"docs/index.md", "app/main.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rebuilds []string
+	for _, entry := range plan.Handlers {
+		if entry.Rebuild {
+			rebuilds = append(rebuilds, entry.MainFile)
+		}
+		if entry.MainFile == "docs" && (!entry.Virtual || !reflect.DeepEqual(entry.Files, []string{filepath.Join(root, "docs", "index.md")})) {
+			t.Errorf("unexpected docs entry: %+v", entry)
+		}
+	}
+	if want := []string{"app/main.go", "docs"}; !reflect.DeepEqual(rebuilds, want) {
+		t.Errorf("rebuilds = %v, want %v", rebuilds, want)
+	}
+
+	finder.RemoveVirtualHandler("docs")
+	if got := finder.VirtualHandlers(); !reflect.DeepEqual(got, []string{"infra"}) {
+		t.Errorf("VirtualHandlers() after removal = %v", got)
+	}
+}
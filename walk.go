@@ -0,0 +1,67 @@
+package depfind
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Direction selects the edges followed by Walk
+type Direction int
+
+const (
+	Forward Direction = iota // from a package to the packages it imports
+	Reverse                  // from a package to the packages importing it
+)
+
+// Walk visits the cached graph breadth-first from the package from, calling
+// fn once per package with its distance in imports (from itself at depth 0).
+// Returning false prunes the walk below that package; to stop early, return
+// false from then on: only the packages already queued are still visited.
+// Forward walks reach standard library and external packages as leaves.
+// Neighbors are visited in import path order; IncludeStdlib and
+// OnlyModuleLocal skip the packages they filter out. fn runs while the
+// finder lock is held and must not call back into the finder.
+func (g *GoDepFind) Walk(from string, direction Direction, fn func(pkg string, depth int) bool, opts ...Option) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+	var options queryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	edges, reverse := g.queryEdges(options)
+	switch direction {
+	case Forward:
+	case Reverse:
+		edges = reverse
+	default:
+		return fmt.Errorf("unknown walk direction %d", direction)
+	}
+	if _, ok := g.packageCache[from]; !ok {
+		if _, imported := g.reverseDeps[from]; !imported {
+			return fmt.Errorf("package not found in cache: %s", from)
+		}
+	}
+
+	depth := map[string]int{from: 0}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if !fn(current, depth[current]) {
+			continue
+		}
+		next := append([]string(nil), edges[current]...)
+		sort.Strings(next)
+		for _, pkg := range next {
+			if _, seen := depth[pkg]; !seen && !options.excludes(g.nodeKind(pkg)) {
+				depth[pkg] = depth[current] + 1
+				queue = append(queue, pkg)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package depfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":       "module wk\n\ngo 1.21\n",
+		"app/main.go":  "package main\n\nimport (\n\t\"wk/api\"\n\t\"wk/ui\"\n)\n\nfunc main() { api.Do(); ui.Do() }\n",
+		"api/api.go":   "package api\n\nimport \"wk/util\"\n\nfunc Do() { util.Do() }\n",
+		"ui/ui.go":     "package ui\n\nimport \"wk/util\"\n\nfunc Do() { util.Do() }\n",
+		"util/util.go": "package util\n\nimport \"strings\"\n\nfunc Do() { _ = strings.ToUpper(\"\") }\n",
+	})
+	finder := New(root)
+
+	type visit struct {
+		pkg   string
+		depth int
+	}
+	walk := func(from string, direction Direction, keep func(string) bool) []visit {
+		t.Helper()
+		var visits []visit
+		err := finder.Walk(from, direction, func(pkg string, depth int) bool {
+			visits = append(visits, visit{pkg, depth})
+			return keep(pkg)
+		})
+		if err != nil {
+			t.Fatalf("Walk(%s): %v", from, err)
+		}
+		return visits
+	}
+	all := func(string) bool { return true }
+
+	got := walk("wk/app", Forward, all)
+	want := []visit{{"wk/app", 0}, {"wk/api", 1}, {"wk/ui", 1}, {"wk/util", 2}, {"strings", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("forward walk = %v, want %v", got, want)
+	}
+
+	got = walk("wk/util", Reverse, all)
+	want = []visit{{"wk/util", 0}, {"wk/api", 1}, {"wk/ui", 1}, {"wk/app", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reverse walk = %v, want %v", got, want)
+	}
+
+	// Pruning api still reaches util through ui
+	got = walk("wk/app", Forward, func(pkg string) bool { return pkg != "wk/api" && pkg != "wk/util" })
+	want = []visit{{"wk/app", 0}, {"wk/api", 1}, {"wk/ui", 1}, {"wk/util", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pruned walk = %v, want %v", got, want)
+	}
+
+	if err := finder.Walk("wk/missing", Forward, func(string, int) bool { return true }); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+	if err := finder.Walk("wk/app", Direction(7), func(string, int) bool { return true }); err == nil {
+		t.Error("expected an error for an unknown direction")
+	}
+}
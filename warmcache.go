@@ -0,0 +1,65 @@
+package depfind
+
+import (
+	"context"
+)
+
+// warmup is the context and progress callback of a running WarmCache
+type warmup struct {
+	ctx      context.Context
+	progress func(done, total int)
+}
+
+// step reports that done of total packages are loaded, then whether the
+// warmup was canceled; a nil warmup (a lazy rebuild) always continues
+func (w *warmup) step(done, total int) error {
+	if w == nil {
+		return nil
+	}
+	if w.progress != nil {
+		w.progress(done, total)
+	}
+	return w.ctx.Err()
+}
+
+// WarmCache builds the cache now instead of on the first query, so a tool
+// can pay the scan at startup behind a progress bar rather than stall the
+// first file event. progress, which may be nil, is called with the number
+// of packages loaded so far and the number listed, from (0, total) to
+// (total, total); it runs with the finder locked and must not call it.
+// Canceling ctx stops the build between two packages and returns ctx.Err():
+// the cache stays unbuilt and the next query builds it lazily. The listing
+// of the packages itself is not interrupted. An already built cache is kept
+// and progress is not called, unless StrategyLazy left packages to load. As with Rebuild, a *MultiError lists the
+// packages that could not be loaded; the others are cached.
+func (g *GoDepFind) WarmCache(ctx context.Context, progress func(done, total int)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+	if g.cachedModule && len(g.lazyPending) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	g.warming = &warmup{ctx: ctx, progress: progress}
+	defer func() { g.warming = nil }()
+	if g.cachedModule {
+		// Built lazily: load the packages still missing
+		err := g.settleLazy()
+		if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+			return ctxErr
+		}
+		return err
+	}
+	err := g.rebuildCache()
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil && !isPartialFailure(err) {
+		return ctxErr
+	}
+	g.cachedModule = true
+	return err
+}
@@ -0,0 +1,65 @@
+package depfind
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWarmCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module wc\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"wc/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nimport \"wc/util\"\n\nfunc Do() { util.U() }\n",
+		"util/u.go":   "package util\n\nfunc U() {}\n",
+	})
+	libFile := filepath.Join(root, "lib", "lib.go")
+
+	finder := New(root)
+	var calls [][2]int
+	if err := finder.WarmCache(context.Background(), func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	}); err != nil {
+		t.Fatalf("WarmCache: %v", err)
+	}
+	if len(calls) < 2 || calls[0] != [2]int{0, calls[0][1]} || calls[len(calls)-1] != [2]int{calls[0][1], calls[0][1]} {
+		t.Fatalf("expected progress from (0, total) to (total, total), got %v", calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i][0] != calls[i-1][0]+1 {
+			t.Errorf("expected progress one package at a time, got %v", calls)
+		}
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned, got %v, %v", isMine, err)
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 1 {
+		t.Errorf("expected the warm cache to serve queries, got %d full rebuilds", rebuilds)
+	}
+
+	// A built cache is kept
+	called := false
+	if err := finder.WarmCache(context.Background(), func(int, int) { called = true }); err != nil || called {
+		t.Errorf("expected a no-op on a built cache, got %v (progress called: %v)", err, called)
+	}
+
+	// Canceling stops the build; the next query builds lazily
+	finder = New(root)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := finder.WarmCache(ctx, func(done, total int) {
+		if done == 1 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if isMine, err := finder.ThisFileIsMine("app/main.go", libFile, EventWrite); err != nil || !isMine {
+		t.Errorf("expected lib.go to be owned after a lazy build, got %v, %v", isMine, err)
+	}
+	if rebuilds := finder.Metrics().FullRebuilds; rebuilds != 2 {
+		t.Errorf("expected the canceled warmup then a lazy build, got %d full rebuilds", rebuilds)
+	}
+}
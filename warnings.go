@@ -0,0 +1,72 @@
+package depfind
+
+import "fmt"
+
+// maxWarnings bounds the warnings kept in memory; older ones are dropped
+const maxWarnings = 256
+
+// WarningKind classifies a tolerated error
+type WarningKind string
+
+const (
+	// WarnGoList is a message printed by "go list" on stderr
+	WarnGoList WarningKind = "go-list"
+	// WarnBuildConstraints marks a package whose files are all excluded by build constraints
+	WarnBuildConstraints WarningKind = "build-constraints"
+	// WarnPackageSkipped marks a package left out of the graph (see SkippedPackages)
+	WarnPackageSkipped WarningKind = "package-skipped"
+	// WarnListFailed marks a root that could not be listed while others could
+	WarnListFailed WarningKind = "list-failed"
+	// WarnCacheRebuild marks a failed cache build; queries fall back to path heuristics
+	WarnCacheRebuild WarningKind = "cache-rebuild"
+	// WarnFSError marks a file that could not be read during a lookup, see SetFSErrorPolicy
+	WarnFSError WarningKind = "fs-error"
+)
+
+// Warning is an error depfind tolerated instead of failing a query, explaining
+// why a package may be missing from results
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Package string      `json:"package,omitempty"`
+	Dir     string      `json:"dir,omitempty"`
+	Message string      `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Package != "" {
+		return fmt.Sprintf("%s: %s: %s", w.Kind, w.Package, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", w.Kind, w.Message)
+}
+
+// SetLogger streams every warning to logger as it is recorded. Pass nil to
+// stop streaming; warnings remain available through Warnings.
+func (g *GoDepFind) SetLogger(logger func(message ...any)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.logger = logger
+}
+
+// Warnings returns the warnings recorded since the last full cache rebuild,
+// oldest first
+func (g *GoDepFind) Warnings() []Warning {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+
+	return append([]Warning(nil), g.warnings...)
+}
+
+// warn records a warning and streams it to the logger, if any
+func (g *GoDepFind) warn(w Warning) {
+	g.listMu.Lock()
+	g.warnings = append(g.warnings, w)
+	if len(g.warnings) > maxWarnings {
+		g.warnings = g.warnings[len(g.warnings)-maxWarnings:]
+	}
+	g.listMu.Unlock()
+
+	if g.logger != nil {
+		g.logger("depfind warning:", w.String())
+	}
+}
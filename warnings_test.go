@@ -0,0 +1,49 @@
+package depfind
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWarningsCollected(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":        "module warn\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nfunc main() {}\n",
+		"wasmonly/w.go": "//go:build wasm\n\npackage wasmonly\n",
+		"broken/a.go":   "package a\n",
+		"broken/b.go":   "package b\n",
+	})
+	finder := New(root)
+
+	var logged []string
+	finder.SetLogger(func(message ...any) {
+		logged = append(logged, fmt.Sprint(message...))
+	})
+
+	if _, err := finder.GoFileComesFromMain("main.go"); err != nil {
+		t.Fatalf("GoFileComesFromMain: %v", err)
+	}
+	// "./..." silently omits wasm-only packages; naming one explicitly reports why it is missing
+	finder.FindReverseDeps("./wasmonly", []string{"fmt"})
+
+	kinds := make(map[WarningKind]string)
+	for _, w := range finder.Warnings() {
+		kinds[w.Kind] = w.Package
+	}
+	if kinds[WarnBuildConstraints] != "warn/wasmonly" {
+		t.Errorf("expected build constraint warning for warn/wasmonly, got %v", finder.Warnings())
+	}
+	if kinds[WarnPackageSkipped] != "warn/broken" {
+		t.Errorf("expected skipped warning for warn/broken, got %v", finder.Warnings())
+	}
+
+	if len(logged) != len(finder.Warnings()) {
+		t.Errorf("expected every warning to be logged, got %d logged for %d warnings", len(logged), len(finder.Warnings()))
+	}
+	for _, line := range logged {
+		if !strings.HasPrefix(line, "depfind warning:") {
+			t.Errorf("unexpected log line %q", line)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package depfind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoredDirNames are directories that never contain packages of the module
+// (tool metadata, JS dependencies, test fixtures, module vendoring)
+var ignoredDirNames = map[string]bool{
+	"node_modules": true,
+	"testdata":     true,
+	"vendor":       true,
+}
+
+// sourceExts are the extensions of files compiled into a package
+var sourceExts = map[string]bool{
+	".go":   true,
+	".s":    true,
+	".syso": true,
+}
+
+// moduleFileNames are non-Go files whose changes alter package resolution
+var moduleFileNames = map[string]bool{
+	"go.mod":  true,
+	"go.sum":  true,
+	"go.work": true,
+}
+
+// IsWatchRelevant cheaply reports whether path could ever matter to the
+// finder: it lies inside a root (and the scope), no path element is ignored
+// (hidden or "_" prefixed directories, node_modules, testdata, vendor,
+// excluded vendored trees, gitignored paths when SetRespectGitignore is on,
+// tool-managed directories, see SetSkipToolDirs)
+// and it is either a directory, a source file (.go, .s, .syso) or a module
+// file (go.mod, go.sum, go.work). It never loads or rebuilds the cache, so
+// watchers can use it to filter events before routing them through
+// ThisFileIsMine.
+func (g *GoDepFind) IsWatchRelevant(path string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if path == "" {
+		return false
+	}
+	absPath := g.rootPath(path)
+	if !g.inScope(absPath) || g.isExcluded(absPath) || g.gitIgnored(absPath) || g.inToolDir(absPath) {
+		return false
+	}
+
+	rel, ok := g.relToRoot(absPath)
+	if !ok {
+		return false
+	}
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			if strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_") || ignoredDirNames[part] {
+				return false
+			}
+		}
+	}
+
+	base := filepath.Base(absPath)
+	if sourceExts[filepath.Ext(base)] || moduleFileNames[base] {
+		return true
+	}
+	info, err := os.Stat(absPath)
+	return err == nil && info.IsDir()
+}
+
+// relToRoot returns absPath relative to the first root containing it
+func (g *GoDepFind) relToRoot(absPath string) (string, bool) {
+	for _, root := range g.rootDirs {
+		rel, err := filepath.Rel(root, absPath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,57 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWatchRelevant(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                      "module watch\n\ngo 1.21\n",
+		"app/main.go":                 "package main\n\nfunc main() {}\n",
+		"web/node_modules/x/index.js": "",
+		".git/HEAD":                   "",
+		"web/styles.css":              "",
+	})
+	finder := New(root)
+	outside := t.TempDir()
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"app/main.go", true},
+		{filepath.Join(root, "app", "main.go"), true},
+		{"app/new_file.go", true}, // not created yet
+		{"go.mod", true},
+		{"go.sum", true},
+		{"app", true},
+		{".", true},
+		{"web/styles.css", false},
+		{"web/node_modules/x/index.js", false},
+		{"web/node_modules/x/dep.go", false},
+		{".git/HEAD", false},
+		{"_scratch/main.go", false},
+		{"pkg/testdata/fixture.go", false},
+		{"vendor/github.com/x/y.go", false},
+		{filepath.Join(outside, "main.go"), false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := finder.IsWatchRelevant(tc.path); got != tc.want {
+			t.Errorf("IsWatchRelevant(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if len(finder.packageCache) != 0 || finder.cachedModule {
+		t.Error("expected IsWatchRelevant not to touch the cache")
+	}
+
+	scoped := finder.ScopedFinder("app")
+	if scoped.IsWatchRelevant("go.mod") {
+		t.Error("expected files outside the scope to be irrelevant")
+	}
+	if !scoped.IsWatchRelevant("app/main.go") {
+		t.Error("expected files inside the scope to be relevant")
+	}
+}
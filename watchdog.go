@@ -0,0 +1,225 @@
+package depfind
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchdogAction is what the watchdog did about a changed environment
+type WatchdogAction string
+
+const (
+	WatchdogNone      WatchdogAction = ""          // the environment is unchanged
+	WatchdogRefreshed WatchdogAction = "refreshed" // the cache was rebuilt for the new environment
+	WatchdogShutdown  WatchdogAction = "shutdown"  // a root disappeared: the finder was closed
+)
+
+// WatchdogStatus is the outcome of an environment check, see SetWatchdog
+type WatchdogStatus struct {
+	Checked time.Time      `json:"checked"`
+	Changes []string       `json:"changes,omitempty"` // what changed, e.g. "go.mod changed: /src/app/go.mod"
+	Action  WatchdogAction `json:"action,omitempty"`
+	Error   string         `json:"error,omitempty"` // why the refresh failed, if it did
+}
+
+// Healthy reports whether the environment was unchanged
+func (s WatchdogStatus) Healthy() bool {
+	return len(s.Changes) == 0
+}
+
+// environment is what answers depend on besides the sources: the go.mod and
+// go.work files of the roots, the go toolchain and the roots themselves
+type environment struct {
+	goVersion    string
+	moduleFiles  map[string]string // go.mod/go.work path -> sha256, "" when missing
+	missingRoots []string
+}
+
+// SetWatchdog makes a long-running finder verify its environment every
+// interval: the content of the go.mod and go.work files of the roots, the
+// version of the go command and the existence of the roots. When a branch
+// switch or a toolchain upgrade changed them, the cache is rebuilt
+// (WatchdogRefreshed); when a root is gone the finder is closed
+// (WatchdogShutdown) so queries fail with ErrClosed instead of serving stale
+// answers. onChange, which may be nil, receives the status of every check
+// that found a change; it runs on the watchdog goroutine. The baseline is the
+// environment when the watchdog starts. Zero stops the watchdog, as does
+// Close.
+func (g *GoDepFind) SetWatchdog(interval time.Duration, onChange func(WatchdogStatus)) {
+	g.mu.Lock()
+	if g.watchdogStop != nil {
+		close(g.watchdogStop)
+		g.watchdogStop = nil
+	}
+	if interval <= 0 || g.closed {
+		g.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	g.watchdogStop = stop
+	if !g.watchdogCloser {
+		g.watchdogCloser = true
+		g.onClose(func() error {
+			if g.watchdogStop != nil {
+				close(g.watchdogStop)
+				g.watchdogStop = nil
+			}
+			return nil
+		})
+	}
+	roots := append([]string(nil), g.rootDirs...)
+	g.mu.Unlock()
+
+	baseline := currentEnvironment(roots)
+	g.mu.Lock()
+	g.watchdogBaseline = baseline
+	g.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if status := g.CheckEnvironment(); !status.Healthy() && onChange != nil {
+					onChange(status)
+				}
+			}
+		}
+	}()
+}
+
+// CheckEnvironment runs a watchdog check now, see SetWatchdog. Without a
+// running watchdog the first check only records the baseline.
+func (g *GoDepFind) CheckEnvironment() WatchdogStatus {
+	g.mu.RLock()
+	roots := append([]string(nil), g.rootDirs...)
+	g.mu.RUnlock()
+	current := currentEnvironment(roots)
+
+	g.mu.Lock()
+	status := WatchdogStatus{Checked: time.Now()}
+	if g.closed {
+		status.Error = ErrClosed.Error()
+		g.watchdogStatus = status
+		g.mu.Unlock()
+		return status
+	}
+	if g.watchdogBaseline != nil {
+		status.Changes = g.watchdogBaseline.changes(current)
+	}
+	g.watchdogBaseline = current
+
+	switch {
+	case len(status.Changes) == 0:
+	case len(current.missingRoots) > 0:
+		status.Action = WatchdogShutdown
+		g.watchdogStatus = status
+		g.mu.Unlock()
+		if err := g.Close(); err != nil {
+			status.Error = err.Error()
+		}
+		return status
+	default:
+		status.Action = WatchdogRefreshed
+		g.cachedModule = false
+		if err := g.rebuildCache(); err != nil && !isPartialFailure(err) {
+			status.Error = err.Error()
+		}
+		g.cachedModule = true
+	}
+	g.watchdogStatus = status
+	g.mu.Unlock()
+	return status
+}
+
+// LastWatchdogStatus returns the status of the latest environment check,
+// zero before the first one
+func (g *GoDepFind) LastWatchdogStatus() WatchdogStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.watchdogStatus
+}
+
+// currentEnvironment fingerprints the environment of roots
+func currentEnvironment(roots []string) *environment {
+	env := &environment{goVersion: goCommandVersion("")}
+	var present []string
+	for _, root := range roots {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			env.missingRoots = append(env.missingRoots, root)
+			continue
+		}
+		present = append(present, root)
+	}
+	env.moduleFiles = moduleFileSums(present)
+	return env
+}
+
+// moduleFileSums returns the sha256 of the go.mod and go.work files of
+// roots, "" for a missing one
+func moduleFileSums(roots []string) map[string]string {
+	sums := make(map[string]string)
+	for _, root := range roots {
+		for _, name := range []string{"go.mod", "go.work"} {
+			path := filepath.Join(root, name)
+			sums[path] = ""
+			if data, err := os.ReadFile(path); err == nil {
+				sum := sha256.Sum256(data)
+				sums[path] = hex.EncodeToString(sum[:])
+			}
+		}
+	}
+	return sums
+}
+
+// changes describes how current differs from the baseline e, sorted
+func (e *environment) changes(current *environment) []string {
+	var changes []string
+	if e.goVersion != current.goVersion {
+		changes = append(changes, fmt.Sprintf("go version changed: %s -> %s", e.goVersion, current.goVersion))
+	}
+	for path, sum := range current.moduleFiles {
+		before, known := e.moduleFiles[path]
+		switch {
+		case !known || before == sum:
+		case before == "":
+			changes = append(changes, fmt.Sprintf("%s appeared: %s", filepath.Base(path), path))
+		case sum == "":
+			changes = append(changes, fmt.Sprintf("%s removed: %s", filepath.Base(path), path))
+		default:
+			changes = append(changes, fmt.Sprintf("%s changed: %s", filepath.Base(path), path))
+		}
+	}
+	for _, root := range current.missingRoots {
+		if !contains(e.missingRoots, root) {
+			changes = append(changes, "root missing: "+root)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// goCommandVersion returns the version of the go command on PATH ("go1.22.1")
+// as run from dir, where a go.mod may select another toolchain, "" when it
+// cannot be run
+func goCommandVersion(dir string) string {
+	var out bytes.Buffer
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
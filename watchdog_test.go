@@ -0,0 +1,83 @@
+package depfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckEnvironment(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module wd\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+
+	if status := finder.CheckEnvironment(); !status.Healthy() || status.Action != WatchdogNone {
+		t.Fatalf("expected the baseline check to be healthy, got %+v", status)
+	}
+	if status := finder.CheckEnvironment(); !status.Healthy() {
+		t.Fatalf("expected an unchanged environment, got %+v", status)
+	}
+
+	// A branch switch replaced go.mod: the cache is rebuilt for the new module
+	goMod := filepath.Join(root, "go.mod")
+	if err := os.WriteFile(goMod, []byte("module wd2\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status := finder.CheckEnvironment()
+	if want := []string{"go.mod changed: " + goMod}; !reflect.DeepEqual(status.Changes, want) || status.Action != WatchdogRefreshed {
+		t.Fatalf("expected a refresh for the new go.mod, got %+v", status)
+	}
+	if _, cached := finder.packageCache["wd2/app"]; !cached {
+		t.Error("expected the cache to be rebuilt under the new module path")
+	}
+	if finder.LastWatchdogStatus().Action != WatchdogRefreshed {
+		t.Errorf("LastWatchdogStatus() = %+v", finder.LastWatchdogStatus())
+	}
+
+	// The root is gone: the finder shuts down instead of answering
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	status = finder.CheckEnvironment()
+	if want := []string{"root missing: " + root}; !reflect.DeepEqual(status.Changes, want) || status.Action != WatchdogShutdown {
+		t.Fatalf("expected a shutdown, got %+v", status)
+	}
+	if _, err := finder.FindForwardDeps("wd2/app"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected queries to fail with ErrClosed, got %v", err)
+	}
+}
+
+func TestSetWatchdog(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":      "module wdt\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	defer finder.Close()
+
+	changes := make(chan WatchdogStatus, 1)
+	finder.SetWatchdog(10*time.Millisecond, func(s WatchdogStatus) {
+		select {
+		case changes <- s:
+		default:
+		}
+	})
+	if err := os.WriteFile(filepath.Join(root, "go.work"), []byte("go 1.21\n\nuse .\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case status := <-changes:
+		if want := []string{"go.work appeared: " + filepath.Join(root, "go.work")}; !reflect.DeepEqual(status.Changes, want) {
+			t.Errorf("unexpected changes: %+v", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the watchdog to report the new go.work")
+	}
+	finder.SetWatchdog(0, nil)
+}
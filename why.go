@@ -0,0 +1,93 @@
+package depfind
+
+import (
+	"strings"
+)
+
+// WhyChain is why one handler needs a package, in the spirit of go mod why
+type WhyChain struct {
+	Main    string   `json:"main"`            // handler main file, relative to the primary root
+	Package string   `json:"package"`         // package asked about
+	Chain   []string `json:"chain,omitempty"` // main package, then each import down to Package; empty when not needed
+}
+
+// String renders the chain in the go mod why format: a "# package" header,
+// then one package per line, or a parenthesized note when Main does not
+// need the package
+func (c WhyChain) String() string {
+	var b strings.Builder
+	b.WriteString("# " + c.Package)
+	if c.Main != "" {
+		b.WriteString(" (" + c.Main + ")")
+	}
+	b.WriteString("\n")
+	if len(c.Chain) == 0 {
+		main := c.Main
+		if main == "" {
+			main = "main module"
+		}
+		b.WriteString("(" + main + " does not need package " + c.Package + ")\n")
+		return b.String()
+	}
+	for _, pkg := range c.Chain {
+		b.WriteString(pkg + "\n")
+	}
+	return b.String()
+}
+
+// RenderWhy renders chains one after the other, separated by blank lines as
+// go mod why separates its answers
+func RenderWhy(chains []WhyChain) string {
+	blocks := make([]string, len(chains))
+	for i, c := range chains {
+		blocks[i] = c.String()
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// WhyChain renders the explanation as a go mod why answer: the import chain
+// from the handler main file for files owned through imports
+func (e Explanation) WhyChain() WhyChain {
+	c := WhyChain{Main: e.Handler, Package: e.Package}
+	if c.Package == "" {
+		c.Package = e.File
+	}
+	if e.Owned {
+		c.Chain = e.Chain
+		if len(c.Chain) == 0 {
+			c.Chain = []string{e.Handler} // owned without imports: the handler's own package or subtree
+		}
+	}
+	return c
+}
+
+// Why returns, for every handler (sorted by main file), the shortest import
+// chain from its main package to pkgPath, empty when the handler does not
+// need the package. RenderWhy prints them in the go mod why format.
+func (g *GoDepFind) Why(pkgPath string) ([]WhyChain, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+	handlers, err := g.discoverHandlers()
+	if err != nil {
+		return nil, err
+	}
+	chains := make([]WhyChain, 0, len(handlers))
+	for _, h := range handlers {
+		c := WhyChain{Main: h.MainFile, Package: pkgPath}
+		if h.Package == pkgPath {
+			c.Chain = []string{pkgPath}
+		} else if chain := g.importChain(h.MainFile, g.rootPath(h.MainFile), pkgPath); len(chain) > 0 {
+			mainPkg := h.Package
+			if mainPkg == "" {
+				mainPkg = h.MainFile
+			}
+			c.Chain = append([]string{mainPkg}, chain[1:]...)
+		}
+		chains = append(chains, c)
+	}
+	return chains, nil
+}
@@ -0,0 +1,58 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWhy(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":             "module why\n\ngo 1.21\n",
+		"cmd/server/main.go": "package main\n\nimport \"why/api\"\n\nfunc main() { api.Serve() }\n",
+		"cmd/tool/main.go":   "package main\n\nfunc main() {}\n",
+		"api/api.go":         "package api\n\nimport \"why/store\"\n\nfunc Serve() { store.Open() }\n",
+		"store/store.go":     "package store\n\nfunc Open() {}\n",
+	})
+	finder := New(root)
+
+	chains, err := finder.Why("why/store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# why/store (cmd/server/main.go)\n" +
+		"why/cmd/server\n" +
+		"why/api\n" +
+		"why/store\n" +
+		"\n" +
+		"# why/store (cmd/tool/main.go)\n" +
+		"(cmd/tool/main.go does not need package why/store)\n"
+	if got := RenderWhy(chains); got != want {
+		t.Errorf("RenderWhy =\n%s\nwant\n%s", got, want)
+	}
+
+	// A main package needs itself
+	chains, err = finder.Why("why/cmd/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 2 || len(chains[0].Chain) != 0 || len(chains[1].Chain) != 1 {
+		t.Errorf("Why(why/cmd/tool) = %+v", chains)
+	}
+
+	// Explanations render the same way
+	exp, err := finder.ExplainOwnership("cmd/server/main.go", filepath.Join(root, "store/store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantExp := "# why/store (cmd/server/main.go)\ncmd/server/main.go\nwhy/api\nwhy/store\n"
+	if got := exp.WhyChain().String(); got != wantExp {
+		t.Errorf("Explanation.WhyChain =\n%s\nwant\n%s", got, wantExp)
+	}
+	exp, err = finder.ExplainOwnership("cmd/tool/main.go", filepath.Join(root, "store/store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exp.WhyChain().String(); got != "# why/store (cmd/tool/main.go)\n(cmd/tool/main.go does not need package why/store)\n" {
+		t.Errorf("not needed explanation = %q", got)
+	}
+}
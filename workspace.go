@@ -0,0 +1,154 @@
+package depfind
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModulePackage is a package qualified by the module of the root providing
+// it, see FindReverseDepsInModules
+type ModulePackage struct {
+	Module  string `json:"module"` // module path of the root, its directory when it has no go.mod
+	Package string `json:"package"`
+}
+
+func (p ModulePackage) String() string {
+	return p.Module + ": " + p.Package
+}
+
+// FindReverseDepsInModules is FindReverseDeps fanned out over every root,
+// each a module of the workspace (New with several roots, AddReplaceRoots):
+// sourcePath is listed and searched in all of them concurrently, and the
+// packages importing a target are merged, qualified by their module and
+// sorted by module then package. "Who imports the shared logging module" is
+// then one call covering every member. A package listed from another
+// module's root (an import path pattern resolving into a sibling) is
+// reported once, under its own module. Roots that cannot be listed are
+// warned about; the call only fails when none can.
+func (g *GoDepFind) FindReverseDepsInModules(sourcePath string, targetPaths []string) ([]ModulePackage, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return nil, ErrClosed
+	}
+	targets, external, err := g.reverseDepTargets(targetPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	type moduleResult struct {
+		packages []ModulePackage
+		err      error
+	}
+	results := make([]moduleResult, len(g.rootDirs))
+	var wg sync.WaitGroup
+	for i, root := range g.rootDirs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			module := rootModulePath(root)
+			paths, err := g.listModulePackages(sourcePath, root)
+			if err != nil {
+				results[i].err = fmt.Errorf("%s: %w", module, err)
+				return
+			}
+			var own []string
+			for _, path := range paths {
+				if inModule(path, module) || !g.ownedByAnotherRoot(path, root) {
+					own = append(own, path)
+				}
+			}
+			results[i].err = g.scanReverseDeps(own, maps.Clone(targets), external.clone(), func(pkg string) bool {
+				results[i].packages = append(results[i].packages, ModulePackage{Module: module, Package: pkg})
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+
+	var found []ModulePackage
+	var errs []error
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.err != nil {
+			g.warn(Warning{Kind: WarnListFailed, Message: result.err.Error()})
+			errs = append(errs, result.err)
+			continue
+		}
+		for _, p := range result.packages {
+			if !seen[p.Package] {
+				seen[p.Package] = true
+				found = append(found, p)
+			}
+		}
+	}
+	if len(errs) == len(g.rootDirs) && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Module != found[j].Module {
+			return found[i].Module < found[j].Module
+		}
+		return found[i].Package < found[j].Package
+	})
+	return found, nil
+}
+
+// listModulePackages lists sourcePath in one root only
+func (g *GoDepFind) listModulePackages(sourcePath, root string) ([]string, error) {
+	path := g.scopedPattern(sourcePath)
+	if g.usesScanner() {
+		packages, err := g.listSyntheticPackages(path)
+		if err != nil {
+			return nil, err
+		}
+		var own []string
+		for _, pkgPath := range packages {
+			if dir, ok := g.packageDir(pkgPath); !ok || strings.HasPrefix(dir+string(filepath.Separator), root+string(filepath.Separator)) {
+				own = append(own, pkgPath)
+			}
+		}
+		return own, nil
+	}
+	if g.toolchainErr != nil {
+		return nil, g.toolchainErr
+	}
+	return g.listPackagesIn(path, []string{root})
+}
+
+// ownedByAnotherRoot reports whether a package listed from root lives in
+// another root, which reports it itself
+func (g *GoDepFind) ownedByAnotherRoot(pkgPath, root string) bool {
+	dir, ok := g.packageDir(pkgPath)
+	if !ok {
+		return false
+	}
+	for _, other := range g.rootDirs {
+		if other != root && (dir == other || strings.HasPrefix(dir, other+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootModulePath returns the module path declared by the go.mod governing
+// root, or root itself
+func rootModulePath(root string) string {
+	if modRoot := findModuleRoot(root); modRoot != "" {
+		if modulePath := readModulePath(filepath.Join(modRoot, "go.mod")); modulePath != "" {
+			return modulePath
+		}
+	}
+	return root
+}
+
+// inModule reports whether an import path belongs to a module path
+func inModule(pkgPath, module string) bool {
+	return pkgPath == module || strings.HasPrefix(pkgPath, module+"/")
+}
@@ -0,0 +1,45 @@
+package depfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindReverseDepsInModules(t *testing.T) {
+	ws := writeTree(t, map[string]string{
+		"shared/go.mod":            "module example.com/shared\n\ngo 1.21\n",
+		"shared/log/log.go":        "package log\n\nfunc Info() {}\n",
+		"shared/util/x.go":         "package util\n",
+		"app/go.mod":               "module example.com/app\n\ngo 1.21\n\nrequire example.com/shared v0.0.0\n\nreplace example.com/shared => ../shared\n",
+		"app/server/main.go":       "package main\n\nimport \"example.com/shared/log\"\n\nfunc main() { log.Info() }\n",
+		"app/other/other.go":       "package other\n",
+		"tool/go.mod":              "module example.com/tool\n\ngo 1.21\n\nrequire example.com/shared v0.0.0\n\nreplace example.com/shared => ../shared\n",
+		"tool/cmd/main.go":         "package main\n\nimport \"example.com/tool/internal/run\"\n\nfunc main() { run.Run() }\n",
+		"tool/internal/run/run.go": "package run\n\nimport \"example.com/shared/log\"\n\nfunc Run() { log.Info() }\n",
+	})
+	finder := New(filepath.Join(ws, "app"), filepath.Join(ws, "tool"), filepath.Join(ws, "shared"))
+
+	found, err := finder.FindReverseDepsInModules("./...", []string{"example.com/shared/log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModulePackage{
+		{Module: "example.com/app", Package: "example.com/app/server"},
+		{Module: "example.com/shared", Package: "example.com/shared/log"}, // targets in the source pattern are reported, as by FindReverseDeps
+		{Module: "example.com/tool", Package: "example.com/tool/cmd"},
+		{Module: "example.com/tool", Package: "example.com/tool/internal/run"},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("FindReverseDepsInModules =\n%v\nwant\n%v", found, want)
+	}
+
+	// An import path pattern is listed in every member but reported once
+	found, err = finder.FindReverseDepsInModules("example.com/shared/...", []string{"example.com/shared/log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ModulePackage{{Module: "example.com/shared", Package: "example.com/shared/log"}}; !reflect.DeepEqual(found, want) {
+		t.Errorf("import path pattern = %v, want %v", found, want)
+	}
+}
@@ -0,0 +1,63 @@
+package depfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalTestFilesAlwaysIndexed(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":              "module xt\n\ngo 1.21\n",
+		"app/main.go":         "package main\n\nimport \"xt/foo\"\n\nfunc main() { foo.Do() }\n",
+		"tool/main.go":        "package main\n\nimport \"xt/foo\"\n\nfunc main() { foo.Do() }\n",
+		"other/main.go":       "package main\n\nfunc main() {}\n",
+		"foo/foo.go":          "package foo\n\nfunc Do() {}\n",
+		"foo/foo_ext_test.go": "package foo_test\n\nimport (\n\t\"testing\"\n\n\t\"xt/foo\"\n)\n\nfunc TestDo(t *testing.T) { foo.Do() }\n",
+	})
+	xtestFile := filepath.Join(root, "foo", "foo_ext_test.go")
+
+	finder := New(root)
+	finder.SetHandlerClaimsTests("tool/main.go", true)
+
+	testOnly, err := finder.IsTestOnly(xtestFile)
+	if err != nil {
+		t.Fatalf("IsTestOnly: %v", err)
+	}
+	if !testOnly {
+		t.Fatal("expected the external test file to be indexed as test-only")
+	}
+	if pkg := finder.filePathToPackage[xtestFile]; pkg != "xt/foo" {
+		t.Errorf("expected the external test file to map to xt/foo, got %q", pkg)
+	}
+	if testOnly, _ := finder.IsTestOnly("foo/foo.go"); testOnly {
+		t.Error("expected foo/foo.go not to be test-only")
+	}
+
+	cases := []struct {
+		handler string
+		want    bool
+	}{
+		{"app/main.go", false},   // test imports disabled, no explicit claim
+		{"tool/main.go", true},   // explicitly claims tests
+		{"other/main.go", false}, // does not depend on foo
+	}
+	for _, tc := range cases {
+		isMine, err := finder.ThisFileIsMine(tc.handler, xtestFile, "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s): %v", tc.handler, err)
+		}
+		if isMine != tc.want {
+			t.Errorf("ThisFileIsMine(%s) = %v, want %v", tc.handler, isMine, tc.want)
+		}
+	}
+
+	// Enabling test imports makes claiming the default
+	finder.SetTestImports(true)
+	if isMine, err := finder.ThisFileIsMine("app/main.go", xtestFile, "write"); err != nil || !isMine {
+		t.Errorf("expected app/main.go to claim tests with test imports enabled, got %v, %v", isMine, err)
+	}
+	finder.SetHandlerClaimsTests("app/main.go", false)
+	if isMine, err := finder.ThisFileIsMine("app/main.go", xtestFile, "write"); err != nil || isMine {
+		t.Errorf("expected an explicit opt-out to win, got %v, %v", isMine, err)
+	}
+}